@@ -15,7 +15,10 @@ import (
 func main() {
 
 	orbconfig := flag.String("orbconfig", "~/.orb/config", "The orbconfig file to use")
-	verbose := flag.Bool("verbose", false, "Print debug levelled logs")
+	verbose := flag.Bool("verbose", false, "Print debug levelled logs, same as --log-level debug")
+	logLevel := flag.String("log-level", "info", "Minimal level of printed logs, one of debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Format of printed logs, text or json")
+	logOutput := flag.String("log-output", "stdout", "Where logs are written to, stdout, stderr or a file path")
 
 	flag.Parse()
 
@@ -26,7 +29,12 @@ func main() {
 	}
 
 	if *verbose {
-		monitor = monitor.Verbose()
+		*logLevel = "debug"
+	}
+
+	monitor, err := monitor.WithLogging(*logLevel, *logFormat, *logOutput)
+	if err != nil {
+		panic(err)
 	}
 
 	ensure := git.New(context.Background(), monitor.WithField("task", "ensure"), "Boom", "boom@caos.ch")
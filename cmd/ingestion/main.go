@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/caos/orbos/internal/ingestion/server"
+	"github.com/caos/orbos/mntr"
+)
+
+var gitCommit string
+var version string
+
+func main() {
+
+	monitor := mntr.Monitor{
+		OnInfo:         mntr.LogMessage,
+		OnChange:       mntr.LogMessage,
+		OnError:        mntr.LogError,
+		OnRecoverPanic: mntr.LogPanic,
+	}
+
+	defer monitor.RecoverPanic()
+
+	listen := flag.String("listen", ":8080", "Serve the gRPC ingestion API at this address")
+	queryListen := flag.String("query-listen", ":8081", "Serve the HTTP query API at this address")
+	database := flag.String("database", "ingestion.db", "Path to the database file the events are persisted to")
+	retention := flag.Duration("retention", 0, "Delete events older than this duration, 0 keeps all events")
+	tlsCert := flag.String("tls-cert", "", "Path to the PEM encoded TLS certificate. If empty, TLS is disabled")
+	tlsKey := flag.String("tls-key", "", "Path to the PEM encoded TLS key")
+	token := flag.String("token", os.Getenv("INGESTION_TOKEN"), "Token the clients must send as bearer token, defaults to the environment variable INGESTION_TOKEN")
+	verbose := flag.Bool("verbose", false, "Print logs for debugging, same as --log-level debug")
+	logLevel := flag.String("log-level", "info", "Minimal level of printed logs, one of debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Format of printed logs, text or json")
+	logOutput := flag.String("log-output", "stdout", "Where logs are written to, stdout, stderr or a file path")
+	printVersion := flag.Bool("version", false, "Print build information")
+
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Printf("%s %s\n", version, gitCommit)
+		os.Exit(0)
+	}
+
+	if *verbose {
+		*logLevel = "debug"
+	}
+
+	monitor, err := monitor.WithLogging(*logLevel, *logFormat, *logOutput)
+	if err != nil {
+		panic(err)
+	}
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		panic("flags --tls-cert and --tls-key must be passed together")
+	}
+
+	if *token == "" {
+		monitor.Warn("No token configured, everybody can push and query events")
+	}
+
+	store, err := server.Open(*database)
+	if err != nil {
+		panic(err)
+	}
+	defer store.Close()
+
+	if *retention > 0 {
+		go prune(monitor, store, *retention)
+	}
+
+	srv := server.New(monitor, store, *token)
+
+	var grpcOpts []grpc.ServerOption
+	var tlsConf *tls.Config
+	if *tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			panic(fmt.Errorf("loading tls key pair failed: %w", err))
+		}
+		tlsConf = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConf)))
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		panic(err)
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- srv.Register(grpcOpts...).Serve(lis)
+	}()
+
+	go func() {
+		query := &http.Server{Addr: *queryListen, Handler: srv.Handler(), TLSConfig: tlsConf}
+		if tlsConf != nil {
+			errs <- query.ListenAndServeTLS("", "")
+			return
+		}
+		errs <- query.ListenAndServe()
+	}()
+
+	monitor.WithFields(map[string]interface{}{
+		"version":  version,
+		"commit":   gitCommit,
+		"listen":   *listen,
+		"query":    *queryListen,
+		"database": *database,
+		"tls":      tlsConf != nil,
+	}).Info("Ingestion server is serving")
+
+	panic(<-errs)
+}
+
+func prune(monitor mntr.Monitor, store *server.Store, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for ; true; <-ticker.C {
+		pruned, err := store.Prune(time.Now().Add(-retention))
+		if err != nil {
+			monitor.Error(fmt.Errorf("pruning events failed: %w", err))
+			continue
+		}
+		if pruned > 0 {
+			monitor.WithField("events", pruned).Info("Pruned events")
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/caos/orbos/internal/events"
 	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/mntr"
 
@@ -31,7 +32,10 @@ func main() {
 		}
 	}()
 
-	verbose := flag.Bool("verbose", false, "Print logs for debugging")
+	verbose := flag.Bool("verbose", false, "Print logs for debugging, same as --log-level debug")
+	logLevel := flag.String("log-level", "info", "Minimal level of printed logs, one of debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Format of printed logs, text or json")
+	logOutput := flag.String("log-output", "stdout", "Where logs are written to, stdout, stderr or a file path")
 	printVersion := flag.Bool("version", false, "Print build information")
 	ignorePorts := flag.String("ignore-ports", "", "Comma separated list of firewall ports that are ignored")
 	nodeAgentID := flag.String("id", "", "The managed machines ID")
@@ -53,7 +57,12 @@ func main() {
 	}
 
 	if *verbose {
-		monitor = monitor.Verbose()
+		*logLevel = "debug"
+	}
+
+	monitor, err := monitor.WithLogging(*logLevel, *logFormat, *logOutput)
+	if err != nil {
+		panic(err)
 	}
 
 	monitor.WithFields(map[string]interface{}{
@@ -68,6 +77,20 @@ func main() {
 		panic(err)
 	}
 
+	ingestionConfig, err := nodeagent.Ingestion()
+	if err != nil {
+		panic(err)
+	}
+
+	if ingestionConfig != nil {
+		// The buffer survives reboots, so the rebooting event is pushed afterwards
+		queue, err := events.New(monitor, ingestionConfig, "", events.FileBuffer("/var/orbiter/events"))
+		if err != nil {
+			panic(err)
+		}
+		monitor = queue.Monitor(monitor.WithField("machine", *nodeAgentID), "nodeagent")
+	}
+
 	repoKey, err := nodeagent.RepoKey()
 	if err != nil {
 		panic(err)
@@ -78,6 +101,7 @@ func main() {
 	conv := conv.New(monitor, os, fmt.Sprintf("%x", hashed[:]))
 
 	gitClient := git.New(context.Background(), monitor, fmt.Sprintf("Node Agent %s", *nodeAgentID), "node-agent@caos.ch")
+	gitClient.SetDirectory("/var/orbiter/repo")
 
 	var portsSlice []string
 	if len(*ignorePorts) > 0 {
@@ -31,7 +31,7 @@ func APICommand(rv RootValues) *cobra.Command {
 			return err
 		}
 
-		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 			return err
 		}
 
@@ -45,7 +45,7 @@ func APICommand(rv RootValues) *cobra.Command {
 		}
 
 		if foundOrbiter {
-			_, _, _, migrate, desired, _, _, err := orbiter.Adapt(gitClient, monitor, make(chan struct{}), orb.AdaptFunc(
+			_, _, _, migrate, desired, _, _, err := orbiter.Adapt(gitClient, nil, monitor, make(chan struct{}), orb.AdaptFunc(
 				orbConfig,
 				gitCommit,
 				true,
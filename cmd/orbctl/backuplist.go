@@ -29,7 +29,7 @@ func BackupListCommand(rv RootValues) *cobra.Command {
 			return err
 		}
 
-		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 			return err
 		}
 
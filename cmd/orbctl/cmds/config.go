@@ -0,0 +1,41 @@
+package cmds
+
+import (
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/secret/operators"
+	"github.com/caos/orbos/mntr"
+)
+
+// EnsureConfig deploys the orbconfig for the operators to the cluster.
+// The operators identity and signing key are reused from the passed operators orbconfig, which is the one deployed
+// to a previous cluster of the orb, or from the orbconfig already deployed to this cluster.
+// The deployed operators orbconfig is returned for the next cluster
+func EnsureConfig(monitor mntr.Monitor, gitClient *git.Client, k8sClient *kubernetes.Client, orbConfig *orb.Orb, deployed *orb.Orb) (*orb.Orb, error) {
+
+	if deployed == nil {
+		var err error
+		if deployed, err = kubernetes.DeployedOrbConfig(k8sClient); err != nil {
+			return nil, err
+		}
+	}
+
+	var deployedIdentity, deployedSigningkey string
+	if deployed != nil {
+		deployedIdentity, deployedSigningkey = deployed.Identity, deployed.Signingkey
+	}
+
+	identity, err := operators.EnsureIdentity(monitor, gitClient, orbConfig, deployedIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	signingkey, err := operators.EnsureSigningKey(monitor, gitClient, orbConfig, deployedSigningkey)
+	if err != nil {
+		return nil, err
+	}
+
+	operatorsConfig := orbConfig.ForOperators(identity, signingkey)
+	return operatorsConfig, kubernetes.EnsureConfigArtifacts(monitor, k8sClient, operatorsConfig)
+}
@@ -26,12 +26,19 @@ func Takeoff(
 	version string,
 	gitCommit string,
 	kubeconfig string,
+	confirmHostKey func(hostKey *git.HostKey) bool,
 ) error {
 	if err := orbConfig.IsComplete(); err != nil {
 		return err
 	}
 
-	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+	// Orbs configured before host keys were pinned are migrated here, so the operators get the pinned host key too.
+	// As takeoff runs on a workstation, the host key is confirmed instead of trusted on first use
+	if err := orbConfig.ConfirmKnownHosts(monitor, confirmHostKey, orbConfig.WriteBackOrbConfig); err != nil {
+		return err
+	}
+
+	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 		return err
 	}
 
@@ -77,6 +84,7 @@ func Takeoff(
 		return nil
 	}
 
+	var operatorsConfig *orb.Orb
 	for _, kubeconfig := range allKubeconfigs {
 		k8sClient := kubernetes.NewK8sClient(monitor, &kubeconfig)
 		if k8sClient.Available() {
@@ -86,7 +94,7 @@ func Takeoff(
 			}
 			monitor.Info("Applied common resources")
 
-			if err := kubernetes.EnsureConfigArtifacts(monitor, k8sClient, orbConfig); err != nil {
+			if operatorsConfig, err = EnsureConfig(monitor, gitClient, k8sClient, orbConfig, operatorsConfig); err != nil {
 				monitor.Info("failed to apply configuration resources into k8s-cluster")
 				return err
 			}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 
 	boomapi "github.com/caos/orbos/internal/operator/boom/api"
+	zitadelOrb "github.com/caos/orbos/internal/operator/zitadel/kinds/orb"
 
+	"github.com/caos/orbos/cmd/orbctl/cmds"
 	"github.com/caos/orbos/internal/start"
 
 	"github.com/caos/orbos/internal/operator/orbiter"
@@ -18,7 +23,9 @@ import (
 	"github.com/caos/orbos/internal/stores/github"
 
 	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
+	orbconfig "github.com/caos/orbos/internal/orb"
 	"github.com/caos/orbos/internal/secret"
 	"github.com/spf13/cobra"
 )
@@ -29,6 +36,8 @@ func ConfigCommand(rv RootValues) *cobra.Command {
 		kubeconfig   string
 		newMasterKey string
 		newRepoURL   string
+		newIdentity  string
+		acceptHost   bool
 		cmd          = &cobra.Command{
 			Use:     "configure",
 			Short:   "Configures and reconfigures an orb",
@@ -41,6 +50,8 @@ func ConfigCommand(rv RootValues) *cobra.Command {
 	flags.StringVar(&kubeconfig, "kubeconfig", "", "Needed in boom-only scenarios")
 	flags.StringVar(&newMasterKey, "masterkey", "", "Reencrypts all secrets")
 	flags.StringVar(&newRepoURL, "repourl", "", "Configures the repository URL")
+	flags.StringVar(&newIdentity, "identity", "", "Configures the age identity for decrypting secrets encrypted to recipients")
+	flags.BoolVar(&acceptHost, "accept-host-key", false, "Pins the SSH host key of the repository server without asking for confirmation")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 		ctx, monitor, orbConfig, gitClient, errFunc, err := rv()
@@ -60,8 +71,8 @@ func ConfigCommand(rv RootValues) *cobra.Command {
 			return fmt.Errorf("repository url %s is not reconfigurable", orbConfig.URL)
 		}
 
-		if orbConfig.Masterkey == "" && newMasterKey == "" {
-			return errors.New("master key is neighter passed by flag masterkey nor written in orbconfig")
+		if orbConfig.Masterkey == "" && newMasterKey == "" && orbConfig.Identity == "" && newIdentity == "" {
+			return errors.New("neighter a master key nor an identity is passed by flag or written in orbconfig")
 		}
 
 		var changes bool
@@ -73,14 +84,40 @@ func ConfigCommand(rv RootValues) *cobra.Command {
 			orbConfig.Masterkey = newMasterKey
 			changes = true
 		}
+		if newIdentity != "" {
+			monitor.Info("Changing identity in current orbconfig")
+			secret.Identity = newIdentity
+			orbConfig.Identity = newIdentity
+			changes = true
+		}
 		if newRepoURL != "" {
 			monitor.Info("Changing repository url in current orbconfig")
 			orbConfig.URL = newRepoURL
 			changes = true
 		}
 
+		if !git.IsHTTP(orbConfig.URL) && orbConfig.KnownHosts == "" {
+			hostKey, err := git.ScanHostKey(orbConfig.URL)
+			if err != nil {
+				return err
+			}
+			if !acceptHost && !confirmHostKey(hostKey) {
+				return errors.New("host key of the repository server is not trusted")
+			}
+			monitor.Info("Pinning host key of the repository server in current orbconfig")
+			orbConfig.KnownHosts = hostKey.KnownHostsLine
+			changes = true
+		}
+
 		configureGit := func() error {
-			return gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey))
+			return gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts))
+		}
+
+		// Deploy keys are only generated for SSH repositories
+		if git.IsHTTP(orbConfig.URL) {
+			if err := configureGit(); err != nil {
+				return fmt.Errorf("write an access token as repokey to the orbconfig: %w", err)
+			}
 		}
 
 		// If the repokey already has read/write permissions, don't generate a new one.
@@ -144,7 +181,7 @@ func ConfigCommand(rv RootValues) *cobra.Command {
 
 		if foundOrbiter {
 
-			_, _, configure, _, desired, _, _, err := orbiter.Adapt(gitClient, monitor, make(chan struct{}), orb.AdaptFunc(
+			_, _, configure, _, desired, _, _, err := orbiter.Adapt(gitClient, nil, monitor, make(chan struct{}), orb.AdaptFunc(
 				orbConfig,
 				gitCommit,
 				true,
@@ -215,11 +252,38 @@ func ConfigCommand(rv RootValues) *cobra.Command {
 			}
 		}
 
+		foundZitadel, err := api.ExistsZitadelYml(gitClient)
+		if err != nil {
+			return err
+		}
+		if foundZitadel {
+			monitor.Info("Repopulating zitadel secrets")
+
+			tree, err := api.ReadZitadelYml(gitClient)
+			if err != nil {
+				return err
+			}
+
+			if _, _, _, err := zitadelOrb.AdaptFunc("", "networking", "zitadel", "database", "backup")(monitor, tree, nil); err != nil {
+				return err
+			}
+
+			if err := secret.Rewrite(
+				monitor,
+				gitClient,
+				rewriteKey,
+				tree,
+				api.PushZitadelDesiredFunc); err != nil {
+				return err
+			}
+		}
+
+		var operatorsConfig *orbconfig.Orb
 		for _, kubeconfig := range allKubeconfigs {
 			k8sClient := kubernetes.NewK8sClient(monitor, &kubeconfig)
 			if k8sClient.Available() {
 				monitor.Info("Ensuring orbconfig in kubernetes cluster")
-				if err := kubernetes.EnsureConfigArtifacts(monitor, k8sClient, orbConfig); err != nil {
+				if operatorsConfig, err = cmds.EnsureConfig(monitor, gitClient, k8sClient, orbConfig, operatorsConfig); err != nil {
 					monitor.Error(errors.New("failed to apply configuration resources into k8s-cluster"))
 					return err
 				}
@@ -234,3 +298,13 @@ func ConfigCommand(rv RootValues) *cobra.Command {
 	}
 	return cmd
 }
+
+func confirmHostKey(hostKey *git.HostKey) bool {
+	fmt.Printf("The repository server presents the host key with fingerprint %s\nDo you trust it? [y/N] ", hostKey.Fingerprint)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
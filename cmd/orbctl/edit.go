@@ -36,7 +36,7 @@ func EditCommand(rv RootValues) *cobra.Command {
 				return err
 			}
 
-			if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+			if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 				return err
 			}
 
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/events"
+	"github.com/caos/orbos/internal/ingestion"
+)
+
+func EventsCommand(rv RootValues) *cobra.Command {
+	var (
+		address string
+		allOrbs bool
+		orbURL  string
+		types   []string
+		since   string
+		until   string
+		limit   int
+		output  string
+		cmd     = &cobra.Command{
+			Use:   "events",
+			Short: "Print the events pushed to a self-hosted ingestion server",
+			Long: `Print the events the operators and node agents pushed to a self-hosted ingestion server, the latest event last.
+By default, only the events of the orb in your orbconfig are printed.
+The query API and the token are read from the ingestion section of your orbconfig`,
+			Args: cobra.NoArgs,
+			Example: `orbctl events --since 24h --type machine. --type node.joined
+orbctl events --all --output json`,
+		}
+	)
+
+	persistent := cmd.PersistentFlags()
+	persistent.StringVar(&address, "server", "", "URL of the ingestion servers query API, overwrites ingestion.query from the orbconfig")
+	persistent.StringVar(&output, "output", "text", "Output format, text or json")
+
+	flags := cmd.Flags()
+	flags.BoolVar(&allOrbs, "all", false, "Print the events of all orbs")
+	flags.StringVar(&orbURL, "orb", "", "Print the events of the orb with this repository URL instead of the orbconfigs one")
+	flags.StringArrayVar(&types, "type", nil, "Only print events of this type. A type ending with a dot matches all types with this prefix. Can be passed multiple times")
+	flags.StringVar(&since, "since", "", "Only print events created after this time, either RFC3339 or a duration like 24h")
+	flags.StringVar(&until, "until", "", "Only print events created before this time, either RFC3339 or a duration like 1h")
+	flags.IntVar(&limit, "limit", 100, "Maximum number of events to print, 0 prints all")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		ctx, _, orbConfig, _, errFunc, err := rv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errFunc(err)
+		}()
+
+		client, err := events.NewQueryClient(orbConfig.Ingestion, address)
+		if err != nil {
+			return err
+		}
+
+		filter := ingestion.Filter{
+			Orb:   orbConfig.URL,
+			Types: types,
+			Limit: limit,
+		}
+		if orbURL != "" {
+			filter.Orb = orbURL
+		}
+		if allOrbs {
+			filter.Orb = ""
+		}
+		if filter.Since, err = parseTime(since); err != nil {
+			return fmt.Errorf("parsing --since failed: %w", err)
+		}
+		if filter.Until, err = parseTime(until); err != nil {
+			return fmt.Errorf("parsing --until failed: %w", err)
+		}
+
+		evts, err := client.Events(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		if output == "json" {
+			return printJSON(evts)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		header := "TIME\tTYPE\tDATA"
+		if filter.Orb == "" {
+			header = "TIME\tORB\tTYPE\tDATA"
+		}
+		fmt.Fprintln(w, header)
+		for _, evt := range evts {
+			columns := []string{evt.Time.Local().Format("2006-01-02 15:04:05"), evt.Type, sprintData(evt.Data)}
+			if filter.Orb == "" {
+				columns = append(columns[:1], append([]string{evt.Orb}, columns[1:]...)...)
+			}
+			fmt.Fprintln(w, strings.Join(columns, "\t"))
+		}
+		return w.Flush()
+	}
+
+	cmd.AddCommand(eventsOrbsCommand(rv, &address, &output))
+	return cmd
+}
+
+func eventsOrbsCommand(rv RootValues, address, output *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "orbs",
+		Short: "Print an overview of all orbs that pushed events",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx, _, orbConfig, _, errFunc, err := rv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errFunc(err)
+			}()
+
+			client, err := events.NewQueryClient(orbConfig.Ingestion, *address)
+			if err != nil {
+				return err
+			}
+
+			orbs, err := client.Orbs(ctx)
+			if err != nil {
+				return err
+			}
+
+			if *output == "json" {
+				return printJSON(orbs)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ORB\tEVENTS\tLAST EVENT\tLAST TYPE")
+			for _, orb := range orbs {
+				var lastTime, lastType string
+				if orb.Last != nil {
+					lastTime = orb.Last.Time.Local().Format("2006-01-02 15:04:05")
+					lastType = orb.Last.Type
+				}
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", orb.Orb, orb.Events, lastTime, lastType)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// parseTime parses RFC3339 or a duration before now
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func printJSON(value interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+func sprintData(data map[string]interface{}) string {
+	fields := make(map[string]string, len(data))
+	for key, value := range data {
+		fields[key] = fmt.Sprintf("%v", value)
+	}
+	return sprintFields(fields)
+}
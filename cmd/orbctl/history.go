@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/secret/operators"
+)
+
+func HistoryCommand(rv RootValues) *cobra.Command {
+	var (
+		filter api.HistoryFilter
+		limit  int
+		cmd    = &cobra.Command{
+			Use:   "history",
+			Short: "Print the changes of the desired and current state",
+			Long: `Print the changes of orbiter.yml, boom.yml, zitadel.yml and caos-internal, the latest change first.
+Each line shows the commit, when and by whom it was made, the recorded event and its fields`,
+			Args:    cobra.NoArgs,
+			Example: `orbctl history --machine=gce-prod-masters-abc --limit=20`,
+		}
+	)
+
+	flags := cmd.Flags()
+	flags.StringVar(&filter.Machine, "machine", "", "Only print changes affecting the machine with this ID")
+	flags.StringVar(&filter.Operator, "operator", "", "Only print changes affecting this operator: orbiter, nodeagent, boom or zitadel")
+	flags.IntVar(&limit, "limit", 0, "Maximum number of changes to print, 0 prints all")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		_, _, orbConfig, gitClient, errFunc, err := rv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errFunc(err)
+		}()
+
+		if err := cloneHistory(orbConfig, gitClient); err != nil {
+			return err
+		}
+
+		entries, err := api.History(gitClient, filter)
+		if err != nil {
+			return err
+		}
+
+		if limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COMMIT\tDATE\tAUTHOR\tOPERATORS\tEVENT\tFIELDS")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				entry.Commit.ShortHash(),
+				entry.Commit.When.Format("2006-01-02 15:04:05"),
+				entry.Commit.Author,
+				strings.Join(entry.Operators, ","),
+				entry.Event,
+				sprintFields(entry.Fields),
+			)
+		}
+		return w.Flush()
+	}
+	return cmd
+}
+
+func sprintFields(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for idx, key := range keys {
+		pairs[idx] = fmt.Sprintf("%s=%q", key, fields[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+func RollbackCommand(rv RootValues) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rollback <commit> [file]",
+		Short: "Restore the desired state of a previous commit",
+		Long: `Restore orbiter.yml, boom.yml and zitadel.yml as they were at the passed commit and push them as a new commit.
+If a file is passed, only this file is restored.
+The secrets are decrypted with your current orbconfig and reencrypted to the current recipients.
+So your current master key or identity must have been able to decrypt them at the passed commit.
+If the master key was rotated since, roll back with an orbconfig containing the previous master key`,
+		Args:    cobra.RangeArgs(1, 2),
+		Example: `orbctl rollback 3f2a9c1 orbiter.yml`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			_, monitor, orbConfig, gitClient, errFunc, err := rv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errFunc(err)
+			}()
+
+			var restore []string
+			if len(args) > 1 {
+				operator := strings.TrimSuffix(args[1], ".yml")
+				if !contains(api.DesiredFiles(), operator+".yml") {
+					return fmt.Errorf("%s is no desired state file, use one of %s", args[1], strings.Join(api.DesiredFiles(), ", "))
+				}
+				restore = []string{operator}
+			}
+
+			if err := cloneHistory(orbConfig, gitClient); err != nil {
+				return err
+			}
+
+			return secret.Rollback(monitor, gitClient, args[0], restore, operators.GetAllSecretsFunc(orbConfig), operators.PushAllFunc())
+		},
+	}
+}
@@ -18,7 +18,7 @@ func machines(monitor mntr.Monitor, gitClient *git.Client, orbConfig *cfg.Orb, d
 		return err
 	}
 
-	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 		return err
 	}
 
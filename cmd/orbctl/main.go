@@ -50,6 +50,21 @@ func main() {
 		ListCommand(rootValues),
 	)
 
+	recipients := RecipientsCommand()
+	recipients.AddCommand(
+		RecipientsListCommand(rootValues),
+		RecipientsAddCommand(rootValues),
+		RecipientsRemoveCommand(rootValues),
+		RecipientsGenerateCommand(),
+	)
+
+	secrets := SecretsCommand()
+	secrets.AddCommand(
+		SecretsListCommand(rootValues),
+		SecretsDiffCommand(rootValues),
+		SecretsRotateCommand(rootValues),
+	)
+
 	rootCmd.AddCommand(
 		ReadSecretCommand(rootValues),
 		WriteSecretCommand(rootValues),
@@ -60,8 +75,16 @@ func main() {
 		BackupListCommand(rootValues),
 		RestoreCommand(rootValues),
 		BackupCommand(rootValues),
+		HistoryCommand(rootValues),
+		RollbackCommand(rootValues),
+		ValidateCommand(rootValues),
+		EventsCommand(rootValues),
+		MaintenanceCommand(rootValues),
+		SchemaCommand(),
 		takeoff,
 		nodes,
+		recipients,
+		secrets,
 	)
 
 	if err := rootCmd.Execute(); err != nil {
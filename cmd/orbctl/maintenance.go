@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/orb"
+	"github.com/caos/orbos/internal/tree"
+)
+
+func MaintenanceCommand(rv RootValues) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Control the maintenance windows of your kubernetes clusters",
+	}
+	cmd.AddCommand(
+		maintenanceForceCommand(rv),
+		maintenanceResetCommand(rv),
+	)
+	return cmd
+}
+
+func maintenanceForceCommand(rv RootValues) *cobra.Command {
+	var (
+		duration time.Duration
+		cmd      = &cobra.Command{
+			Use:   "force",
+			Short: "Execute postponed disruptive actions immediately",
+			Long:  "The Orbiter executes disruptive actions like reboots, replacements, downscaling and upgrades regardless of the maintenance windows for the passed duration",
+			Args:  cobra.NoArgs,
+		}
+	)
+
+	cmd.Flags().DurationVar(&duration, "for", 2*time.Hour, "Ignore the maintenance windows for this duration")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		if duration <= 0 {
+			return fmt.Errorf("--for must be positive")
+		}
+		until := time.Now().Add(duration)
+		return forceMaintenance(rv, until, fmt.Sprintf("Maintenance forced until %s", until.UTC().Format(time.RFC3339)))
+	}
+	return cmd
+}
+
+func maintenanceResetCommand(rv RootValues) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reset",
+		Short: "Respect the maintenance windows again",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			return forceMaintenance(rv, time.Time{}, "Maintenance override removed")
+		},
+	}
+}
+
+func forceMaintenance(rv RootValues, until time.Time, msg string) (err error) {
+	_, monitor, orbConfig, gitClient, errFunc, err := rv()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errFunc(err)
+	}()
+
+	return machines(monitor, gitClient, orbConfig, func(_ []string, _ map[string]infra.Machine, desired *tree.Tree) error {
+		orbDesired, ok := desired.Parsed.(*orb.DesiredV0)
+		if !ok {
+			return fmt.Errorf("%s is not adapted", desired.Common.Kind)
+		}
+
+		for name, cluster := range orbDesired.Clusters {
+			if cluster.Common.Kind != "orbiter.caos.ch/KubernetesCluster" {
+				continue
+			}
+			if err := kubernetes.ForceMaintenance(cluster, until); err != nil {
+				return fmt.Errorf("updating maintenance of cluster %s failed: %w", name, err)
+			}
+		}
+
+		monitor.Info(msg)
+		return api.PushOrbiterYml(monitor, msg, gitClient, desired)
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/orb"
+	orbconfig "github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/mntr"
+)
+
+func printPlan(monitor mntr.Monitor, orbConfig *orbconfig.Orb, gitClient *git.Client, output string) error {
+
+	if err := cloneRepository(orbConfig, gitClient); err != nil {
+		return err
+	}
+
+	plan, err := orbiter.Planned(monitor, gitClient, orb.AdaptFunc(
+		orbConfig,
+		gitCommit,
+		true,
+		false,
+		gitClient,
+	))
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+
+	if plan.IsEmpty() {
+		fmt.Println("No changes planned")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tTARGET\tACTION\tDETAILS")
+	for _, machine := range plan.Machines {
+		target := machine.Provider + "/" + machine.Pool
+		if machine.Machine != "" {
+			target += "/" + machine.Machine
+		}
+		fmt.Fprintf(w, "machine\t%s\t%s\t%s\n", target, machine.Action, machine.Reason)
+	}
+	for _, node := range plan.Nodes {
+		fmt.Fprintf(w, "node\t%s\t%s\t%s\n", node.Machine, node.Action, node.Reason)
+	}
+	for _, version := range plan.Versions {
+		fmt.Fprintf(w, "kubernetes\t%s\tupgrade\t%s -> %s (target %s)\n", version.Cluster, version.From, version.To, version.Target)
+	}
+	for _, software := range plan.Software {
+		fmt.Fprintf(w, "software\t%s\t%s\t%s -> %s\n", software.Machine, software.Package, software.From, software.To)
+	}
+	for _, firewall := range plan.Firewall {
+		target := firewall.Machine
+		if target == "" {
+			target = firewall.Provider
+		}
+		fmt.Fprintf(w, "firewall\t%s\t%s\t%s %s\n", target, firewall.Action, firewall.Zone, firewall.Rule)
+	}
+	for _, lb := range plan.LoadBalancers {
+		target := lb.Machine
+		if target == "" {
+			target = lb.Provider
+		}
+		details := lb.Name
+		if lb.Count > 0 {
+			details = fmt.Sprintf("%d %s", lb.Count, lb.Name)
+		}
+		fmt.Fprintf(w, "loadbalancer\t%s\t%s\t%s\n", target, lb.Action, details)
+	}
+	return w.Flush()
+}
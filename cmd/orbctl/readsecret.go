@@ -1,8 +1,11 @@
 package main
 
 import (
-	"github.com/caos/orbos/internal/secret/operators"
+	"fmt"
 	"os"
+	"sort"
+
+	"github.com/caos/orbos/internal/secret/operators"
 
 	"github.com/caos/orbos/internal/secret"
 
@@ -11,51 +14,89 @@ import (
 
 func ReadSecretCommand(rv RootValues) *cobra.Command {
 
-	return &cobra.Command{
-		Use:     "readsecret [path]",
-		Short:   "Print a secrets decrypted value to stdout",
-		Long:    "Print a secrets decrypted value to stdout.\nIf no path is provided, a secret can interactively be chosen from a list of all possible secrets",
-		Args:    cobra.MaximumNArgs(1),
-		Example: `orbctl readsecret orbiter.k8s.kubeconfig > ~/.kube/config`,
-		RunE: func(cmd *cobra.Command, args []string) (err error) {
+	var (
+		source bool
+		cmd    = &cobra.Command{
+			Use:   "readsecret [path]",
+			Short: "Print a secrets decrypted value to stdout",
+			Long:  "Print a secrets decrypted value to stdout.\nIf no path is provided, a secret can interactively be chosen from a list of all possible secrets",
+			Args:  cobra.MaximumNArgs(1),
+			Example: `orbctl readsecret orbiter.k8s.kubeconfig > ~/.kube/config
+orbctl readsecret --source`,
+		}
+	)
 
-			_, monitor, orbConfig, gitClient, errFunc, err := rv()
-			if err != nil {
-				return err
-			}
-			defer func() {
-				err = errFunc(err)
-			}()
+	flags := cmd.Flags()
+	flags.BoolVar(&source, "source", false, "Print where the value is read from instead of the value. If no path is provided, the sources of all secrets are printed")
 
-			if err := orbConfig.IsComplete(); err != nil {
-				return err
-			}
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 
-			if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
-				return err
-			}
+		_, monitor, orbConfig, gitClient, errFunc, err := rv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errFunc(err)
+		}()
 
-			if err := gitClient.Clone(); err != nil {
-				return err
-			}
+		if err := orbConfig.IsComplete(); err != nil {
+			return err
+		}
 
-			path := ""
-			if len(args) > 0 {
-				path = args[0]
-			}
+		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
+			return err
+		}
+
+		if err := gitClient.Clone(); err != nil {
+			return err
+		}
+
+		path := ""
+		if len(args) > 0 {
+			path = args[0]
+		}
 
-			value, err := secret.Read(
+		if source {
+			sources, err := secret.Sources(
 				monitor,
 				gitClient,
-				path,
 				operators.GetAllSecretsFunc(orbConfig))
 			if err != nil {
 				return err
 			}
-			if _, err := os.Stdout.Write([]byte(value)); err != nil {
-				panic(err)
+
+			if path != "" {
+				src, ok := sources[path]
+				if !ok {
+					return fmt.Errorf("Secret %s not found", path)
+				}
+				fmt.Println(src)
+				return nil
+			}
+
+			paths := make([]string, 0, len(sources))
+			for p := range sources {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			for _, p := range paths {
+				fmt.Printf("%s\t%s\n", p, sources[p])
 			}
 			return nil
-		},
+		}
+
+		value, err := secret.Read(
+			monitor,
+			gitClient,
+			path,
+			operators.GetAllSecretsFunc(orbConfig))
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stdout.Write([]byte(value)); err != nil {
+			panic(err)
+		}
+		return nil
 	}
+	return cmd
 }
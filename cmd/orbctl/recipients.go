@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/secret/operators"
+	"github.com/caos/orbos/internal/start"
+	"github.com/caos/orbos/mntr"
+)
+
+func RecipientsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "recipients",
+		Short: "Manage who is able to decrypt the orbs secrets",
+		Long: `Manage who is able to decrypt the orbs secrets.
+As long as no recipients are declared in the repository, all secrets are encrypted with the master key.
+As soon as recipients are declared, all secrets are encrypted to their age public keys
+and everybody decrypts them with the identity from its own orbconfig.
+The operators get their own identity, which orbctl takeoff and orbctl configure declare as recipient orbos-operators.`,
+		Example: `orbctl recipients <list|add|remove|generate>`,
+	}
+}
+
+func RecipientsListCommand(rv RootValues) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all recipients declared in the repository",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			_, _, orbConfig, gitClient, errFunc, err := rv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errFunc(err)
+			}()
+
+			if err := cloneRepository(orbConfig, gitClient); err != nil {
+				return err
+			}
+
+			recipients, err := secret.ReadRecipients(gitClient)
+			if err != nil {
+				return err
+			}
+
+			for _, recipient := range recipients.Recipients {
+				fmt.Printf("%s\t%s\n", recipient.Name, recipient.PublicKey)
+			}
+			return nil
+		},
+	}
+}
+
+func RecipientsAddCommand(rv RootValues) *cobra.Command {
+	var kubeconfig string
+
+	cmd := &cobra.Command{
+		Use:   "add [name] [publickey]",
+		Short: "Add a recipient and reencrypt all secrets",
+		Long: `Add a recipient and reencrypt all secrets in orbiter.yml, boom.yml and zitadel.yml to all recipients.
+The identity in your orbconfig must belong to one of the recipients afterwards.
+When the first recipient is added, the operators get their own identity, which is deployed to all clusters beforehand.`,
+		Args:    cobra.ExactArgs(2),
+		Example: `orbctl recipients add alice age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			_, monitor, orbConfig, gitClient, errFunc, err := rv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errFunc(err)
+			}()
+
+			if err := cloneRepository(orbConfig, gitClient); err != nil {
+				return err
+			}
+
+			return operators.AddRecipient(
+				monitor,
+				gitClient,
+				orbConfig,
+				args[0],
+				args[1],
+				func(identity string) error {
+					return deployOperatorsIdentity(monitor, gitClient, orbConfig, kubeconfig, identity)
+				})
+		},
+	}
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Needed in boom-only scenarios")
+	return cmd
+}
+
+// deployOperatorsIdentity writes the identity to the orbconfigs deployed to all clusters.
+// If a cluster is not reachable, its operators wouldn't be able to decrypt the secrets, so an error is returned
+func deployOperatorsIdentity(monitor mntr.Monitor, gitClient *git.Client, orbConfig *orb.Orb, kubeconfig, identity string) error {
+
+	foundOrbiter, err := api.ExistsOrbiterYml(gitClient)
+	if err != nil {
+		return err
+	}
+
+	var kubeconfigs []string
+	if foundOrbiter {
+		if kubeconfigs, err = start.GetKubeconfigs(monitor, gitClient, orbConfig); err != nil {
+			return err
+		}
+	} else {
+		if kubeconfig == "" {
+			return errors.New("no orbiter.yml existent, pass the clusters kubeconfig as parameter")
+		}
+		value, err := ioutil.ReadFile(kubeconfig)
+		if err != nil {
+			return err
+		}
+		kubeconfigs = append(kubeconfigs, string(value))
+	}
+
+	for _, kubeconfig := range kubeconfigs {
+		k8sClient := kubernetes.NewK8sClient(monitor, &kubeconfig)
+		if !k8sClient.Available() {
+			return errors.New("no connection to the k8s-cluster possible")
+		}
+
+		deployed, err := kubernetes.DeployedOrbConfig(k8sClient)
+		if err != nil {
+			return err
+		}
+		var signingkey string
+		if deployed != nil {
+			signingkey = deployed.Signingkey
+		}
+
+		if err := kubernetes.EnsureConfigArtifacts(monitor, k8sClient, orbConfig.ForOperators(identity, signingkey)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func RecipientsRemoveCommand(rv RootValues) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a recipient and reencrypt all secrets",
+		Long: `Remove a recipient and reencrypt all secrets in orbiter.yml, boom.yml and zitadel.yml to the remaining recipients.
+If no recipient remains, all secrets are encrypted with the master key again.
+As the removed recipient is still able to decrypt older commits, you should rotate the secret values.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			_, monitor, orbConfig, gitClient, errFunc, err := rv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errFunc(err)
+			}()
+
+			if err := cloneRepository(orbConfig, gitClient); err != nil {
+				return err
+			}
+
+			return secret.RemoveRecipient(
+				monitor,
+				gitClient,
+				args[0],
+				operators.GetAllSecretsFunc(orbConfig),
+				operators.PushAllFunc())
+		},
+	}
+}
+
+func RecipientsGenerateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a new identity",
+		Long:  "Generate a new identity. Write the identity to your orbconfig and let somebody add the public key as recipient",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			identity, publicKey, err := secret.GenerateIdentity()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("identity: %s\npublickey: %s\n", identity, publicKey)
+			return nil
+		},
+	}
+}
+
+func cloneRepository(orbConfig *orb.Orb, gitClient *git.Client) error {
+	if err := orbConfig.IsComplete(); err != nil {
+		return err
+	}
+
+	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
+		return err
+	}
+
+	return gitClient.Clone()
+}
+
+func cloneHistory(orbConfig *orb.Orb, gitClient *git.Client) error {
+	if err := orbConfig.IsComplete(); err != nil {
+		return err
+	}
+
+	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
+		return err
+	}
+
+	return gitClient.CloneHistory()
+}
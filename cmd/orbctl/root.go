@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"os"
+	"time"
 
 	"github.com/caos/orbos/internal/helpers"
 
@@ -22,6 +24,10 @@ func RootCommand() (*cobra.Command, RootValues) {
 	var (
 		verbose       bool
 		orbConfigPath string
+		logLevel      string
+		logFormat     string
+		logOutputs    []string
+		otlpEndpoint  string
 	)
 
 	cmd := &cobra.Command{
@@ -43,12 +49,30 @@ $ orbctl -f ~/.orb/myorb [command]
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&orbConfigPath, "orbconfig", "f", "~/.orb/config", "Path to the file containing the orbs git repo URL, deploy key and the master key for encrypting and decrypting secrets")
-	flags.BoolVar(&verbose, "verbose", false, "Print debug levelled logs")
+	flags.BoolVar(&verbose, "verbose", false, "Print debug levelled logs, same as --log-level debug")
+	flags.StringVar(&logLevel, "log-level", "info", "Minimal level of printed logs, one of debug, info, warn or error")
+	flags.StringVar(&logFormat, "log-format", "text", "Format of printed logs, text or json")
+	flags.StringArrayVar(&logOutputs, "log-output", []string{"stdout"}, "Where logs are written to, stdout, stderr or a file path. Can be passed multiple times")
+	flags.StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OpenTelemetry collector to export traces to using OTLP over HTTP, for example http://localhost:4318")
 
 	return cmd, func() (context.Context, mntr.Monitor, *orb.Orb, *git.Client, errFunc, error) {
 
 		if verbose {
-			monitor = monitor.Verbose()
+			logLevel = "debug"
+		}
+
+		var err error
+		monitor, err = monitor.WithLogging(logLevel, logFormat, logOutputs...)
+		if err != nil {
+			return nil, mntr.Monitor{}, nil, nil, nil, err
+		}
+
+		var exporter *mntr.OTLPExporter
+		if otlpEndpoint != "" {
+			exporter = mntr.NewOTLPExporter(otlpEndpoint, "orbos", 5*time.Second)
+			// git clients and SSH machines capture their monitors at construction,
+			// so their spans become children of the iteration, query and ensure spans by the scope
+			monitor = monitor.WithSpanExporter(exporter).WithSpanScope()
 		}
 
 		prunedPath := helpers.PruneHome(orbConfigPath)
@@ -60,10 +84,18 @@ $ orbctl -f ~/.orb/myorb [command]
 
 		ctx := context.Background()
 
-		return ctx, monitor, orbConfig, git.New(ctx, monitor, "orbos", "orbos@caos.ch"), func(err error) error {
+		gitClient := git.New(ctx, monitor, "orbos", "orbos@caos.ch")
+		if err := gitClient.ConfigureSigning([]byte(orbConfig.Signingkey)); err != nil {
+			return nil, mntr.Monitor{}, nil, nil, nil, err
+		}
+
+		return ctx, monitor, orbConfig, gitClient, func(err error) error {
 			if err != nil {
 				monitor.Error(err)
 			}
+			if exporter != nil {
+				monitor.Error(exporter.Flush())
+			}
 			return nil
 		}, nil
 	}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/orb"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/secret/operators"
+)
+
+// rotatableSecrets are generated by ORBOS and are regenerated when they are empty
+var rotatableSecrets = []string{
+	"maintenancekeyprivate",
+	"maintenancekeypublic",
+	"sshkeyprivate",
+	"sshkeypublic",
+}
+
+func SecretsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "secrets",
+		Short:   "Audit and rotate all secrets of an orb",
+		Example: `orbctl secrets <list|diff|rotate>`,
+	}
+}
+
+func SecretsListCommand(rv RootValues) *cobra.Command {
+	var (
+		maxCommits int
+		cmd        = &cobra.Command{
+			Use:   "list",
+			Short: "List all secrets, where they are read from and which commit changed them the last time",
+			Long: `List all secrets of boom.yml, orbiter.yml and zitadel.yml, where they are read from and which commit changed them the last time.
+The values are not printed. If the history can't be searched any further, the commit is prefixed with <=`,
+			Args: cobra.NoArgs,
+		}
+	)
+
+	cmd.Flags().IntVar(&maxCommits, "max-commits", 100, "Maximum number of commits to search for changes, 0 searches the whole history")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		_, monitor, orbConfig, gitClient, errFunc, err := rv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errFunc(err)
+		}()
+
+		if err := cloneHistory(orbConfig, gitClient); err != nil {
+			return err
+		}
+
+		entries, err := secret.List(monitor, gitClient, operators.GetAllSecretsFunc(orbConfig), maxCommits, api.DesiredFiles()...)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PATH\tSET\tSOURCE\tLAST CHANGED\tDATE\tAUTHOR")
+		for _, entry := range entries {
+			lastChanged, date, author := "-", "-", "-"
+			if entry.LastChanged != nil {
+				lastChanged = entry.LastChanged.ShortHash()
+				date = entry.LastChanged.When.Format("2006-01-02 15:04")
+				author = entry.LastChanged.Author
+			}
+			if entry.Earliest {
+				lastChanged = "<=" + lastChanged
+			}
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\t%s\n", entry.Path, entry.Set, entry.Source, lastChanged, date, author)
+		}
+		return w.Flush()
+	}
+	return cmd
+}
+
+func SecretsDiffCommand(rv RootValues) *cobra.Command {
+	return &cobra.Command{
+		Use:     "diff [from] [to]",
+		Short:   "Print which secrets changed between two commits without printing their values",
+		Long:    "Print which secrets changed between two commits without printing their values.\nIf to is omitted, the latest commit is compared",
+		Args:    cobra.RangeArgs(1, 2),
+		Example: `orbctl secrets diff HEAD~10`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			_, monitor, orbConfig, gitClient, errFunc, err := rv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errFunc(err)
+			}()
+
+			if err := cloneHistory(orbConfig, gitClient); err != nil {
+				return err
+			}
+
+			to := "HEAD"
+			if len(args) > 1 {
+				to = args[1]
+			}
+
+			changes, err := secret.Diff(monitor, gitClient, operators.GetAllSecretsFunc(orbConfig), args[0], to)
+			if err != nil {
+				return err
+			}
+
+			for _, change := range changes {
+				fmt.Printf("%s\t%s\n", change.Type, change.Path)
+			}
+			return nil
+		},
+	}
+}
+
+func SecretsRotateCommand(rv RootValues) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate [path...]",
+		Short: "Regenerate the secrets ORBOS generates itself and push them",
+		Long: fmt.Sprintf(`Regenerate the secrets ORBOS generates itself and push them.
+Rotatable secrets are the ones ending with %s.
+If no path is provided, all rotatable secrets are regenerated`, strings.Join(rotatableSecrets, ", ")),
+		Example: `orbctl secrets rotate orbiter.mystaticprovider.maintenancekeyprivate`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			_, monitor, orbConfig, gitClient, errFunc, err := rv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errFunc(err)
+			}()
+
+			if err := cloneRepository(orbConfig, gitClient); err != nil {
+				return err
+			}
+
+			found, err := api.ExistsOrbiterYml(gitClient)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return errors.New("no secrets generated by ORBOS found as orbiter.yml does not exist")
+			}
+
+			_, _, configure, _, desired, _, secrets, err := orbiter.Adapt(gitClient, nil, monitor, make(chan struct{}), orb.AdaptFunc(
+				orbConfig,
+				gitCommit,
+				true,
+				false,
+				gitClient,
+			))
+			if err != nil {
+				return err
+			}
+
+			rotate := make(map[string]*secret.Secret)
+			for path, sec := range secrets {
+				if sec == nil || !isRotatable(path) {
+					continue
+				}
+				if len(args) == 0 || contains(args, "orbiter."+path) {
+					rotate[path] = sec
+				}
+			}
+
+			for _, arg := range args {
+				if _, ok := rotate[strings.TrimPrefix(arg, "orbiter.")]; !ok {
+					return fmt.Errorf("secret %s is not rotatable", arg)
+				}
+			}
+
+			if len(rotate) == 0 {
+				monitor.Info("No rotatable secrets found")
+				return nil
+			}
+
+			// As key pairs are regenerated together, clearing one of them is sufficient
+			for path, sec := range rotate {
+				monitor.WithField("secret", "orbiter."+path).Info("Rotating secret")
+				sec.Value = ""
+			}
+
+			// The providers authorize the new keys on all machines additionally to the rotated ones
+			if err := configure(*orbConfig); err != nil {
+				return err
+			}
+
+			// If pushing fails, the machines stay reachable with the rotated keys
+			if err := api.PushOrbiterYml(monitor, "Secrets rotated", gitClient, desired); err != nil {
+				return err
+			}
+
+			// As the new keys are pushed, configuring again revokes the rotated keys
+			return configure(*orbConfig)
+		},
+	}
+}
+
+func isRotatable(path string) bool {
+	for _, suffix := range rotatableSecrets {
+		if strings.HasSuffix(path, "."+suffix) || path == suffix {
+			return true
+		}
+	}
+	return false
+}
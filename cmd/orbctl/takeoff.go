@@ -2,10 +2,12 @@ package main
 
 import (
 	"github.com/caos/orbos/cmd/orbctl/cmds"
+	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
 	"github.com/caos/orbos/internal/start"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 )
 
@@ -18,10 +20,16 @@ func TakeoffCommand(rv RootValues) *cobra.Command {
 		deploy           bool
 		kubeconfig       string
 		ingestionAddress string
+		acceptHost       bool
+		plan             bool
+		output           string
 		cmd              = &cobra.Command{
 			Use:   "takeoff",
 			Short: "Launch an orbiter",
-			Long:  "Ensures a desired state",
+			Long: `Ensures a desired state.
+With --plan, the changes of the next iteration are printed instead. The plan is partial:
+node agent installations, changes caused by the results of earlier changes and
+autoscaling decisions, which are made while ensuring, are not included`,
 		}
 	)
 
@@ -30,12 +38,30 @@ func TakeoffCommand(rv RootValues) *cobra.Command {
 	flags.BoolVar(&deploy, "deploy", true, "Ensure Orbiter and Boom deployments continously")
 	flags.StringVar(&ingestionAddress, "ingestion", "", "Ingestion API address")
 	flags.StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig for boom deployment")
+	flags.BoolVar(&acceptHost, "accept-host-key", false, "Pins the SSH host key of the repository server without asking for confirmation if none is pinned yet")
+	flags.BoolVar(&plan, "plan", false, "Print the changes the orbiter would make instead of making them")
+	flags.StringVarP(&output, "output", "o", "text", "Output format of the plan: text or json")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 		if recur && destroy {
 			return errors.New("flags --recur and --destroy are mutually exclusive, please provide eighter one or none")
 		}
 
+		if plan && (recur || destroy) {
+			return errors.New("flag --plan can not be combined with --recur or --destroy")
+		}
+
+		if output != "text" && output != "json" {
+			return errors.Errorf("unknown output format %s", output)
+		}
+
+		if plan && output == "json" {
+			// Only the plan is printed to stdout
+			if err := logToStderr(cmd); err != nil {
+				return err
+			}
+		}
+
 		ctx, monitor, orbConfig, gitClient, errFunc, err := rv()
 		if err != nil {
 			return err
@@ -44,6 +70,10 @@ func TakeoffCommand(rv RootValues) *cobra.Command {
 			err = errFunc(err)
 		}()
 
+		if plan {
+			return printPlan(monitor, orbConfig, gitClient, output)
+		}
+
 		return cmds.Takeoff(
 			monitor,
 			ctx,
@@ -57,11 +87,35 @@ func TakeoffCommand(rv RootValues) *cobra.Command {
 			version,
 			gitCommit,
 			kubeconfig,
+			func(hostKey *git.HostKey) bool {
+				return acceptHost || confirmHostKey(hostKey)
+			},
 		)
 	}
 	return cmd
 }
 
+// logToStderr writes all logs to stderr which would be written to stdout
+func logToStderr(cmd *cobra.Command) error {
+	flag := cmd.Flags().Lookup("log-output")
+	if flag == nil {
+		return nil
+	}
+
+	outputs, ok := flag.Value.(pflag.SliceValue)
+	if !ok {
+		return nil
+	}
+
+	redirected := outputs.GetSlice()
+	for idx, output := range redirected {
+		if output == "stdout" || output == "-" {
+			redirected[idx] = "stderr"
+		}
+	}
+	return outputs.Replace(redirected)
+}
+
 func StartOrbiter(rv RootValues) *cobra.Command {
 	var (
 		verbose          bool
@@ -94,7 +148,7 @@ func StartOrbiter(rv RootValues) *cobra.Command {
 			err = errFunc(err)
 		}()
 
-		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 			return err
 		}
 
@@ -165,7 +219,7 @@ func StartZitadel(rv RootValues) *cobra.Command {
 
 		k8sClient := kubernetes.NewK8sClient(monitor, &kubeconfig)
 		if k8sClient.Available() {
-			return start.Zitadel(monitor, orbConfig.Path, k8sClient)
+			return start.Zitadel(monitor, orbConfig.Path, k8sClient, version)
 		}
 		return nil
 	}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/schema/kinds"
+)
+
+func ValidateCommand(rv RootValues) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [file...]",
+		Short: "Validate desired state files",
+		Long: `Validate desired state files against the schemas of their kinds and run the kinds own checks.
+If no file is passed, orbiter.yml, boom.yml and zitadel.yml are read from the orbs repository.
+Passed files are validated offline, secrets are never decrypted`,
+		Example:      `orbctl validate orbiter.yml boom.yml`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			files := make(map[string][]byte)
+			for _, file := range args {
+				content, err := ioutil.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				files[file] = content
+			}
+
+			if len(args) == 0 {
+				_, _, orbConfig, gitClient, _, err := rv()
+				if err != nil {
+					return err
+				}
+
+				if err := cloneRepository(orbConfig, gitClient); err != nil {
+					return err
+				}
+
+				for _, file := range api.DesiredFiles() {
+					if content := gitClient.Read(file); len(content) > 0 {
+						files[file] = content
+						args = append(args, file)
+					}
+				}
+			}
+
+			registry := kinds.Registry()
+			var problems int
+			for _, file := range args {
+				errs := registry.Validate(files[file])
+				for _, err := range errs {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+				}
+				if len(errs) == 0 {
+					fmt.Printf("%s is valid\n", file)
+				}
+				problems += len(errs)
+			}
+
+			if problems > 0 {
+				return fmt.Errorf("found %d problems", problems)
+			}
+			return nil
+		},
+	}
+}
+
+func SchemaCommand() *cobra.Command {
+	var (
+		out string
+		cmd = &cobra.Command{
+			Use:   "schema [kind] [version]",
+			Short: "Print the JSON Schemas of the desired state kinds",
+			Long: `Print the JSON Schema of the passed kind or list all kinds.
+With --out, the schemas of all kinds are written to the passed directory, where they reference each other`,
+			Args:    cobra.MaximumNArgs(2),
+			Example: `orbctl schema orbiter.caos.ch/GCEProvider v0`,
+		}
+	)
+
+	cmd.Flags().StringVar(&out, "out", "", "Directory to write the schemas of all kinds to")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		registry := kinds.Registry()
+
+		if out != "" {
+			if err := os.MkdirAll(out, os.ModePerm); err != nil {
+				return err
+			}
+			for _, kind := range registry {
+				content, err := json.MarshalIndent(registry.Schema(kind), "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(filepath.Join(out, kind.File()), append(content, '\n'), 0644); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if len(args) == 0 {
+			for _, kind := range registry {
+				fmt.Println(kind)
+			}
+			return nil
+		}
+
+		var version string
+		if len(args) > 1 {
+			version = args[1]
+		}
+
+		kind, err := registry.Lookup(args[0], version)
+		if err != nil {
+			return err
+		}
+
+		content, err := json.MarshalIndent(registry.Schema(kind), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+	return cmd
+}
@@ -13,7 +13,11 @@ import (
 func main() {
 	orbConfigPath := flag.String("orbconfig", "~/.orb/config", "The orbconfig file to use")
 	kubeconfig := flag.String("kubeconfig", "~/.kube/config", "The kubeconfig file to use")
-	verbose := flag.Bool("verbose", false, "Print debug levelled logs")
+	verbose := flag.Bool("verbose", false, "Print debug levelled logs, same as --log-level debug")
+	logLevel := flag.String("log-level", "info", "Minimal level of printed logs, one of debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Format of printed logs, text or json")
+	logOutput := flag.String("log-output", "stdout", "Where logs are written to, stdout, stderr or a file path")
+	acceptHostKey := flag.Bool("accept-host-key", false, "Pins the SSH host key of the repository server if none is pinned yet")
 
 	flag.Parse()
 
@@ -30,7 +34,12 @@ func main() {
 	}
 
 	if *verbose {
-		monitor = monitor.Verbose()
+		*logLevel = "debug"
+	}
+
+	monitor, err = monitor.WithLogging(*logLevel, *logFormat, *logOutput)
+	if err != nil {
+		panic(err)
 	}
 	ctx := context.Background()
 
@@ -50,6 +59,7 @@ func main() {
 		string(version),
 		string(gitCommit),
 		*kubeconfig,
+		func(*git.HostKey) bool { return *acceptHostKey },
 	); err != nil {
 		monitor.Error(err)
 		panic(err)
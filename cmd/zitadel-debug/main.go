@@ -16,7 +16,10 @@ func main() {
 
 	orbconfig := flag.String("orbconfig", "~/.orb/config", "The orbconfig file to use")
 	kubeconfig := flag.String("kubeconfig", "~/.kube/config", "The kubeconfig file to use")
-	verbose := flag.Bool("verbose", false, "Print debug levelled logs")
+	verbose := flag.Bool("verbose", false, "Print debug levelled logs, same as --log-level debug")
+	logLevel := flag.String("log-level", "info", "Minimal level of printed logs, one of debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Format of printed logs, text or json")
+	logOutput := flag.String("log-output", "stdout", "Where logs are written to, stdout, stderr or a file path")
 
 	flag.Parse()
 
@@ -27,7 +30,12 @@ func main() {
 	}
 
 	if *verbose {
-		monitor = monitor.Verbose()
+		*logLevel = "debug"
+	}
+
+	monitor, err := monitor.WithLogging(*logLevel, *logFormat, *logOutput)
+	if err != nil {
+		panic(err)
 	}
 
 	kc, err := ioutil.ReadFile(helpers.PruneHome(*kubeconfig))
@@ -39,6 +47,7 @@ func main() {
 		monitor,
 		helpers.PruneHome(*orbconfig),
 		kubernetes.NewK8sClient(monitor, strPtr(string(kc))),
+		"debug",
 	); err != nil {
 		panic(err)
 	}
@@ -3,6 +3,8 @@ package api
 import (
 	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/store"
 	"github.com/caos/orbos/internal/tree"
 	"github.com/caos/orbos/mntr"
 	"gopkg.in/yaml.v3"
@@ -14,70 +16,77 @@ const (
 	zitadelFile = "zitadel.yml"
 )
 
-type PushDesiredFunc func(monitor mntr.Monitor) error
+// PushDesiredFunc is an alias, so the secret package can accept it without importing api
+type PushDesiredFunc = func(monitor mntr.Monitor) error
 
-func ExistsOrbiterYml(gitClient *git.Client) (bool, error) {
-	return existsFileInGit(gitClient, orbiterFile)
+// DesiredFiles returns the paths of all files containing an operators desired state
+func DesiredFiles() []string {
+	return []string{orbiterFile, boomFile, zitadelFile}
 }
 
-func ReadOrbiterYml(gitClient *git.Client) (*tree.Tree, error) {
-	return readFileInGit(gitClient, orbiterFile)
+func ExistsOrbiterYml(st store.Store) (bool, error) {
+	return existsFileInGit(st, orbiterFile)
 }
 
-func PushOrbiterYml(monitor mntr.Monitor, msg string, gitClient *git.Client, desired *tree.Tree) (err error) {
-	return pushFileInGit(monitor, msg, gitClient, desired, orbiterFile)
+func ReadOrbiterYml(st store.Store) (*tree.Tree, error) {
+	return readFileInGit(st, orbiterFile)
 }
 
-func PushOrbiterDesiredFunc(gitClient *git.Client, desired *tree.Tree) PushDesiredFunc {
+func PushOrbiterYml(monitor mntr.Monitor, msg string, st store.Store, desired *tree.Tree) (err error) {
+	return pushFileInGit(monitor, msg, st, desired, orbiterFile)
+}
+
+func PushOrbiterDesiredFunc(st store.Store, desired *tree.Tree) PushDesiredFunc {
 	return func(monitor mntr.Monitor) error {
 		monitor.Info("Writing orbiter desired state")
-		return PushOrbiterYml(monitor, "Orbiter desired state written", gitClient, desired)
+		return PushOrbiterYml(monitor, "Orbiter desired state written", st, desired)
 	}
 }
 
-func ExistsBoomYml(gitClient *git.Client) (bool, error) {
-	return existsFileInGit(gitClient, boomFile)
+func ExistsBoomYml(st store.Store) (bool, error) {
+	return existsFileInGit(st, boomFile)
 }
 
-func ReadBoomYml(gitClient *git.Client) (*tree.Tree, error) {
-	return readFileInGit(gitClient, boomFile)
+func ReadBoomYml(st store.Store) (*tree.Tree, error) {
+	return readFileInGit(st, boomFile)
 }
 
-func PushBoomYml(monitor mntr.Monitor, msg string, gitClient *git.Client, desired *tree.Tree) (err error) {
-	return pushFileInGit(monitor, msg, gitClient, desired, boomFile)
+func PushBoomYml(monitor mntr.Monitor, msg string, st store.Store, desired *tree.Tree) (err error) {
+	return pushFileInGit(monitor, msg, st, desired, boomFile)
 }
 
-func PushBoomDesiredFunc(gitClient *git.Client, desired *tree.Tree) PushDesiredFunc {
+func PushBoomDesiredFunc(st store.Store, desired *tree.Tree) PushDesiredFunc {
 	return func(monitor mntr.Monitor) error {
 		monitor.Info("Writing boom desired state")
-		return PushBoomYml(monitor, "Boom desired state written", gitClient, desired)
+		return PushBoomYml(monitor, "Boom desired state written", st, desired)
 	}
 }
 
-func ExistsZitadelYml(gitClient *git.Client) (bool, error) {
-	return existsFileInGit(gitClient, zitadelFile)
+func ExistsZitadelYml(st store.Store) (bool, error) {
+	return existsFileInGit(st, zitadelFile)
 }
 
-func ReadZitadelYml(gitClient *git.Client) (*tree.Tree, error) {
-	return readFileInGit(gitClient, zitadelFile)
+func ReadZitadelYml(st store.Store) (*tree.Tree, error) {
+	return readFileInGit(st, zitadelFile)
 }
 
-func PushZitadelYml(monitor mntr.Monitor, msg string, gitClient *git.Client, desired *tree.Tree) (err error) {
-	return pushFileInGit(monitor, msg, gitClient, desired, zitadelFile)
+func PushZitadelYml(monitor mntr.Monitor, msg string, st store.Store, desired *tree.Tree) (err error) {
+	return pushFileInGit(monitor, msg, st, desired, zitadelFile)
 }
 
-func PushZitadelDesiredFunc(gitClient *git.Client, desired *tree.Tree) PushDesiredFunc {
+func PushZitadelDesiredFunc(st store.Store, desired *tree.Tree) PushDesiredFunc {
 	return func(monitor mntr.Monitor) error {
 		monitor.Info("Writing zitadel desired state")
-		return PushZitadelYml(monitor, "Zitadel desired state written", gitClient, desired)
+		return PushZitadelYml(monitor, "Zitadel desired state written", st, desired)
 	}
 }
 
-func pushFileInGit(monitor mntr.Monitor, msg string, gitClient *git.Client, desired *tree.Tree, path string) (err error) {
+func pushFileInGit(monitor mntr.Monitor, msg string, st store.Store, desired *tree.Tree, path string) (err error) {
 	monitor.OnChange = func(_ string, fields map[string]string) {
-		err = gitClient.UpdateRemote(mntr.SprintCommit(msg, fields), git.File{
+		err = st.UpdateRemote(mntr.SprintCommit(msg, fields), git.File{
 			Path:    path,
 			Content: common.MarshalYAML(desired),
+			Merge:   git.MergeSealedYAML(secret.Unseal),
 		})
 		mntr.LogMessage(msg, fields)
 	}
@@ -85,17 +94,17 @@ func pushFileInGit(monitor mntr.Monitor, msg string, gitClient *git.Client, desi
 	return err
 }
 
-func existsFileInGit(gitClient *git.Client, path string) (bool, error) {
-	of := gitClient.Read(path)
+func existsFileInGit(st store.Store, path string) (bool, error) {
+	of := st.Read(path)
 	if of != nil && len(of) > 0 {
 		return true, nil
 	}
 	return false, nil
 }
 
-func readFileInGit(gitClient *git.Client, path string) (*tree.Tree, error) {
+func readFileInGit(st store.Store, path string) (*tree.Tree, error) {
 	tree := &tree.Tree{}
-	if err := yaml.Unmarshal(gitClient.Read(path), tree); err != nil {
+	if err := yaml.Unmarshal(st.Read(path), tree); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/mntr"
+)
+
+const internalFolder = "caos-internal"
+
+// HistoryEntry is a commit changing desired or current state, parsed from its mntr.CommitRecord message
+type HistoryEntry struct {
+	Commit    *git.Commit
+	Event     string
+	Fields    map[string]string
+	Operators []string
+	Machines  []string
+}
+
+// HistoryFilter selects history entries. Empty values match every entry
+type HistoryFilter struct {
+	Machine  string
+	Operator string
+}
+
+func (f HistoryFilter) matches(entry *HistoryEntry) bool {
+	return (f.Machine == "" || contains(entry.Machines, f.Machine)) &&
+		(f.Operator == "" || contains(entry.Operators, f.Operator))
+}
+
+// History returns the entries of all commits changing orbiter.yml, boom.yml, zitadel.yml or caos-internal, the latest first.
+// The repository has to be cloned using CloneHistory for getting more than the latest commit.
+func History(gitClient *git.Client, filter HistoryFilter) ([]*HistoryEntry, error) {
+
+	commits, err := gitClient.Changes(append(DesiredFiles(), internalFolder)...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*HistoryEntry, 0, len(commits))
+	for _, commit := range commits {
+		entry := NewHistoryEntry(commit)
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// NewHistoryEntry derives the operators and machines a commit affects from its message, author and changed files
+func NewHistoryEntry(commit *git.Commit) *HistoryEntry {
+	evt, fields := mntr.ParseCommitRecord(commit.Message)
+	entry := &HistoryEntry{
+		Commit: commit,
+		Event:  evt,
+		Fields: fields,
+	}
+
+	operators := make(map[string]struct{})
+	machines := make(map[string]struct{})
+	for _, file := range commit.Files {
+		operators[operatorOf(file)] = struct{}{}
+		if strings.HasPrefix(file, nodeAgentsCurrentFolder+"/") {
+			machines[strings.TrimSuffix(strings.TrimPrefix(file, nodeAgentsCurrentFolder+"/"), ".yml")] = struct{}{}
+		}
+	}
+
+	if machine, ok := fields["machine"]; ok {
+		machines[machine] = struct{}{}
+	}
+	if id := strings.TrimPrefix(commit.Author, "Node Agent "); id != commit.Author {
+		machines[id] = struct{}{}
+		operators["nodeagent"] = struct{}{}
+	}
+
+	entry.Operators = sortedKeys(operators)
+	entry.Machines = sortedKeys(machines)
+	return entry
+}
+
+func operatorOf(file string) string {
+	switch {
+	case strings.HasPrefix(file, nodeAgentsCurrentFolder):
+		return "nodeagent"
+	case file == orbiterFile, strings.HasPrefix(file, internalFolder+"/orbiter/"):
+		return "orbiter"
+	case file == boomFile, strings.HasPrefix(file, internalFolder+"/boom/"):
+		return "boom"
+	case file == zitadelFile, strings.HasPrefix(file, internalFolder+"/zitadel/"):
+		return "zitadel"
+	}
+	return "other"
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, item string) bool {
+	for _, el := range list {
+		if el == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/mntr"
+)
+
+func TestNewHistoryEntry(t *testing.T) {
+	entry := NewHistoryEntry(&git.Commit{
+		Author: "Node Agent gce-prod-abc",
+		Message: mntr.CommitRecord(mntr.AggregateCommitFields(map[string]string{
+			"evt":     "Software changed",
+			"err":     "package not found",
+			"package": "kubelet",
+		})),
+		Files: []string{"caos-internal/orbiter/node-agents-current/gce-prod-abc.yml"},
+	})
+
+	if entry.Event != "Software changed" {
+		t.Errorf("unexpected event %q", entry.Event)
+	}
+	if !reflect.DeepEqual(entry.Fields, map[string]string{"err": "package not found", "package": "kubelet"}) {
+		t.Errorf("unexpected fields %v", entry.Fields)
+	}
+	if !reflect.DeepEqual(entry.Machines, []string{"gce-prod-abc"}) {
+		t.Errorf("unexpected machines %v", entry.Machines)
+	}
+	if !reflect.DeepEqual(entry.Operators, []string{"nodeagent"}) {
+		t.Errorf("unexpected operators %v", entry.Operators)
+	}
+
+	filter := HistoryFilter{Operator: "orbiter"}
+	if filter.matches(entry) {
+		t.Error("node agent change matched the orbiter filter")
+	}
+}
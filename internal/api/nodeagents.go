@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/store"
+	"github.com/caos/orbos/internal/tree"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// OrbiterCurrentFile contains the current state of the orbiter
+	OrbiterCurrentFile      = "caos-internal/orbiter/current.yml"
+	nodeAgentsCurrentFile   = "caos-internal/orbiter/node-agents-current.yml"
+	nodeAgentsCurrentFolder = "caos-internal/orbiter/node-agents-current"
+)
+
+// ReadOrbiterCurrent reads the current state the orbiter persisted in its last iteration.
+// The returned tree is empty if there is none yet
+func ReadOrbiterCurrent(st store.Store) (*tree.Tree, error) {
+	current := &tree.Tree{}
+	if err := yaml.Unmarshal(st.Read(OrbiterCurrentFile), current); err != nil {
+		return nil, fmt.Errorf("parsing the orbiters current state failed: %w", err)
+	}
+	return current, nil
+}
+
+// NodeAgentCurrentFile is written by each node agent, so node agents never push changes to the same file
+func NodeAgentCurrentFile(id string, current *common.NodeAgentCurrent) git.File {
+	return git.File{
+		Path:    nodeAgentCurrentPath(id),
+		Content: common.MarshalYAML(current),
+	}
+}
+
+func nodeAgentCurrentPath(id string) string {
+	return fmt.Sprintf("%s/%s.yml", nodeAgentsCurrentFolder, id)
+}
+
+// ReadNodeAgentsCurrent reads the current states of all node agents.
+// States written by node agents to the shared file before they wrote their own files are overwritten by the latter.
+func ReadNodeAgentsCurrent(st store.Store) (*common.NodeAgentsCurrentKind, error) {
+
+	current := &common.NodeAgentsCurrentKind{}
+	if err := yaml.Unmarshal(st.Read(nodeAgentsCurrentFile), current); err != nil {
+		return nil, err
+	}
+	current.Kind = "nodeagent.caos.ch/NodeAgents"
+	current.Version = "v0"
+
+	files, _, err := st.ReadFolder(nodeAgentsCurrentFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, content := range files {
+		if len(content) == 0 {
+			continue
+		}
+		naCurrent := &common.NodeAgentCurrent{}
+		if err := yaml.Unmarshal(content, naCurrent); err != nil {
+			return nil, fmt.Errorf("parsing current state of node agent from %s failed: %w", name, err)
+		}
+		current.Current.Set(strings.TrimSuffix(name, ".yml"), naCurrent)
+	}
+	return current, nil
+}
+
+// ClearNodeAgentsCurrentFiles returns files overwriting the current states of all node agents
+func ClearNodeAgentsCurrentFiles(st store.Store) ([]git.File, error) {
+
+	files, _, err := st.ReadFolder(nodeAgentsCurrentFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	cleared := []git.File{{
+		Path:    nodeAgentsCurrentFile,
+		Content: []byte(""),
+	}}
+	for name := range files {
+		cleared = append(cleared, git.File{
+			Path:    fmt.Sprintf("%s/%s", nodeAgentsCurrentFolder, name),
+			Content: []byte(""),
+		})
+	}
+	return cleared, nil
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/store"
+	"github.com/caos/orbos/mntr"
+)
+
+// maxStoredBytes keeps buffers in stores below the size limit of Kubernetes secrets
+const maxStoredBytes = 512 * 1024
+
+// Buffer persists the pending events of a queue, so they survive restarts
+type Buffer interface {
+	Load() ([]byte, error)
+	Save(content []byte) error
+	// Limit is the maximum size of the saved content. Zero means unlimited
+	Limit() int
+}
+
+type fileBuffer string
+
+// FileBuffer persists the events to a file, which is written atomically
+func FileBuffer(path string) Buffer {
+	return fileBuffer(path)
+}
+
+func (f fileBuffer) Load() ([]byte, error) {
+	content, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+func (f fileBuffer) Save(content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(string(f)), 0700); err != nil {
+		return err
+	}
+
+	tmp := string(f) + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(f))
+}
+
+func (f fileBuffer) Limit() int { return 0 }
+
+type storeBuffer struct {
+	monitor mntr.Monitor
+	store   store.Store
+	path    string
+	mux     sync.Mutex
+	latest  []byte
+	notify  chan struct{}
+}
+
+// StoreBuffer persists the events at path in a store, for example in a Kubernetes secret.
+// Saving doesn't wait for the store, the latest content is written in the background
+func StoreBuffer(monitor mntr.Monitor, st store.Store, path string) Buffer {
+	s := &storeBuffer{
+		monitor: monitor,
+		store:   st,
+		path:    path,
+		notify:  make(chan struct{}, 1),
+	}
+	go s.run()
+	return s
+}
+
+func (s *storeBuffer) Load() ([]byte, error) {
+	if err := s.store.Clone(); err != nil {
+		return nil, err
+	}
+	return s.store.Read(s.path), nil
+}
+
+func (s *storeBuffer) Save(content []byte) error {
+	s.mux.Lock()
+	s.latest = content
+	s.mux.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *storeBuffer) Limit() int { return maxStoredBytes }
+
+func (s *storeBuffer) run() {
+	for range s.notify {
+		s.mux.Lock()
+		content := s.latest
+		s.mux.Unlock()
+
+		if err := s.store.UpdateRemote("Buffer events", git.File{
+			Path:    s.path,
+			Content: content,
+		}); err != nil {
+			s.monitor.Error(fmt.Errorf("persisting buffered events failed: %w", err))
+		}
+	}
+}
+
+// DefaultBuffer returns the buffer for the events of a component.
+// In a Kubernetes cluster, the events are buffered in a secret, as the files of pods are lost when they are rescheduled.
+// Otherwise, they are buffered in a file in the users cache directory
+func DefaultBuffer(monitor mntr.Monitor, component, orbURL string) Buffer {
+	if k8sStore, err := store.NewInClusterKubernetes(monitor, "caos-system", component+"-events"); err == nil {
+		return StoreBuffer(monitor, k8sStore, "events")
+	}
+	return FileBuffer(BufferFile(component, orbURL))
+}
+
+// BufferFile returns the default file to buffer the events of a component in
+func BufferFile(component, orbURL string) string {
+	h := fnv.New32()
+	h.Write([]byte(orbURL))
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "orbos", fmt.Sprintf("%s-%d.events", component, h.Sum32()))
+}
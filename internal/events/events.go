@@ -0,0 +1,302 @@
+// Package events reliably pushes the events of the operators and node agents to the ingestion API
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/caos/orbos/internal/ingestion"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/mntr"
+)
+
+const (
+	// maxBuffered events are kept, older events are dropped
+	maxBuffered = 10000
+	batchSize   = 100
+	minBackoff  = time.Second
+	maxBackoff  = 5 * time.Minute
+)
+
+// Queue buffers events and pushes them in the background, retrying with backoff until the ingestion API accepts them.
+// If it is configured with a buffer, the buffered events survive restarts and reboots.
+// All methods are safe to call on a nil queue, which drops all events
+type Queue struct {
+	monitor mntr.Monitor
+	orb     string
+	client  ingestion.IngestionServiceClient
+	buffer  Buffer
+	mux     sync.Mutex
+	sending sync.Mutex
+	pending []*ingestion.EventRequest
+	notify  chan struct{}
+}
+
+// New connects to the ingestion API configured in the orbconfig and starts pushing.
+// It returns a nil queue if no ingestion API is configured.
+// The address parameter overwrites the configured address.
+// Buffered events are persisted to buffer if it is not nil
+func New(monitor mntr.Monitor, orbConfig *orb.Orb, address string, buffer Buffer) (*Queue, error) {
+
+	var conf orb.Ingestion
+	if orbConfig.Ingestion != nil {
+		conf = *orbConfig.Ingestion
+	}
+	if address != "" {
+		conf.Address = address
+	}
+	if conf.Address == "" {
+		return nil, nil
+	}
+
+	conn, err := dial(conf)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to ingestion API at %s failed: %w", conf.Address, err)
+	}
+
+	q := &Queue{
+		monitor: monitor.WithField("ingestion", conf.Address),
+		orb:     orbConfig.URL,
+		client:  ingestion.NewIngestionServiceClient(conn),
+		buffer:  buffer,
+		notify:  make(chan struct{}, 1),
+	}
+
+	if err := q.load(); err != nil {
+		q.monitor.Error(fmt.Errorf("loading buffered events failed: %w", err))
+	}
+
+	go q.run()
+	q.wake()
+	return q, nil
+}
+
+func dial(conf orb.Ingestion) (*grpc.ClientConn, error) {
+
+	if conf.Insecure {
+		if conf.Token != "" {
+			return nil, errors.New("sending a token over an insecure connection is not allowed")
+		}
+		return grpc.Dial(conf.Address, grpc.WithInsecure())
+	}
+
+	tlsConf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if conf.CA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(conf.CA)) {
+			return nil, errors.New("parsing ca failed")
+		}
+		tlsConf.RootCAs = pool
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))}
+	if conf.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(token(conf.Token)))
+	}
+	return grpc.Dial(conf.Address, opts...)
+}
+
+type token string
+
+func (t token) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t token) RequireTransportSecurity() bool {
+	return true
+}
+
+// Push buffers the events. It never blocks on the ingestion API
+func (q *Queue) Push(events ...*ingestion.EventRequest) {
+	if q == nil || len(events) == 0 {
+		return
+	}
+
+	q.mux.Lock()
+	q.pending = append(q.pending, events...)
+	if dropped := len(q.pending) - maxBuffered; dropped > 0 {
+		q.pending = q.pending[dropped:]
+		q.monitor.WithField("dropped", dropped).Warn("Event buffer is full, dropping oldest events")
+	}
+	if err := q.persist(); err != nil {
+		q.monitor.Error(fmt.Errorf("persisting buffered events failed: %w", err))
+	}
+	q.mux.Unlock()
+
+	q.wake()
+}
+
+// PushEvents has the signature the orbiter expects. Events are buffered, so it never fails
+func (q *Queue) PushEvents(events []*ingestion.EventRequest) error {
+	q.Push(events...)
+	return nil
+}
+
+// Event returns an event of the type with the data
+func Event(typ string, data map[string]interface{}) *ingestion.EventRequest {
+	fields := make(map[string]*structpb.Value, len(data))
+	for key, value := range data {
+		switch v := value.(type) {
+		case float64:
+			fields[key] = &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: v}}
+		case bool:
+			fields[key] = &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: v}}
+		default:
+			fields[key] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: fmt.Sprintf("%v", v)}}
+		}
+	}
+	return &ingestion.EventRequest{
+		CreationDate: ptypes.TimestampNow(),
+		Type:         typ,
+		Data:         &structpb.Struct{Fields: fields},
+	}
+}
+
+// Monitor returns a monitor which additionally pushes every change as event.
+// The namespace prefixes the types of changes without a known event type
+func (q *Queue) Monitor(monitor mntr.Monitor, namespace string) mntr.Monitor {
+	if q == nil {
+		return monitor
+	}
+	monitor.OnChange = mntr.Concat(func(evt string, fields map[string]string) {
+		q.Push(mntr.EventRecord(namespace, evt, fields))
+	}, monitor.OnChange)
+	return monitor
+}
+
+// Flush pushes all pending events synchronously until the timeout elapses
+func (q *Queue) Flush(timeout time.Duration) error {
+	if q == nil {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		sent, err := q.send()
+		if err != nil {
+			return fmt.Errorf("pushing events failed: %w", err)
+		}
+		if !sent {
+			return nil
+		}
+	}
+	return errors.New("pushing events timed out")
+}
+
+func (q *Queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) run() {
+	backoff := minBackoff
+	for range q.notify {
+		for {
+			sent, err := q.send()
+			if err != nil {
+				q.monitor.WithField("retry", backoff).Debug(fmt.Sprintf("Pushing events failed: %s", err.Error()))
+				time.Sleep(backoff)
+				if backoff *= 2; backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+				continue
+			}
+			backoff = minBackoff
+			if !sent {
+				break
+			}
+		}
+	}
+}
+
+// send pushes the oldest batch and returns false if nothing was pending
+func (q *Queue) send() (bool, error) {
+
+	q.sending.Lock()
+	defer q.sending.Unlock()
+
+	q.mux.Lock()
+	batch := q.pending
+	if len(batch) > batchSize {
+		batch = batch[:batchSize]
+	}
+	batch = append([]*ingestion.EventRequest(nil), batch...)
+	q.mux.Unlock()
+
+	if len(batch) == 0 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := q.client.PushEvents(ctx, &ingestion.EventsRequest{
+		Orb:    q.orb,
+		Events: batch,
+	}); err != nil {
+		return false, err
+	}
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	// Events could have been dropped meanwhile
+	for len(q.pending) > 0 && len(batch) > 0 && q.pending[0] == batch[0] {
+		q.pending = q.pending[1:]
+		batch = batch[1:]
+	}
+	if err := q.persist(); err != nil {
+		q.monitor.Error(fmt.Errorf("persisting buffered events failed: %w", err))
+	}
+	return true, nil
+}
+
+func (q *Queue) load() error {
+	if q.buffer == nil {
+		return nil
+	}
+
+	content, err := q.buffer.Load()
+	if err != nil {
+		return err
+	}
+
+	buffered := &ingestion.EventsRequest{}
+	if err := proto.Unmarshal(content, buffered); err != nil {
+		return err
+	}
+	q.pending = buffered.Events
+	return nil
+}
+
+// persist saves the pending events to the buffer. If they exceed the buffers limit, only the newest events are saved.
+// The caller must hold the lock
+func (q *Queue) persist() error {
+	if q.buffer == nil {
+		return nil
+	}
+
+	events := q.pending
+	for {
+		content, err := proto.Marshal(&ingestion.EventsRequest{Events: events})
+		if err != nil {
+			return err
+		}
+		if limit := q.buffer.Limit(); limit > 0 && len(content) > limit && len(events) > 0 {
+			events = events[len(events)/10+1:]
+			continue
+		}
+		return q.buffer.Save(content)
+	}
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+
+	"github.com/caos/orbos/internal/ingestion"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/store"
+	"github.com/caos/orbos/mntr"
+)
+
+type flakyServer struct {
+	ingestion.UnimplementedIngestionServiceServer
+	mux      sync.Mutex
+	failures int
+	received []string
+}
+
+func (f *flakyServer) PushEvents(_ context.Context, req *ingestion.EventsRequest) (*empty.Empty, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("unavailable")
+	}
+	for _, evt := range req.Events {
+		f.received = append(f.received, evt.Type)
+	}
+	return &empty.Empty{}, nil
+}
+
+func TestQueueRetriesAndPersists(t *testing.T) {
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &flakyServer{failures: 1}
+	srv := grpc.NewServer()
+	ingestion.RegisterIngestionServiceServer(srv, server)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	file := filepath.Join(t.TempDir(), "test.events")
+	conf := &orb.Orb{
+		URL:       "git@example.com:orb.git",
+		Ingestion: &orb.Ingestion{Address: lis.Addr().String(), Insecure: true},
+	}
+
+	queue, err := New(mntr.Monitor{}, conf, "", FileBuffer(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue.Push(Event(ingestion.TypeMachineCreated, nil), Event(ingestion.TypeNodeJoined, nil))
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		server.mux.Lock()
+		received := len(server.received)
+		server.mux.Unlock()
+		if received == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 events after retrying, got %d", received)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	reloaded := &Queue{buffer: FileBuffer(file)}
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.pending) != 0 {
+		t.Errorf("expected the buffer file to be empty after pushing, got %d events", len(reloaded.pending))
+	}
+}
+
+type limitedBuffer struct {
+	Buffer
+	limit int
+}
+
+func (l limitedBuffer) Limit() int { return l.limit }
+
+func TestPersistKeepsNewestEventsWithinLimit(t *testing.T) {
+
+	dir := t.TempDir()
+	buffer := limitedBuffer{Buffer: StoreBuffer(mntr.Monitor{}, store.NewDirectory(mntr.Monitor{}, dir), "events"), limit: 1024}
+	queue := &Queue{buffer: buffer}
+	for i := 0; i < 100; i++ {
+		queue.pending = append(queue.pending, Event(ingestion.TypeMachineCreated, map[string]interface{}{"machine": fmt.Sprintf("machine-%d", i)}))
+	}
+	if err := queue.persist(); err != nil {
+		t.Fatal(err)
+	}
+
+	var reloaded *Queue
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		reloaded = &Queue{buffer: buffer}
+		if err := reloaded.load(); err != nil {
+			t.Fatal(err)
+		}
+		if len(reloaded.pending) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if len(reloaded.pending) == 0 || len(reloaded.pending) >= 100 {
+		t.Fatalf("expected some but not all events to be persisted, got %d", len(reloaded.pending))
+	}
+	if last := reloaded.pending[len(reloaded.pending)-1].Data.Fields["machine"].GetStringValue(); last != "machine-99" {
+		t.Errorf("expected the newest event to be persisted, got %s", last)
+	}
+}
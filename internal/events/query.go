@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/caos/orbos/internal/ingestion"
+	"github.com/caos/orbos/internal/ingestion/server"
+	"github.com/caos/orbos/internal/orb"
+)
+
+// QueryClient reads events from the query API of a self-hosted ingestion server
+type QueryClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewQueryClient returns a client for the query API configured in the orbconfig.
+// The address parameter overwrites the configured query URL
+func NewQueryClient(conf *orb.Ingestion, address string) (*QueryClient, error) {
+
+	var ingestionConf orb.Ingestion
+	if conf != nil {
+		ingestionConf = *conf
+	}
+	if address != "" {
+		ingestionConf.Query = address
+	}
+	if ingestionConf.Query == "" {
+		return nil, errors.New("no query API configured, pass --server or add ingestion.query to your orbconfig")
+	}
+
+	url := strings.TrimSuffix(ingestionConf.Query, "/")
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+	if strings.HasPrefix(url, "http://") && ingestionConf.Token != "" {
+		return nil, errors.New("sending a token over an insecure connection is not allowed")
+	}
+
+	tlsConf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ingestionConf.CA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ingestionConf.CA)) {
+			return nil, errors.New("parsing ca failed")
+		}
+		tlsConf.RootCAs = pool
+	}
+
+	return &QueryClient{
+		url:   url,
+		token: ingestionConf.Token,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConf, Proxy: http.ProxyFromEnvironment},
+		},
+	}, nil
+}
+
+// Events returns the events matching the filter in chronological order
+func (q *QueryClient) Events(ctx context.Context, filter ingestion.Filter) ([]*ingestion.Event, error) {
+	var events []*ingestion.Event
+	return events, q.get(ctx, "/events?"+filter.Values().Encode(), &events)
+}
+
+// Orbs returns an overview of all orbs that pushed events
+func (q *QueryClient) Orbs(ctx context.Context) ([]server.OrbSummary, error) {
+	var orbs []server.OrbSummary
+	return orbs, q.get(ctx, "/orbs", &orbs)
+}
+
+func (q *QueryClient) get(ctx context.Context, path string, into interface{}) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, q.url+path, nil)
+	if err != nil {
+		return err
+	}
+	if q.token != "" {
+		req.Header.Set("Authorization", "Bearer "+q.token)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("querying %s failed: %w", q.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("querying %s failed: %s: %s", q.url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(into)
+}
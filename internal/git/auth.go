@@ -0,0 +1,187 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+
+	"github.com/caos/orbos/mntr"
+)
+
+// ErrNoKnownHosts is returned when an SSH repository is configured without pinned host keys
+var ErrNoKnownHosts = errors.New("no known host keys configured, run orbctl configure to fetch and confirm them")
+
+var firstUse = struct {
+	knownHosts map[string][]byte
+	sync.Mutex
+}{knownHosts: make(map[string][]byte)}
+
+// IsHTTP returns true if the repository is accessed by HTTP(S) instead of SSH
+func IsHTTP(repoURL string) bool {
+	lower := strings.ToLower(repoURL)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+func httpAuth(repoURL string, token []byte) (transport.AuthMethod, error) {
+	trimmed := strings.TrimSpace(string(token))
+	if trimmed == "" {
+		return nil, errors.New("access token is missing")
+	}
+
+	endpoint, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository url failed: %w", err)
+	}
+
+	// Most git servers accept an arbitrary username when authenticating with a token
+	user := endpoint.User
+	if user == "" {
+		user = "git"
+	}
+
+	return &http.BasicAuth{
+		Username: user,
+		Password: trimmed,
+	}, nil
+}
+
+func sshAuth(repoURL string, deploykey []byte, knownHosts []byte) (transport.AuthMethod, error) {
+	signer, err := ssh.ParsePrivateKey(deploykey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing deployment key failed: %w", err)
+	}
+
+	callback, err := hostKeyCallback(knownHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository url failed: %w", err)
+	}
+
+	user := endpoint.User
+	if user == "" {
+		user = "git"
+	}
+
+	auth := &gitssh.PublicKeys{
+		User:   user,
+		Signer: signer,
+	}
+	auth.HostKeyCallback = callback
+	return auth, nil
+}
+
+func hostKeyCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	if strings.TrimSpace(string(knownHosts)) == "" {
+		return nil, ErrNoKnownHosts
+	}
+
+	// knownhosts only parses files
+	file, err := ioutil.TempFile("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := file.Write(knownHosts); err != nil {
+		return nil, err
+	}
+
+	callback, err := knownhosts.New(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("parsing known hosts failed: %w", err)
+	}
+	return callback, nil
+}
+
+// HostKey is an SSH host key presented by a repository server
+type HostKey struct {
+	// KnownHostsLine pins the key in known_hosts format
+	KnownHostsLine string
+	// Fingerprint is the SHA256 fingerprint of the key as printed by ssh-keygen -lf
+	Fingerprint string
+}
+
+// ScanHostKey connects to the SSH server of repoURL and returns the host key it presents
+// without authenticating. The key must be confirmed before it is trusted.
+func ScanHostKey(repoURL string) (*HostKey, error) {
+	if IsHTTP(repoURL) {
+		return nil, errors.New("host keys are only scanned for SSH repositories")
+	}
+
+	endpoint, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository url failed: %w", err)
+	}
+
+	port := endpoint.Port
+	if port == 0 {
+		port = 22
+	}
+	address := net.JoinHostPort(endpoint.Host, strconv.Itoa(port))
+
+	var hostKey *HostKey
+	errScanned := errors.New("host key scanned")
+	conn, err := ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:    "git",
+		Timeout: 30 * time.Second,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = &HostKey{
+				KnownHostsLine: knownhosts.Line([]string{knownhosts.Normalize(address)}, key),
+				Fingerprint:    ssh.FingerprintSHA256(key),
+			}
+			// Abort the handshake as soon as the key is known
+			return errScanned
+		},
+	})
+	if conn != nil {
+		conn.Close()
+	}
+	if hostKey == nil {
+		return nil, fmt.Errorf("scanning host key of %s failed: %w", address, err)
+	}
+	return hostKey, nil
+}
+
+// TrustOnFirstUse returns known hosts pinning the host key the SSH repository server presents.
+// It migrates orbs which were configured before host keys were verified.
+// Within a process, the key is scanned only once. Callers must persist the returned known hosts,
+// so that the key is enforced from then on.
+func TrustOnFirstUse(monitor mntr.Monitor, repoURL string) ([]byte, error) {
+	firstUse.Lock()
+	defer firstUse.Unlock()
+
+	if knownHosts, ok := firstUse.knownHosts[repoURL]; ok {
+		return knownHosts, nil
+	}
+
+	hostKey, err := ScanHostKey(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor.WithFields(map[string]interface{}{
+		"url":         repoURL,
+		"fingerprint": hostKey.Fingerprint,
+	}).Info("No host key of the repository server is pinned yet, trusting it on first use")
+
+	knownHosts := []byte(hostKey.KnownHostsLine)
+	firstUse.knownHosts[repoURL] = knownHosts
+	return knownHosts, nil
+}
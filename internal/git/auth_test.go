@@ -0,0 +1,64 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	pinned := newHostKey(t)
+	other := newHostKey(t)
+	addr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+
+	if _, err := hostKeyCallback(nil); err != ErrNoKnownHosts {
+		t.Fatalf("expected ErrNoKnownHosts, got %v", err)
+	}
+
+	callback, err := hostKeyCallback([]byte(knownhosts.Line([]string{"git.example.com"}, pinned)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := callback("git.example.com:22", addr, pinned); err != nil {
+		t.Errorf("pinned key was rejected: %v", err)
+	}
+	if err := callback("git.example.com:22", addr, other); err == nil {
+		t.Error("changed key was accepted")
+	}
+	if err := callback("other.example.com:22", addr, pinned); err == nil {
+		t.Error("unknown host was accepted")
+	}
+}
+
+func TestHTTPAuth(t *testing.T) {
+	auth, err := httpAuth("https://oauth2@gitlab.example.com/me/my-orb.git", []byte("token\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	basic := auth.(*http.BasicAuth)
+	if basic.Username != "oauth2" || basic.Password != "token" {
+		t.Errorf("unexpected credentials %s:%s", basic.Username, basic.Password)
+	}
+
+	if _, err := httpAuth("https://gitlab.example.com/me/my-orb.git", nil); err == nil {
+		t.Error("missing token was accepted")
+	}
+}
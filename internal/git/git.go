@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,17 +18,28 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/caos/orbos/mntr"
-	"golang.org/x/crypto/ssh"
 	"gopkg.in/src-d/go-billy.v4"
 	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
-	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
 	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
 const (
 	writeCheckTag = "writecheck"
+	pushAttempts  = 8
+	// Objects of fetched commits are never pruned, so the clone is renewed from time to time
+	fetchesBeforeReclone = 50
+	// minHistoryDepth is the depth a clone is first deepened to for verifying older commits
+	minHistoryDepth = 16
+	pushBackoff     = 500 * time.Millisecond
+	maxPushBackoff  = 15 * time.Second
 )
 
 type Client struct {
@@ -35,13 +47,22 @@ type Client struct {
 	ctx       context.Context
 	committer string
 	email     string
-	auth      *gitssh.PublicKeys
+	auth      transport.AuthMethod
 	repo      *gogit.Repository
 	fs        billy.Filesystem
 	workTree  *gogit.Worktree
 	progress  io.Writer
 	repoURL   string
 	cloned    bool
+	depth     int
+	fetches   int
+	dir       string
+	// historyDepth is the depth verifying signatures needed so far
+	historyDepth int
+	signer       commitSigner
+	// base is the commit the local commits are based on
+	base    plumbing.Hash
+	pending []*pendingCommit
 }
 
 func New(ctx context.Context, monitor mntr.Monitor, committer, email string) *Client {
@@ -62,23 +83,27 @@ func (g *Client) GetURL() string {
 	return g.repoURL
 }
 
-func (g *Client) Configure(repoURL string, deploykey []byte) error {
-	signer, err := ssh.ParsePrivateKey(deploykey)
+// Configure prepares the client for connecting to the repository at repoURL.
+// For HTTP(S) URLs, repokey is an access token and the username is read from the URL.
+// For SSH URLs, repokey is a private deploy key and the servers host key must be pinned in knownHosts.
+func (g *Client) Configure(repoURL string, repokey []byte, knownHosts []byte) (err error) {
+
+	if IsHTTP(repoURL) {
+		g.auth, err = httpAuth(repoURL, repokey)
+	} else {
+		g.auth, err = sshAuth(repoURL, repokey, knownHosts)
+	}
 	if err != nil {
-		return errors.Wrap(err, "parsing deployment key failed")
+		return err
 	}
 
-	g.repoURL = repoURL
-	g.monitor = g.monitor.WithField("repository", repoURL)
-
-	g.auth = &gitssh.PublicKeys{
-		User:   "git",
-		Signer: signer,
+	if g.repoURL != repoURL {
+		g.repo = nil
+		g.cloned = false
 	}
 
-	// TODO: Fix
-	g.auth.HostKeyCallback = ssh.InsecureIgnoreHostKey()
-
+	g.repoURL = repoURL
+	g.monitor = g.monitor.WithField("repository", repoURL)
 	return nil
 }
 
@@ -161,9 +186,178 @@ func (g *Client) writeCheck() error {
 	return nil
 }
 
+// Clone makes the latest commit available. If the repository is already cloned,
+// only new commits are fetched and local changes are discarded.
 func (g *Client) Clone() (err error) {
+	_, span := g.monitor.StartSpan("git.clone")
+	defer func(started time.Time) {
+		observe("clone", started, err)
+		span.End(err)
+	}(time.Now())
+
+	if g.historyDepth > 0 {
+		return g.update(g.historyDepth)
+	}
+	return g.update(1)
+}
+
+// CloneHistory clones the whole history instead of only the latest commit
+func (g *Client) CloneHistory() (err error) {
+	_, span := g.monitor.StartSpan("git.clone")
+	defer func(started time.Time) {
+		observe("clone", started, err)
+		span.End(err)
+	}(time.Now())
+
+	return g.update(0)
+}
+
+// SetDirectory persists the clone in dir instead of keeping it in memory,
+// so it survives restarts and is only updated incrementally
+func (g *Client) SetDirectory(dir string) {
+	g.dir = dir
+	g.repo = nil
+	g.cloned = false
+}
+
+func (g *Client) update(depth int) error {
+	err := g.fetch(depth)
+	if err == nil {
+		return nil
+	}
+	if err != errNoClone {
+		g.monitor.WithFields(map[string]interface{}{
+			"reason": err.Error(),
+		}).Debug("Fetching failed, cloning again")
+	}
+	return g.cloneRetrying(depth)
+}
+
+var errNoClone = errors.New("no reusable clone")
+
+// fetch updates the existing clone to the remote head
+func (g *Client) fetch(depth int) error {
+
+	if g.repo == nil && g.dir != "" {
+		if err := g.open(); err != nil {
+			return errNoClone
+		}
+	}
+
+	if g.repo == nil || g.depth != depth || g.fetches >= fetchesBeforeReclone {
+		return errNoClone
+	}
+
+	head, err := g.repo.Head()
+	if err != nil {
+		return err
+	}
+
+	// A detached head is left by Checkout, so the branch to fetch is unknown
+	if !head.Name().IsBranch() {
+		return errNoClone
+	}
+
+	remoteHead, err := g.remoteHead(head.Name())
+	if err != nil {
+		return err
+	}
+
+	status, err := g.workTree.Status()
+	if err != nil {
+		return err
+	}
+
+	if remoteHead == head.Hash() && status.IsClean() {
+		g.monitor.Debug("Remote head unchanged")
+		g.base = remoteHead
+		g.pending = nil
+		return nil
+	}
+
+	g.monitor.Debug("Fetching")
+	remoteRef := plumbing.NewRemoteReferenceName("origin", head.Name().Short())
+	if err := g.repo.FetchContext(g.ctx, &gogit.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", head.Name(), remoteRef))},
+		Auth:       g.auth,
+		Depth:      depth,
+		Progress:   g.progress,
+		Force:      true,
+	}); err != nil && err != gogit.NoErrAlreadyUpToDate {
+		return err
+	}
+	g.fetches++
+
+	if err := g.workTree.Reset(&gogit.ResetOptions{
+		Commit: remoteHead,
+		Mode:   gogit.HardReset,
+	}); err != nil {
+		return err
+	}
+
+	if err := g.workTree.Clean(&gogit.CleanOptions{Dir: true}); err != nil {
+		return err
+	}
+	g.monitor.Debug("Fetched")
+
+	g.base = remoteHead
+	g.pending = nil
+	g.cloned = true
+	return nil
+}
+
+// open reuses a clone persisted in the directory
+func (g *Client) open() (err error) {
+	g.fs = osfs.New(g.dir)
+	g.repo, err = gogit.Open(filesystem.NewStorage(osfs.New(filepath.Join(g.dir, ".git")), cache.NewObjectLRUDefault()), g.fs)
+	if err != nil {
+		return err
+	}
+
+	remote, err := g.repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 || remote.Config().URLs[0] != g.repoURL {
+		g.repo = nil
+		return errNoClone
+	}
+
+	g.workTree, err = g.repo.Worktree()
+	if err != nil {
+		g.repo = nil
+		return err
+	}
+
+	g.depth = 1
+	if shallow, err := g.repo.Storer.Shallow(); err == nil && len(shallow) == 0 {
+		g.depth = 0
+	}
+	g.fetches = 0
+	return nil
+}
+
+// remoteHead lists the remote references like git ls-remote and returns the hash of branch
+func (g *Client) remoteHead(branch plumbing.ReferenceName) (plumbing.Hash, error) {
+	remote, err := g.repo.Remote("origin")
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	refs, err := remote.List(&gogit.ListOptions{Auth: g.auth})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	for _, ref := range refs {
+		if ref.Name() == branch {
+			return ref.Hash(), nil
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("branch %s not found", branch)
+}
+
+func (g *Client) cloneRetrying(depth int) (err error) {
 	for i := 0; i < 10; i++ {
-		if err = g.clone(); err == nil {
+		if err = g.clone(depth); err == nil {
 			return nil
 		}
 		time.Sleep(time.Second)
@@ -171,20 +365,26 @@ func (g *Client) Clone() (err error) {
 	return err
 }
 
-func (g *Client) clone() error {
+func (g *Client) clone(depth int) error {
 
-	g.fs = memfs.New()
+	storage, err := g.newStorage()
+	if err != nil {
+		return err
+	}
+	g.depth = depth
+	g.fetches = 0
+	g.pending = nil
 
 	g.monitor.Debug("Cloning")
-	var err error
-	g.repo, err = gogit.CloneContext(g.ctx, memory.NewStorage(), g.fs, &gogit.CloneOptions{
+	g.repo, err = gogit.CloneContext(g.ctx, storage, g.fs, &gogit.CloneOptions{
 		URL:          g.repoURL,
 		Auth:         g.auth,
 		SingleBranch: true,
-		Depth:        1,
+		Depth:        depth,
 		Progress:     g.progress,
 	})
 	if err != nil {
+		g.repo = nil
 		return errors.Wrapf(err, "cloning repository from %s failed", g.repoURL)
 	}
 	g.monitor.Debug("Cloned")
@@ -194,11 +394,28 @@ func (g *Client) clone() error {
 		panic(err)
 	}
 
+	if head, headErr := g.repo.Head(); headErr == nil {
+		g.base = head.Hash()
+	}
+
 	g.cloned = true
 
 	return nil
 }
 
+func (g *Client) newStorage() (storage.Storer, error) {
+	if g.dir == "" {
+		g.fs = memfs.New()
+		return memory.NewStorage(), nil
+	}
+
+	if err := os.RemoveAll(g.dir); err != nil {
+		return nil, errors.Wrapf(err, "removing %s failed", g.dir)
+	}
+	g.fs = osfs.New(g.dir)
+	return filesystem.NewStorage(osfs.New(filepath.Join(g.dir, ".git")), cache.NewObjectLRUDefault()), nil
+}
+
 func (g *Client) Read(path string) []byte {
 
 	readmonitor := g.monitor.WithFields(map[string]interface{}{
@@ -224,6 +441,18 @@ func (g *Client) Read(path string) []byte {
 	return fileBytes
 }
 
+func (g *Client) readFile(path string) ([]byte, error) {
+	file, err := g.fs.Open(path)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
 func (g *Client) ReadYamlIntoStruct(path string, struc interface{}) error {
 	data := g.Read(path)
 
@@ -292,17 +521,47 @@ func (g *Client) ReadFolder(path string) (map[string][]byte, []string, error) {
 	return dirBytes, subdirs, nil
 }
 
+// MergeFunc resolves conflicts between the local changes of a file and the ones pushed concurrently
+type MergeFunc func(base, ours, theirs []byte) ([]byte, error)
+
 type File struct {
 	Path    string
 	Content []byte
+	// Merge is called if the push is rejected due to concurrent changes.
+	// If Merge is nil, Content overwrites the concurrent changes
+	Merge MergeFunc
+}
+
+type pendingCommit struct {
+	msg   string
+	files []File
+	bases map[string][]byte
 }
 
 func (g *Client) StageAndCommit(msg string, files ...File) (bool, error) {
+	bases := make(map[string][]byte, len(files))
+	for _, f := range files {
+		base, err := g.readFile(f.Path)
+		if err != nil {
+			return false, err
+		}
+		bases[f.Path] = base
+	}
+
 	if g.stage(files...) {
 		return false, nil
 	}
 
-	return true, g.Commit(msg)
+	if err := g.commit(msg); err != nil {
+		return false, err
+	}
+
+	g.pending = append(g.pending, &pendingCommit{
+		msg:   msg,
+		files: files,
+		bases: bases,
+	})
+	return true, nil
 }
 
 func (g *Client) UpdateRemote(msg string, files ...File) error {
@@ -357,32 +616,140 @@ func (g *Client) stage(files ...File) bool {
 }
 
 func (g *Client) Commit(msg string) error {
+	if err := g.commit(msg); err != nil {
+		return err
+	}
+	g.pending = append(g.pending, &pendingCommit{msg: msg})
+	return nil
+}
 
-	if _, err := g.workTree.Commit(msg, &gogit.CommitOptions{
+func (g *Client) commit(msg string) error {
+
+	hash, err := g.workTree.Commit(msg, &gogit.CommitOptions{
 		Author: &object.Signature{
 			Name:  g.committer,
 			Email: g.email,
 			When:  time.Now(),
 		},
-	}); err != nil {
+	})
+	if err != nil {
 		return errors.Wrap(err, "committing changes failed")
 	}
+
+	if g.signer != nil {
+		if err := g.sign(hash); err != nil {
+			return errors.Wrap(err, "signing commit failed")
+		}
+	}
 	g.monitor.Debug("Changes commited")
 	return nil
 }
 
-func (g *Client) Push() error {
+// Push pushes all local commits. If the push is rejected because the remote branch moved,
+// the local commits are reapplied on top of the new head and the push is retried.
+func (g *Client) Push() (err error) {
+	_, span := g.monitor.StartSpan("git.push")
+	defer func(started time.Time) {
+		observe("push", started, err)
+		span.End(err)
+	}(time.Now())
+
+	for attempt := 0; attempt < pushAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff(attempt))
+			if rebaseErr := g.rebase(); rebaseErr != nil {
+				return errors.Wrap(rebaseErr, "reapplying local commits failed")
+			}
+		}
+
+		if err = g.push(); err == nil {
+			g.pending = nil
+			if head, headErr := g.repo.Head(); headErr == nil {
+				g.base = head.Hash()
+			}
+			g.monitor.Info("Repository pushed")
+			return nil
+		}
+
+		moved, checkErr := g.remoteMoved()
+		if checkErr != nil || !moved {
+			return errors.Wrap(err, "pushing repository failed")
+		}
 
-	err := g.repo.PushContext(g.ctx, &gogit.PushOptions{
+		g.monitor.WithFields(map[string]interface{}{
+			"attempt": attempt + 1,
+		}).Warn("Push rejected due to concurrent changes")
+	}
+	return errors.Wrapf(err, "pushing repository failed after %d attempts", pushAttempts)
+}
+
+func (g *Client) push() error {
+	return g.repo.PushContext(g.ctx, &gogit.PushOptions{
 		RemoteName: "origin",
 		//			RefSpecs:   refspecs,
 		Auth:     g.auth,
 		Progress: g.progress,
 	})
+}
+
+// remoteMoved returns true if the remote branch points to another commit than the local commits are based on
+func (g *Client) remoteMoved() (bool, error) {
+	head, err := g.repo.Head()
+	if err != nil {
+		return false, err
+	}
+
+	remoteHead, err := g.remoteHead(head.Name())
 	if err != nil {
-		return errors.Wrap(err, "pushing repository failed")
+		return false, err
 	}
+	return remoteHead != g.base, nil
+}
 
-	g.monitor.Info("Repository pushed")
+// rebase fetches the remote head and reapplies all pending commits on top of it
+func (g *Client) rebase() error {
+	pending := g.pending
+	if err := g.update(g.depth); err != nil {
+		return err
+	}
+
+	for _, commit := range pending {
+		if len(commit.files) == 0 {
+			if err := g.Commit(commit.msg); err != nil {
+				return err
+			}
+			continue
+		}
+
+		files := make([]File, len(commit.files))
+		for idx, f := range commit.files {
+			files[idx] = f
+			if f.Merge == nil {
+				continue
+			}
+			theirs, err := g.readFile(f.Path)
+			if err != nil {
+				return err
+			}
+			merged, err := f.Merge(commit.bases[f.Path], f.Content, theirs)
+			if err != nil {
+				return errors.Wrapf(err, "merging %s failed", f.Path)
+			}
+			files[idx].Content = merged
+		}
+
+		if _, err := g.StageAndCommit(commit.msg, files...); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func backoff(attempt int) time.Duration {
+	wait := pushBackoff << uint(attempt-1)
+	if wait > maxPushBackoff {
+		wait = maxPushBackoff
+	}
+	// Jitter prevents concurrent writers from retrying in lockstep
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
@@ -0,0 +1,196 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/caos/orbos/mntr"
+)
+
+func remoteRepository(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("local repositories need the git executable")
+	}
+
+	dir, err := ioutil.TempDir("", "orbos-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	remote := filepath.Join(dir, "remote.git")
+	if _, err := gogit.PlainInit(remote, true); err != nil {
+		t.Fatal(err)
+	}
+
+	seedDir := filepath.Join(dir, "seed")
+	seed, err := gogit.PlainInit(seedDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(seedDir, "state.yml"), []byte("a: 1\nb: 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := seed.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("state.yml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("seed", &gogit.CommitOptions{Author: &object.Signature{Name: "test", When: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seed.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.Push(&gogit.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+	return remote
+}
+
+func localClient(t *testing.T, remote string) *Client {
+	client := New(context.Background(), mntr.Monitor{}, "test", "test@caos.ch")
+	client.repoURL = remote
+	if err := client.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPushRebasesOnConcurrentChanges(t *testing.T) {
+	remote := remoteRepository(t)
+
+	first := localClient(t, remote)
+	second := localClient(t, remote)
+
+	if _, err := first.StageAndCommit("first", File{Path: "state.yml", Content: []byte("a: 2\nb: 1\n"), Merge: MergeYAML}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := second.StageAndCommit("second", File{Path: "state.yml", Content: []byte("a: 1\nb: 2\n"), Merge: MergeYAML}, File{Path: "other.yml", Content: []byte("c: 1\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := localClient(t, remote)
+	if state := string(result.Read("state.yml")); state != "a: 2\nb: 2\n" {
+		t.Errorf("concurrent changes were not merged: %s", state)
+	}
+	if other := string(result.Read("other.yml")); other != "c: 1\n" {
+		t.Errorf("unmerged file was not reapplied: %s", other)
+	}
+}
+
+func TestPushMergesConcurrentSecretWriters(t *testing.T) {
+	remote := remoteRepository(t)
+
+	seed := localClient(t, remote)
+	if err := seed.UpdateRemote("seed", File{Path: "secrets.yml", Content: []byte("a:\n  sealed: x:1\nb:\n  sealed: x:1\n")}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := localClient(t, remote)
+	second := localClient(t, remote)
+	third := localClient(t, remote)
+	merge := MergeSealedYAML(unsealTest)
+
+	// Every writer encrypts all secrets anew
+	if _, err := first.StageAndCommit("first", File{Path: "secrets.yml", Content: []byte("a:\n  sealed: y:2\nb:\n  sealed: y:1\n"), Merge: merge}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Push(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.StageAndCommit("second", File{Path: "secrets.yml", Content: []byte("a:\n  sealed: z:1\nb:\n  sealed: z:2\n"), Merge: merge}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := localClient(t, remote)
+	if secrets := string(result.Read("secrets.yml")); secrets != "a:\n    sealed: y:2\nb:\n    sealed: z:2\n" {
+		t.Errorf("concurrently written secrets were not merged: %s", secrets)
+	}
+
+	if _, err := third.StageAndCommit("third", File{Path: "secrets.yml", Content: []byte("a:\n  sealed: w:3\nb:\n  sealed: w:1\n"), Merge: merge}); err != nil {
+		t.Fatal(err)
+	}
+	if err := third.Push(); err == nil {
+		t.Error("expected the push overwriting a concurrently changed secret to fail")
+	}
+}
+
+func TestCloneFetchesIncrementally(t *testing.T) {
+	remote := remoteRepository(t)
+
+	dir, err := ioutil.TempDir("", "orbos-clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reader := New(context.Background(), mntr.Monitor{}, "test", "test@caos.ch")
+	reader.repoURL = remote
+	reader.SetDirectory(dir)
+	if err := reader.Clone(); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := localClient(t, remote)
+	if err := writer.UpdateRemote("changed", File{Path: "state.yml", Content: []byte("a: 2\n")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Local changes are discarded
+	if _, err := reader.StageAndCommit("discarded", File{Path: "local.yml", Content: []byte("b: 1\n")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reader.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if reader.fetches != 1 {
+		t.Errorf("expected one fetch, got %d", reader.fetches)
+	}
+	if state := string(reader.Read("state.yml")); state != "a: 2\n" {
+		t.Errorf("fetched change is missing: %s", state)
+	}
+	if local := reader.Read("local.yml"); len(local) != 0 {
+		t.Errorf("local change is not discarded: %s", string(local))
+	}
+
+	// A new client reuses the persisted clone
+	marker := filepath.Join(dir, ".git", "marker")
+	if err := ioutil.WriteFile(marker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	restarted := New(context.Background(), mntr.Monitor{}, "test", "test@caos.ch")
+	restarted.repoURL = remote
+	restarted.SetDirectory(dir)
+	if err := restarted.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("persisted clone was not reused: %v", err)
+	}
+	if state := string(restarted.Read("state.yml")); state != "a: 2\n" {
+		t.Errorf("persisted clone is outdated: %s", state)
+	}
+}
@@ -0,0 +1,199 @@
+package git
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+type Commit struct {
+	Hash    string
+	Author  string
+	Email   string
+	When    time.Time
+	Message string
+	// Files are the changed paths. They are only set by Changes
+	Files []string
+}
+
+func (c *Commit) ShortHash() string {
+	if len(c.Hash) < 7 {
+		return c.Hash
+	}
+	return c.Hash[:7]
+}
+
+func toCommit(c *object.Commit) *Commit {
+	return &Commit{
+		Hash:    c.Hash.String(),
+		Author:  c.Author.Name,
+		Email:   c.Author.Email,
+		When:    c.Author.When,
+		Message: c.Message,
+	}
+}
+
+// Log returns the commits changing at least one of the passed paths, the latest commit first.
+// The repository has to be cloned using CloneHistory for getting more than the latest commit.
+func (g *Client) Log(paths ...string) ([]*Commit, error) {
+
+	head, err := g.repo.Head()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting head failed")
+	}
+
+	found := make(map[string]*Commit)
+	for idx := range paths {
+		iter, err := g.repo.Log(&gogit.LogOptions{
+			From:     head.Hash(),
+			FileName: &paths[idx],
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading log for %s failed", paths[idx])
+		}
+
+		if err := iter.ForEach(func(c *object.Commit) error {
+			found[c.Hash.String()] = toCommit(c)
+			return nil
+		}); err != nil && err != storer.ErrStop && err != plumbing.ErrObjectNotFound {
+			// ErrObjectNotFound is returned when the history is shallow
+			return nil, errors.Wrapf(err, "iterating log for %s failed", paths[idx])
+		}
+	}
+
+	commits := make([]*Commit, 0, len(found))
+	for _, c := range found {
+		commits = append(commits, c)
+	}
+	sort.Slice(commits, func(i, j int) bool {
+		return commits[i].When.After(commits[j].When)
+	})
+	return commits, nil
+}
+
+// Changes returns the commits changing any file at or below one of the passed paths, the latest commit first.
+// Contrary to Log, the changed files are returned too.
+// The repository has to be cloned using CloneHistory for getting more than the latest commit.
+func (g *Client) Changes(paths ...string) ([]*Commit, error) {
+
+	head, err := g.repo.Head()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting head failed")
+	}
+
+	iter, err := g.repo.Log(&gogit.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading log failed")
+	}
+
+	commits := make([]*Commit, 0)
+	if err := iter.ForEach(func(c *object.Commit) error {
+		files, err := changedFiles(c)
+		if err == plumbing.ErrObjectNotFound {
+			// The history is shallow
+			return storer.ErrStop
+		}
+		if err != nil {
+			return errors.Wrapf(err, "diffing commit %s failed", c.Hash.String())
+		}
+
+		matching := make([]string, 0)
+		for _, file := range files {
+			if matchesAny(file, paths) {
+				matching = append(matching, file)
+			}
+		}
+		if len(matching) == 0 {
+			return nil
+		}
+
+		commit := toCommit(c)
+		commit.Files = matching
+		commits = append(commits, commit)
+		return nil
+	}); err != nil && err != storer.ErrStop && err != plumbing.ErrObjectNotFound {
+		return nil, errors.Wrap(err, "iterating log failed")
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].When.After(commits[j].When)
+	})
+	return commits, nil
+}
+
+func changedFiles(c *object.Commit) ([]string, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func matchesAny(file string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, path := range paths {
+		path = strings.TrimSuffix(path, "/")
+		if file == path || strings.HasPrefix(file, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Checkout sets the worktree to the state of the passed revision, so that Read returns the files content at that revision.
+func (g *Client) Checkout(revision string) error {
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return errors.Wrapf(err, "resolving revision %s failed", revision)
+	}
+
+	if err := g.workTree.Checkout(&gogit.CheckoutOptions{
+		Hash:  *hash,
+		Force: true,
+	}); err != nil {
+		return errors.Wrapf(err, "checking out revision %s failed", revision)
+	}
+	return nil
+}
+
+// HeadHash returns the hash of the currently checked out commit
+func (g *Client) HeadHash() (string, error) {
+	head, err := g.repo.Head()
+	if err != nil {
+		return "", errors.Wrap(err, "getting head failed")
+	}
+	return head.Hash().String(), nil
+}
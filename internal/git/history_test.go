@@ -0,0 +1,44 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangesMatchesFolders(t *testing.T) {
+	remote := remoteRepository(t)
+
+	client := localClient(t, remote)
+	if _, err := client.StageAndCommit("node agent", File{Path: "caos-internal/orbiter/node-agents-current/a.yml", Content: []byte("ready: true\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StageAndCommit("state", File{Path: "state.yml", Content: []byte("a: 2\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.CloneHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := client.Changes("caos-internal/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Message != "node agent" {
+		t.Fatalf("expected only the node agent commit, got %+v", changes)
+	}
+	if !reflect.DeepEqual(changes[0].Files, []string{"caos-internal/orbiter/node-agents-current/a.yml"}) {
+		t.Errorf("unexpected files %v", changes[0].Files)
+	}
+
+	all, err := client.Changes("state.yml", "caos-internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected the seed, node agent and state commits, got %d", len(all))
+	}
+}
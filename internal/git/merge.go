@@ -0,0 +1,177 @@
+package git
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// UnsealFunc returns the plaintext of an encrypted YAML node. sealed is false for all other nodes.
+type UnsealFunc func(node *yaml.Node) (plaintext string, sealed bool, err error)
+
+// MergeYAML is a MergeFunc for YAML files. Mappings are merged key by key,
+// so concurrent changes of different keys are both kept. If the same value is changed
+// concurrently, the local change wins.
+func MergeYAML(base, ours, theirs []byte) ([]byte, error) {
+	return MergeSealedYAML(nil)(base, ours, theirs)
+}
+
+// MergeSealedYAML returns a MergeFunc like MergeYAML that compares encrypted values by their plaintexts,
+// as encrypting the same plaintext twice results in different ciphertexts.
+// If an encrypted value is changed concurrently, merging fails instead of letting the local change win.
+func MergeSealedYAML(unseal UnsealFunc) MergeFunc {
+	return func(base, ours, theirs []byte) ([]byte, error) {
+
+		baseNode, err := parseYAML(base)
+		if err != nil {
+			return nil, err
+		}
+		oursNode, err := parseYAML(ours)
+		if err != nil {
+			return nil, err
+		}
+		theirsNode, err := parseYAML(theirs)
+		if err != nil {
+			return nil, err
+		}
+
+		merged, err := merger{unseal: unseal}.mergeNodes("", baseNode, oursNode, theirsNode)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			return []byte{}, nil
+		}
+		return yaml.Marshal(merged)
+	}
+}
+
+func parseYAML(data []byte) (*yaml.Node, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0], nil
+	}
+	return nil, nil
+}
+
+type merger struct {
+	unseal UnsealFunc
+}
+
+func (m merger) mergeNodes(path string, base, ours, theirs *yaml.Node) (*yaml.Node, error) {
+	switch {
+	case m.same(ours, theirs), m.same(base, theirs):
+		return ours, nil
+	case m.same(base, ours):
+		return theirs, nil
+	case m.sealed(base) || m.sealed(ours) || m.sealed(theirs):
+		return nil, errors.Errorf("encrypted value %s was changed concurrently", path)
+	case ours == nil || theirs == nil || ours.Kind != yaml.MappingNode || theirs.Kind != yaml.MappingNode:
+		return ours, nil
+	}
+
+	merged := *ours
+	merged.Content = make([]*yaml.Node, 0, len(ours.Content))
+
+	for i := 0; i+1 < len(ours.Content); i += 2 {
+		key := ours.Content[i].Value
+		baseValue := mappingValue(base, key)
+		theirValue := mappingValue(theirs, key)
+		ourValue := ours.Content[i+1]
+
+		// Removed concurrently and not changed locally
+		if theirValue == nil && baseValue != nil && m.same(baseValue, ourValue) {
+			continue
+		}
+
+		value := ourValue
+		if theirValue != nil {
+			var err error
+			if value, err = m.mergeNodes(childPath(path, key), baseValue, ourValue, theirValue); err != nil {
+				return nil, err
+			}
+		}
+		merged.Content = append(merged.Content, ours.Content[i], value)
+	}
+
+	for i := 0; i+1 < len(theirs.Content); i += 2 {
+		key := theirs.Content[i].Value
+		// Only keys added concurrently are missing
+		if mappingValue(ours, key) != nil || mappingValue(base, key) != nil {
+			continue
+		}
+		merged.Content = append(merged.Content, theirs.Content[i], theirs.Content[i+1])
+	}
+
+	return &merged, nil
+}
+
+// same returns true if the nodes are equal or if they are encryptions of the same plaintext.
+// Values that can't be decrypted are treated as changed.
+func (m merger) same(a, b *yaml.Node) bool {
+	if equalNodes(a, b) {
+		return true
+	}
+	if m.unseal == nil || a == nil || b == nil {
+		return false
+	}
+	aPlain, aSealed, aErr := m.unseal(a)
+	if !aSealed || aErr != nil {
+		return false
+	}
+	bPlain, bSealed, bErr := m.unseal(b)
+	return bSealed && bErr == nil && aPlain == bPlain
+}
+
+func (m merger) sealed(node *yaml.Node) bool {
+	if m.unseal == nil || node == nil {
+		return false
+	}
+	_, sealed, _ := m.unseal(node)
+	return sealed
+}
+
+func childPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func equalNodes(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind == yaml.AliasNode {
+		a = a.Alias
+	}
+	if b.Kind == yaml.AliasNode {
+		b = b.Alias
+	}
+	if a.Kind != b.Kind || a.Value != b.Value || a.ShortTag() != b.ShortTag() || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !equalNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeYAML(t *testing.T) {
+	base := `kind: test
+spec:
+  a: 1
+  b: 1
+  removed: 1
+`
+	ours := `kind: test
+spec:
+  a: 1
+  b: 2
+  removed: 1
+`
+	theirs := `kind: test
+spec:
+  a: 3
+  b: 1
+  added: 1
+`
+	expected := `kind: test
+spec:
+    a: 3
+    b: 2
+    added: 1
+`
+	merged, err := MergeYAML([]byte(base), []byte(ours), []byte(theirs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, string(merged))
+	}
+}
+
+func TestMergeYAMLConflict(t *testing.T) {
+	merged, err := MergeYAML([]byte("a: 1\n"), []byte("a: 2\n"), []byte("a: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != "a: 2\n" {
+		t.Errorf("local change did not win: %s", string(merged))
+	}
+}
+
+// unsealTest treats mappings with a sealed key as encrypted, their plaintexts follow a random prefix
+func unsealTest(node *yaml.Node) (string, bool, error) {
+	sealed := mappingValue(node, "sealed")
+	if sealed == nil {
+		return "", false, nil
+	}
+	parts := strings.SplitN(sealed.Value, ":", 2)
+	if len(parts) != 2 {
+		return "", true, errors.New("not decryptable")
+	}
+	return parts[1], true, nil
+}
+
+func TestMergeSealedYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		ours     string
+		theirs   string
+		expected string
+		wantErr  bool
+	}{{
+		name:     "It should keep concurrent changes of re-encrypted values",
+		ours:     "a:\n  sealed: y:1\nb:\n  sealed: y:2\n",
+		theirs:   "a:\n  sealed: z:2\nb:\n  sealed: z:1\n",
+		expected: "a:\n    sealed: z:2\nb:\n    sealed: y:2\n",
+	}, {
+		name:     "It should keep local changes of values re-encrypted concurrently",
+		ours:     "a:\n  sealed: y:2\nb:\n  sealed: y:1\n",
+		theirs:   "a:\n  sealed: z:1\nb:\n  sealed: z:1\n",
+		expected: "a:\n    sealed: y:2\nb:\n    sealed: y:1\n",
+	}, {
+		name:     "It should accept the same value written concurrently",
+		ours:     "a:\n  sealed: y:2\nb:\n  sealed: y:1\n",
+		theirs:   "a:\n  sealed: z:2\nb:\n  sealed: z:1\n",
+		expected: "a:\n    sealed: y:2\nb:\n    sealed: y:1\n",
+	}, {
+		name:    "It should fail if an encrypted value is changed concurrently",
+		ours:    "a:\n  sealed: y:2\nb:\n  sealed: y:1\n",
+		theirs:  "a:\n  sealed: z:3\nb:\n  sealed: z:1\n",
+		wantErr: true,
+	}, {
+		name:    "It should treat values that can't be decrypted as changed",
+		base:    "a:\n  sealed: broken\nb:\n  sealed: x:1\n",
+		ours:    "a:\n  sealed: y:1\nb:\n  sealed: y:1\n",
+		theirs:  "a:\n  sealed: z:2\nb:\n  sealed: z:1\n",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := tt.base
+			if base == "" {
+				base = "a:\n  sealed: x:1\nb:\n  sealed: x:1\n"
+			}
+			merged, err := MergeSealedYAML(unsealTest)([]byte(base), []byte(tt.ours), []byte(tt.theirs))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeSealedYAML() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(merged) != tt.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", tt.expected, string(merged))
+			}
+		})
+	}
+}
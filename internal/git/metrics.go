@@ -0,0 +1,36 @@
+package git
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	durationsHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "orbos_git_duration_seconds",
+			Help:    "Duration of cloning and pushing the orbs repository, including retries.",
+			Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"operation"},
+	)
+	failuresCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orbos_git_failures_total",
+			Help: "Failed clones and pushes of the orbs repository.",
+		},
+		[]string{"operation"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(durationsHistogram, failuresCounter)
+}
+
+func observe(operation string, started time.Time, err error) {
+	durationsHistogram.WithLabelValues(operation).Observe(time.Since(started).Seconds())
+	if err != nil {
+		failuresCounter.WithLabelValues(operation).Inc()
+	}
+}
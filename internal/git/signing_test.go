@@ -0,0 +1,203 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func signingKey(t *testing.T) ([]byte, string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), string(ssh.MarshalAuthorizedKey(sshPub))
+}
+
+func TestVerifyLastChange(t *testing.T) {
+	remote := remoteRepository(t)
+	key, trusted := signingKey(t)
+	_, other := signingKey(t)
+
+	signing := localClient(t, remote)
+	if err := signing.ConfigureSigning(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signing.StageAndCommit("signed", File{Path: "state.yml", Content: []byte("a: 2\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := signing.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	verifying := localClient(t, remote)
+	if _, err := verifying.VerifyLastChange([]string{trusted}, "state.yml"); err != nil {
+		t.Errorf("signed commit was refused: %v", err)
+	}
+	if _, err := verifying.VerifyLastChange([]string{other}, "state.yml"); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("expected ErrUntrusted for an unknown key, got %v", err)
+	}
+
+	unsigned := localClient(t, remote)
+	if _, err := unsigned.StageAndCommit("unsigned", File{Path: "state.yml", Content: []byte("a: 3\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := unsigned.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifying.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifying.VerifyLastChange([]string{trusted}, "state.yml"); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("expected ErrUntrusted for an unsigned commit, got %v", err)
+	}
+}
+
+func TestTrustRequiresSignedPolicyChanges(t *testing.T) {
+	remote := remoteRepository(t)
+	key, trusted := signingKey(t)
+	_, other := signingKey(t)
+
+	signing := localClient(t, remote)
+	if err := signing.ConfigureSigning(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signing.StageAndCommit("signed", File{Path: "state.yml", Content: []byte("a: 2\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := signing.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	verifying := localClient(t, remote)
+	trust := &Trust{}
+	if _, err := trust.Verify(verifying, []string{trusted}, "state.yml"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A change declaring another trusted key is still verified against the previous one
+	if _, err := trust.Verify(verifying, []string{other}, "state.yml"); err != nil {
+		t.Errorf("change signed by the previously trusted key was refused: %v", err)
+	}
+	if _, err := trust.Verify(verifying, []string{other}, "state.yml"); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("expected ErrUntrusted after trusting another key, got %v", err)
+	}
+}
+
+func TestTrustSurvivesRestarts(t *testing.T) {
+	remote := remoteRepository(t)
+	key, trusted := signingKey(t)
+
+	signing := localClient(t, remote)
+	if err := signing.ConfigureSigning(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signing.StageAndCommit("signed", File{Path: "state.yml", Content: []byte("a: 2\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := signing.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	verifying := localClient(t, remote)
+	trust := &Trust{}
+	if _, err := trust.Verify(verifying, []string{trusted}, "state.yml"); err != nil {
+		t.Fatal(err)
+	}
+
+	state := trust.State()
+	if len(state.Keys) != 1 || state.Commit == "" {
+		t.Fatalf("expected the trusted key and the verified commit to be persisted, got %+v", state)
+	}
+
+	unsigned := localClient(t, remote)
+	if _, err := unsigned.StageAndCommit("unsigned", File{Path: "state.yml", Content: []byte("a: 3\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := unsigned.Push(); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifying.Clone(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unsigned change dropping the policy is refused after a restart
+	restarted := &Trust{}
+	restarted.Restore(state)
+	if _, err := restarted.Verify(verifying, nil, "state.yml"); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("expected ErrUntrusted for dropping the policy unsigned, got %v", err)
+	}
+}
+
+func TestVerificationDeepensShallowClones(t *testing.T) {
+	remote := remoteRepository(t)
+	key, trusted := signingKey(t)
+
+	signing := localClient(t, remote)
+	if err := signing.ConfigureSigning(key); err != nil {
+		t.Fatal(err)
+	}
+	commitOther := func(from, to int) {
+		for i := from; i < to; i++ {
+			if _, err := signing.StageAndCommit("other", File{Path: "other.yml", Content: []byte(fmt.Sprintf("c: %d\n", i))}); err != nil {
+				t.Fatal(err)
+			}
+			if err := signing.Push(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	commitOther(0, 20)
+	if _, err := signing.StageAndCommit("signed", File{Path: "state.yml", Content: []byte("a: 2\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := signing.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	verifying := localClient(t, remote)
+	trust := &Trust{}
+	if _, err := trust.Verify(verifying, []string{trusted}, "state.yml"); err != nil {
+		t.Fatal(err)
+	}
+	state := trust.State()
+
+	commitOther(20, 40)
+	if _, err := signing.StageAndCommit("signed again", File{Path: "state.yml", Content: []byte("a: 3\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := signing.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The last verified commit is older than the last change
+	restarted := localClient(t, remote)
+	restartedTrust := &Trust{}
+	restartedTrust.Restore(state)
+	if _, err := restartedTrust.Verify(restarted, []string{trusted}, "state.yml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if restarted.depth == 0 {
+		t.Error("expected the clone to be deepened only as far as needed, but the whole history was cloned")
+	}
+	if shallow, err := restarted.repo.Storer.Shallow(); err != nil || len(shallow) == 0 {
+		t.Errorf("expected the clone to stay shallow, got %v, %v", shallow, err)
+	}
+}
@@ -0,0 +1,134 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes"
+)
+
+// Event is an event as the ingestion server stores and returns it
+type Event struct {
+	Orb      string                 `json:"orb"`
+	Type     string                 `json:"type"`
+	Time     time.Time              `json:"time"`
+	Received time.Time              `json:"received"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// ToEvent converts a pushed event of an orb
+func ToEvent(orb string, req *EventRequest, received time.Time) (*Event, error) {
+
+	evt := &Event{
+		Orb:      orb,
+		Type:     req.GetType(),
+		Received: received,
+		Time:     received,
+	}
+
+	if req.GetCreationDate() != nil {
+		created, err := ptypes.Timestamp(req.GetCreationDate())
+		if err != nil {
+			return nil, fmt.Errorf("invalid creation date of %s event: %w", evt.Type, err)
+		}
+		evt.Time = created
+	}
+
+	if req.GetData() != nil {
+		data, err := (&jsonpb.Marshaler{}).MarshalToString(req.GetData())
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &evt.Data); err != nil {
+			return nil, err
+		}
+	}
+	return evt, nil
+}
+
+// Filter selects events. Zero values match everything
+type Filter struct {
+	// Orb is the orbs repository URL
+	Orb string
+	// Types match if any of them equals the events type or is a prefix of it ending with a dot, like machine.
+	Types []string
+	Since time.Time
+	Until time.Time
+	// Limit returns only the latest events if it is greater than zero
+	Limit int
+}
+
+// Matches returns true if the event is selected by the filter
+func (f Filter) Matches(evt *Event) bool {
+	if f.Orb != "" && f.Orb != evt.Orb {
+		return false
+	}
+	if !f.Since.IsZero() && evt.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !evt.Time.Before(f.Until) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, typ := range f.Types {
+		if typ == evt.Type || strings.HasSuffix(typ, ".") && strings.HasPrefix(evt.Type, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// Values encodes the filter as query parameters of the query API
+func (f Filter) Values() url.Values {
+	values := url.Values{}
+	if f.Orb != "" {
+		values.Set("orb", f.Orb)
+	}
+	for _, typ := range f.Types {
+		values.Add("type", typ)
+	}
+	if !f.Since.IsZero() {
+		values.Set("since", f.Since.Format(time.RFC3339Nano))
+	}
+	if !f.Until.IsZero() {
+		values.Set("until", f.Until.Format(time.RFC3339Nano))
+	}
+	if f.Limit > 0 {
+		values.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return values
+}
+
+// ParseFilter decodes the query parameters of the query API
+func ParseFilter(values url.Values) (Filter, error) {
+
+	filter := Filter{
+		Orb:   values.Get("orb"),
+		Types: values["type"],
+	}
+
+	var err error
+	if since := values.Get("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339Nano, since); err != nil {
+			return filter, fmt.Errorf("parsing since failed: %w", err)
+		}
+	}
+	if until := values.Get("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339Nano, until); err != nil {
+			return filter, fmt.Errorf("parsing until failed: %w", err)
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return filter, fmt.Errorf("parsing limit failed: %w", err)
+		}
+	}
+	return filter, nil
+}
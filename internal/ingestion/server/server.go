@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/caos/orbos/internal/ingestion"
+	"github.com/caos/orbos/mntr"
+)
+
+// Server implements the ingestion API and the query API
+type Server struct {
+	ingestion.UnimplementedIngestionServiceServer
+	monitor mntr.Monitor
+	store   *Store
+	token   string
+}
+
+// New returns a server persisting to the store.
+// If token is not empty, clients must send it as bearer token
+func New(monitor mntr.Monitor, store *Store, token string) *Server {
+	return &Server{
+		monitor: monitor,
+		store:   store,
+		token:   token,
+	}
+}
+
+// Register registers the ingestion API with an authenticating interceptor
+func (s *Server) Register(opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(append(opts, grpc.UnaryInterceptor(s.authenticate))...)
+	ingestion.RegisterIngestionServiceServer(srv, s)
+	return srv
+}
+
+func (s *Server) authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasSuffix(info.FullMethod, "/Healthz") || strings.HasSuffix(info.FullMethod, "/Ready") {
+		return handler(ctx, req)
+	}
+
+	var auth string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			auth = values[0]
+		}
+	}
+	if !s.authorized(auth) {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authorized(header string) bool {
+	if s.token == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+s.token)) == 1
+}
+
+func (s *Server) Healthz(context.Context, *empty.Empty) (*empty.Empty, error) {
+	return &empty.Empty{}, nil
+}
+
+func (s *Server) Ready(context.Context, *empty.Empty) (*empty.Empty, error) {
+	if err := s.store.Ping(); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &empty.Empty{}, nil
+}
+
+func (s *Server) PushEvents(_ context.Context, req *ingestion.EventsRequest) (*empty.Empty, error) {
+
+	if req.GetOrb() == "" {
+		return nil, status.Error(codes.InvalidArgument, "orb is required")
+	}
+
+	received := time.Now()
+	events := make([]*ingestion.Event, len(req.GetEvents()))
+	for idx, pushed := range req.GetEvents() {
+		evt, err := ingestion.ToEvent(req.GetOrb(), pushed, received)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		events[idx] = evt
+	}
+
+	if err := s.store.Add(events...); err != nil {
+		s.monitor.Error(fmt.Errorf("persisting %d events of orb %s failed: %w", len(events), req.GetOrb(), err))
+		return nil, status.Error(codes.Internal, "persisting events failed")
+	}
+
+	s.monitor.WithFields(map[string]interface{}{
+		"orb":    req.GetOrb(),
+		"events": len(events),
+	}).Debug("Events persisted")
+	return &empty.Empty{}, nil
+}
+
+// Handler returns the query API.
+// GET /events returns the events matching the query parameters orb, type, since, until and limit.
+// GET /orbs returns an overview of all orbs
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/events", s.query(func(r *http.Request) (interface{}, error) {
+		filter, err := ingestion.ParseFilter(r.URL.Query())
+		if err != nil {
+			return nil, badRequest{err}
+		}
+		return s.store.Query(filter)
+	}))
+	mux.HandleFunc("/orbs", s.query(func(*http.Request) (interface{}, error) {
+		return s.store.Orbs()
+	}))
+	return mux
+}
+
+type badRequest struct{ error }
+
+func (s *Server) query(get func(*http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !s.authorized(r.Header.Get("Authorization")) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		result, err := get(r)
+		if _, ok := err.(badRequest); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			s.monitor.Error(fmt.Errorf("querying %s failed: %w", r.URL.String(), err))
+			http.Error(w, "querying failed", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			s.monitor.Error(fmt.Errorf("writing response failed: %w", err))
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package server implements the ingestion API and persists the pushed events in an embedded database
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/caos/orbos/internal/ingestion"
+)
+
+// Store persists events in a bbolt database file with a bucket per orb.
+// Keys start with the events creation time, so events are iterated chronologically
+type Store struct {
+	db *bolt.DB
+}
+
+// OrbSummary is the overview of an orbs events
+type OrbSummary struct {
+	Orb    string           `json:"orb"`
+	Events int              `json:"events"`
+	Last   *ingestion.Event `json:"last,omitempty"`
+}
+
+// Open opens or creates the database file
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s failed: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Ping returns an error if the database is not accessible
+func (s *Store) Ping() error {
+	return s.db.View(func(*bolt.Tx) error { return nil })
+}
+
+// Add persists the events of an orb
+func (s *Store) Add(events ...*ingestion.Event) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, evt := range events {
+			if evt.Orb == "" {
+				return errors.New("orb is empty")
+			}
+			bucket, err := tx.CreateBucketIfNotExists([]byte(evt.Orb))
+			if err != nil {
+				return err
+			}
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(evt)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key(evt.Time, seq), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Query returns the events selected by the filter in chronological order
+func (s *Store) Query(filter ingestion.Filter) ([]*ingestion.Event, error) {
+
+	var events []*ingestion.Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(orb []byte, bucket *bolt.Bucket) error {
+			if filter.Orb != "" && filter.Orb != string(orb) {
+				return nil
+			}
+			orbEvents, err := query(bucket, filter)
+			events = append(events, orbEvents...)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
+
+// query iterates backwards, so a limited query only reads the latest events
+func query(bucket *bolt.Bucket, filter ingestion.Filter) ([]*ingestion.Event, error) {
+
+	cursor := bucket.Cursor()
+	var k, v []byte
+	if filter.Until.IsZero() {
+		k, v = cursor.Last()
+	} else if k, v = cursor.Seek(key(filter.Until, 0)); k == nil {
+		k, v = cursor.Last()
+	} else {
+		k, v = cursor.Prev()
+	}
+
+	var events []*ingestion.Event
+	for ; k != nil; k, v = cursor.Prev() {
+		evt := &ingestion.Event{}
+		if err := json.Unmarshal(v, evt); err != nil {
+			return nil, err
+		}
+		if !filter.Since.IsZero() && evt.Time.Before(filter.Since) {
+			break
+		}
+		if !filter.Matches(evt) {
+			continue
+		}
+		events = append(events, evt)
+		if filter.Limit > 0 && len(events) >= filter.Limit {
+			break
+		}
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
+
+// Orbs returns an overview of all orbs that pushed events
+func (s *Store) Orbs() ([]OrbSummary, error) {
+	var orbs []OrbSummary
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(orb []byte, bucket *bolt.Bucket) error {
+			summary := OrbSummary{
+				Orb:    string(orb),
+				Events: bucket.Stats().KeyN,
+			}
+			if _, v := bucket.Cursor().Last(); v != nil {
+				summary.Last = &ingestion.Event{}
+				if err := json.Unmarshal(v, summary.Last); err != nil {
+					return err
+				}
+			}
+			orbs = append(orbs, summary)
+			return nil
+		})
+	})
+	return orbs, err
+}
+
+// Prune deletes all events created before the passed time
+func (s *Store) Prune(before time.Time) (int, error) {
+	var pruned int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			cursor := bucket.Cursor()
+			limit := key(before, 0)
+			for k, _ := cursor.First(); k != nil && bytes.Compare(k, limit) < 0; k, _ = cursor.First() {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				pruned++
+			}
+			return nil
+		})
+	})
+	return pruned, err
+}
+
+func key(t time.Time, seq uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(k[8:], seq)
+	return k
+}
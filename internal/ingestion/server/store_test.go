@@ -0,0 +1,83 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caos/orbos/internal/ingestion"
+)
+
+func TestStoreQuery(t *testing.T) {
+
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	start := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	at := func(orb, typ string, minutes int) *ingestion.Event {
+		return &ingestion.Event{Orb: orb, Type: typ, Time: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	if err := store.Add(
+		at("prod", ingestion.TypeMachineCreated, 2),
+		at("prod", ingestion.TypeNodeJoined, 3),
+		at("dev", ingestion.TypeMachineRemoved, 1),
+		at("prod", ingestion.TypeMachineRebooting, 4),
+		at("prod", ingestion.TypeMachineCreated, 0),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	types := func(events []*ingestion.Event) []string {
+		typs := make([]string, len(events))
+		for idx, evt := range events {
+			typs[idx] = evt.Orb + "/" + evt.Type
+		}
+		return typs
+	}
+
+	for _, c := range []struct {
+		name     string
+		filter   ingestion.Filter
+		expected []string
+	}{{
+		name:     "all orbs chronologically",
+		filter:   ingestion.Filter{},
+		expected: []string{"prod/machine.created", "dev/machine.removed", "prod/machine.created", "prod/node.joined", "prod/machine.rebooting"},
+	}, {
+		name:     "type prefix of one orb",
+		filter:   ingestion.Filter{Orb: "prod", Types: []string{"machine."}},
+		expected: []string{"prod/machine.created", "prod/machine.created", "prod/machine.rebooting"},
+	}, {
+		name:     "latest events in time range",
+		filter:   ingestion.Filter{Since: start.Add(time.Minute), Until: start.Add(4 * time.Minute), Limit: 2},
+		expected: []string{"prod/machine.created", "prod/node.joined"},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			events, err := store.Query(c.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := types(events); len(got) != len(c.expected) {
+				t.Fatalf("expected %v, got %v", c.expected, got)
+			} else {
+				for idx := range got {
+					if got[idx] != c.expected[idx] {
+						t.Fatalf("expected %v, got %v", c.expected, got)
+					}
+				}
+			}
+		})
+	}
+
+	pruned, err := store.Prune(start.Add(2 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 2 {
+		t.Errorf("expected 2 pruned events, got %d", pruned)
+	}
+}
@@ -0,0 +1,51 @@
+package ingestion
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Event types pushed by orbos
+const (
+	TypeOrbiterTookOff     = "orbiter.tookoff"
+	TypeOrbiterRunning     = "orbiter.running"
+	TypeOrbiterUntrusted   = "orbiter.untrusted"
+	TypeOperatorTookOff    = "operator.tookoff"
+	TypeMachineCreated     = "machine.created"
+	TypeMachineRemoved     = "machine.removed"
+	TypeMachineRebooting   = "machine.rebooting"
+	TypeNodeJoined         = "node.joined"
+	TypeNodeDrained        = "node.drained"
+	TypeNodeDeleted        = "node.deleted"
+	TypeNodeUnready        = "node.unready"
+	TypeClusterInitialized = "cluster.initialized"
+	TypeKubernetesUpgraded = "kubernetes.upgraded"
+	TypeFirewallChanged    = "firewall.changed"
+	TypeBackupFinished     = "backup.finished"
+	TypeOrbDestroyed       = "orb.destroyed"
+)
+
+// changes maps the messages passed to mntr.Monitor.Changed to event types
+var changes = map[string]string{
+	"machine created":        TypeMachineCreated,
+	"machine removed":        TypeMachineRemoved,
+	"rebooting":              TypeMachineRebooting,
+	"node joined":            TypeNodeJoined,
+	"node drained":           TypeNodeDrained,
+	"node deleted":           TypeNodeDeleted,
+	"marked node as unready": TypeNodeUnready,
+	"cluster initialized":    TypeClusterInitialized,
+	"kubernetes upgraded":    TypeKubernetesUpgraded,
+	"firewall changed":       TypeFirewallChanged,
+	"backup finished":        TypeBackupFinished,
+	"orb destroyed":          TypeOrbDestroyed,
+}
+
+// ChangedType returns the event type of a message passed to mntr.Monitor.Changed.
+// Unknown messages are prefixed with the namespace, which is orbiter, nodeagent, boom or zitadel
+func ChangedType(namespace, evt string) string {
+	if typ, ok := changes[strings.ToLower(evt)]; ok {
+		return typ
+	}
+	return strings.ReplaceAll(strings.ToLower(fmt.Sprintf("%s.%s", namespace, evt)), " ", ".")
+}
@@ -50,4 +50,6 @@ type Logs struct {
 	Prometheus bool `json:"prometheus"`
 	//Bool if logs will get persisted for the metrics-secret
 	MetricsServer bool `json:"metrics-server" yaml:"metrics-server"`
+	//Bool if logs will get persisted for orbiter, boom and zitadel
+	Orbos bool `json:"orbos"`
 }
@@ -55,9 +55,9 @@ func (a *App) CleanUp() error {
 	return nil
 }
 
-func (a *App) ReadSpecs(gitCrdConf *gitcrdconfig.Config, repoURL string, repoKey []byte) error {
+func (a *App) ReadSpecs(gitCrdConf *gitcrdconfig.Config, repoURL string, repoKey, knownHosts []byte) error {
 	c := gitcrd.New(gitCrdConf)
-	if err := c.Clone(repoURL, repoKey); err != nil {
+	if err := c.Clone(repoURL, repoKey, knownHosts); err != nil {
 		return err
 	}
 
@@ -80,6 +80,16 @@ func getGrafanaDashboards(dashboardsfolder string, toolsetCRDSpec *toolsetslates
 		providers = append(providers, provider)
 	}
 
+	if toolsetCRDSpec.MetricsPersisting != nil && (toolsetCRDSpec.MetricsPersisting.Metrics == nil || toolsetCRDSpec.MetricsPersisting.Metrics.Orbiter) {
+		provider := &Provider{
+			ConfigMaps: []string{
+				"grafana-dashboard-orbiter",
+			},
+			Folder: filepath.Join(dashboardsfolder, "orbiter"),
+		}
+		providers = append(providers, provider)
+	}
+
 	if toolsetCRDSpec.MetricsPersisting != nil && (toolsetCRDSpec.MetricsPersisting.Metrics == nil || toolsetCRDSpec.MetricsPersisting.Metrics.Zitadel) {
 		provider := &Provider{
 			ConfigMaps: []string{
@@ -13,6 +13,7 @@ import (
 	lologs "github.com/caos/orbos/internal/operator/boom/application/applications/loggingoperator/logs"
 	"github.com/caos/orbos/internal/operator/boom/application/applications/loki/info"
 	mslogs "github.com/caos/orbos/internal/operator/boom/application/applications/metricsserver/logs"
+	orboslogs "github.com/caos/orbos/internal/operator/boom/application/applications/orbiter/logs"
 	plogs "github.com/caos/orbos/internal/operator/boom/application/applications/prometheus/logs"
 	pnelogs "github.com/caos/orbos/internal/operator/boom/application/applications/prometheusnodeexporter/logs"
 	pologs "github.com/caos/orbos/internal/operator/boom/application/applications/prometheusoperator/logs"
@@ -111,6 +112,10 @@ func getAllFlows(toolsetCRDSpec *toolsetslatest.ToolsetSpec, outputNames []strin
 		flows = append(flows, logging.NewFlow(pselogs.GetFlow(outputNames, clusterOutputs)))
 	}
 
+	if toolsetCRDSpec.LogsPersisting.Logs == nil || toolsetCRDSpec.LogsPersisting.Logs.Orbos {
+		flows = append(flows, logging.NewFlow(orboslogs.GetFlow(outputNames, clusterOutputs)))
+	}
+
 	return flows
 }
 
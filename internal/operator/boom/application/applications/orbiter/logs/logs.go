@@ -0,0 +1,21 @@
+package logs
+
+import (
+	"github.com/caos/orbos/internal/operator/boom/application/applications/loggingoperator/logging"
+)
+
+// GetFlow selects the orbiter, boom and zitadel operators, which all log json
+func GetFlow(outputs []string, clusterOutputs []string) *logging.FlowConfig {
+	ls := map[string]string{
+		"app.kubernetes.io/part-of": "orbos",
+	}
+
+	return &logging.FlowConfig{
+		Name:           "flow-orbos",
+		Namespace:      "caos-system",
+		SelectLabels:   ls,
+		Outputs:        outputs,
+		ClusterOutputs: clusterOutputs,
+		ParserType:     "json",
+	}
+}
@@ -28,11 +28,11 @@ func GetServicemonitor(instanceName string) *servicemonitor.Config {
 
 	metricRelabelings := []*servicemonitor.ConfigRelabeling{{
 		Action:       "keep",
-		Regex:        "probe",
+		Regex:        "probe|orbiter_.+|orbos_git_.+",
 		SourceLabels: []string{"__name__"},
 	}, {
 		Action: "labelkeep",
-		Regex:  "__.+|job|name|type|target",
+		Regex:  "__.+|job|name|type|target|outcome|le|provider|pool|state|machine|commit|current|desired|reason|operation",
 	}, {
 		Action:       "replace",
 		SourceLabels: []string{"__name__"},
@@ -95,6 +95,8 @@ groups:
      record: caos_upstream_probe_ryg
    - expr: max_over_time(caos_probe{type="VIP"}[1m])
      record: caos_vip_probe_ryg
+   - expr: clamp_max(sum(increase(caos_orbiter_iteration_duration_seconds_count{outcome!="error"}[15m])), 1)
+     record: caos_orbiter_ryg
    - expr: sum(1 - avg(rate(dist_node_cpu_seconds_total[5m])))
      record: caos_cluster_cpu_utilisation_5m
    - expr: 100 - (avg by (instance) (irate(dist_node_cpu_seconds_total[5m])) * 100)
@@ -18,6 +18,7 @@ import (
 	"github.com/caos/orbos/internal/operator/boom/templator/helm"
 	helperTemp "github.com/caos/orbos/internal/operator/boom/templator/helper"
 	"github.com/caos/orbos/internal/operator/boom/templator/yaml"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
 	"github.com/caos/orbos/internal/utils/clientgo"
 	"github.com/caos/orbos/mntr"
 )
@@ -35,6 +36,8 @@ type Bundle struct {
 	HelmTemplator     templator.Templator
 	YamlTemplator     templator.Templator
 	monitor           mntr.Monitor
+	eventsOnce        sync.Once
+	eventsClient      *kubernetes.Client
 }
 
 func New(conf *config.Config) *Bundle {
@@ -140,19 +143,30 @@ func (b *Bundle) ReconcileApplication(currentResourceList []*clientgo.Resource,
 		"application": appName,
 		"action":      "reconciling",
 	}
-	monitor := b.monitor.WithFields(logFields)
+	monitor, span := b.monitor.WithFields(logFields).StartSpan("boom.reconcile")
+
+	var (
+		err          error
+		deploy       bool
+		hadResources bool
+	)
+	defer func() {
+		span.End(err)
+		b.recordReconciled(appName.String(), deploy, hadResources, err)
+		errChan <- err
+	}()
 
 	app, found := b.Applications[appName]
 	if !found {
-		err := errors.New("Application not found")
+		err = errors.New("Application not found")
 		monitor.Error(err)
-		errChan <- err
 		return
 	}
 	monitor.Info("Start")
 
-	deploy := app.Deploy(spec)
+	deploy = app.Deploy(spec)
 	currentApplicationResourceList := current.FilterForApplication(appName, currentResourceList)
+	hadResources = len(currentApplicationResourceList) > 0
 
 	var resultFunc func(string, string) error
 	if Testmode {
@@ -170,10 +184,9 @@ func (b *Bundle) ReconcileApplication(currentResourceList []*clientgo.Resource,
 	_, usedHelm := app.(application.HelmApplication)
 	if usedHelm {
 		templatorName := helm.GetName()
-		err := b.HelmTemplator.Template(app, spec, resultFunc)
+		err = b.HelmTemplator.Template(app, spec, resultFunc)
 		if err != nil {
 			metrics.FailureReconcilingApplication(appName.String(), templatorName.String(), deploy)
-			errChan <- err
 			return
 		}
 		metrics.SuccessfulReconcilingApplication(appName.String(), templatorName.String(), deploy)
@@ -181,15 +194,13 @@ func (b *Bundle) ReconcileApplication(currentResourceList []*clientgo.Resource,
 	_, usedYaml := app.(application.YAMLApplication)
 	if usedYaml {
 		templatorName := yaml.GetName()
-		err := b.YamlTemplator.Template(app, spec, resultFunc)
+		err = b.YamlTemplator.Template(app, spec, resultFunc)
 		if err != nil {
 			metrics.FailureReconcilingApplication(appName.String(), templatorName.String(), deploy)
-			errChan <- err
 			return
 		}
 		metrics.SuccessfulReconcilingApplication(appName.String(), templatorName.String(), deploy)
 	}
 
 	monitor.Info("Done")
-	errChan <- nil
 }
@@ -0,0 +1,48 @@
+package bundle
+
+import (
+	"fmt"
+
+	core "k8s.io/api/core/v1"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
+	"github.com/caos/orbos/internal/utils/clientgo"
+)
+
+// BOOMs own deployment is the involved object of its Kubernetes Events
+var boomDeployment = kubernetes.ObjectReference("apps/v1", "Deployment", "caos-system", "boom")
+
+func (b *Bundle) recordEvent(eventType, reason, message string) {
+	if Testmode {
+		return
+	}
+
+	b.eventsOnce.Do(func() {
+		conf, err := clientgo.GetClusterConfig()
+		if err != nil {
+			b.monitor.Debug(fmt.Sprintf("Not recording Kubernetes events: %s", err.Error()))
+			return
+		}
+		client := kubernetes.NewK8sClient(b.monitor, nil)
+		if err := client.RefreshConfig(conf); err != nil {
+			b.monitor.Debug(fmt.Sprintf("Not recording Kubernetes events: %s", err.Error()))
+			return
+		}
+		b.eventsClient = client
+	})
+
+	if b.eventsClient != nil {
+		b.eventsClient.RecordEvent(boomDeployment, kubernetes.ComponentBoom, eventType, reason, message)
+	}
+}
+
+func (b *Bundle) recordReconciled(app string, deploy, hadResources bool, err error) {
+	switch {
+	case err != nil:
+		b.recordEvent(core.EventTypeWarning, kubernetes.ReasonReconcileFailed, fmt.Sprintf("Reconciling application %s failed: %s", app, err.Error()))
+	case deploy:
+		b.recordEvent(core.EventTypeNormal, kubernetes.ReasonApplied, fmt.Sprintf("Application %s applied", app))
+	case hadResources:
+		b.recordEvent(core.EventTypeNormal, kubernetes.ReasonDeleted, fmt.Sprintf("Application %s deleted", app))
+	}
+}
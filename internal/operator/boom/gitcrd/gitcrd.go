@@ -19,6 +19,7 @@ import (
 	"github.com/caos/orbos/internal/operator/boom/current"
 	"github.com/caos/orbos/internal/operator/boom/gitcrd/config"
 	"github.com/caos/orbos/internal/operator/boom/metrics"
+	"github.com/caos/orbos/internal/secret"
 	"github.com/caos/orbos/internal/utils/clientgo"
 	"github.com/caos/orbos/internal/utils/helper"
 	"github.com/caos/orbos/internal/utils/kubectl"
@@ -61,8 +62,8 @@ func New(conf *config.Config) *GitCrd {
 	return gitCrd
 }
 
-func (c *GitCrd) Clone(url string, key []byte) error {
-	err := c.git.Configure(url, key)
+func (c *GitCrd) Clone(url string, key, knownHosts []byte) error {
+	err := c.git.Configure(url, key, knownHosts)
 	if err != nil {
 		c.monitor.Error(err)
 		return err
@@ -75,6 +76,11 @@ func (c *GitCrd) Clone(url string, key []byte) error {
 		return err
 	}
 	metrics.SuccessfulGitClone(url)
+
+	if err := secret.LoadRecipients(c.git); err != nil {
+		c.monitor.Error(err)
+		return err
+	}
 	return nil
 }
 
@@ -13,6 +13,7 @@ import (
 	"github.com/caos/orbos/internal/operator/boom/app"
 	gconfig "github.com/caos/orbos/internal/operator/boom/application/applications/grafana/config"
 	gitcrdconfig "github.com/caos/orbos/internal/operator/boom/gitcrd/config"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
 	"github.com/caos/orbos/internal/utils/clientgo"
 	"github.com/caos/orbos/mntr"
 	"github.com/pkg/errors"
@@ -71,7 +72,7 @@ func gitConf(monitor mntr.Monitor, client *git.Client, toolsDirectoryPath string
 	}
 }
 
-func task(monitor mntr.Monitor, orbpath string, gitcrdConf gitcrdconfig.Config, readSpecs func(gitCrdConf *gitcrdconfig.Config, repoURL string, repoKey []byte) error, do func() error) func() {
+func task(monitor mntr.Monitor, orbpath string, gitcrdConf gitcrdconfig.Config, readSpecs func(gitCrdConf *gitcrdconfig.Config, repoURL string, repoKey, knownHosts []byte) error, do func() error) func() {
 	return func() {
 		// TODO: use a function scoped error variable
 		started := time.Now()
@@ -82,7 +83,12 @@ func task(monitor mntr.Monitor, orbpath string, gitcrdConf gitcrdconfig.Config,
 			return
 		}
 
-		if err := readSpecs(&gitcrdConf, orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+		if err := orbConfig.PinKnownHosts(monitor, kubernetes.PersistOrbConfigFunc(monitor, orbConfig)); err != nil {
+			monitor.Error(err)
+			return
+		}
+
+		if err := readSpecs(&gitcrdConf, orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 			monitor.Error(errors.Wrap(err, "unable to start supervised crd"))
 		}
 
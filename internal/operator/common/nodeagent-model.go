@@ -525,8 +525,9 @@ func (n *CurrentNodeAgents) Get(id string) (*NodeAgentCurrent, bool) {
 }
 
 type NodeAgentsSpec struct {
-	Commit     string
-	NodeAgents DesiredNodeAgents
+	Commit       string
+	Verification *Verification `yaml:",omitempty"`
+	NodeAgents   DesiredNodeAgents
 }
 
 type DesiredNodeAgents struct {
@@ -0,0 +1,17 @@
+package common
+
+// Verification is the signature verification policy for desired state.
+// If TrustedKeys is not empty, only desired state changes committed with a signature
+// of one of the keys are applied. Keys are SSH public keys in authorized_keys format
+// or armored PGP public keys.
+type Verification struct {
+	TrustedKeys []string `yaml:"trustedKeys,omitempty"`
+}
+
+// Keys returns the trusted keys. A nil policy trusts no keys, so it disables the verification.
+func (v *Verification) Keys() []string {
+	if v == nil {
+		return nil
+	}
+	return v.TrustedKeys
+}
@@ -0,0 +1,66 @@
+package nodeagent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the USER_HZ value the kernel uses for process times in /proc, which is 100 on all supported architectures
+const clockTicks = 100
+
+// booted returns when the machine was booted.
+// In a container, uptime returns when the host was booted,
+// so the start of the containers init process is returned instead
+func booted() (time.Time, error) {
+	if exec.Command("systemd-detect-virt", "--container", "--quiet").Run() == nil {
+		return initStarted()
+	}
+
+	dateTime, err := exec.Command("uptime", "-s").CombinedOutput()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse("2006-01-02 15:04:05", strings.TrimSuffix(string(dateTime), "\n"))
+}
+
+func initStarted() (time.Time, error) {
+	procStat, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var btime int64 = -1
+	for _, line := range strings.Split(string(procStat), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "btime" {
+			if btime, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+				return time.Time{}, err
+			}
+		}
+	}
+	if btime < 0 {
+		return time.Time{}, fmt.Errorf("no btime found in /proc/stat")
+	}
+
+	initStat, err := ioutil.ReadFile("/proc/1/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// The command in the second field may contain spaces, so fields are counted from its closing parenthesis
+	stat := string(initStat)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("unexpected format of /proc/1/stat")
+	}
+	startTicks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(btime+startTicks/clockTicks, 0).UTC(), nil
+}
@@ -5,8 +5,6 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"strings"
-	"time"
 
 	"github.com/caos/orbos/internal/operator/common"
 	"github.com/caos/orbos/mntr"
@@ -54,14 +52,7 @@ func prepareQuery(monitor mntr.Monitor, commit string, firewallEnsurer FirewallE
 
 		defer persistReadyness(curr.NodeIsReady)
 
-		dateTime, err := exec.Command("uptime", "-s").CombinedOutput()
-		if err != nil {
-			return noop, err
-		}
-
-		//dateTime := strings.Fields(string(who))[2:]
-		//str := strings.Join(dateTime, " ") + ":00"
-		t, err := time.Parse("2006-01-02 15:04:05", strings.TrimSuffix(string(dateTime), "\n"))
+		t, err := booted()
 		if err != nil {
 			return noop, err
 		}
@@ -75,7 +66,7 @@ func prepareQuery(monitor mntr.Monitor, commit string, firewallEnsurer FirewallE
 				return noop, nil
 			}
 			return func() error {
-				monitor.Info("Rebooting")
+				monitor.Changed("Rebooting")
 				if err := exec.Command("sudo", "reboot").Run(); err != nil {
 					return fmt.Errorf("rebooting system failed: %w", err)
 				}
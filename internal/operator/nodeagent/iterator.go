@@ -3,14 +3,18 @@
 package nodeagent
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"runtime/debug"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/caos/orbos/internal/api"
 	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/orb"
 	"github.com/caos/orbos/mntr"
 )
 
@@ -34,10 +38,108 @@ func RepoKey() ([]byte, error) {
 	return key, err
 }
 
+// RepoKnownHosts returns the pinned host keys of the repository server.
+// They are not needed for HTTP(S) repositories, so a missing file is no error.
+// Machines which were set up before host keys were pinned trust the host key on first use.
+func RepoKnownHosts(monitor mntr.Monitor, repoURL string) ([]byte, error) {
+
+	path := "/var/orbiter/repo-known-hosts"
+	knownHosts, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading known hosts from %s failed: %w", path, err)
+	}
+
+	if len(bytes.TrimSpace(knownHosts)) > 0 || git.IsHTTP(repoURL) {
+		return knownHosts, nil
+	}
+
+	knownHosts, err = git.TrustOnFirstUse(monitor, repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(path, knownHosts, 0400); err != nil {
+		return nil, fmt.Errorf("persisting the pinned host key to %s failed: %w", path, err)
+	}
+	return knownHosts, nil
+}
+
+// Ingestion returns the orbs URL with the ingestion config the orbiter wrote.
+// It returns nil if pushing events is not configured.
+func Ingestion() (*orb.Orb, error) {
+
+	path := "/var/orbiter/ingestion"
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || err == nil && len(data) == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ingestion config from %s failed: %w", path, err)
+	}
+
+	ingestion := &orb.Ingestion{}
+	if err := yaml.Unmarshal(data, ingestion); err != nil {
+		return nil, fmt.Errorf("parsing ingestion config from %s failed: %w", path, err)
+	}
+
+	repoURL, err := ioutil.ReadFile("/var/orbiter/repo-url")
+	if err != nil {
+		return nil, err
+	}
+
+	return &orb.Orb{
+		URL:       string(repoURL),
+		Ingestion: ingestion,
+	}, nil
+}
+
+const trustedKeysPath = "/var/orbiter/trusted-keys"
+
+// loadTrust restores the keys trusted before the node agent restarted
+func loadTrust() (*git.Trust, error) {
+	trust := &git.Trust{}
+
+	data, err := ioutil.ReadFile(trustedKeysPath)
+	if os.IsNotExist(err) {
+		return trust, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading trusted keys from %s failed: %w", trustedKeysPath, err)
+	}
+
+	keys := make([]string, 0)
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing trusted keys from %s failed: %w", trustedKeysPath, err)
+	}
+	trust.Remember(keys)
+	return trust, nil
+}
+
+func saveTrust(trust *git.Trust) error {
+	keys := trust.Trusted()
+	if len(keys) == 0 {
+		if err := os.Remove(trustedKeysPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := yaml.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(trustedKeysPath, data, 0600)
+}
+
 func Iterator(monitor mntr.Monitor, gitClient *git.Client, nodeAgentCommit string, id string, firewallEnsurer FirewallEnsurer, conv Converter, before func() error) func() {
 
 	doQuery := prepareQuery(monitor, nodeAgentCommit, firewallEnsurer, conv)
 
+	trust, err := loadTrust()
+	if err != nil {
+		panic(err)
+	}
+
 	return func() {
 
 		repoKey, err := RepoKey()
@@ -52,7 +154,13 @@ func Iterator(monitor mntr.Monitor, gitClient *git.Client, nodeAgentCommit strin
 			return
 		}
 
-		if err := gitClient.Configure(string(repoURL), repoKey); err != nil {
+		knownHosts, err := RepoKnownHosts(monitor, string(repoURL))
+		if err != nil {
+			monitor.Error(err)
+			return
+		}
+
+		if err := gitClient.Configure(string(repoURL), repoKey, knownHosts); err != nil {
 			monitor.Error(err)
 			return
 		}
@@ -68,6 +176,16 @@ func Iterator(monitor mntr.Monitor, gitClient *git.Client, nodeAgentCommit strin
 			return
 		}
 
+		if _, err := trust.Verify(gitClient, desired.Spec.Verification.Keys(), "caos-internal/orbiter/node-agents-desired.yml"); err != nil {
+			monitor.Error(fmt.Errorf("refusing desired state: %w", err))
+			return
+		}
+
+		if err := saveTrust(trust); err != nil {
+			monitor.Error(fmt.Errorf("persisting trusted keys failed: %w", err))
+			return
+		}
+
 		naDesired, ok := desired.Spec.NodeAgents.Get(id)
 		if !ok {
 			monitor.Error(fmt.Errorf("no desired state for node agent with id %s found", id))
@@ -103,25 +221,13 @@ func Iterator(monitor mntr.Monitor, gitClient *git.Client, nodeAgentCommit strin
 			return
 		}
 
-		readCurrent := func() common.NodeAgentsCurrentKind {
-			if err := gitClient.Clone(); err != nil {
-				panic(err)
-			}
-			current := common.NodeAgentsCurrentKind{}
-			yaml.Unmarshal(gitClient.Read("caos-internal/orbiter/node-agents-current.yml"), &current)
-			current.Kind = "nodeagent.caos.ch/NodeAgents"
-			current.Version = "v0"
-			return current
+		if err := gitClient.Clone(); err != nil {
+			monitor.Error(err)
+			return
 		}
 
-		current := readCurrent()
-		current.Current.Set(id, curr)
-
 		reconciledCurrentStateMsg := "Current state reconciled"
-		reconciledCurrent, err := gitClient.StageAndCommit(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: reconciledCurrentStateMsg}}), git.File{
-			Path:    "caos-internal/orbiter/node-agents-current.yml",
-			Content: common.MarshalYAML(current),
-		})
+		reconciledCurrent, err := gitClient.StageAndCommit(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: reconciledCurrentStateMsg}}), api.NodeAgentCurrentFile(id, curr))
 		if err != nil {
 			monitor.Error(fmt.Errorf("commiting event \"%s\" failed: %s", reconciledCurrentStateMsg, err.Error()))
 			return
@@ -137,20 +243,19 @@ func Iterator(monitor mntr.Monitor, gitClient *git.Client, nodeAgentCommit strin
 			return
 		}
 
-		current = readCurrent()
+		if err := gitClient.Clone(); err != nil {
+			monitor.Error(err)
+			return
+		}
 
 		for _, event := range events {
-			current.Current.Set(id, event.current)
-			changed, err := gitClient.StageAndCommit(event.commit, git.File{
-				Path:    "caos-internal/orbiter/node-agents-current.yml",
-				Content: common.MarshalYAML(current),
-			})
+			changed, err := gitClient.StageAndCommit(event.commit, api.NodeAgentCurrentFile(id, event.current))
 			if err != nil {
 				monitor.Error(fmt.Errorf("commiting event \"%s\" failed: %s", event.commit, err.Error()))
 				return
 			}
 			if !changed {
-				monitor.Error(fmt.Errorf("event has no effect:", event.commit))
+				monitor.Error(fmt.Errorf("event has no effect: %s", event.commit))
 				return
 			}
 		}
@@ -17,10 +17,12 @@ type retAdapt struct {
 	err       error
 }
 
-func AdaptFuncGoroutine(adapt func() (QueryFunc, DestroyFunc, ConfigureFunc, bool, map[string]*secret.Secret, error)) (QueryFunc, DestroyFunc, ConfigureFunc, bool, map[string]*secret.Secret, error) {
+func AdaptFuncGoroutine(monitor mntr.Monitor, adapt func() (QueryFunc, DestroyFunc, ConfigureFunc, bool, map[string]*secret.Secret, error)) (QueryFunc, DestroyFunc, ConfigureFunc, bool, map[string]*secret.Secret, error) {
+	_, span := monitor.EnterSpan("adapt")
 	retChan := make(chan retAdapt)
 	go func() {
 		query, destroy, configure, migrate, secret, err := adapt()
+		span.End(err)
 		retChan <- retAdapt{query, destroy, configure, migrate, secret, err}
 	}()
 	ret := <-retChan
@@ -4,13 +4,18 @@ import (
 	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/ingestion"
 	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/store"
 )
 
 type Config struct {
 	OrbiterCommit string
 	GitClient     *git.Client
-	Adapt         AdaptFunc
-	FinishedChan  chan struct{}
-	PushEvents    func(events []*ingestion.EventRequest) error
-	OrbConfig     orb.Orb
+	// CurrentStore persists the current state. If it is nil, the current state is pushed to GitClient
+	CurrentStore store.Store
+	// TrustStore persists the trusted keys, so a restart doesn't reset them. If it is nil, they are only kept in memory
+	TrustStore   store.Store
+	Adapt        AdaptFunc
+	FinishedChan chan struct{}
+	PushEvents   func(events []*ingestion.EventRequest) error
+	OrbConfig    orb.Orb
 }
@@ -24,6 +24,10 @@ func DestroyFuncGoroutine(query func() error) error {
 }
 
 func Destroy(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc, finishedChan chan struct{}) error {
+	if err := secret.LoadRecipients(gitClient); err != nil {
+		return err
+	}
+
 	treeDesired, err := api.ReadOrbiterYml(gitClient)
 	if err != nil {
 		return err
@@ -35,7 +39,7 @@ func Destroy(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc, finis
 		return adapt(monitor, finishedChan, treeDesired, treeCurrent)
 	}
 
-	_, destroy, _, _, _, err := AdaptFuncGoroutine(adaptFunc)
+	_, destroy, _, _, _, err := AdaptFuncGoroutine(monitor, adaptFunc)
 	if err != nil {
 		return err
 	}
@@ -45,19 +49,23 @@ func Destroy(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc, finis
 	}
 
 	monitor.OnChange = func(evt string, fields map[string]string) {
-		if err := gitClient.UpdateRemote(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: evt}}), git.File{
+		if err := gitClient.Clone(); err != nil {
+			panic(err)
+		}
+		clearedNodeAgents, err := api.ClearNodeAgentsCurrentFiles(gitClient)
+		if err != nil {
+			panic(err)
+		}
+		if err := gitClient.UpdateRemote(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: evt}}), append(clearedNodeAgents, git.File{
 			Path:    "caos-internal/orbiter/current.yml",
 			Content: []byte(""),
-		}, git.File{
-			Path:    "caos-internal/orbiter/node-agents-current.yml",
-			Content: []byte(""),
 		}, git.File{
 			Path:    "caos-internal/orbiter/node-agents-desired.yml",
 			Content: []byte(""),
 		}, git.File{
 			Path:    "orbiter.yml",
 			Content: common.MarshalYAML(treeDesired),
-		}); err != nil {
+		})...); err != nil {
 			panic(err)
 		}
 	}
@@ -54,7 +54,7 @@ func GetQueryAndDestroyFuncs(
 				clusterCurrent,
 			)
 		}
-		return orbiter.AdaptFuncGoroutine(adaptFunc)
+		return orbiter.AdaptFuncGoroutine(monitor, adaptFunc)
 		//				subassemblers[provIdx] = static.New(providerPath, generalOverwriteSpec, staticadapter.New(providermonitor, providerID, "/healthz", updatesDisabled, cfg.NodeAgent))
 	default:
 		return nil, nil, nil, false, nil, errors.Errorf("unknown cluster kind %s", clusterTree.Common.Kind)
@@ -45,6 +45,12 @@ type Machine interface {
 	ReplacementRequired() (required bool, require func(), unrequire func())
 }
 
+// ZonedMachine is implemented by machines of providers that spread machines across failure zones
+type ZonedMachine interface {
+	Machine
+	Zone() string
+}
+
 type Machines []Machine
 
 func (c Machines) ToChan() <-chan Machine {
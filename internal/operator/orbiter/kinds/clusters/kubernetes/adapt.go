@@ -120,6 +120,13 @@ func AdaptFunc(
 		}
 
 		current := &CurrentCluster{}
+		if currentTree.Original != nil {
+			// The autoscaler decides based on the timestamps of the last iterations
+			previous := &Current{}
+			if err := currentTree.Original.Decode(previous); err == nil && previous.Current != nil {
+				current.Autoscaling = previous.Current.Autoscaling
+			}
+		}
 		currentTree.Parsed = &Current{
 			Common: tree.Common{
 				Kind:    "orbiter.caos.ch/KubernetesCluster",
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -11,6 +12,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	macherrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"gopkg.in/yaml.v2"
@@ -36,10 +38,46 @@ func EnsureCommonArtifacts(monitor mntr.Monitor, client *Client) error {
 	})
 }
 
-func EnsureConfigArtifacts(monitor mntr.Monitor, client *Client, orb *orb.Orb) error {
+// DeployedOrbConfig returns the orbconfig the operators in the cluster run with or nil if none is deployed yet
+func DeployedOrbConfig(client *Client) (*orb.Orb, error) {
+	secret, err := client.GetSecret("caos-system", "caos")
+	if macherrs.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deployed := &orb.Orb{}
+	if err := yaml.Unmarshal(secret.Data["orbconfig"], deployed); err != nil {
+		return nil, err
+	}
+	return deployed, nil
+}
+
+// PersistOrbConfigFunc returns a function writing the orbconfig back to its file.
+// In a cluster, the file is mounted read-only from the caos secret, so the secret is updated instead
+func PersistOrbConfigFunc(monitor mntr.Monitor, orbConfig *orb.Orb) func() error {
+	return func() error {
+		if err := orbConfig.WriteBackOrbConfig(); err == nil {
+			return nil
+		}
+
+		inCluster := ""
+		client := NewK8sClient(monitor, &inCluster)
+		if !client.Available() {
+			return errors.New("orbconfig is neither writable nor is the Kubernetes API available")
+		}
+		return EnsureConfigArtifacts(monitor, client, orbConfig.ForOperators(orbConfig.Identity, orbConfig.Signingkey))
+	}
+}
+
+// EnsureConfigArtifacts deploys the orbconfig for the operators.
+// It must be built by orb.ForOperators, so it contains no secrets of the engineer
+func EnsureConfigArtifacts(monitor mntr.Monitor, client *Client, operators *orb.Orb) error {
 	monitor.Debug("Ensuring configuration artifacts")
 
-	orbfile, err := yaml.Marshal(orb)
+	orbfile, err := yaml.Marshal(operators)
 	if err != nil {
 		return err
 	}
@@ -160,7 +198,7 @@ func EnsureZitadelArtifacts(
 						Name:            "zitadel",
 						ImagePullPolicy: core.PullIfNotPresent,
 						Image:           fmt.Sprintf("%s/caos/orbos:%s", imageRegistry, version),
-						Command:         []string{"/orbctl", "takeoff", "zitadel", "-f", "/secrets/orbconfig"},
+						Command:         []string{"/orbctl", "takeoff", "zitadel", "-f", "/secrets/orbconfig", "--log-format", "json"},
 						Args:            []string{},
 						Ports: []core.ContainerPort{{
 							Name:          "metrics",
@@ -318,7 +356,7 @@ func EnsureBoomArtifacts(
 						Name:            "boom",
 						ImagePullPolicy: core.PullIfNotPresent,
 						Image:           fmt.Sprintf("%s/caos/orbos:%s", imageRegistry, version),
-						Command:         []string{"/orbctl", "takeoff", "boom", "-f", "/secrets/orbconfig"},
+						Command:         []string{"/orbctl", "takeoff", "boom", "-f", "/secrets/orbconfig", "--log-format", "json"},
 						Args:            []string{},
 						Ports: []core.ContainerPort{{
 							Name:          "metrics",
@@ -429,7 +467,7 @@ func EnsureOrbiterArtifacts(
 						Name:            "orbiter",
 						ImagePullPolicy: core.PullIfNotPresent,
 						Image:           fmt.Sprintf("%s/caos/orbos:%s", imageRegistry, orbiterversion),
-						Command:         []string{"/orbctl", "--orbconfig", "/etc/orbiter/orbconfig", "takeoff", "orbiter", "--recur", "--ingestion="},
+						Command:         []string{"/orbctl", "--orbconfig", "/etc/orbiter/orbconfig", "--log-format", "json", "takeoff", "orbiter", "--recur", "--ingestion="},
 						VolumeMounts: []core.VolumeMount{{
 							Name:      "keys",
 							ReadOnly:  true,
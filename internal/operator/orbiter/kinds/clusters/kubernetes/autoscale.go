@@ -0,0 +1,582 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	mach "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/kubectl/pkg/util/resource"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/mntr"
+)
+
+const (
+	defaultScaleOutDelay      = 2 * time.Minute
+	defaultScaleInDelay       = 10 * time.Minute
+	defaultScaleInUtilization = 50
+	defaultScaleCooldown      = 10 * time.Minute
+	defaultMaxScaleStep       = 1
+)
+
+// Autoscaling tunes when the autoscaler adds and removes a pools nodes
+type Autoscaling struct {
+	// Nodes are added for pods that are unschedulable for longer than this delay
+	//@default: 2m
+	ScaleOutDelay time.Duration `yaml:",omitempty"`
+	// Nodes are removed if they are underutilized for longer than this delay
+	//@default: 10m
+	ScaleInDelay time.Duration `yaml:",omitempty"`
+	// A node is underutilized if its pods request less than this percentage of the nodes allocatable cpu and memory.
+	// DaemonSet pods are not considered
+	//@default: 50
+	ScaleInUtilization int `yaml:",omitempty"`
+	// Minimum time between two scaling decisions in the pool
+	//@default: 10m
+	Cooldown time.Duration `yaml:",omitempty"`
+	// Maximum count of nodes that are added or removed in one iteration
+	//@default: 1
+	MaxStep int `yaml:",omitempty"`
+}
+
+// AutoscalingStatus reports the autoscalers view of a pool
+type AutoscalingStatus struct {
+	Provider          string
+	Pool              string
+	Nodes             int
+	MinNodes          int
+	MaxNodes          int
+	UnschedulablePods int
+	// Nodes that are candidates for being removed and since when they are underutilized
+	Underutilized map[string]time.Time `yaml:",omitempty"`
+	LastDecision  string               `yaml:",omitempty"`
+	LastScaled    *time.Time           `yaml:",omitempty"`
+}
+
+var (
+	autoscalerNodesGauge = newClusterGauges(
+		prometheus.GaugeOpts{
+			Name: "orbiter_autoscaler_desired_nodes",
+			Help: "Desired nodes per autoscaled provider and pool.",
+		},
+		[]string{"provider", "pool"},
+	)
+	autoscalerUnschedulableGauge = newClusterGauges(
+		prometheus.GaugeOpts{
+			Name: "orbiter_autoscaler_unschedulable_pods",
+			Help: "Pods that are unschedulable for longer than the scale out delay per autoscaled provider and pool.",
+		},
+		[]string{"provider", "pool"},
+	)
+	autoscalerDecisionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orbiter_autoscaler_decisions_total",
+			Help: "Scaling decisions per provider, pool and direction.",
+		},
+		[]string{"provider", "pool", "direction"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(autoscalerNodesGauge.vec, autoscalerUnschedulableGauge.vec, autoscalerDecisionsCounter)
+}
+
+func (p *Pool) autoscaled() bool {
+	return p.MaxNodes > 0
+}
+
+func (p *Pool) autoscaling() Autoscaling {
+	a := Autoscaling{}
+	if p.Autoscaling != nil {
+		a = *p.Autoscaling
+	}
+	if a.ScaleOutDelay <= 0 {
+		a.ScaleOutDelay = defaultScaleOutDelay
+	}
+	if a.ScaleInDelay <= 0 {
+		a.ScaleInDelay = defaultScaleInDelay
+	}
+	if a.ScaleInUtilization <= 0 {
+		a.ScaleInUtilization = defaultScaleInUtilization
+	}
+	if a.Cooldown <= 0 {
+		a.Cooldown = defaultScaleCooldown
+	}
+	if a.MaxStep <= 0 {
+		a.MaxStep = defaultMaxScaleStep
+	}
+	return a
+}
+
+func (p *Pool) validateAutoscaling(tier Tier) error {
+	if !p.autoscaled() {
+		if p.MinNodes != 0 || p.Autoscaling != nil {
+			return errors.Errorf("pool %s configures autoscaling without maxNodes", p.Pool)
+		}
+		return nil
+	}
+	if tier == Controlplane {
+		return errors.New("the controlplane can't be autoscaled")
+	}
+	if p.MinNodes < 0 || p.MinNodes > p.MaxNodes {
+		return errors.Errorf("minNodes in pool %s must be between 0 and maxNodes %d", p.Pool, p.MaxNodes)
+	}
+	if p.Autoscaling == nil {
+		return nil
+	}
+	if p.Autoscaling.ScaleInUtilization < 0 || p.Autoscaling.ScaleInUtilization > 100 {
+		return errors.Errorf("scaleInUtilization in pool %s must be a percentage between 0 and 100", p.Pool)
+	}
+	if p.Autoscaling.MaxStep < 0 ||
+		p.Autoscaling.ScaleOutDelay < 0 ||
+		p.Autoscaling.ScaleInDelay < 0 ||
+		p.Autoscaling.Cooldown < 0 {
+		return errors.Errorf("autoscaling values in pool %s must not be negative", p.Pool)
+	}
+	return nil
+}
+
+// autoscale adjusts the desired nodes of autoscaled worker pools.
+// Added nodes are created by ensureUpScale, removed nodes are drained and deleted by scaleDown.
+// The decisions depend on the timestamps of the previous iterations, which are read from and written to current.Autoscaling
+func autoscale(
+	monitor mntr.Monitor,
+	clusterID string,
+	desired *DesiredV0,
+	current *CurrentCluster,
+	workers []*initializedPool,
+	workerMachines []*initializedMachine,
+	k8sClient *Client,
+	pdf api.PushDesiredFunc,
+	windows *maintenanceWindows,
+) error {
+
+	autoscalerNodesGauge.reset(clusterID)
+	autoscalerUnschedulableGauge.reset(clusterID)
+
+	var pools []*initializedPool
+	for _, pool := range workers {
+		if pool.desired.autoscaled() {
+			pools = append(pools, pool)
+		}
+	}
+	if len(pools) == 0 {
+		current.Autoscaling = nil
+		return nil
+	}
+	if !k8sClient.Available() {
+		// Keep the timestamps for when the API is reachable again
+		return nil
+	}
+
+	previous := make(map[string]*AutoscalingStatus, len(current.Autoscaling))
+	for _, status := range current.Autoscaling {
+		previous[status.Provider+"/"+status.Pool] = status
+	}
+	current.Autoscaling = nil
+
+	podList, err := k8sClient.set.CoreV1().Pods("").List(context.Background(), mach.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "listing pods for autoscaling failed")
+	}
+
+	now := time.Now()
+	assigned := assignPods(pools, workerMachines, unschedulablePods(podList.Items), now)
+
+	var scaled bool
+	for _, pool := range pools {
+		settings := pool.desired.autoscaling()
+		machines := poolMachines(pool, workerMachines)
+
+		status := &AutoscalingStatus{
+			Provider:          pool.desired.Provider,
+			Pool:              pool.desired.Pool,
+			MinNodes:          pool.desired.MinNodes,
+			MaxNodes:          pool.desired.MaxNodes,
+			UnschedulablePods: assigned[pool].overdue,
+			Underutilized:     make(map[string]time.Time),
+		}
+		if prev, ok := previous[status.Provider+"/"+status.Pool]; ok {
+			status.LastDecision = prev.LastDecision
+			status.LastScaled = prev.LastScaled
+			for id, since := range prev.Underutilized {
+				status.Underutilized[id] = since
+			}
+		}
+		current.Autoscaling = append(current.Autoscaling, status)
+
+		underutilized := underutilizedMachines(machines, podList.Items, settings.ScaleInUtilization)
+		for id := range status.Underutilized {
+			if _, ok := underutilized[id]; !ok {
+				delete(status.Underutilized, id)
+			}
+		}
+		for id := range underutilized {
+			if _, ok := status.Underutilized[id]; !ok {
+				status.Underutilized[id] = now
+			}
+		}
+
+		nodes := pool.desired.Nodes
+		coolingDown := status.LastScaled != nil && now.Sub(*status.LastScaled) < settings.Cooldown
+		target, removed, reason := decideScaling(pool, machines, assigned[pool], underutilized, status.Underutilized, coolingDown, now, windows)
+
+		if target != nodes {
+			direction := "out"
+			if target < nodes {
+				direction = "in"
+			}
+			if err := setDesiredNodes(desired, pool, target); err != nil {
+				return err
+			}
+			if target > nodes {
+				pool.upscaling += target - nodes
+			}
+			pool.downscaling = append(pool.downscaling, removed...)
+
+			scaledAt := now
+			status.LastScaled = &scaledAt
+			status.LastDecision = reason
+			autoscalerDecisionsCounter.WithLabelValues(pool.desired.Provider, pool.desired.Pool, direction).Inc()
+			monitor.WithFields(map[string]interface{}{
+				"provider": pool.desired.Provider,
+				"pool":     pool.desired.Pool,
+				"from":     nodes,
+				"to":       target,
+			}).Changed(fmt.Sprintf("Autoscaler %s", reason))
+			scaled = true
+		}
+
+		status.Nodes = target
+		autoscalerNodesGauge.with(clusterID, pool.desired.Provider, pool.desired.Pool).Set(float64(target))
+		autoscalerUnschedulableGauge.with(clusterID, pool.desired.Provider, pool.desired.Pool).Set(float64(assigned[pool].overdue))
+	}
+
+	if !scaled {
+		return nil
+	}
+	return pdf(monitor.WithField("reason", "autoscaling"))
+}
+
+// decideScaling returns the pools new desired nodes, the machines to remove and the reason of the decision.
+// Pools are only scaled in if none of the unschedulable pods fits them
+func decideScaling(
+	pool *initializedPool,
+	machines []*initializedMachine,
+	assigned podAssignment,
+	underutilized map[string]float64,
+	underutilizedSince map[string]time.Time,
+	coolingDown bool,
+	now time.Time,
+	windows *maintenanceWindows,
+) (int, []*initializedMachine, string) {
+
+	settings := pool.desired.autoscaling()
+	nodes := pool.desired.Nodes
+	busy := pool.upscaling > 0 || len(pool.downscaling) > 0 || len(machines) != nodes || !settled(machines)
+
+	switch {
+	case nodes < pool.desired.MinNodes:
+		return pool.desired.MinNodes, nil, fmt.Sprintf("scaled out to minNodes %d", pool.desired.MinNodes)
+	case nodes > pool.desired.MaxNodes:
+		return pool.desired.MaxNodes, nil, fmt.Sprintf("scaled in to maxNodes %d", pool.desired.MaxNodes)
+	case busy || coolingDown:
+	case assigned.overdue > 0:
+		if step := minInt(settings.MaxStep, assigned.overdue, pool.desired.MaxNodes-nodes); step > 0 {
+			return nodes + step, nil, fmt.Sprintf("scaled out by %d nodes for %d unschedulable pods", step, assigned.overdue)
+		}
+	case assigned.pending == 0 && nodes > pool.desired.MinNodes:
+		if removed := removableMachines(machines, underutilized, underutilizedSince, now.Add(-settings.ScaleInDelay), minInt(settings.MaxStep, nodes-pool.desired.MinNodes), windows); len(removed) > 0 {
+			return nodes - len(removed), removed, fmt.Sprintf("scaled in by %d underutilized nodes", len(removed))
+		}
+	}
+	return nodes, nil, ""
+}
+
+// setDesiredNodes updates both the pushed desired state and the pools copy of it
+func setDesiredNodes(desired *DesiredV0, pool *initializedPool, nodes int) error {
+	for _, worker := range desired.Spec.Workers {
+		if worker.Provider == pool.desired.Provider && worker.Pool == pool.desired.Pool {
+			worker.Nodes = nodes
+			pool.desired.Nodes = nodes
+			return nil
+		}
+	}
+	return errors.Errorf("worker pool %s of provider %s is not desired", pool.desired.Pool, pool.desired.Provider)
+}
+
+// settled returns true if all machines are joined, ready and not drained for other reasons
+func settled(machines []*initializedMachine) bool {
+	for _, machine := range machines {
+		if !machine.currentMachine.Joined ||
+			!machine.currentMachine.Ready ||
+			machine.currentMachine.Unknown ||
+			machine.unavailable() {
+			return false
+		}
+	}
+	return true
+}
+
+func poolMachines(pool *initializedPool, machines []*initializedMachine) []*initializedMachine {
+	var poolMachines []*initializedMachine
+	for _, machine := range machines {
+		if machine.pool == pool {
+			poolMachines = append(poolMachines, machine)
+		}
+	}
+	return poolMachines
+}
+
+func unschedulablePods(pods []core.Pod) []core.Pod {
+	var unschedulable []core.Pod
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" || pod.Status.Phase != core.PodPending {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == core.PodScheduled &&
+				cond.Status == core.ConditionFalse &&
+				cond.Reason == core.PodReasonUnschedulable {
+				unschedulable = append(unschedulable, pod)
+				break
+			}
+		}
+	}
+	return unschedulable
+}
+
+// podAssignment counts the unschedulable pods a pool could run
+type podAssignment struct {
+	// Pods that fit the pools nodes, which prevents the pool from being scaled in
+	pending int
+	// Pods that are unschedulable for longer than the pools scale out delay and that fit no previous pool
+	overdue int
+}
+
+// assignPods counts the unschedulable pods that fit each pool.
+// Overdue pods are only counted for the first pool they fit, so they don't scale out several pools.
+// Pods that fit no pool are ignored, as adding nodes wouldn't make them schedulable
+func assignPods(pools []*initializedPool, machines []*initializedMachine, pods []core.Pod, now time.Time) map[*initializedPool]podAssignment {
+	assigned := make(map[*initializedPool]podAssignment)
+	for _, pod := range pods {
+		first := true
+		for _, pool := range pools {
+			if !podFits(pod, pool, poolMachines(pool, machines)) {
+				continue
+			}
+			assignment := assigned[pool]
+			assignment.pending++
+			if first && unschedulableSince(pod).Before(now.Add(-pool.desired.autoscaling().ScaleOutDelay)) {
+				assignment.overdue++
+			}
+			first = false
+			assigned[pool] = assignment
+		}
+	}
+	return assigned
+}
+
+func unschedulableSince(pod core.Pod) time.Time {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == core.PodScheduled {
+			return cond.LastTransitionTime.Time
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// podFits returns true if the pod could be scheduled on a new node of the pool.
+// The pools node labels must match the pods node selector and required node affinity, the pod must tolerate the pools taints
+// and the pods resource requests must not exceed a nodes allocatable resources. Pools without nodes can't be checked for resources
+func podFits(pod core.Pod, pool *initializedPool, machines []*initializedMachine) bool {
+
+	labels := map[string]string{
+		"orbos.ch/pool": pool.desired.Pool,
+		"orbos.ch/tier": string(pool.tier),
+	}
+	var allocatable core.ResourceList
+	for _, machine := range machines {
+		if machine.node == nil {
+			continue
+		}
+		for key, value := range machine.node.GetLabels() {
+			if _, ok := labels[key]; !ok {
+				labels[key] = value
+			}
+		}
+		allocatable = machine.node.Status.Allocatable
+		break
+	}
+
+	for key, value := range pod.Spec.NodeSelector {
+		if labels[key] != value {
+			return false
+		}
+	}
+
+	if affinity := pod.Spec.Affinity; affinity != nil &&
+		affinity.NodeAffinity != nil &&
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil &&
+		!nodeSelectorMatches(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution, labels) {
+		return false
+	}
+
+taints:
+	for _, taint := range pool.desired.Taints.ToK8sTaints() {
+		if taint.Effect == core.TaintEffectPreferNoSchedule {
+			continue
+		}
+		for _, toleration := range pod.Spec.Tolerations {
+			if toleration.ToleratesTaint(&taint) {
+				continue taints
+			}
+		}
+		return false
+	}
+
+	if len(allocatable) == 0 {
+		return true
+	}
+	requests, _ := resource.PodRequestsAndLimits(&pod)
+	for name, requested := range requests {
+		available, ok := allocatable[name]
+		if !ok && !requested.IsZero() || ok && requested.Cmp(available) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeSelectorMatches returns true if any of the selectors terms matches the labels.
+// Terms with field requirements never match, as they select existing nodes by their name
+func nodeSelectorMatches(selector *core.NodeSelector, nodeLabels map[string]string) bool {
+terms:
+	for _, term := range selector.NodeSelectorTerms {
+		if len(term.MatchFields) > 0 || len(term.MatchExpressions) == 0 {
+			continue
+		}
+		for _, expression := range term.MatchExpressions {
+			operator, ok := selectionOperators[expression.Operator]
+			if !ok {
+				continue terms
+			}
+			requirement, err := labels.NewRequirement(expression.Key, operator, expression.Values)
+			if err != nil || !requirement.Matches(labels.Set(nodeLabels)) {
+				continue terms
+			}
+		}
+		return true
+	}
+	return false
+}
+
+var selectionOperators = map[core.NodeSelectorOperator]selection.Operator{
+	core.NodeSelectorOpIn:           selection.In,
+	core.NodeSelectorOpNotIn:        selection.NotIn,
+	core.NodeSelectorOpExists:       selection.Exists,
+	core.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	core.NodeSelectorOpGt:           selection.GreaterThan,
+	core.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// underutilizedMachines returns the utilization of machines whose pods request less than the threshold of the nodes allocatable cpu and memory
+func underutilizedMachines(machines []*initializedMachine, pods []core.Pod, threshold int) map[string]float64 {
+
+	requests := make(map[string]core.ResourceList)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" ||
+			pod.Status.Phase == core.PodSucceeded ||
+			pod.Status.Phase == core.PodFailed {
+			continue
+		}
+		if controllerRef := mach.GetControllerOf(&pod); controllerRef != nil && controllerRef.Kind == apps.SchemeGroupVersion.WithKind("DaemonSet").Kind {
+			continue
+		}
+		nodeRequests, ok := requests[pod.Spec.NodeName]
+		if !ok {
+			nodeRequests = core.ResourceList{}
+			requests[pod.Spec.NodeName] = nodeRequests
+		}
+		for _, container := range pod.Spec.Containers {
+			for name, quantity := range container.Resources.Requests {
+				sum := nodeRequests[name]
+				sum.Add(quantity)
+				nodeRequests[name] = sum
+			}
+		}
+	}
+
+	underutilized := make(map[string]float64)
+	for _, machine := range machines {
+		if machine.node == nil {
+			continue
+		}
+		allocatable := machine.node.Status.Allocatable
+		nodeRequests := requests[machine.node.GetName()]
+
+		utilization := 0.0
+		if cpu := allocatable.Cpu().MilliValue(); cpu > 0 {
+			utilization = float64(nodeRequests.Cpu().MilliValue()) / float64(cpu)
+		}
+		if memory := allocatable.Memory().Value(); memory > 0 {
+			if memUtilization := float64(nodeRequests.Memory().Value()) / float64(memory); memUtilization > utilization {
+				utilization = memUtilization
+			}
+		}
+		if utilization*100 < float64(threshold) {
+			underutilized[machine.infra.ID()] = utilization
+		}
+	}
+	return underutilized
+}
+
+// removableMachines returns up to max machines that are underutilized since before the passed time, least utilized first
+func removableMachines(machines []*initializedMachine, underutilized map[string]float64, underutilizedSince map[string]time.Time, before time.Time, max int, windows *maintenanceWindows) []*initializedMachine {
+
+	var candidates []*initializedMachine
+	for _, machine := range machines {
+		id := machine.infra.ID()
+		if _, ok := underutilized[id]; !ok {
+			continue
+		}
+		if since, ok := underutilizedSince[id]; !ok || since.After(before) {
+			continue
+		}
+		candidates = append(candidates, machine)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return underutilized[candidates[i].infra.ID()] < underutilized[candidates[j].infra.ID()]
+	})
+
+	var removable []*initializedMachine
+	for _, machine := range candidates {
+		if len(removable) >= max {
+			break
+		}
+		if !windows.allows(machine, actionDownscale) {
+			continue
+		}
+		removable = append(removable, machine)
+	}
+	return removable
+}
+
+func minInt(first int, others ...int) int {
+	min := first
+	for _, other := range others {
+		if other < min {
+			min = other
+		}
+	}
+	return min
+}
@@ -0,0 +1,543 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	mach "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+)
+
+func resources(cpu, memory string) core.ResourceList {
+	list := core.ResourceList{}
+	if cpu != "" {
+		list[core.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[core.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+func autoscaledMachine(pool *initializedPool, id string, allocatable core.ResourceList) *initializedMachine {
+	return &initializedMachine{
+		infra:          &testMachine{id: id},
+		currentMachine: &Machine{Joined: true, Ready: true},
+		pool:           pool,
+		node: &core.Node{
+			ObjectMeta: mach.ObjectMeta{
+				Name:   id,
+				Labels: map[string]string{"topology.kubernetes.io/zone": "a"},
+			},
+			Status: core.NodeStatus{Allocatable: allocatable},
+		},
+	}
+}
+
+func requestingPod(node string, requests core.ResourceList) core.Pod {
+	return core.Pod{Spec: core.PodSpec{
+		NodeName:   node,
+		Containers: []core.Container{{Resources: core.ResourceRequirements{Requests: requests}}},
+	}}
+}
+
+func unschedulablePod(since time.Time) core.Pod {
+	return core.Pod{
+		Status: core.PodStatus{
+			Phase: core.PodPending,
+			Conditions: []core.PodCondition{{
+				Type:               core.PodScheduled,
+				Status:             core.ConditionFalse,
+				Reason:             core.PodReasonUnschedulable,
+				LastTransitionTime: mach.NewTime(since),
+			}},
+		},
+	}
+}
+
+func requiredAffinity(terms ...core.NodeSelectorTerm) *core.Affinity {
+	return &core.Affinity{NodeAffinity: &core.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &core.NodeSelector{NodeSelectorTerms: terms},
+	}}
+}
+
+func matchExpression(key string, operator core.NodeSelectorOperator, values ...string) core.NodeSelectorTerm {
+	return core.NodeSelectorTerm{MatchExpressions: []core.NodeSelectorRequirement{{
+		Key:      key,
+		Operator: operator,
+		Values:   values,
+	}}}
+}
+
+func Test_podFits(t *testing.T) {
+
+	taints := Taints{{Key: "dedicated", Value: "db", Effect: core.TaintEffectNoSchedule}}
+	preferred := Taints{{Key: "spot", Effect: core.TaintEffectPreferNoSchedule}}
+
+	tests := []struct {
+		name     string
+		pod      core.Pod
+		taints   *Taints
+		noNodes  bool
+		wantFits bool
+	}{{
+		name:     "It should fit pods without constraints",
+		pod:      core.Pod{},
+		wantFits: true,
+	}, {
+		name:     "It should match the pools labels",
+		pod:      core.Pod{Spec: core.PodSpec{NodeSelector: map[string]string{"orbos.ch/pool": "workers", "orbos.ch/tier": string(Workers)}}},
+		wantFits: true,
+	}, {
+		name:     "It should match the nodes labels",
+		pod:      core.Pod{Spec: core.PodSpec{NodeSelector: map[string]string{"topology.kubernetes.io/zone": "a"}}},
+		wantFits: true,
+	}, {
+		name:     "It should not fit pods selecting other labels",
+		pod:      core.Pod{Spec: core.PodSpec{NodeSelector: map[string]string{"orbos.ch/pool": "other"}}},
+		wantFits: false,
+	}, {
+		name:     "It should not fit pods not tolerating the pools taints",
+		pod:      core.Pod{},
+		taints:   &taints,
+		wantFits: false,
+	}, {
+		name: "It should fit pods tolerating the pools taints",
+		pod: core.Pod{Spec: core.PodSpec{Tolerations: []core.Toleration{{
+			Key:      "dedicated",
+			Operator: core.TolerationOpEqual,
+			Value:    "db",
+			Effect:   core.TaintEffectNoSchedule,
+		}}}},
+		taints:   &taints,
+		wantFits: true,
+	}, {
+		name:     "It should ignore preferred taints",
+		pod:      core.Pod{},
+		taints:   &preferred,
+		wantFits: true,
+	}, {
+		name:     "It should fit pods with a matching required node affinity",
+		pod:      core.Pod{Spec: core.PodSpec{Affinity: requiredAffinity(matchExpression("topology.kubernetes.io/zone", core.NodeSelectorOpIn, "a", "b"))}},
+		wantFits: true,
+	}, {
+		name:     "It should not fit pods with a mismatching required node affinity",
+		pod:      core.Pod{Spec: core.PodSpec{Affinity: requiredAffinity(matchExpression("topology.kubernetes.io/zone", core.NodeSelectorOpNotIn, "a"))}},
+		wantFits: false,
+	}, {
+		name:     "It should fit pods if any node selector term matches",
+		pod:      core.Pod{Spec: core.PodSpec{Affinity: requiredAffinity(matchExpression("gpu", core.NodeSelectorOpExists), matchExpression("gpu", core.NodeSelectorOpDoesNotExist))}},
+		wantFits: true,
+	}, {
+		name: "It should not fit pods selecting nodes by their names",
+		pod: core.Pod{Spec: core.PodSpec{Affinity: requiredAffinity(core.NodeSelectorTerm{MatchFields: []core.NodeSelectorRequirement{{
+			Key:      "metadata.name",
+			Operator: core.NodeSelectorOpIn,
+			Values:   []string{"machine"},
+		}}})}},
+		wantFits: false,
+	}, {
+		name:     "It should ignore preferred node affinities",
+		pod:      core.Pod{Spec: core.PodSpec{Affinity: &core.Affinity{NodeAffinity: &core.NodeAffinity{PreferredDuringSchedulingIgnoredDuringExecution: []core.PreferredSchedulingTerm{{Weight: 1, Preference: matchExpression("gpu", core.NodeSelectorOpExists)}}}}}},
+		wantFits: true,
+	}, {
+		name:     "It should fit pods requesting the nodes allocatable resources",
+		pod:      requestingPod("", resources("2", "4Gi")),
+		wantFits: true,
+	}, {
+		name:     "It should not fit pods requesting more cpu than allocatable",
+		pod:      requestingPod("", resources("2500m", "1Gi")),
+		wantFits: false,
+	}, {
+		name:     "It should not fit pods requesting more memory than allocatable",
+		pod:      requestingPod("", resources("1", "5Gi")),
+		wantFits: false,
+	}, {
+		name:     "It should not fit pods requesting resources the nodes don't have",
+		pod:      requestingPod("", core.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}),
+		wantFits: false,
+	}, {
+		name: "It should consider the requests of init containers",
+		pod: core.Pod{Spec: core.PodSpec{
+			InitContainers: []core.Container{{Resources: core.ResourceRequirements{Requests: resources("3", "")}}},
+			Containers:     []core.Container{{Resources: core.ResourceRequirements{Requests: resources("1", "")}}},
+		}},
+		wantFits: false,
+	}, {
+		name:     "It should not check the resources of pools without nodes",
+		pod:      requestingPod("", resources("64", "")),
+		noNodes:  true,
+		wantFits: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &initializedPool{tier: Workers, desired: Pool{Pool: "workers", Taints: tt.taints}}
+			var machines []*initializedMachine
+			if !tt.noNodes {
+				machines = append(machines, autoscaledMachine(pool, "machine", resources("2", "4Gi")))
+			}
+			if got := podFits(tt.pod, pool, machines); got != tt.wantFits {
+				t.Errorf("podFits() got = %t, want %t", got, tt.wantFits)
+			}
+		})
+	}
+}
+
+func Test_assignPods(t *testing.T) {
+
+	now := time.Now()
+	overdue := now.Add(-time.Hour)
+	recent := now.Add(-time.Second)
+
+	small := &initializedPool{tier: Workers, desired: Pool{Pool: "small", MaxNodes: 3}}
+	large := &initializedPool{tier: Workers, desired: Pool{Pool: "large", MaxNodes: 3}}
+	machines := []*initializedMachine{
+		autoscaledMachine(small, "small", resources("2", "4Gi")),
+		autoscaledMachine(large, "large", resources("8", "32Gi")),
+	}
+
+	withRequests := func(pod core.Pod, requests core.ResourceList) core.Pod {
+		pod.Spec.Containers = []core.Container{{Resources: core.ResourceRequirements{Requests: requests}}}
+		return pod
+	}
+	withSelector := func(pod core.Pod, pool string) core.Pod {
+		pod.Spec.NodeSelector = map[string]string{"orbos.ch/pool": pool}
+		return pod
+	}
+
+	tests := []struct {
+		name string
+		pods []core.Pod
+		want map[*initializedPool]podAssignment
+	}{{
+		name: "It should count overdue pods only for the first fitting pool",
+		pods: []core.Pod{unschedulablePod(overdue)},
+		want: map[*initializedPool]podAssignment{
+			small: {pending: 1, overdue: 1},
+			large: {pending: 1},
+		},
+	}, {
+		name: "It should count recent pods as pending only",
+		pods: []core.Pod{unschedulablePod(recent)},
+		want: map[*initializedPool]podAssignment{
+			small: {pending: 1},
+			large: {pending: 1},
+		},
+	}, {
+		name: "It should assign pods to the pools they fit",
+		pods: []core.Pod{withRequests(unschedulablePod(overdue), resources("4", ""))},
+		want: map[*initializedPool]podAssignment{
+			large: {pending: 1, overdue: 1},
+		},
+	}, {
+		name: "It should assign pods to the pools they select",
+		pods: []core.Pod{withSelector(unschedulablePod(overdue), "large"), unschedulablePod(overdue)},
+		want: map[*initializedPool]podAssignment{
+			small: {pending: 1, overdue: 1},
+			large: {pending: 2, overdue: 1},
+		},
+	}, {
+		name: "It should ignore pods that fit no pool",
+		pods: []core.Pod{withRequests(unschedulablePod(overdue), resources("16", ""))},
+		want: map[*initializedPool]podAssignment{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignPods([]*initializedPool{small, large}, machines, tt.pods, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assignPods() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_underutilizedMachines(t *testing.T) {
+
+	pool := &initializedPool{}
+	machines := []*initializedMachine{
+		autoscaledMachine(pool, "idle", resources("4", "8Gi")),
+		autoscaledMachine(pool, "cpu", resources("4", "8Gi")),
+		autoscaledMachine(pool, "memory", resources("4", "8Gi")),
+		autoscaledMachine(pool, "low", resources("4", "8Gi")),
+		{infra: &testMachine{id: "joining"}, currentMachine: &Machine{}, pool: pool},
+	}
+
+	daemonSetPod := requestingPod("idle", resources("4", "8Gi"))
+	daemonSetPod.OwnerReferences = []mach.OwnerReference{{Kind: "DaemonSet", Name: "ds", Controller: boolPtr(true)}}
+	succeededPod := requestingPod("idle", resources("4", "8Gi"))
+	succeededPod.Status.Phase = core.PodSucceeded
+
+	pods := []core.Pod{
+		daemonSetPod,
+		succeededPod,
+		requestingPod("cpu", resources("3", "1Gi")),
+		requestingPod("memory", resources("1", "6Gi")),
+		requestingPod("low", resources("1", "2Gi")),
+		requestingPod("", resources("4", "8Gi")),
+	}
+
+	want := map[string]float64{
+		"idle": 0,
+		"low":  0.25,
+	}
+	if got := underutilizedMachines(machines, pods, 50); !reflect.DeepEqual(got, want) {
+		t.Errorf("underutilizedMachines() got = %v, want %v", got, want)
+	}
+}
+
+func Test_removableMachines(t *testing.T) {
+
+	now := time.Now()
+	before := now.Add(-10 * time.Minute)
+
+	pool := &initializedPool{desired: Pool{Pool: "workers"}}
+	machines := []*initializedMachine{
+		autoscaledMachine(pool, "a", nil),
+		autoscaledMachine(pool, "b", nil),
+		autoscaledMachine(pool, "c", nil),
+		autoscaledMachine(pool, "recent", nil),
+		autoscaledMachine(pool, "busy", nil),
+	}
+	underutilized := map[string]float64{"a": 0.3, "b": 0.1, "c": 0.2, "recent": 0}
+
+	underutilizedSince := map[string]time.Time{
+		"a":      now.Add(-time.Hour),
+		"b":      now.Add(-time.Hour),
+		"c":      now.Add(-time.Hour),
+		"recent": now.Add(-time.Minute),
+		"busy":   now.Add(-time.Hour),
+	}
+
+	closed := &maintenanceWindows{
+		now: now,
+		desired: &Spec{Maintenance: &Maintenance{Windows: []*MaintenanceWindow{{
+			Start:    now.UTC().Add(time.Hour).Format("4 15 * * *"),
+			Duration: time.Minute,
+		}}}},
+		current: &CurrentCluster{},
+	}
+
+	tests := []struct {
+		name    string
+		max     int
+		windows *maintenanceWindows
+		want    []string
+	}{{
+		name: "It should remove the least utilized machines first",
+		max:  2,
+		want: []string{"b", "c"},
+	}, {
+		name: "It should remove only machines underutilized for longer than the delay",
+		max:  5,
+		want: []string{"b", "c", "a"},
+	}, {
+		name:    "It should remove no machines outside maintenance windows",
+		max:     5,
+		windows: closed,
+		want:    nil,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, machine := range removableMachines(machines, underutilized, underutilizedSince, before, tt.max, tt.windows) {
+				got = append(got, machine.infra.ID())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removableMachines() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decideScaling(t *testing.T) {
+
+	now := time.Now()
+
+	type args struct {
+		nodes       int
+		machines    int
+		upscaling   int
+		downscaling bool
+		unsettled   bool
+		assigned    podAssignment
+		coolingDown bool
+		autoscaling *Autoscaling
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantTarget  int
+		wantRemoved int
+	}{{
+		name:       "It should scale out to minNodes immediately",
+		args:       args{nodes: 0, machines: 0, coolingDown: true},
+		wantTarget: 1,
+	}, {
+		name:       "It should scale in to maxNodes immediately",
+		args:       args{nodes: 6, machines: 6, coolingDown: true},
+		wantTarget: 5,
+	}, {
+		name:       "It should not scale while cooling down",
+		args:       args{nodes: 2, machines: 2, coolingDown: true, assigned: podAssignment{pending: 1, overdue: 1}},
+		wantTarget: 2,
+	}, {
+		name:       "It should not scale while machines are created",
+		args:       args{nodes: 2, machines: 2, upscaling: 1, assigned: podAssignment{pending: 1, overdue: 1}},
+		wantTarget: 2,
+	}, {
+		name:       "It should not scale while machines are removed",
+		args:       args{nodes: 2, machines: 2, downscaling: true, assigned: podAssignment{pending: 1, overdue: 1}},
+		wantTarget: 2,
+	}, {
+		name:       "It should not scale while the machines don't match the nodes",
+		args:       args{nodes: 2, machines: 3},
+		wantTarget: 2,
+	}, {
+		name:       "It should not scale while machines are not settled",
+		args:       args{nodes: 2, machines: 2, unsettled: true, assigned: podAssignment{pending: 1, overdue: 1}},
+		wantTarget: 2,
+	}, {
+		name:       "It should scale out by one node by default",
+		args:       args{nodes: 2, machines: 2, assigned: podAssignment{pending: 3, overdue: 3}},
+		wantTarget: 3,
+	}, {
+		name:       "It should scale out by at most maxStep nodes",
+		args:       args{nodes: 2, machines: 2, assigned: podAssignment{pending: 3, overdue: 3}, autoscaling: &Autoscaling{MaxStep: 2}},
+		wantTarget: 4,
+	}, {
+		name:       "It should scale out by at most the overdue pods",
+		args:       args{nodes: 2, machines: 2, assigned: podAssignment{pending: 1, overdue: 1}, autoscaling: &Autoscaling{MaxStep: 2}},
+		wantTarget: 3,
+	}, {
+		name:       "It should not scale out above maxNodes",
+		args:       args{nodes: 4, machines: 4, assigned: podAssignment{pending: 3, overdue: 3}, autoscaling: &Autoscaling{MaxStep: 3}},
+		wantTarget: 5,
+	}, {
+		name:       "It should not scale in while pending pods fit the pool",
+		args:       args{nodes: 3, machines: 3, assigned: podAssignment{pending: 1}},
+		wantTarget: 3,
+	}, {
+		name:        "It should scale in underutilized machines",
+		args:        args{nodes: 3, machines: 3},
+		wantTarget:  2,
+		wantRemoved: 1,
+	}, {
+		name:       "It should not scale in below minNodes",
+		args:       args{nodes: 1, machines: 1},
+		wantTarget: 1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &initializedPool{
+				tier:      Workers,
+				upscaling: tt.args.upscaling,
+				desired: Pool{
+					Pool:        "workers",
+					Nodes:       tt.args.nodes,
+					MinNodes:    1,
+					MaxNodes:    5,
+					Autoscaling: tt.args.autoscaling,
+				},
+			}
+			var machines []*initializedMachine
+			underutilized := make(map[string]float64)
+			for i := 0; i < tt.args.machines; i++ {
+				machine := autoscaledMachine(pool, pool.desired.Pool+string(rune('a'+i)), resources("4", "8Gi"))
+				machines = append(machines, machine)
+				underutilized[machine.infra.ID()] = 0
+			}
+			if tt.args.unsettled {
+				machines[0].currentMachine.Ready = false
+			}
+			if tt.args.downscaling {
+				pool.downscaling = machines[:1]
+			}
+
+			underutilizedSince := make(map[string]time.Time)
+			for id := range underutilized {
+				underutilizedSince[id] = now.Add(-time.Hour)
+			}
+			gotTarget, gotRemoved, _ := decideScaling(pool, machines, tt.args.assigned, underutilized, underutilizedSince, tt.args.coolingDown, now, nil)
+
+			if gotTarget != tt.wantTarget {
+				t.Errorf("decideScaling() got target = %d, want %d", gotTarget, tt.wantTarget)
+			}
+			if len(gotRemoved) != tt.wantRemoved {
+				t.Errorf("decideScaling() removed %d machines, want %d", len(gotRemoved), tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func Test_setDesiredNodes(t *testing.T) {
+
+	desired := &DesiredV0{Spec: Spec{Workers: []*Pool{
+		{Provider: "gce", Pool: "workers", Nodes: 2},
+		{Provider: "static", Pool: "workers", Nodes: 2},
+	}}}
+
+	pool := &initializedPool{desired: *desired.Spec.Workers[0]}
+	if err := setDesiredNodes(desired, pool, 4); err != nil {
+		t.Fatal(err)
+	}
+	if desired.Spec.Workers[0].Nodes != 4 || pool.desired.Nodes != 4 {
+		t.Errorf("expected the desired state and the pool to desire 4 nodes, got %d and %d", desired.Spec.Workers[0].Nodes, pool.desired.Nodes)
+	}
+	if desired.Spec.Workers[1].Nodes != 2 {
+		t.Errorf("expected the pool of the other provider to stay untouched, got %d nodes", desired.Spec.Workers[1].Nodes)
+	}
+
+	unknown := &initializedPool{desired: Pool{Provider: "gce", Pool: "unknown"}}
+	if err := setDesiredNodes(desired, unknown, 1); err == nil {
+		t.Error("expected an error for a pool that is not desired")
+	}
+}
+
+func TestAdaptFunc_ContinuesAutoscaling(t *testing.T) {
+
+	lastScaled := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	underutilizedSince := lastScaled.Add(time.Minute)
+	persisted := []*AutoscalingStatus{{
+		Provider:      "fake",
+		Pool:          "workers",
+		Nodes:         3,
+		MaxNodes:      5,
+		Underutilized: map[string]time.Time{"workers-0": underutilizedSince},
+		LastDecision:  "scaled out by 1 nodes for 1 unschedulable pods",
+		LastScaled:    &lastScaled,
+	}}
+
+	previousCurrent, err := yaml.Marshal(&Current{
+		Common:  tree.Common{Kind: "orbiter.caos.ch/KubernetesCluster", Version: "v0"},
+		Current: &CurrentCluster{Autoscaling: persisted},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desiredTree, currentTree := &tree.Tree{}, &tree.Tree{}
+	if err := yaml.Unmarshal([]byte(plannedOrbiterYml), desiredTree); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(previousCurrent, currentTree); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, _, _, err := AdaptFunc("test", false, false, nil, func([]*orbiter.CIDR) {}, nil)(mntr.Monitor{}, make(chan struct{}, 1), desiredTree, currentTree); err != nil {
+		t.Fatal(err)
+	}
+
+	got := currentTree.Parsed.(*Current).Current.Autoscaling
+	if len(got) != 1 ||
+		!got[0].LastScaled.Equal(lastScaled) ||
+		!got[0].Underutilized["workers-0"].Equal(underutilizedSince) ||
+		got[0].LastDecision != persisted[0].LastDecision {
+		t.Errorf("expected the autoscaling state of the last iteration, got %+v", got)
+	}
+}
@@ -396,6 +396,10 @@ func (c *Client) ApplySecret(rsc *core.Secret) error {
 	})
 }
 
+func (c *Client) GetSecret(namespace, name string) (*core.Secret, error) {
+	return c.set.CoreV1().Secrets(namespace).Get(context.Background(), name, mach.GetOptions{})
+}
+
 func (c *Client) WaitForSecret(namespace string, name string, timeoutSeconds time.Duration) error {
 	returnChannel := make(chan error, 1)
 	go func() {
@@ -716,6 +720,7 @@ func (c *Client) cordon(node *core.Node, reason drainReason) (err error) {
 		return err
 	}
 	monitor.Info("Node cordoned")
+	c.RecordEvent(NodeReference(node), ComponentOrbiter, core.EventTypeNormal, ReasonCordoned, fmt.Sprintf("Node cordoned by the orbiter for %s", reason))
 	return nil
 }
 
@@ -743,13 +748,28 @@ func (c *Client) RemoveFromTaints(taints []core.Taint, reason drainReason) (resu
 
 type drainReason int
 
+// eviction configures how pods are evicted when a node is drained
+type eviction struct {
+	// evictions refused because of PodDisruptionBudgets are retried until the timeout exceeds
+	timeout time.Duration
+	// pods that can't be evicted within the timeout are deleted
+	force bool
+}
+
+func (p *Pool) eviction() eviction {
+	return eviction{
+		timeout: p.evictionTimeout(),
+		force:   p.ForceEviction,
+	}
+}
+
 const (
 	updating drainReason = iota
 	rebooting
 	deleting
 )
 
-func (c *Client) Drain(machine *Machine, node *core.Node, reason drainReason) (err error) {
+func (c *Client) Drain(machine *Machine, node *core.Node, reason drainReason, evict eviction) (err error) {
 	defer func() {
 		err = errors.Wrapf(err, "draining node %s failed", node.GetName())
 	}()
@@ -763,17 +783,19 @@ func (c *Client) Drain(machine *Machine, node *core.Node, reason drainReason) (e
 		return err
 	}
 
-	if err := c.evictPods(node); err != nil {
+	if err := c.evictPods(node, reason, evict); err != nil {
 		return err
 	}
 	if !machine.Updating {
 		machine.Updating = true
+		drainsCounter.WithLabelValues(machine.Metadata.Provider, machine.Metadata.Pool, reason.String()).Inc()
 		monitor.Changed("Node drained")
+		c.RecordEvent(NodeReference(node), ComponentOrbiter, core.EventTypeNormal, ReasonDrained, fmt.Sprintf("Node drained by the orbiter for %s", reason))
 	}
 	return nil
 }
 
-func (c *Client) EnsureDeleted(name string, machine *Machine, node NodeWithKubeadm) (err error) {
+func (c *Client) EnsureDeleted(name string, machine *Machine, node NodeWithKubeadm, evict eviction) (err error) {
 
 	defer func() {
 		err = errors.Wrapf(err, "deleting node %s failed", name)
@@ -798,7 +820,7 @@ func (c *Client) EnsureDeleted(name string, machine *Machine, node NodeWithKubea
 				return errors.Wrapf(err, "getting node %s from kube api failed", name)
 			}
 
-			return c.Drain(machine, nodeStruct, deleting)
+			return c.Drain(machine, nodeStruct, deleting, evict)
 		}
 		return nil
 	}
@@ -818,6 +840,7 @@ func (c *Client) EnsureDeleted(name string, machine *Machine, node NodeWithKubea
 		return nil
 	}
 	monitor.Info("Deleting node")
+	c.RecordEvent(ObjectReference("v1", "Node", "", name), ComponentOrbiter, core.EventTypeNormal, ReasonDeleting, "Node is deleted by the orbiter")
 	if err := api.Delete(context.Background(), name, mach.DeleteOptions{}); err != nil {
 		if !macherrs.IsNotFound(err) {
 			return err
@@ -831,7 +854,7 @@ func (c *Client) EnsureDeleted(name string, machine *Machine, node NodeWithKubea
 	return nil
 }
 
-func (c *Client) evictPods(node *core.Node) (err error) {
+func (c *Client) evictPods(node *core.Node, reason drainReason, evict eviction) (err error) {
 
 	defer func() {
 		err = errors.Wrapf(err, "evicting pods from node %s failed", node.GetName())
@@ -880,25 +903,19 @@ func (c *Client) evictPods(node *core.Node) (err error) {
 			}
 			defer watcher.Stop()
 
-			if goErr := c.set.PolicyV1beta1().Evictions(pod.Namespace).Evict(context.Background(), &policy.Eviction{
-				TypeMeta: mach.TypeMeta{
-					Kind:       "EvictionKind",
-					APIVersion: c.set.PolicyV1beta1().RESTClient().APIVersion().String(),
-				},
-				ObjectMeta: mach.ObjectMeta{
-					Name:      pod.Name,
-					Namespace: pod.Namespace,
-				},
-				DeleteOptions: &mach.DeleteOptions{
-					GracePeriodSeconds: &gracePeriodSeconds,
-				},
-			}); goErr != nil {
-				synchronizer.Done(errors.Wrapf(goErr, "evicting pod %s failed", pod.Name))
+			evicted, goErr := c.evictPod(monitor, pod, gracePeriodSeconds, evict)
+			if goErr != nil {
+				synchronizer.Done(goErr)
+				return
+			}
+			if !evicted {
+				synchronizer.Done(nil)
 				return
 			}
+			c.RecordEvent(PodReference(&pod), ComponentOrbiter, core.EventTypeNormal, ReasonEvicted, fmt.Sprintf("Pod evicted by the orbiter to drain node %s for %s", node.GetName(), reason))
 			monitor.Debug("Watching pod")
 
-			timeout := time.After(time.Duration(safeUint64(pod.Spec.TerminationGracePeriodSeconds)) + 30)
+			timeout := time.After(time.Duration(safeUint64(pod.Spec.TerminationGracePeriodSeconds)+30) * time.Second)
 			for {
 				select {
 				case event := <-watcher.ResultChan():
@@ -934,6 +951,52 @@ func (c *Client) evictPods(node *core.Node) (err error) {
 	return nil
 }
 
+// evictPod evicts the pod using the Eviction API, so PodDisruptionBudgets are honored.
+// Evictions refused because of a PodDisruptionBudget are retried until the timeout exceeds.
+// Then, the pod is deleted if forcing is configured. It returns false if the pod is already gone
+func (c *Client) evictPod(monitor mntr.Monitor, pod core.Pod, gracePeriodSeconds int64, evict eviction) (bool, error) {
+
+	deadline := time.Now().Add(evict.timeout)
+	for {
+		err := c.set.PolicyV1beta1().Evictions(pod.Namespace).Evict(context.Background(), &policy.Eviction{
+			TypeMeta: mach.TypeMeta{
+				Kind:       "EvictionKind",
+				APIVersion: c.set.PolicyV1beta1().RESTClient().APIVersion().String(),
+			},
+			ObjectMeta: mach.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+			DeleteOptions: &mach.DeleteOptions{
+				GracePeriodSeconds: &gracePeriodSeconds,
+			},
+		})
+		if err == nil {
+			return true, nil
+		}
+		if macherrs.IsNotFound(err) {
+			return false, nil
+		}
+		if !macherrs.IsTooManyRequests(err) {
+			return false, errors.Wrapf(err, "evicting pod %s failed", pod.Name)
+		}
+		if time.Now().After(deadline) {
+			if !evict.force {
+				return false, errors.Wrapf(err, "evicting pod %s is refused by a PodDisruptionBudget for more than %s", pod.Name, evict.timeout)
+			}
+			monitor.Warn(fmt.Sprintf("Deleting pod as it couldn't be evicted within %s: %s", evict.timeout, err.Error()))
+			if err := c.set.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, mach.DeleteOptions{
+				GracePeriodSeconds: &gracePeriodSeconds,
+			}); err != nil && !macherrs.IsNotFound(err) {
+				return false, errors.Wrapf(err, "deleting pod %s failed", pod.Name)
+			}
+			return true, nil
+		}
+		monitor.Info("Pod eviction is refused by a PodDisruptionBudget, retrying")
+		time.Sleep(5 * time.Second)
+	}
+}
+
 func safeUint64(ptr *int64) int64 {
 	if ptr == nil {
 		return 0
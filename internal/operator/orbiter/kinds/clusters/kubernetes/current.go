@@ -9,6 +9,10 @@ import (
 type CurrentCluster struct {
 	Status   string
 	Machines Machines
+	// Postponed lists the disruptive actions waiting for a maintenance window
+	Postponed []*PostponedAction `yaml:",omitempty"`
+	// Autoscaling reports the autoscalers view of the autoscaled worker pools
+	Autoscaling []*AutoscalingStatus `yaml:",omitempty"`
 }
 
 type Machines struct {
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/caos/orbos/internal/secret"
 	"github.com/caos/orbos/internal/tree"
@@ -40,6 +41,8 @@ type Spec struct {
 	//@default: ghcr.io
 	CustomImageRegistry string
 	Workers             []*Pool
+	// Restrict disruptive actions to maintenance windows. Pools can define their own windows
+	Maintenance *Maintenance `yaml:",omitempty"`
 }
 
 func parseDesiredV0(desiredTree *tree.Tree) (*DesiredV0, error) {
@@ -72,11 +75,36 @@ func (d *DesiredV0) validate() error {
 		return err
 	}
 
+	if err := d.Spec.Maintenance.validate(); err != nil {
+		return err
+	}
+
+	if err := d.Spec.ControlPlane.Maintenance.validate(); err != nil {
+		return err
+	}
+
+	if err := d.Spec.ControlPlane.validateDisruption(Controlplane); err != nil {
+		return err
+	}
+
+	if err := d.Spec.ControlPlane.validateAutoscaling(Controlplane); err != nil {
+		return err
+	}
+
 	seenPools := map[string][]string{
 		d.Spec.ControlPlane.Provider: []string{d.Spec.ControlPlane.Pool},
 	}
 
 	for _, worker := range d.Spec.Workers {
+		if err := worker.Maintenance.validate(); err != nil {
+			return err
+		}
+		if err := worker.validateDisruption(Workers); err != nil {
+			return err
+		}
+		if err := worker.validateAutoscaling(Workers); err != nil {
+			return err
+		}
 		pools, ok := seenPools[worker.Provider]
 		if !ok {
 			seenPools[worker.Provider] = []string{worker.Pool}
@@ -99,6 +127,27 @@ type Pool struct {
 	Nodes           int
 	Pool            string
 	Taints          *Taints `yaml:"taints,omitempty"`
+	// Overrides the clusters maintenance windows for this pools machines
+	Maintenance *Maintenance `yaml:",omitempty"`
+	// Count or percentage of the pools nodes that may be drained or rebooted at the same time, for example 2 or 25%.
+	// Percentages are rounded down but at least one node is always allowed. The controlplane allows only one
+	//@default: 1
+	MaxUnavailable string `yaml:",omitempty"`
+	// Count or percentage of machines that may be created above the desired nodes for replacing machines before they are drained.
+	// Percentages are rounded up. With 0, machines are drained and removed before their replacements are created
+	//@default: 1
+	MaxSurge string `yaml:",omitempty"`
+	// Pod evictions that are refused because of PodDisruptionBudgets are retried until this timeout exceeds
+	//@default: 5m
+	EvictionTimeout time.Duration `yaml:",omitempty"`
+	// Delete pods that can't be evicted within the eviction timeout instead of failing the drain
+	ForceEviction bool `yaml:",omitempty"`
+	// The autoscaler doesn't remove nodes below this count
+	MinNodes int `yaml:",omitempty"`
+	// The autoscaler is enabled for worker pools with a maxNodes value greater than zero and doesn't add nodes above this count
+	MaxNodes int `yaml:",omitempty"`
+	// Tunes the autoscalers decisions
+	Autoscaling *Autoscaling `yaml:",omitempty"`
 }
 
 type Taint struct {
@@ -123,3 +172,12 @@ func (t *Taints) ToK8sTaints() []core.Taint {
 	}
 	return taints
 }
+
+// ValidateDesired checks the desired state without adapting it
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := parseDesiredV0(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.validate()
+}
@@ -0,0 +1,143 @@
+package kubernetes
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxUnavailable  = "1"
+	defaultMaxSurge        = "1"
+	defaultEvictionTimeout = 5 * time.Minute
+)
+
+// parseBudget resolves a count like 2 or a percentage like 25% of the pools nodes.
+// Percentages are rounded up if roundUp is true and down otherwise
+func parseBudget(value string, nodes int, roundUp bool) (int, error) {
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil || percent < 0 {
+			return 0, errors.Errorf("%s is not a valid percentage", value)
+		}
+		exact := float64(nodes*percent) / 100
+		if roundUp {
+			return int(math.Ceil(exact)), nil
+		}
+		return int(math.Floor(exact)), nil
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, errors.Errorf("%s is neither a positive count nor a percentage", value)
+	}
+	return count, nil
+}
+
+// maxUnavailable returns how many of the pools nodes may be drained or rebooted at the same time.
+// At least one node is always allowed so maintenance can't get stuck
+func (p *Pool) maxUnavailable() int {
+	if unavailable := p.replacementUnavailable(); unavailable > 0 {
+		return unavailable
+	}
+	return 1
+}
+
+// replacementUnavailable returns how many of the pools nodes may be unavailable while machines are replaced.
+// In contrast to maxUnavailable, it can be zero, so replaced machines are only removed after their replacements joined
+func (p *Pool) replacementUnavailable() int {
+	value := p.MaxUnavailable
+	if value == "" {
+		value = defaultMaxUnavailable
+	}
+	unavailable, err := parseBudget(value, p.Nodes, false)
+	if err != nil {
+		// validated before
+		panic(err)
+	}
+	return unavailable
+}
+
+// maxSurge returns how many machines may be created above the desired nodes in order to replace machines before draining them
+func (p *Pool) maxSurge() int {
+	value := p.MaxSurge
+	if value == "" {
+		value = defaultMaxSurge
+	}
+	surge, err := parseBudget(value, p.Nodes, true)
+	if err != nil {
+		// validated before
+		panic(err)
+	}
+	return surge
+}
+
+func (p *Pool) evictionTimeout() time.Duration {
+	if p.EvictionTimeout <= 0 {
+		return defaultEvictionTimeout
+	}
+	return p.EvictionTimeout
+}
+
+func (p *Pool) validateDisruption(tier Tier) error {
+	if p.MaxUnavailable != "" {
+		if _, err := parseBudget(p.MaxUnavailable, p.Nodes, false); err != nil {
+			return errors.Wrapf(err, "invalid maxUnavailable in pool %s", p.Pool)
+		}
+	}
+	if p.MaxSurge != "" {
+		if _, err := parseBudget(p.MaxSurge, p.Nodes, true); err != nil {
+			return errors.Wrapf(err, "invalid maxSurge in pool %s", p.Pool)
+		}
+	}
+	if p.replacementUnavailable() == 0 && p.maxSurge() == 0 {
+		return errors.Errorf("maxUnavailable and maxSurge in pool %s must not both be zero", p.Pool)
+	}
+	if p.EvictionTimeout < 0 {
+		return errors.Errorf("evictionTimeout in pool %s must not be negative", p.Pool)
+	}
+	// Losing more than one controlplane node at a time risks the etcd quorum
+	if tier == Controlplane && p.maxUnavailable() > 1 {
+		return errors.Errorf("the controlplane allows only one unavailable node at a time but maxUnavailable resolves to %d", p.maxUnavailable())
+	}
+	return nil
+}
+
+// unavailable returns true if the machines node is drained or rebooting
+func (i *initializedMachine) unavailable() bool {
+	return i.currentMachine.Updating ||
+		i.currentMachine.Rebooting ||
+		i.node != nil && i.node.Spec.Unschedulable
+}
+
+// disruptionBudget tracks how many more nodes of each pool may become unavailable in this iteration
+type disruptionBudget map[*initializedPool]int
+
+func newDisruptionBudget(machines []*initializedMachine) disruptionBudget {
+	budget := make(disruptionBudget)
+	for _, machine := range machines {
+		if _, ok := budget[machine.pool]; !ok {
+			budget[machine.pool] = machine.pool.desired.maxUnavailable()
+		}
+		if machine.unavailable() {
+			budget[machine.pool]--
+		}
+	}
+	return budget
+}
+
+// take returns true if the machine is already unavailable or if its pool allows one more unavailable node.
+// In the latter case, the pools budget is decreased
+func (d disruptionBudget) take(machine *initializedMachine) bool {
+	if machine.unavailable() {
+		return true
+	}
+	if d[machine.pool] <= 0 {
+		return false
+	}
+	d[machine.pool]--
+	return true
+}
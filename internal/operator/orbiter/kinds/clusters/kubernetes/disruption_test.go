@@ -0,0 +1,244 @@
+package kubernetes
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/mntr"
+)
+
+func Test_parseBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		nodes   int
+		roundUp bool
+		want    int
+		wantErr bool
+	}{
+		{name: "It should return absolute counts", value: "2", nodes: 10, want: 2},
+		{name: "It should not limit absolute counts to the nodes", value: "5", nodes: 3, want: 5},
+		{name: "It should accept a zero count", value: "0", nodes: 10, want: 0},
+		{name: "It should resolve percentages", value: "50%", nodes: 4, want: 2},
+		{name: "It should round percentages down", value: "25%", nodes: 10, roundUp: false, want: 2},
+		{name: "It should round percentages up", value: "25%", nodes: 10, roundUp: true, want: 3},
+		{name: "It should round small percentages down to zero", value: "10%", nodes: 3, roundUp: false, want: 0},
+		{name: "It should round small percentages up to one", value: "10%", nodes: 3, roundUp: true, want: 1},
+		{name: "It should accept a zero percentage", value: "0%", nodes: 10, roundUp: true, want: 0},
+		{name: "It should resolve a percentage of zero nodes to zero", value: "50%", nodes: 0, roundUp: true, want: 0},
+		{name: "It should fail for negative counts", value: "-1", nodes: 10, wantErr: true},
+		{name: "It should fail for negative percentages", value: "-5%", nodes: 10, wantErr: true},
+		{name: "It should fail for fractions", value: "1.5", nodes: 10, wantErr: true},
+		{name: "It should fail for a bare percent sign", value: "%", nodes: 10, wantErr: true},
+		{name: "It should fail for text", value: "many", nodes: 10, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBudget(tt.value, tt.nodes, tt.roundUp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBudget() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseBudget() got = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPool_maxUnavailable(t *testing.T) {
+	tests := []struct {
+		name                       string
+		pool                       Pool
+		wantMaxUnavailable         int
+		wantReplacementUnavailable int
+	}{
+		{name: "It should default to one", pool: Pool{Nodes: 10}, wantMaxUnavailable: 1, wantReplacementUnavailable: 1},
+		{name: "It should resolve percentages of the nodes", pool: Pool{Nodes: 10, MaxUnavailable: "30%"}, wantMaxUnavailable: 3, wantReplacementUnavailable: 3},
+		{name: "It should allow at least one node for maintenance", pool: Pool{Nodes: 3, MaxUnavailable: "10%"}, wantMaxUnavailable: 1, wantReplacementUnavailable: 0},
+		{name: "It should allow zero nodes for replacements", pool: Pool{Nodes: 3, MaxUnavailable: "0"}, wantMaxUnavailable: 1, wantReplacementUnavailable: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pool.maxUnavailable(); got != tt.wantMaxUnavailable {
+				t.Errorf("maxUnavailable() got = %d, want %d", got, tt.wantMaxUnavailable)
+			}
+			if got := tt.pool.replacementUnavailable(); got != tt.wantReplacementUnavailable {
+				t.Errorf("replacementUnavailable() got = %d, want %d", got, tt.wantReplacementUnavailable)
+			}
+		})
+	}
+}
+
+// testMachineState configures a test machine
+type testMachineState struct {
+	notJoined   bool
+	updating    bool
+	rebooting   bool
+	cordoned    bool
+	replacement bool
+}
+
+func testMachines(pool *initializedPool, states ...testMachineState) []*initializedMachine {
+	machines := make([]*initializedMachine, len(states))
+	for idx, state := range states {
+		machine := &initializedMachine{
+			infra: &testMachine{
+				id:                  fmt.Sprintf("%s-%d", pool.desired.Pool, idx),
+				replacementRequired: state.replacement,
+			},
+			currentMachine: &Machine{
+				Joined:    !state.notJoined,
+				Updating:  state.updating,
+				Rebooting: state.rebooting,
+			},
+			pool: pool,
+		}
+		if !state.notJoined {
+			machine.node = &v1.Node{
+				Spec: v1.NodeSpec{Unschedulable: state.cordoned},
+			}
+		}
+		machines[idx] = machine
+	}
+	return machines
+}
+
+func TestDisruptionBudget_take(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxUnavailable string
+		machines       []testMachineState
+		want           []bool
+	}{{
+		name:           "It should allow as many nodes as the pool allows",
+		maxUnavailable: "2",
+		machines:       []testMachineState{{}, {}, {}, {}},
+		want:           []bool{true, true, false, false},
+	}, {
+		name:     "It should allow one node by default",
+		machines: []testMachineState{{}, {}, {}},
+		want:     []bool{true, false, false},
+	}, {
+		name:           "It should subtract already unavailable nodes from the budget",
+		maxUnavailable: "2",
+		machines:       []testMachineState{{updating: true}, {}, {}, {}},
+		want:           []bool{true, true, false, false},
+	}, {
+		name:           "It should always allow already unavailable nodes",
+		maxUnavailable: "1",
+		machines:       []testMachineState{{}, {rebooting: true}, {cordoned: true}, {}},
+		want:           []bool{false, true, true, false},
+	}, {
+		name:           "It should count cordoned nodes as unavailable",
+		maxUnavailable: "2",
+		machines:       []testMachineState{{cordoned: true}, {}, {}},
+		want:           []bool{true, true, false},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &initializedPool{desired: Pool{Pool: "workers", Nodes: len(tt.machines), MaxUnavailable: tt.maxUnavailable}}
+			machines := testMachines(pool, tt.machines...)
+			budget := newDisruptionBudget(machines)
+			for idx, machine := range machines {
+				if got := budget.take(machine); got != tt.want[idx] {
+					t.Errorf("take() for machine %d got = %t, want %t", idx, got, tt.want[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestDisruptionBudget_takeIsPerPool(t *testing.T) {
+	first := &initializedPool{desired: Pool{Pool: "first", Nodes: 2}}
+	second := &initializedPool{desired: Pool{Pool: "second", Nodes: 2}}
+	firstMachines := testMachines(first, testMachineState{}, testMachineState{})
+	secondMachines := testMachines(second, testMachineState{updating: true}, testMachineState{})
+
+	budget := newDisruptionBudget(append(firstMachines, secondMachines...))
+
+	if !budget.take(firstMachines[0]) {
+		t.Error("expected the first pool to allow one node")
+	}
+	if budget.take(firstMachines[1]) {
+		t.Error("expected the first pools budget to be exhausted")
+	}
+	if budget.take(secondMachines[1]) {
+		t.Error("expected the second pools budget to be exhausted by its updating node")
+	}
+}
+
+func Test_stepRespectsDisruptionBudget(t *testing.T) {
+
+	from := V1x17x11.DefineSoftware()
+	to := V1x18x8.DefineSoftware()
+
+	tests := []struct {
+		name           string
+		maxUnavailable string
+		machines       []testMachineState
+		wantUpgraded   int
+	}{{
+		name:           "It should upgrade as many nodes at the same time as the pool allows",
+		maxUnavailable: "2",
+		machines:       []testMachineState{{}, {}, {}, {}, {}},
+		wantUpgraded:   2,
+	}, {
+		name:           "It should resolve percentages",
+		maxUnavailable: "60%",
+		machines:       []testMachineState{{}, {}, {}, {}, {}},
+		wantUpgraded:   3,
+	}, {
+		name:           "It should count nodes that are already updating",
+		maxUnavailable: "2",
+		machines:       []testMachineState{{updating: true}, {}, {}, {}, {}},
+		wantUpgraded:   2,
+	}, {
+		name:           "It should count nodes that are rebooting",
+		maxUnavailable: "2",
+		machines:       []testMachineState{{}, {rebooting: true}, {}, {}, {}},
+		wantUpgraded:   2,
+	}, {
+		name:           "It should only continue updating nodes if the budget is exceeded",
+		maxUnavailable: "1",
+		machines:       []testMachineState{{updating: true}, {}, {updating: true}, {}},
+		wantUpgraded:   2,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &initializedPool{
+				tier:    Workers,
+				desired: Pool{Pool: "workers", Nodes: len(tt.machines), MaxUnavailable: tt.maxUnavailable},
+			}
+			machines := testMachines(pool, tt.machines...)
+			for _, machine := range machines {
+				current := from
+				desired := from
+				machine.currentNodeagent = &common.NodeAgentCurrent{Software: current, NodeIsReady: true}
+				machine.desiredNodeagent = &common.NodeAgentSpec{Software: &desired}
+				machine.node.Labels = map[string]string{}
+				machine.node.Status.NodeInfo.KubeletVersion = from.Kubelet.Version
+			}
+
+			done, err := step(&Client{}, mntr.Monitor{}, machines, from, to, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done {
+				t.Error("expected the upgrade not to be done")
+			}
+
+			var upgraded int
+			for _, machine := range machines {
+				if machine.desiredNodeagent.Software.Kubeadm.Equals(to.Kubeadm) {
+					upgraded++
+				}
+			}
+			if upgraded != tt.wantUpgraded {
+				t.Errorf("step() upgraded %d nodes at the same time, want %d", upgraded, tt.wantUpgraded)
+			}
+		})
+	}
+}
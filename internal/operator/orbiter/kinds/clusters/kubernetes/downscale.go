@@ -5,11 +5,19 @@ import (
 	"github.com/caos/orbos/mntr"
 )
 
-func scaleDown(pools []*initializedPool, k8sClient *Client, uninitializeMachine uninitializeMachineFunc, monitor mntr.Monitor, pdf api.PushDesiredFunc) error {
+func scaleDown(pools []*initializedPool, k8sClient *Client, uninitializeMachine uninitializeMachineFunc, monitor mntr.Monitor, pdf api.PushDesiredFunc, windows *maintenanceWindows) error {
 	for _, pool := range pools {
 		for _, machine := range pool.downscaling {
+			action := actionDownscale
+			if req, _, _ := machine.infra.ReplacementRequired(); req {
+				action = actionReplace
+			}
+			if !windows.allows(machine, action) {
+				continue
+			}
+
 			id := machine.infra.ID()
-			if err := k8sClient.EnsureDeleted(id, machine.currentMachine, machine.infra); err != nil {
+			if err := k8sClient.EnsureDeleted(id, machine.currentMachine, machine.infra, machine.pool.desired.eviction()); err != nil {
 				return err
 			}
 			uninitializeMachine(id)
@@ -11,6 +11,7 @@ func ensure(
 	monitor mntr.Monitor,
 	clusterID string,
 	desired *DesiredV0,
+	current *CurrentCluster,
 	kubeAPIAddress *infra.Address,
 	pdf api.PushDesiredFunc,
 	k8sClient *Client,
@@ -22,6 +23,7 @@ func ensure(
 	initializeMachine initializeMachineFunc,
 	uninitializeMachine uninitializeMachineFunc,
 	gitClient *git.Client,
+	windows *maintenanceWindows,
 ) (done bool, err error) {
 
 	desireFW := firewallFunc(monitor, *desired)
@@ -29,11 +31,15 @@ func ensure(
 		desireFW(machine)
 	}
 
-	if err := scaleDown(append(workers, controlplane), k8sClient, uninitializeMachine, monitor, pdf); err != nil {
+	if err := autoscale(monitor, clusterID, desired, current, workers, workerMachines, k8sClient, pdf, windows); err != nil {
 		return false, err
 	}
 
-	done, err = maintainNodes(append(controlplaneMachines, workerMachines...), monitor, k8sClient, pdf)
+	if err := scaleDown(append(workers, controlplane), k8sClient, uninitializeMachine, monitor, pdf, windows); err != nil {
+		return false, err
+	}
+
+	done, err = maintainNodes(append(controlplaneMachines, workerMachines...), monitor, k8sClient, pdf, windows)
 	if err != nil || !done {
 		return done, err
 	}
@@ -45,7 +51,8 @@ func ensure(
 		targetVersion,
 		k8sClient,
 		controlplaneMachines,
-		workerMachines)
+		workerMachines,
+		windows)
 	if err != nil || !upgradingDone {
 		monitor.Info("Upgrading is not done yet")
 		return upgradingDone, err
@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+
+	core "k8s.io/api/core/v1"
+	macherrs "k8s.io/apimachinery/pkg/api/errors"
+	mach "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Components reporting Kubernetes Events
+const (
+	ComponentOrbiter = "orbiter"
+	ComponentBoom    = "boom"
+	ComponentZitadel = "zitadel"
+)
+
+// Reasons of the Kubernetes Events orbos records
+const (
+	ReasonCordoned        = "Cordoned"
+	ReasonDrained         = "Drained"
+	ReasonEvicted         = "Evicted"
+	ReasonRebooting       = "Rebooting"
+	ReasonUpgrading       = "Upgrading"
+	ReasonDeleting        = "Deleting"
+	ReasonApplied         = "Applied"
+	ReasonDeleted         = "Deleted"
+	ReasonReconciled      = "Reconciled"
+	ReasonReconcileFailed = "ReconcileFailed"
+	ReasonBackupFinished  = "BackupFinished"
+)
+
+// Events about cluster scoped objects like nodes are recorded in the default namespace
+const eventsDefaultNamespace = "default"
+
+// NodeReference references a node as involved object
+func NodeReference(node *core.Node) *core.ObjectReference {
+	return &core.ObjectReference{
+		Kind:       "Node",
+		APIVersion: "v1",
+		Name:       node.GetName(),
+		UID:        node.GetUID(),
+	}
+}
+
+// PodReference references a pod as involved object
+func PodReference(pod *core.Pod) *core.ObjectReference {
+	return &core.ObjectReference{
+		Kind:            "Pod",
+		APIVersion:      "v1",
+		Namespace:       pod.GetNamespace(),
+		Name:            pod.GetName(),
+		UID:             pod.GetUID(),
+		ResourceVersion: pod.GetResourceVersion(),
+	}
+}
+
+// ObjectReference references any object as involved object
+func ObjectReference(apiVersion, kind, namespace, name string) *core.ObjectReference {
+	return &core.ObjectReference{
+		Kind:       kind,
+		APIVersion: apiVersion,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+// RecordEvent records a Kubernetes Event about the involved object, so it shows up in kubectl get events and kubectl describe.
+// Recording the same reason and message again increases the events count.
+// Events are informational, so failures are only logged
+func (c *Client) RecordEvent(ref *core.ObjectReference, component, eventType, reason, message string) {
+	if !c.Available() {
+		return
+	}
+
+	monitor := c.monitor.WithFields(map[string]interface{}{
+		"kind":   ref.Kind,
+		"name":   ref.Name,
+		"reason": reason,
+	})
+
+	if err := c.recordEvent(ref, component, eventType, reason, message); err != nil {
+		monitor.Warn(fmt.Sprintf("Recording Kubernetes event failed: %s", err.Error()))
+		return
+	}
+	monitor.Debug("Kubernetes event recorded")
+}
+
+func (c *Client) recordEvent(ref *core.ObjectReference, component, eventType, reason, message string) error {
+
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = eventsDefaultNamespace
+	}
+
+	// Events of the same object, component, reason and message are aggregated like the kubelet does
+	h := fnv.New64a()
+	h.Write([]byte(ref.Kind + ref.Name + component + reason + message))
+	name := fmt.Sprintf("%s.%x", ref.Name, h.Sum64())
+
+	api := c.set.CoreV1().Events(namespace)
+	now := mach.Now()
+
+	existing, err := api.Get(context.Background(), name, mach.GetOptions{})
+	if err == nil {
+		existing.Count++
+		existing.LastTimestamp = now
+		_, err = api.Update(context.Background(), existing, mach.UpdateOptions{})
+		return err
+	}
+	if !macherrs.IsNotFound(err) {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	_, err = api.Create(context.Background(), &core.Event{
+		ObjectMeta: mach.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Source: core.EventSource{
+			Component: component,
+			Host:      host,
+		},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}, mach.CreateOptions{})
+	return err
+}
@@ -70,6 +70,7 @@ func initialize(
 	nodeAgentsDesired *common.DesiredNodeAgents,
 	providerPools map[string]map[string]infra.Pool,
 	k8s *Client,
+	windows *maintenanceWindows,
 	postInit func(machine *initializedMachine)) (
 	controlplane *initializedPool,
 	controlplaneMachines []*initializedMachine,
@@ -108,35 +109,7 @@ func initialize(
 			return pool, err
 		}
 
-		var replace initializedMachines
-		var backReplacement int
-		for _, machine := range machines {
-			if req, _, _ := machine.infra.ReplacementRequired(); req {
-				replace = append(replace, machine)
-				continue
-			}
-			if machine.currentMachine.Joined {
-				backReplacement++
-			}
-		}
-
-		upscale := desired.Nodes + len(replace) - len(machines)
-		if upscale > 0 {
-			pool.upscaling = upscale
-			return pool, nil
-		}
-
-		if len(replace) > 0 {
-			for backReplacement >= desired.Nodes && len(replace) > 0 {
-				backReplacement--
-				pool.downscaling = append(pool.downscaling, replace[0])
-				replace = replace[1:]
-			}
-			return pool, nil
-		}
-
-		pool.downscaling = machines[desired.Nodes:]
-
+		pool.upscaling, pool.downscaling = scalePool(desired, machines)
 		return pool, nil
 	}
 
@@ -170,7 +143,11 @@ func initialize(
 
 		reconcile := func() error { return nil }
 		if node != nil && !current.Unknown {
-			reconcile = reconcileNodeFunc(*node, monitor, pool.desired, k8s, pool.tier, naSpec, naCurr)
+			var zone string
+			if zoned, ok := machine.(infra.ZonedMachine); ok {
+				zone = zoned.Zone()
+			}
+			reconcile = reconcileNodeFunc(*node, monitor, pool.desired, k8s, pool.tier, zone, naSpec, naCurr)
 			current.Joined = true
 			for _, cond := range node.Status.Conditions {
 				if cond.Type == v1.NodeReady {
@@ -206,6 +183,20 @@ func initialize(
 			node:             node,
 		}
 
+		// Kubernetes software changes on joined nodes are disruptive unless the node is already drained.
+		// Outside maintenance windows, the installed packages stay desired, so other changes like firewall rules are still ensured
+		installed := upgradeSoftware(naCurr.Software)
+		if current.Joined &&
+			!current.Updating &&
+			!current.Rebooting &&
+			!softwareContains(installed, upgradeSoftware(*naSpec.Software)) &&
+			!windows.allows(initMachine, actionSoftware) {
+			naSpec.Software.Kubelet = installed.Kubelet
+			naSpec.Software.Kubeadm = installed.Kubeadm
+			naSpec.Software.Kubectl = installed.Kubectl
+			naSpec.Software.Containerruntime = installed.Containerruntime
+		}
+
 		postInit(initMachine)
 
 		return initMachine
@@ -288,7 +279,7 @@ func initialize(
 		}, nil
 }
 
-func reconcileNodeFunc(node v1.Node, monitor mntr.Monitor, pool Pool, k8s *Client, tier Tier, naSpec *common.NodeAgentSpec, naCurr *common.NodeAgentCurrent) func() error {
+func reconcileNodeFunc(node v1.Node, monitor mntr.Monitor, pool Pool, k8s *Client, tier Tier, zone string, naSpec *common.NodeAgentSpec, naCurr *common.NodeAgentCurrent) func() error {
 	n := &node
 	reconcileNode := false
 	reconcileMonitor := monitor.WithField("node", n.Name)
@@ -301,6 +292,9 @@ func reconcileNodeFunc(node v1.Node, monitor mntr.Monitor, pool Pool, k8s *Clien
 
 	handleMaybe(reconcileLabel(n, "orbos.ch/pool", pool.Pool))
 	handleMaybe(reconcileLabel(n, "orbos.ch/tier", string(tier)))
+	if zone != "" {
+		handleMaybe(reconcileLabel(n, core.LabelZoneFailureDomainStable, zone))
+	}
 	handleMaybe(reconcileTaints(n, pool, k8s, naSpec, naCurr))
 
 	if !reconcileNode {
@@ -312,27 +306,77 @@ func reconcileNodeFunc(node v1.Node, monitor mntr.Monitor, pool Pool, k8s *Clien
 	}
 }
 
+// scalePool returns how many machines the pool needs additionally or which of its machines can be removed.
+// Replacements are created before the replaced machines are removed as far as the pools surge allows
+func scalePool(desired Pool, machines []*initializedMachine) (int, []*initializedMachine) {
+
+	var replace initializedMachines
+	var available int
+	for _, machine := range machines {
+		if machine.currentMachine.Joined && !machine.unavailable() {
+			available++
+		}
+		if req, _, _ := machine.infra.ReplacementRequired(); req {
+			replace = append(replace, machine)
+		}
+	}
+
+	upscale := desired.Nodes + len(replace) - len(machines)
+	if surged := desired.Nodes + desired.maxSurge() - len(machines); surged < upscale {
+		upscale = surged
+	}
+	if upscale > 0 {
+		return upscale, nil
+	}
+
+	if len(replace) > 0 {
+		var downscaling []*initializedMachine
+		minAvailable := desired.Nodes - desired.replacementUnavailable()
+		for _, machine := range replace {
+			if machine.currentMachine.Joined && !machine.unavailable() {
+				if available <= minAvailable {
+					break
+				}
+				available--
+			}
+			downscaling = append(downscaling, machine)
+		}
+		return 0, downscaling
+	}
+
+	// Prefer removing machines whose nodes are already cordoned, for example because a previous downscaling was interrupted
+	sorted := append([]*initializedMachine{}, machines...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return !cordoned(sorted[i]) && cordoned(sorted[j])
+	})
+	return 0, sorted[desired.Nodes:]
+}
+
 func reconcileTaints(node *v1.Node, pool Pool, k8s *Client, naSpec *common.NodeAgentSpec, naCurr *common.NodeAgentCurrent) map[string]interface{} {
 	desiredTaints := pool.Taints.ToK8sTaints()
 	newTaints := append([]core.Taint{}, desiredTaints...)
 	updateTaints := false
 
-	// user defined taints
-outer:
-	for _, existing := range node.Spec.Taints {
-		if strings.HasPrefix(existing.Key, "node.kubernetes.io/") || strings.HasPrefix(existing.Key, taintKeyPrefix) {
-			newTaints = append(newTaints, existing)
-			continue
-		}
-		for _, des := range desiredTaints {
-			if existing.Key == des.Key &&
-				existing.Effect == des.Effect &&
-				existing.Value == des.Value {
-				continue outer
+	// user defined taints are left as they are if the pool doesn't define any
+	if pool.Taints == nil {
+		newTaints = append(newTaints, node.Spec.Taints...)
+	} else {
+	outer:
+		for _, existing := range node.Spec.Taints {
+			if strings.HasPrefix(existing.Key, "node.kubernetes.io/") || strings.HasPrefix(existing.Key, taintKeyPrefix) {
+				newTaints = append(newTaints, existing)
+				continue
+			}
+			for _, des := range desiredTaints {
+				if existing.Key == des.Key &&
+					existing.Effect == des.Effect &&
+					existing.Value == des.Value {
+					continue outer
+				}
 			}
+			updateTaints = true
+			break
 		}
-		updateTaints = true
-		break
 	}
 	// internal taints
 	if k8s.Tainted(node, updating) && node.Labels["orbos.ch/updating"] == node.Status.NodeInfo.KubeletVersion {
@@ -364,3 +408,7 @@ func reconcileLabel(node *v1.Node, key, value string) map[string]interface{} {
 		fmt.Sprintf("label.%s", key): value,
 	}
 }
+
+func cordoned(machine *initializedMachine) bool {
+	return machine.node != nil && machine.node.Spec.Unschedulable
+}
@@ -4,7 +4,7 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/internal/operator/common"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -65,9 +65,107 @@ func Test_reconcileTaints(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, _ := reconcileTaints(tt.args.node, tt.args.pool, mntr.Monitor{})
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("reconcileTaints() got = %v, want %v", got, tt.want)
+			node := tt.args.node
+			changed := reconcileTaints(&node, tt.args.pool, &Client{}, &common.NodeAgentSpec{}, &common.NodeAgentCurrent{})
+			if tt.want == nil {
+				if changed != nil {
+					t.Errorf("reconcileTaints() changed %v, want no change", changed)
+				}
+				return
+			}
+			if !reflect.DeepEqual(&node, tt.want) {
+				t.Errorf("reconcileTaints() got = %v, want %v", node, tt.want)
+			}
+		})
+	}
+}
+
+func Test_scalePool(t *testing.T) {
+	tests := []struct {
+		name            string
+		pool            Pool
+		machines        []testMachineState
+		wantUpscale     int
+		wantDownscaling []int
+	}{{
+		name:     "It should do nothing if the pool has the desired nodes",
+		pool:     Pool{Nodes: 3},
+		machines: []testMachineState{{}, {}, {}},
+	}, {
+		name:        "It should add missing machines",
+		pool:        Pool{Nodes: 3},
+		machines:    []testMachineState{{}},
+		wantUpscale: 2,
+	}, {
+		name:            "It should prefer removing cordoned machines",
+		pool:            Pool{Nodes: 2},
+		machines:        []testMachineState{{}, {cordoned: true}, {}},
+		wantDownscaling: []int{1},
+	}, {
+		name:        "It should create a replacement before removing a machine",
+		pool:        Pool{Nodes: 3},
+		machines:    []testMachineState{{replacement: true}, {}, {}},
+		wantUpscale: 1,
+	}, {
+		name:        "It should not create more replacements than the surge allows",
+		pool:        Pool{Nodes: 3},
+		machines:    []testMachineState{{replacement: true}, {replacement: true}, {}},
+		wantUpscale: 1,
+	}, {
+		name:        "It should resolve the surge as rounded up percentage",
+		pool:        Pool{Nodes: 4, MaxSurge: "30%"},
+		machines:    []testMachineState{{replacement: true}, {replacement: true}, {replacement: true}, {}},
+		wantUpscale: 2,
+	}, {
+		name:            "It should remove a replaced machine after its replacement was created",
+		pool:            Pool{Nodes: 3},
+		machines:        []testMachineState{{replacement: true}, {}, {}, {}},
+		wantDownscaling: []int{0},
+	}, {
+		name:            "It should remove replaced machines first without surge",
+		pool:            Pool{Nodes: 3, MaxSurge: "0"},
+		machines:        []testMachineState{{replacement: true}, {}, {}},
+		wantDownscaling: []int{0},
+	}, {
+		name:            "It should not remove more available machines than the pool allows",
+		pool:            Pool{Nodes: 3, MaxSurge: "0", MaxUnavailable: "1"},
+		machines:        []testMachineState{{replacement: true}, {replacement: true}, {}},
+		wantDownscaling: []int{0},
+	}, {
+		name:     "It should wait for the replacement to join if no node may be unavailable",
+		pool:     Pool{Nodes: 3, MaxUnavailable: "0"},
+		machines: []testMachineState{{replacement: true}, {}, {}, {notJoined: true}},
+	}, {
+		name:            "It should remove the replaced machine after the replacement joined if no node may be unavailable",
+		pool:            Pool{Nodes: 3, MaxUnavailable: "0"},
+		machines:        []testMachineState{{replacement: true}, {}, {}, {}},
+		wantDownscaling: []int{0},
+	}, {
+		name:            "It should remove unavailable replaced machines regardless of the budget",
+		pool:            Pool{Nodes: 3, MaxUnavailable: "0"},
+		machines:        []testMachineState{{replacement: true, updating: true}, {}, {}, {notJoined: true}},
+		wantDownscaling: []int{0},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pool.Pool = "pool"
+			machines := testMachines(&initializedPool{desired: tt.pool}, tt.machines...)
+
+			gotUpscale, gotDownscaling := scalePool(tt.pool, machines)
+			if gotUpscale != tt.wantUpscale {
+				t.Errorf("scalePool() got upscale = %d, want %d", gotUpscale, tt.wantUpscale)
+			}
+
+			gotIDs := make([]string, len(gotDownscaling))
+			for idx, machine := range gotDownscaling {
+				gotIDs[idx] = machine.infra.ID()
+			}
+			wantIDs := make([]string, len(tt.wantDownscaling))
+			for idx, machine := range tt.wantDownscaling {
+				wantIDs[idx] = machines[machine].infra.ID()
+			}
+			if !reflect.DeepEqual(gotIDs, wantIDs) {
+				t.Errorf("scalePool() got downscaling = %v, want %v", gotIDs, wantIDs)
 			}
 		})
 	}
@@ -0,0 +1,222 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robfig/cron/v3"
+
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+)
+
+// Maintenance restricts disruptive actions like reboots, replacements, downscaling
+// and software changes on joined nodes to maintenance windows.
+// Without windows, disruptive actions are executed immediately
+type Maintenance struct {
+	// IANA time zone the windows are defined in, for example Europe/Zurich
+	//@default: UTC
+	Timezone string `yaml:",omitempty"`
+	// The maintenance window is open while any of these windows is open
+	Windows []*MaintenanceWindow `yaml:",omitempty"`
+	// Disruptive actions are executed immediately until this time. Use orbctl maintenance force to set it.
+	// Only considered in the clusters maintenance section
+	ForcedUntil *time.Time `yaml:",omitempty"`
+}
+
+// MaintenanceWindow opens at the times its cron expression matches and stays open for its duration
+type MaintenanceWindow struct {
+	// Cron expression with the fields minute, hour, day of month, month and day of week, for example "0 22 * * 1-5"
+	Start string
+	// For example 4h
+	Duration time.Duration
+}
+
+// Disruptive actions that wait for an open maintenance window
+const (
+	actionReboot    = "reboot"
+	actionReplace   = "replace"
+	actionDownscale = "downscale"
+	actionUpgrade   = "upgrade"
+	actionSoftware  = "software"
+)
+
+var postponedGauge = newClusterGauges(
+	prometheus.GaugeOpts{
+		Name: "orbiter_maintenance_postponed_actions",
+		Help: "Disruptive actions waiting for a maintenance window per provider, pool and action.",
+	},
+	[]string{"provider", "pool", "action"},
+)
+
+func init() {
+	prometheus.MustRegister(postponedGauge.vec)
+}
+
+func (m *Maintenance) validate() error {
+	if m == nil {
+		return nil
+	}
+	if _, err := m.location(); err != nil {
+		return err
+	}
+	for _, window := range m.Windows {
+		if _, err := cron.ParseStandard(window.Start); err != nil {
+			return errors.Wrapf(err, "parsing maintenance window start %s failed", window.Start)
+		}
+		if window.Duration <= 0 {
+			return errors.Errorf("maintenance window starting at %s has no positive duration", window.Start)
+		}
+	}
+	return nil
+}
+
+func (m *Maintenance) location() (*time.Location, error) {
+	if m.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(m.Timezone)
+	return loc, errors.Wrapf(err, "loading maintenance timezone %s failed", m.Timezone)
+}
+
+// open returns true if any window is open at now. Otherwise, it returns when the next window opens
+func (m *Maintenance) open(now time.Time) (bool, time.Time) {
+	if m == nil || len(m.Windows) == 0 {
+		return true, now
+	}
+
+	loc, err := m.location()
+	if err != nil {
+		// validated before
+		panic(err)
+	}
+	now = now.In(loc)
+
+	var next time.Time
+	for _, window := range m.Windows {
+		schedule, err := cron.ParseStandard(window.Start)
+		if err != nil {
+			panic(err)
+		}
+		// The window is open if it started within its duration before now
+		if started := schedule.Next(now.Add(-window.Duration)); !started.After(now) {
+			return true, now
+		}
+		if windowNext := schedule.Next(now); next.IsZero() || windowNext.Before(next) {
+			next = windowNext
+		}
+	}
+	return false, next
+}
+
+// PostponedAction is a disruptive action waiting for a maintenance window
+type PostponedAction struct {
+	Machine string
+	Action  string
+	Until   time.Time
+}
+
+// maintenanceWindows decides for each machine if disruptive actions are allowed now
+// and reports the postponed actions to the current state and the metrics
+type maintenanceWindows struct {
+	monitor   mntr.Monitor
+	clusterID string
+	now       time.Time
+	desired   *Spec
+	current   *CurrentCluster
+	mux       sync.Mutex
+}
+
+func newMaintenanceWindows(monitor mntr.Monitor, clusterID string, desired *Spec, current *CurrentCluster) *maintenanceWindows {
+	postponedGauge.reset(clusterID)
+	current.Postponed = nil
+	return &maintenanceWindows{
+		monitor:   monitor,
+		clusterID: clusterID,
+		now:       time.Now(),
+		desired:   desired,
+		current:   current,
+	}
+}
+
+func (m *maintenanceWindows) forced() bool {
+	return m.desired.Maintenance != nil &&
+		m.desired.Maintenance.ForcedUntil != nil &&
+		m.now.Before(*m.desired.Maintenance.ForcedUntil)
+}
+
+// allows returns true if the disruptive action may be executed on the machine now.
+// Otherwise, it records the action as postponed
+func (m *maintenanceWindows) allows(machine *initializedMachine, action string) bool {
+
+	postponed, next := m.postponed(machine)
+	if !postponed {
+		return true
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	id := machine.infra.ID()
+	for _, postponed := range m.current.Postponed {
+		if postponed.Machine == id && postponed.Action == action {
+			return false
+		}
+	}
+
+	m.current.Postponed = append(m.current.Postponed, &PostponedAction{
+		Machine: id,
+		Action:  action,
+		Until:   next,
+	})
+	postponedGauge.with(m.clusterID, machine.currentMachine.Metadata.Provider, machine.currentMachine.Metadata.Pool, action).Inc()
+	m.monitor.WithFields(map[string]interface{}{
+		"machine": id,
+		"action":  action,
+		"until":   next.Format(time.RFC3339),
+	}).Info("Postponing disruptive action until the next maintenance window")
+	return false
+}
+
+// postponed returns true and the time the next maintenance window opens
+// if disruptive actions on the machine have to wait. In contrast to allows, nothing is recorded
+func (m *maintenanceWindows) postponed(machine *initializedMachine) (bool, time.Time) {
+
+	if m == nil || m.forced() {
+		return false, time.Time{}
+	}
+
+	maintenance := m.desired.Maintenance
+	if machine.pool.desired.Maintenance != nil {
+		maintenance = machine.pool.desired.Maintenance
+	}
+
+	open, next := maintenance.open(m.now)
+	return !open, next
+}
+
+// ForceMaintenance lets the orbiter execute disruptive actions immediately until the passed time.
+// The desired tree must be adapted before. A zero time removes the override
+func ForceMaintenance(desiredTree *tree.Tree, until time.Time) error {
+	desiredKind, ok := desiredTree.Parsed.(*DesiredV0)
+	if !ok {
+		return fmt.Errorf("%s is not adapted", desiredTree.Common.Kind)
+	}
+
+	if until.IsZero() {
+		if desiredKind.Spec.Maintenance != nil {
+			desiredKind.Spec.Maintenance.ForcedUntil = nil
+		}
+		return nil
+	}
+
+	if desiredKind.Spec.Maintenance == nil {
+		desiredKind.Spec.Maintenance = &Maintenance{}
+	}
+	until = until.UTC().Truncate(time.Second)
+	desiredKind.Spec.Maintenance.ForcedUntil = &until
+	return nil
+}
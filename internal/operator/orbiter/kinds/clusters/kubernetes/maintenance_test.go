@@ -0,0 +1,244 @@
+package kubernetes
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+)
+
+type testMachine struct {
+	id                  string
+	rebootRequired      bool
+	replacementRequired bool
+}
+
+var _ infra.Machine = (*testMachine)(nil)
+
+func (t *testMachine) ID() string                                { return t.id }
+func (t *testMachine) IP() string                                { return "" }
+func (t *testMachine) Remove() error                             { return nil }
+func (t *testMachine) Execute(io.Reader, string) ([]byte, error) { return nil, nil }
+func (t *testMachine) Shell() error                              { return nil }
+func (t *testMachine) WriteFile(string, io.Reader, uint16) error { return nil }
+func (t *testMachine) ReadFile(string, io.Writer) error          { return nil }
+func (t *testMachine) RebootRequired() (bool, func(), func()) {
+	return t.rebootRequired, func() {}, func() {}
+}
+func (t *testMachine) ReplacementRequired() (bool, func(), func()) {
+	return t.replacementRequired, func() {}, func() {}
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestMaintenance_open(t *testing.T) {
+
+	// Monday to Friday, 22:00 to 02:00
+	weeknights := &MaintenanceWindow{Start: "0 22 * * 1-5", Duration: 4 * time.Hour}
+	// Saturday, 06:00 to 08:00
+	saturdayMornings := &MaintenanceWindow{Start: "0 6 * * 6", Duration: 2 * time.Hour}
+
+	// 2020-10-16 is a Friday
+	tests := []struct {
+		name        string
+		maintenance *Maintenance
+		now         string
+		wantOpen    bool
+		wantNext    string
+	}{{
+		name:        "It should be open without maintenance",
+		maintenance: nil,
+		now:         "2020-10-16T12:00:00Z",
+		wantOpen:    true,
+		wantNext:    "2020-10-16T12:00:00Z",
+	}, {
+		name:        "It should be open without windows",
+		maintenance: &Maintenance{},
+		now:         "2020-10-16T12:00:00Z",
+		wantOpen:    true,
+		wantNext:    "2020-10-16T12:00:00Z",
+	}, {
+		name:        "It should return the next start before a window",
+		maintenance: &Maintenance{Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-10-16T21:00:00Z",
+		wantOpen:    false,
+		wantNext:    "2020-10-16T22:00:00Z",
+	}, {
+		name:        "It should be open when a window starts",
+		maintenance: &Maintenance{Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-10-16T22:00:00Z",
+		wantOpen:    true,
+		wantNext:    "2020-10-16T22:00:00Z",
+	}, {
+		name:        "It should be open after midnight within a window spanning midnight",
+		maintenance: &Maintenance{Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-10-17T01:30:00Z",
+		wantOpen:    true,
+		wantNext:    "2020-10-17T01:30:00Z",
+	}, {
+		name:        "It should be closed when a window spanning midnight ends",
+		maintenance: &Maintenance{Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-10-17T02:00:00Z",
+		wantOpen:    false,
+		wantNext:    "2020-10-19T22:00:00Z",
+	}, {
+		name:        "It should be closed after midnight if no window started the day before",
+		maintenance: &Maintenance{Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-10-19T01:00:00Z",
+		wantOpen:    false,
+		wantNext:    "2020-10-19T22:00:00Z",
+	}, {
+		name:        "It should return the earliest next start of all windows",
+		maintenance: &Maintenance{Windows: []*MaintenanceWindow{weeknights, saturdayMornings}},
+		now:         "2020-10-17T03:00:00Z",
+		wantOpen:    false,
+		wantNext:    "2020-10-17T06:00:00Z",
+	}, {
+		name:        "It should be open if any window is open",
+		maintenance: &Maintenance{Windows: []*MaintenanceWindow{weeknights, saturdayMornings}},
+		now:         "2020-10-17T07:00:00Z",
+		wantOpen:    true,
+		wantNext:    "2020-10-17T07:00:00Z",
+	}, {
+		name:        "It should interpret windows in the time zone",
+		maintenance: &Maintenance{Timezone: "Europe/Zurich", Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-10-16T20:30:00Z",
+		wantOpen:    true,
+		wantNext:    "2020-10-16T20:30:00Z",
+	}, {
+		name:        "It should return the next start in the time zone",
+		maintenance: &Maintenance{Timezone: "Europe/Zurich", Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-10-16T19:30:00Z",
+		wantOpen:    false,
+		wantNext:    "2020-10-16T20:00:00Z",
+	}, {
+		name:        "It should respect daylight saving time in the time zone",
+		maintenance: &Maintenance{Timezone: "Europe/Zurich", Windows: []*MaintenanceWindow{weeknights}},
+		now:         "2020-11-02T20:30:00Z",
+		wantOpen:    false,
+		wantNext:    "2020-11-02T21:00:00Z",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.maintenance.validate(); err != nil {
+				t.Fatal(err)
+			}
+			gotOpen, gotNext := tt.maintenance.open(mustParseTime(t, tt.now))
+			if gotOpen != tt.wantOpen {
+				t.Errorf("open() got open = %t, want %t", gotOpen, tt.wantOpen)
+			}
+			if wantNext := mustParseTime(t, tt.wantNext); !gotNext.Equal(wantNext) {
+				t.Errorf("open() got next = %s, want %s", gotNext.UTC(), wantNext)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindows_postponed(t *testing.T) {
+
+	now := mustParseTime(t, "2020-10-16T12:00:00Z")
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	// Opens at 22:00 and is closed at noon
+	closed := []*MaintenanceWindow{{Start: "0 22 * * *", Duration: time.Hour}}
+	// Opens every hour and is always open
+	open := []*MaintenanceWindow{{Start: "0 * * * *", Duration: time.Hour}}
+
+	tests := []struct {
+		name          string
+		nilWindows    bool
+		cluster       *Maintenance
+		pool          *Maintenance
+		wantPostponed bool
+	}{{
+		name:          "It should not postpone without maintenance windows",
+		nilWindows:    true,
+		wantPostponed: false,
+	}, {
+		name:          "It should not postpone without maintenance",
+		wantPostponed: false,
+	}, {
+		name:          "It should postpone outside the clusters windows",
+		cluster:       &Maintenance{Windows: closed},
+		wantPostponed: true,
+	}, {
+		name:          "It should not postpone if the pools windows are open",
+		cluster:       &Maintenance{Windows: closed},
+		pool:          &Maintenance{Windows: open},
+		wantPostponed: false,
+	}, {
+		name:          "It should postpone outside the pools windows",
+		cluster:       &Maintenance{Windows: open},
+		pool:          &Maintenance{Windows: closed},
+		wantPostponed: true,
+	}, {
+		name:          "It should not postpone while forced",
+		cluster:       &Maintenance{Windows: closed, ForcedUntil: &after},
+		pool:          &Maintenance{Windows: closed},
+		wantPostponed: false,
+	}, {
+		name:          "It should postpone after forcing expired",
+		cluster:       &Maintenance{Windows: closed, ForcedUntil: &before},
+		wantPostponed: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var windows *maintenanceWindows
+			if !tt.nilWindows {
+				windows = &maintenanceWindows{
+					now:     now,
+					desired: &Spec{Maintenance: tt.cluster},
+					current: &CurrentCluster{},
+				}
+			}
+			machine := &initializedMachine{
+				infra:          &testMachine{id: "machine"},
+				currentMachine: &Machine{},
+				pool:           &initializedPool{desired: Pool{Maintenance: tt.pool}},
+			}
+
+			gotPostponed, gotNext := windows.postponed(machine)
+			if gotPostponed != tt.wantPostponed {
+				t.Errorf("postponed() got %t, want %t", gotPostponed, tt.wantPostponed)
+			}
+			if gotPostponed && !gotNext.After(now) {
+				t.Errorf("postponed() got next %s, want a time after %s", gotNext, now)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindows_allows(t *testing.T) {
+
+	windows := newMaintenanceWindows(mntr.Monitor{}, "cluster", &Spec{Maintenance: &Maintenance{
+		Windows: []*MaintenanceWindow{{Start: "0 0 1 1 *", Duration: time.Minute}},
+	}}, &CurrentCluster{})
+
+	machine := &initializedMachine{
+		infra:          &testMachine{id: "machine"},
+		currentMachine: &Machine{},
+		pool:           &initializedPool{},
+	}
+
+	for i := 0; i < 2; i++ {
+		if windows.allows(machine, actionReboot) {
+			t.Fatal("expected the reboot to be postponed")
+		}
+	}
+	if windows.allows(machine, actionUpgrade) {
+		t.Fatal("expected the upgrade to be postponed")
+	}
+
+	if len(windows.current.Postponed) != 2 {
+		t.Errorf("expected each postponed action to be recorded once, got %d", len(windows.current.Postponed))
+	}
+}
@@ -0,0 +1,122 @@
+package kubernetes
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	machinesGauge = newClusterGauges(
+		prometheus.GaugeOpts{
+			Name: "orbiter_machines",
+			Help: "Machines per provider, pool and state.",
+		},
+		[]string{"provider", "pool", "state"},
+	)
+	kubernetesVersionGauge = newClusterGauges(
+		prometheus.GaugeOpts{
+			Name: "orbiter_node_kubernetes_version",
+			Help: "Current and desired kubernetes version per node. The value is 1 if the node runs the desired version, else 0.",
+		},
+		[]string{"machine", "current", "desired"},
+	)
+	drainsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orbiter_drains_total",
+			Help: "Drained nodes.",
+		},
+		[]string{"provider", "pool", "reason"},
+	)
+	rebootsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orbiter_reboots_total",
+			Help: "Required machine reboots.",
+		},
+		[]string{"provider", "pool"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(machinesGauge.vec, kubernetesVersionGauge.vec, drainsCounter, rebootsCounter)
+}
+
+// clusterGauges is a gauge vector which is observed by several clusters.
+// It remembers the label values each cluster observed, so a cluster only resets its own series
+type clusterGauges struct {
+	vec    *prometheus.GaugeVec
+	mux    sync.Mutex
+	series map[string]map[string][]string
+}
+
+func newClusterGauges(opts prometheus.GaugeOpts, labelNames []string) *clusterGauges {
+	return &clusterGauges{
+		vec:    prometheus.NewGaugeVec(opts, labelNames),
+		series: make(map[string]map[string][]string),
+	}
+}
+
+// reset deletes all series the cluster observed
+func (c *clusterGauges) reset(clusterID string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, labelValues := range c.series[clusterID] {
+		c.vec.DeleteLabelValues(labelValues...)
+	}
+	delete(c.series, clusterID)
+}
+
+// with returns the clusters gauge for the label values
+func (c *clusterGauges) with(clusterID string, labelValues ...string) prometheus.Gauge {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.series[clusterID] == nil {
+		c.series[clusterID] = make(map[string][]string)
+	}
+	c.series[clusterID][strings.Join(labelValues, "\x00")] = labelValues
+	return c.vec.WithLabelValues(labelValues...)
+}
+
+// observeMachines replaces the clusters machine and version gauges with the queried state
+func observeMachines(clusterID string, desired *DesiredV0, machines []*initializedMachine) {
+
+	machinesGauge.reset(clusterID)
+	kubernetesVersionGauge.reset(clusterID)
+
+	target := desired.Spec.Versions.Kubernetes
+
+	for _, machine := range machines {
+		metadata := machine.currentMachine.Metadata
+		machinesGauge.with(clusterID, metadata.Provider, metadata.Pool, machineState(machine.currentMachine)).Inc()
+
+		current := "unknown"
+		if machine.node != nil {
+			current = machine.node.Status.NodeInfo.KubeletVersion
+		} else if machine.currentNodeagent != nil && machine.currentNodeagent.Software.Kubelet.Version != "" {
+			current = machine.currentNodeagent.Software.Kubelet.Version
+		}
+
+		var upToDate float64
+		if current == target {
+			upToDate = 1
+		}
+		kubernetesVersionGauge.with(clusterID, machine.infra.ID(), current, target).Set(upToDate)
+	}
+}
+
+func machineState(machine *Machine) string {
+	switch {
+	case machine.Unknown:
+		return "unknown"
+	case machine.Rebooting:
+		return "rebooting"
+	case machine.Updating:
+		return "updating"
+	case !machine.Joined:
+		return "joining"
+	case !machine.Ready:
+		return "notready"
+	}
+	return "ready"
+}
@@ -3,11 +3,13 @@ package kubernetes
 import (
 	"time"
 
+	v1 "k8s.io/api/core/v1"
+
 	"github.com/caos/orbos/internal/api"
 	"github.com/caos/orbos/mntr"
 )
 
-func maintainNodes(allInitializedMachines initializedMachines, monitor mntr.Monitor, k8sClient *Client, pdf api.PushDesiredFunc) (done bool, err error) {
+func maintainNodes(allInitializedMachines initializedMachines, monitor mntr.Monitor, k8sClient *Client, pdf api.PushDesiredFunc, windows *maintenanceWindows) (done bool, err error) {
 
 	allInitializedMachines.forEach(monitor, func(machine *initializedMachine, machineMonitor mntr.Monitor) bool {
 		if err = machine.reconcile(); err != nil {
@@ -19,27 +21,42 @@ func maintainNodes(allInitializedMachines initializedMachines, monitor mntr.Moni
 		return false, err
 	}
 
+	budget := newDisruptionBudget(allInitializedMachines)
+	var rebooted bool
 	allInitializedMachines.forEach(monitor, func(machine *initializedMachine, machineMonitor mntr.Monitor) bool {
 		req, _, unreq := machine.infra.RebootRequired()
-		if !req {
+		if !req || !machine.currentMachine.Rebooting && !windows.allows(machine, actionReboot) {
+			return true
+		}
+		if !budget.take(machine) {
+			machineMonitor.Info("Postponing reboot as too many nodes of the pool are unavailable")
 			return true
 		}
 		if k8sClient.Available() {
-			if err = k8sClient.Drain(machine.currentMachine, machine.node, rebooting); err != nil {
+			if err = k8sClient.Drain(machine.currentMachine, machine.node, rebooting, machine.pool.desired.eviction()); err != nil {
 				return false
 			}
+			k8sClient.RecordEvent(NodeReference(machine.node), ComponentOrbiter, v1.EventTypeNormal, ReasonRebooting, "Node is rebooted by the orbiter")
 		}
 		machine.currentMachine.Rebooting = true
+		rebootsCounter.WithLabelValues(machine.currentMachine.Metadata.Provider, machine.currentMachine.Metadata.Pool).Inc()
 		machineMonitor.Info("Requiring reboot")
 		unreq()
 		machine.desiredNodeagent.RebootRequired = time.Now().Truncate(time.Minute)
-		err = pdf(monitor.WithField("reason", "remove machine from reboot list"))
-		return false
+		rebooted = true
+		return true
 	})
 	if err != nil {
 		return false, err
 	}
 
+	if rebooted {
+		if err := pdf(monitor.WithField("reason", "remove machines from reboot list")); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
 	done = true
 	allInitializedMachines.forEach(monitor, func(machine *initializedMachine, machineMonitor mntr.Monitor) bool {
 		if !machine.currentMachine.FirewallIsReady {
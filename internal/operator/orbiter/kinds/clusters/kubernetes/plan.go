@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/mntr"
+)
+
+// recordPlan records the machines ensure would create or remove,
+// the nodes it would drain, reboot or replace and the next kubernetes version step.
+// Disruptive actions outside the maintenance windows or beyond the pools disruption budget are recorded as postponed.
+// Autoscaling is decided while ensuring, so the plan is based on the desired node counts only
+func recordPlan(
+	plan *orbiter.Plan,
+	monitor mntr.Monitor,
+	clusterID string,
+	desired *DesiredV0,
+	k8sClient *Client,
+	controlplane *initializedPool,
+	controlplaneMachines []*initializedMachine,
+	workers []*initializedPool,
+	workerMachines []*initializedMachine,
+	windows *maintenanceWindows,
+) error {
+
+	postpone := func(machine *initializedMachine, action string) bool {
+		postponed, until := windows.postponed(machine)
+		if postponed {
+			plan.AddNode(orbiter.PlannedNode{
+				Machine: machine.infra.ID(),
+				Action:  "postpone",
+				Reason:  action + " until " + until.Format(time.RFC3339),
+			})
+		}
+		return postponed
+	}
+
+	for _, pool := range append(workers, controlplane) {
+		if pool == nil {
+			continue
+		}
+		for i := 0; i < pool.upscaling; i++ {
+			plan.AddMachine(orbiter.PlannedMachine{
+				Provider: pool.desired.Provider,
+				Pool:     pool.desired.Pool,
+				Action:   "create",
+			})
+		}
+		for _, machine := range pool.downscaling {
+			reason, action := "downscaling", actionDownscale
+			if req, _, _ := machine.infra.ReplacementRequired(); req {
+				reason, action = "replacement", actionReplace
+			}
+			if postpone(machine, action) {
+				continue
+			}
+			if machine.currentMachine.Joined {
+				plan.AddNode(orbiter.PlannedNode{
+					Machine: machine.infra.ID(),
+					Action:  "drain",
+					Reason:  reason,
+				})
+			}
+			plan.AddMachine(orbiter.PlannedMachine{
+				Provider: pool.desired.Provider,
+				Pool:     pool.desired.Pool,
+				Machine:  machine.infra.ID(),
+				Action:   "remove",
+				Reason:   reason,
+			})
+		}
+	}
+
+	machines := append(controlplaneMachines, workerMachines...)
+	budget := newDisruptionBudget(machines)
+	for _, machine := range machines {
+		id := machine.infra.ID()
+		if req, _, _ := machine.infra.RebootRequired(); req && (machine.currentMachine.Rebooting || !postpone(machine, actionReboot)) {
+			switch {
+			case !budget.take(machine):
+				plan.AddNode(orbiter.PlannedNode{Machine: id, Action: "postpone", Reason: "reboot until fewer nodes of the pool are unavailable"})
+			case k8sClient.Available():
+				plan.AddNode(orbiter.PlannedNode{Machine: id, Action: "drain", Reason: "reboot"})
+				fallthrough
+			default:
+				plan.AddNode(orbiter.PlannedNode{Machine: id, Action: "reboot", Reason: "required"})
+			}
+		}
+		if req, _, _ := machine.infra.ReplacementRequired(); req && !isDownscaling(machine) {
+			plan.AddNode(orbiter.PlannedNode{Machine: id, Action: "replace", Reason: "required"})
+		}
+	}
+
+	target := ParseString(desired.Spec.Versions.Kubernetes)
+	from, to, err := findPath(monitor, machines, target)
+	if err != nil {
+		return err
+	}
+
+	if !from.Kubelet.Equals(to.Kubelet) {
+		plan.AddVersion(orbiter.PlannedVersion{
+			Cluster: clusterID,
+			From:    from.Kubelet.Version,
+			To:      to.Kubelet.Version,
+			Target:  target.String(),
+		})
+	}
+	return nil
+}
+
+func isDownscaling(machine *initializedMachine) bool {
+	for _, downscaling := range machine.pool.downscaling {
+		if downscaling.infra.ID() == machine.infra.ID() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/ssh"
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+)
+
+const plannedOrbiterYml = `kind: orbiter.caos.ch/KubernetesCluster
+version: v0
+spec:
+  controlplane:
+    provider: fake
+    pool: controlplane
+    nodes: 1
+  networking:
+    dnsdomain: cluster.orbitertest
+    network: calico
+    servicecidr: 10.0.0.0/16
+    podcidr: 10.1.0.0/16
+  versions:
+    kubernetes: v1.18.8
+  workers:
+  - provider: fake
+    pool: workers
+    nodes: 3
+`
+
+// plannedMachine fails the test on every call that changes the machine
+type plannedMachine struct {
+	t              *testing.T
+	id             string
+	rebootRequired bool
+}
+
+var _ infra.Machine = (*plannedMachine)(nil)
+
+func (p *plannedMachine) mutated(call string) {
+	p.t.Errorf("planning called %s on machine %s", call, p.id)
+}
+
+func (p *plannedMachine) ID() string { return p.id }
+func (p *plannedMachine) IP() string { return "10.0.0.1" }
+func (p *plannedMachine) Remove() error {
+	p.mutated("Remove")
+	return nil
+}
+func (p *plannedMachine) Execute(io.Reader, string) ([]byte, error) {
+	p.mutated("Execute")
+	return nil, nil
+}
+func (p *plannedMachine) Shell() error {
+	p.mutated("Shell")
+	return nil
+}
+func (p *plannedMachine) WriteFile(string, io.Reader, uint16) error {
+	p.mutated("WriteFile")
+	return nil
+}
+func (p *plannedMachine) ReadFile(string, io.Writer) error { return nil }
+func (p *plannedMachine) RebootRequired() (bool, func(), func()) {
+	return p.rebootRequired, func() { p.mutated("require reboot") }, func() { p.mutated("unrequire reboot") }
+}
+func (p *plannedMachine) ReplacementRequired() (bool, func(), func()) {
+	return false, func() { p.mutated("require replacement") }, func() { p.mutated("unrequire replacement") }
+}
+
+// plannedPool fails the test on every call that changes the pool
+type plannedPool struct {
+	t        *testing.T
+	name     string
+	machines infra.Machines
+}
+
+var _ infra.Pool = (*plannedPool)(nil)
+
+func (p *plannedPool) EnsureMembers() error {
+	p.t.Errorf("planning ensured the members of pool %s", p.name)
+	return nil
+}
+func (p *plannedPool) EnsureMember(infra.Machine) error {
+	p.t.Errorf("planning ensured a member of pool %s", p.name)
+	return nil
+}
+func (p *plannedPool) GetMachines() (infra.Machines, error) { return p.machines, nil }
+func (p *plannedPool) AddMachine() (infra.Machine, error) {
+	p.t.Errorf("planning added a machine to pool %s", p.name)
+	return nil, nil
+}
+
+type plannedProvider struct {
+	pools map[string]infra.Pool
+}
+
+var _ infra.ProviderCurrent = (*plannedProvider)(nil)
+
+func (p *plannedProvider) Pools() map[string]infra.Pool { return p.pools }
+func (p *plannedProvider) Ingresses() map[string]*infra.Address {
+	return map[string]*infra.Address{"kubeapi": {Location: "10.0.0.100", FrontendPort: 6443, BackendPort: 6666}}
+}
+
+// plannedRepository returns a client of a local repository containing the orbiter.yml
+// and a function returning the remotes head, so tests can ensure nothing is pushed
+func plannedRepository(t *testing.T) (*git.Client, func() string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("local repositories need the git executable")
+	}
+
+	dir, err := ioutil.TempDir("", "orbos-plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	remote := filepath.Join(dir, "remote.git")
+	remoteRepo, err := gogit.PlainInit(remote, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seedDir := filepath.Join(dir, "seed")
+	seed, err := gogit.PlainInit(seedDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(seedDir, "orbiter.yml"), []byte(plannedOrbiterYml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := seed.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("orbiter.yml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("seed", &gogit.CommitOptions{Author: &object.Signature{Name: "test", When: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seed.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.Push(&gogit.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Local repositories ignore the authentication, but the client needs valid keys
+	key, pub, err := ssh.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := git.New(context.Background(), mntr.Monitor{}, "test", "test@caos.ch")
+	if err := client.Configure(remote, []byte(key), []byte("localhost "+pub)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Clone(); err != nil {
+		t.Fatal(err)
+	}
+
+	return client, func() string {
+		head, err := remoteRepo.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return head.Hash().String()
+	}
+}
+
+func TestPlanned_HasNoSideEffects(t *testing.T) {
+
+	gitClient, remoteHead := plannedRepository(t)
+	headBefore := remoteHead()
+
+	provider := &plannedProvider{pools: map[string]infra.Pool{
+		"controlplane": &plannedPool{t: t, name: "controlplane", machines: infra.Machines{
+			&plannedMachine{t: t, id: "controlplane-0"},
+		}},
+		"workers": &plannedPool{t: t, name: "workers", machines: infra.Machines{
+			&plannedMachine{t: t, id: "workers-0", rebootRequired: true},
+		}},
+	}}
+
+	cluster := AdaptFunc("test", false, false, nil, func([]*orbiter.CIDR) {}, gitClient)
+	adapt := func(monitor mntr.Monitor, finishedChan chan struct{}, desiredTree *tree.Tree, currentTree *tree.Tree) (orbiter.QueryFunc, orbiter.DestroyFunc, orbiter.ConfigureFunc, bool, map[string]*secret.Secret, error) {
+		query, destroy, configure, migrate, secrets, err := cluster(monitor, finishedChan, desiredTree, currentTree)
+		if err != nil {
+			return nil, nil, nil, false, nil, err
+		}
+		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (orbiter.EnsureFunc, error) {
+				queried["fake"] = provider
+				if _, err := query(nodeAgentsCurrent, nodeAgentsDesired, queried); err != nil {
+					return nil, err
+				}
+				return func(api.PushDesiredFunc) *orbiter.EnsureResult {
+					t.Error("planning ensured the desired state")
+					return orbiter.ToEnsureResult(true, nil)
+				}, nil
+			}, func() error {
+				t.Error("planning destroyed the cluster")
+				return destroy()
+			}, func(orb orb.Orb) error {
+				t.Error("planning configured the cluster")
+				return configure(orb)
+			}, migrate, secrets, nil
+	}
+
+	plan, err := orbiter.Planned(mntr.Monitor{}, gitClient, adapt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var created int
+	for _, machine := range plan.Machines {
+		if machine.Action == "create" && machine.Pool == "workers" {
+			created++
+		}
+	}
+	if created != 2 {
+		t.Errorf("expected two workers to be planned, got %+v", plan.Machines)
+	}
+
+	var rebooted bool
+	for _, node := range plan.Nodes {
+		if node.Machine == "workers-0" && node.Action == "reboot" {
+			rebooted = true
+		}
+	}
+	if !rebooted {
+		t.Errorf("expected the reboot to be planned, got %+v", plan.Nodes)
+	}
+
+	if headAfter := remoteHead(); headAfter != headBefore {
+		t.Errorf("planning pushed commit %s", headAfter)
+	}
+}
@@ -39,6 +39,8 @@ func query(
 		}
 	}
 
+	windows := newMaintenanceWindows(monitor, clusterID, &desired.Spec, current)
+
 	controlplane, controlplaneMachines, workers, workerMachines, initializeMachine, uninitializeMachine, err := initialize(
 		monitor,
 		current,
@@ -47,15 +49,25 @@ func query(
 		nodeAgentsDesired,
 		cloudPools,
 		k8sClient,
+		windows,
 		func(machine *initializedMachine) {
 			firewallFunc(monitor, *desired)(machine)
 		})
 
+	if plan := orbiter.PlanOf(providerCurrents); plan != nil && err == nil {
+		if err := recordPlan(plan, monitor, clusterID, desired, k8sClient, controlplane, controlplaneMachines, workers, workerMachines, windows); err != nil {
+			return nil, err
+		}
+	} else if err == nil {
+		observeMachines(clusterID, desired, append(controlplaneMachines, workerMachines...))
+	}
+
 	return func(psf api.PushDesiredFunc) *orbiter.EnsureResult {
 		return orbiter.ToEnsureResult(ensure(
 			monitor,
 			clusterID,
 			desired,
+			current,
 			kubeAPIAddress,
 			psf,
 			k8sClient,
@@ -67,6 +79,7 @@ func query(
 			initializeMachine,
 			uninitializeMachine,
 			gitClient,
+			windows,
 		))
 	}, err
 }
@@ -87,10 +100,13 @@ func GetProviderInfos(desired *DesiredV0, providerCurrents map[string]interface{
 	var kubeAPIAddress *infra.Address
 
 	for providerName, provider := range providerCurrents {
+		prov, ok := provider.(infra.ProviderCurrent)
+		if !ok {
+			continue
+		}
 		if cloudPools[providerName] == nil {
 			cloudPools[providerName] = make(map[string]infra.Pool)
 		}
-		prov := provider.(infra.ProviderCurrent)
 		providerPools := prov.Pools()
 		providerIngresses := prov.Ingresses()
 		for providerPoolName, providerPool := range providerPools {
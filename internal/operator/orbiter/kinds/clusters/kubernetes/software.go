@@ -117,6 +117,16 @@ func KubernetesSoftware(current common.Software) common.Software {
 	}
 }
 
+// upgradeSoftware returns the packages an upgrade changes, which requires the node to be drained
+func upgradeSoftware(current common.Software) common.Software {
+	return common.Software{
+		Containerruntime: current.Containerruntime,
+		Kubelet:          current.Kubelet,
+		Kubeadm:          current.Kubeadm,
+		Kubectl:          current.Kubectl,
+	}
+}
+
 func ParseString(version string) KubernetesVersion {
 	for idx, k8sVersion := range kubernetesVersions {
 		if k8sVersion == version {
@@ -127,7 +137,7 @@ func ParseString(version string) KubernetesVersion {
 }
 
 func (k KubernetesVersion) equals(other KubernetesVersion) bool {
-	return string(k) == string(other)
+	return k == other
 }
 
 func (k KubernetesVersion) NextHighestMinor() KubernetesVersion {
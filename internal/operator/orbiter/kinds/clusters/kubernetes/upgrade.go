@@ -2,9 +2,12 @@ package kubernetes
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 
+	"github.com/caos/orbos/internal/helpers"
 	"github.com/caos/orbos/internal/operator/common"
 	"github.com/caos/orbos/mntr"
 )
@@ -20,7 +23,8 @@ func ensureSoftware(
 	target KubernetesVersion,
 	k8sClient *Client,
 	controlplane []*initializedMachine,
-	workers []*initializedMachine) (bool, error) {
+	workers []*initializedMachine,
+	windows *maintenanceWindows) (bool, error) {
 
 	sortedMachines := append(controlplane, workers...)
 	from, to, err := findPath(monitor, sortedMachines, target)
@@ -35,7 +39,7 @@ func ensureSoftware(
 		"desiredKubernetes": to.Kubelet,
 	}).Debug("Ensuring kubernetes version")
 
-	return step(k8sClient, monitor, sortedMachines, from, to)
+	return step(k8sClient, monitor, sortedMachines, from, to, windows)
 }
 
 func findPath(
@@ -142,6 +146,7 @@ func step(
 	sortedMachines initializedMachines,
 	from common.Software,
 	to common.Software,
+	windows *maintenanceWindows,
 ) (bool, error) {
 
 	for _, machine := range sortedMachines {
@@ -153,8 +158,19 @@ func step(
 			if err := k8sClient.updateNode(machine.node); err != nil {
 				return false, err
 			}
+			monitor.WithFields(map[string]interface{}{
+				"machine": machine.infra.ID(),
+				"version": machine.node.Status.NodeInfo.KubeletVersion,
+			}).Changed("Kubernetes upgraded")
 		}
 	}
+
+	var (
+		budget       = newDisruptionBudget(sortedMachines)
+		wg           sync.WaitGroup
+		synchronizer = helpers.NewSynchronizer(&wg)
+		pending      bool
+	)
 	for idx, machine := range sortedMachines {
 
 		next, err := plan(k8sClient, monitor, machine, idx == 0, from, to)
@@ -165,9 +181,40 @@ func step(
 		if next == nil {
 			continue
 		}
-		return false, next()
+
+		// Nodes that are already drained are finished
+		disruptive := machine.currentMachine.Joined && !machine.currentMachine.Updating
+		isControlplane := machine.pool.tier == Controlplane
+		if disruptive && !windows.allows(machine, actionUpgrade) {
+			if isControlplane {
+				return false, nil
+			}
+			pending = true
+			continue
+		}
+
+		// Controlplane nodes are upgraded one after another before any worker
+		if isControlplane {
+			return false, next()
+		}
+
+		pending = true
+		if disruptive && !budget.take(machine) {
+			monitor.WithField("machine", machine.infra.ID()).Debug("Postponing upgrade as too many nodes of the pool are unavailable")
+			continue
+		}
+
+		wg.Add(1)
+		go func(next func() error) {
+			synchronizer.Done(next())
+		}(next)
 	}
-	return true, nil
+	wg.Wait()
+
+	if synchronizer.IsError() {
+		return false, synchronizer
+	}
+	return !pending, nil
 }
 
 func plan(
@@ -195,7 +242,7 @@ func plan(
 			return nil
 		}
 		machine.node.Labels["orbos.ch/updating"] = to.Kubelet.Version
-		return k8sClient.Drain(machine.currentMachine, machine.node, updating)
+		return k8sClient.Drain(machine.currentMachine, machine.node, updating, machine.pool.desired.eviction())
 	}
 
 	ensureSoftware := func(packages common.Software, phase string) func() error {
@@ -212,6 +259,9 @@ func plan(
 			}
 			if !softwareContains(*machine.desiredNodeagent.Software, packages) {
 				swmonitor.Changed("Kubernetes software desired")
+				if machine.node != nil {
+					k8sClient.RecordEvent(NodeReference(machine.node), ComponentOrbiter, v1.EventTypeNormal, ReasonUpgrading, "Kubernetes software of the node is upgraded by the orbiter")
+				}
 			} else {
 				swmonitor.Info("Awaiting kubernetes software")
 			}
@@ -243,6 +293,8 @@ func plan(
 		}
 
 		machine.node.Labels["orbos.ch/kubeadm-upgraded"] = to.Kubelet.Version
+		// Controlplane nodes are not drained, the label marks them as upgrading until the kubelet reports the new version
+		machine.node.Labels["orbos.ch/updating"] = to.Kubelet.Version
 		return k8sClient.updateNode(machine.node)
 	}
 
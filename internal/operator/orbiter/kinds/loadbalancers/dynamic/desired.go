@@ -164,3 +164,12 @@ func (p Port) validate() error {
 	}
 	return nil
 }
+
+// ValidateDesired checks the desired state without adapting it
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind := &Desired{Common: desiredTree.Common}
+	if err := desiredTree.Original.Decode(desiredKind); err != nil {
+		return errors.Wrap(err, "parsing desired state failed")
+	}
+	return desiredKind.Validate()
+}
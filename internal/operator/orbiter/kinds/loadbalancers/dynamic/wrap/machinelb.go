@@ -23,3 +23,11 @@ func (c *cmpLB) Remove() error {
 	}
 	return c.desire()
 }
+
+// Zone keeps the wrapped machines zone visible
+func (c *cmpLB) Zone() string {
+	if zoned, ok := c.Machine.(infra.ZonedMachine); ok {
+		return zoned.Zone()
+	}
+	return ""
+}
@@ -31,7 +31,7 @@ func GetQueryAndDestroyFunc(
 		adaptFunc := func() (orbiter.QueryFunc, orbiter.DestroyFunc, orbiter.ConfigureFunc, bool, map[string]*secret.Secret, error) {
 			return dynamic.AdaptFunc(whitelist)(monitor, finishedChan, loadBalancingTree, loadBalacingCurrent)
 		}
-		return orbiter.AdaptFuncGoroutine(adaptFunc)
+		return orbiter.AdaptFuncGoroutine(monitor, adaptFunc)
 	default:
 		return nil, nil, nil, false, nil, errors.Errorf("unknown loadbalancing kind %s", loadBalancingTree.Common.Kind)
 	}
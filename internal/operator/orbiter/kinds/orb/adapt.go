@@ -81,6 +81,8 @@ func AdaptFunc(
 				orbiterCommit,
 				orbConfig.URL,
 				orbConfig.Repokey,
+				orbConfig.KnownHosts,
+				orbConfig.NodeAgentIngestion(),
 				oneoff,
 			)
 			if err != nil {
@@ -116,6 +118,15 @@ func AdaptFunc(
 			return provCurr, nil
 		}
 
+		// The clusters continue with the current states of the last iteration.
+		// Current states that can't be parsed anymore are discarded
+		previous := &Current{}
+		if currentTree.Original != nil {
+			if err := currentTree.Original.Decode(previous); err != nil {
+				monitor.WithField("reason", err.Error()).Debug("Discarding unparsable current state")
+			}
+		}
+
 		clusterCurrents := make(map[string]*tree.Tree)
 		clusterQueriers := make([]orbiter.QueryFunc, 0)
 		clusterDestroyers := make([]orbiter.DestroyFunc, 0)
@@ -123,6 +134,9 @@ func AdaptFunc(
 		for clusterID, clusterTree := range desiredKind.Clusters {
 
 			clusterCurrent := &tree.Tree{}
+			if previousCurrent, ok := previous.Clusters[clusterID]; ok && previousCurrent != nil {
+				clusterCurrent.Original = previousCurrent.Original
+			}
 			clusterCurrents[clusterID] = clusterCurrent
 			query, destroy, configure, migrateLocal, clusterSecrets, err := clusters.GetQueryAndDestroyFuncs(
 				monitor,
@@ -157,15 +171,16 @@ func AdaptFunc(
 			Providers: providerCurrents,
 		}
 
-		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, _ map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
+		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
 
+				plan := orbiter.PlanOf(queried)
 				providerEnsurers := make([]orbiter.EnsureFunc, 0)
 				queriedProviders := make(map[string]interface{})
 				for _, querier := range providerQueriers {
 					queryFunc := func() (orbiter.EnsureFunc, error) {
-						return querier(nodeAgentsCurrent, nodeAgentsDesired, nil)
+						return querier(nodeAgentsCurrent, nodeAgentsDesired, queried)
 					}
-					ensurer, err := orbiter.QueryFuncGoroutine(queryFunc)
+					ensurer, err := orbiter.QueryFuncGoroutine(monitor, queryFunc)
 
 					if err != nil {
 						return nil, err
@@ -176,13 +191,16 @@ func AdaptFunc(
 				for currKey, currVal := range providerCurrents {
 					queriedProviders[currKey] = currVal.Parsed
 				}
+				if plan != nil {
+					queriedProviders = orbiter.WithPlan(queriedProviders, plan)
+				}
 
 				clusterEnsurers := make([]orbiter.EnsureFunc, 0)
 				for _, querier := range clusterQueriers {
 					queryFunc := func() (orbiter.EnsureFunc, error) {
 						return querier(nodeAgentsCurrent, nodeAgentsDesired, queriedProviders)
 					}
-					ensurer, err := orbiter.QueryFuncGoroutine(queryFunc)
+					ensurer, err := orbiter.QueryFuncGoroutine(monitor, queryFunc)
 
 					if err != nil {
 						return nil, err
@@ -201,7 +219,7 @@ func AdaptFunc(
 							return ensurer(psf)
 						}
 
-						result := orbiter.EnsureFuncGoroutine(ensureFunc)
+						result := orbiter.EnsureFuncGoroutine(monitor, ensureFunc)
 						if result.Err != nil {
 							return result
 						}
@@ -1,6 +1,7 @@
 package orb
 
 import (
+	"github.com/caos/orbos/internal/operator/common"
 	"github.com/caos/orbos/internal/tree"
 	"github.com/pkg/errors"
 )
@@ -8,7 +9,8 @@ import (
 type DesiredV0 struct {
 	Common *tree.Common `yaml:",inline"`
 	Spec   struct {
-		Verbose bool
+		Verbose      bool
+		Verification *common.Verification `yaml:",omitempty"`
 	}
 	Clusters  map[string]*tree.Tree
 	Providers map[string]*tree.Tree
@@ -45,3 +47,12 @@ func (d *DesiredV0) validate() error {
 	}
 	return nil
 }
+
+// ValidateDesired checks the desired state without adapting it
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := ParseDesiredV0(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.validate()
+}
@@ -0,0 +1,98 @@
+package container
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+)
+
+func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoURL, repoKey, knownHosts, ingestion string) orbiter.AdaptFunc {
+	return func(monitor mntr.Monitor, finishedChan chan struct{}, desiredTree *tree.Tree, currentTree *tree.Tree) (queryFunc orbiter.QueryFunc, destroyFunc orbiter.DestroyFunc, configureFunc orbiter.ConfigureFunc, migrate bool, secrets map[string]*secret.Secret, err error) {
+		defer func() {
+			err = errors.Wrapf(err, "building %s failed", desiredTree.Common.Kind)
+		}()
+		desiredKind, err := parseDesired(desiredTree)
+		if err != nil {
+			return nil, nil, nil, migrate, nil, errors.Wrap(err, "parsing desired state failed")
+		}
+		desiredTree.Parsed = desiredKind
+		secrets = make(map[string]*secret.Secret, 0)
+
+		if desiredKind.Spec.RebootRequired == nil {
+			desiredKind.Spec.RebootRequired = make([]string, 0)
+			migrate = true
+		}
+
+		if desiredKind.Spec.ReplacementRequired == nil {
+			desiredKind.Spec.ReplacementRequired = make([]string, 0)
+			migrate = true
+		}
+
+		if desiredKind.Spec.Verbose && !monitor.IsVerbose() {
+			monitor = monitor.Verbose()
+		}
+
+		if err := desiredKind.validateAdapt(); err != nil {
+			return nil, nil, nil, migrate, nil, err
+		}
+
+		lbCurrent := &tree.Tree{}
+		var lbQuery orbiter.QueryFunc
+
+		lbQuery, lbDestroy, lbConfigure, migrateLocal, lbSecrets, err := loadbalancers.GetQueryAndDestroyFunc(monitor, whitelist, desiredKind.Loadbalancing, lbCurrent, finishedChan)
+		if err != nil {
+			return nil, nil, nil, migrate, nil, err
+		}
+		if migrateLocal {
+			migrate = true
+		}
+		secret.AppendSecrets("", secrets, lbSecrets)
+
+		ctx := buildContext(monitor, &desiredKind.Spec, orbID, providerID)
+
+		current := &Current{
+			Common: &tree.Common{
+				Kind:    "orbiter.caos.ch/ContainerProvider",
+				Version: "v0",
+			},
+		}
+		currentTree.Parsed = current
+
+		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
+				defer func() {
+					err = errors.Wrapf(err, "querying %s failed", desiredKind.Common.Kind)
+				}()
+
+				if _, err := lbQuery(nodeAgentsCurrent, nodeAgentsDesired, nil); err != nil {
+					return nil, err
+				}
+
+				ctx.plan = orbiter.PlanOf(queried)
+
+				_, naFuncs := core.NodeAgentFuncs(monitor, repoURL, repoKey, knownHosts, ingestion)
+
+				return query(&desiredKind.Spec, current, lbCurrent.Parsed, ctx, nodeAgentsCurrent, nodeAgentsDesired, naFuncs, orbiterCommit)
+			}, func() error {
+				if err := lbDestroy(); err != nil {
+					return err
+				}
+
+				return destroy(ctx)
+			}, func(orb orb.Orb) error {
+
+				if err := lbConfigure(orb); err != nil {
+					return err
+				}
+
+				return core.ConfigureNodeAgents(ctx.machinesService, ctx.monitor, orb)
+			}, migrate, secrets, nil
+	}
+}
@@ -0,0 +1,48 @@
+package container
+
+import (
+	"fmt"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/mntr"
+)
+
+type context struct {
+	monitor         mntr.Monitor
+	orbID           string
+	providerID      string
+	desired         *Spec
+	runtime         *runtime
+	machinesService *machinesService
+	// plan records the planned changes if the query is planned
+	plan *orbiter.Plan
+}
+
+func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string) *context {
+
+	newContext := &context{
+		monitor:    monitor,
+		orbID:      orbID,
+		providerID: providerID,
+		desired:    desired,
+		runtime: &runtime{
+			monitor: monitor,
+			binary:  desired.runtime(),
+		},
+	}
+
+	newContext.machinesService = newMachinesService(newContext)
+	return newContext
+}
+
+// labels returns the labels all containers of this provider are labeled with
+func (c *context) labels() map[string]string {
+	return map[string]string{
+		"orbos.orb":      c.orbID,
+		"orbos.provider": c.providerID,
+	}
+}
+
+func (c *context) networkName() string {
+	return fmt.Sprintf("orbos-%s-%s", c.orbID, c.providerID)
+}
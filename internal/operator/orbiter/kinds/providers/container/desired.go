@@ -0,0 +1,128 @@
+package container
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/tree"
+)
+
+type Desired struct {
+	Common        *tree.Common `yaml:",inline"`
+	Spec          Spec
+	Loadbalancing *tree.Tree
+}
+
+type Pool struct {
+	// A systemd enabled image, for example built from build/machine/Dockerfile
+	Image string
+	// Limits the CPUs of each container, for example 1.5
+	CPUs string `yaml:",omitempty"`
+	// Limits the memory of each container, for example 4g
+	Memory string `yaml:",omitempty"`
+}
+
+func (p Pool) validate() error {
+	if p.Image == "" {
+		return errors.New("no image configured")
+	}
+	return nil
+}
+
+type Spec struct {
+	Verbose bool
+	// The container runtime binary, either docker or podman
+	//@default: docker
+	Runtime string `yaml:",omitempty"`
+	// The containers network. Containers get IPs from the upper half,
+	// so the lower half is free for the virtual IPs of the load balancer
+	//@default: 10.99.0.0/24
+	Subnet              string `yaml:",omitempty"`
+	Pools               map[string]*Pool
+	RebootRequired      []string
+	ReplacementRequired []string
+}
+
+const (
+	defaultRuntime = "docker"
+	defaultSubnet  = "10.99.0.0/24"
+)
+
+func (s *Spec) runtime() string {
+	if s.Runtime == "" {
+		return defaultRuntime
+	}
+	return s.Runtime
+}
+
+func (s *Spec) subnet() string {
+	if s.Subnet == "" {
+		return defaultSubnet
+	}
+	return s.Subnet
+}
+
+func (d Desired) validateAdapt() error {
+	if d.Loadbalancing == nil {
+		return errors.New("no loadbalancing configured")
+	}
+	if runtime := d.Spec.runtime(); runtime != "docker" && runtime != "podman" {
+		return fmt.Errorf("runtime must be docker or podman, but is %s", runtime)
+	}
+	if _, _, err := ipRange(d.Spec.subnet()); err != nil {
+		return err
+	}
+	if len(d.Spec.Pools) == 0 {
+		return errors.New("no pools configured")
+	}
+	for poolName, pool := range d.Spec.Pools {
+		if err := pool.validate(); err != nil {
+			return fmt.Errorf("configuring pool %s failed: %w", poolName, err)
+		}
+	}
+	return nil
+}
+
+// ipRange returns the parsed subnet and its upper half, which containers get their IPs from
+func ipRange(subnet string) (*net.IPNet, string, error) {
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "parsing subnet %s failed", subnet)
+	}
+	ones, bits := network.Mask.Size()
+	if bits != 32 || ones > 28 {
+		return nil, "", errors.Errorf("subnet %s must be an IPv4 network with at least 16 addresses", subnet)
+	}
+
+	upper := make(net.IP, len(network.IP.To4()))
+	copy(upper, network.IP.To4())
+	half := uint32(1) << uint(bits-ones-1)
+	for i := 3; i >= 0; i-- {
+		upper[i] |= byte(half >> uint(8*(3-i)))
+	}
+	return network, fmt.Sprintf("%s/%d", upper.String(), ones+1), nil
+}
+
+func parseDesired(desiredTree *tree.Tree) (*Desired, error) {
+	desiredKind := &Desired{
+		Common: desiredTree.Common,
+		Spec:   Spec{},
+	}
+
+	if err := desiredTree.Original.Decode(desiredKind); err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+
+	return desiredKind, nil
+}
+
+// ValidateDesired checks the desired state without adapting it
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := parseDesired(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.validateAdapt()
+}
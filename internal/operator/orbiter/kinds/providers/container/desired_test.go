@@ -0,0 +1,47 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+)
+
+func Test_ipRange(t *testing.T) {
+	tests := []struct {
+		subnet  string
+		want    string
+		wantErr bool
+	}{
+		{subnet: "10.99.0.0/24", want: "10.99.0.128/25"},
+		{subnet: "172.30.0.0/16", want: "172.30.128.0/17"},
+		{subnet: "192.168.10.0/28", want: "192.168.10.8/29"},
+		{subnet: "192.168.10.0/29", wantErr: true},
+		{subnet: "fd00::/64", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subnet, func(t *testing.T) {
+			_, got, err := ipRange(tt.subnet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ipRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ipRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateVIP(t *testing.T) {
+	spec := &Spec{}
+	for ip, valid := range map[string]bool{
+		"10.99.0.10":  true,
+		"10.99.0.127": true,
+		"10.99.0.128": false,
+		"10.98.0.10":  false,
+		"":            false,
+	} {
+		if err := validateVIP(spec, &dynamic.VIP{IP: ip}); (err == nil) != valid {
+			t.Errorf("validateVIP(%s) error = %v, want valid %v", ip, err, valid)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package container
+
+import (
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+)
+
+func destroy(context *context) error {
+
+	if err := core.Each(context.machinesService, func(pool string, machine infra.Machine) error {
+		return machine.Remove()
+	}); err != nil {
+		return err
+	}
+
+	return removeNetwork(context)
+}
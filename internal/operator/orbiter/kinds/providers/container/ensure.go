@@ -0,0 +1,145 @@
+package container
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	dynamiclbmodel "github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic/wrap"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+)
+
+func query(
+	desired *Spec,
+	current *Current,
+	lb interface{},
+	context *context,
+	nodeAgentsCurrent *common.CurrentNodeAgents,
+	nodeAgentsDesired *common.DesiredNodeAgents,
+	naFuncs core.IterateNodeAgentFuncs,
+	orbiterCommit string,
+) (ensureFunc orbiter.EnsureFunc, err error) {
+
+	lbCurrent, ok := lb.(*dynamiclbmodel.Current)
+	if !ok {
+		return nil, errors.Errorf("Unknown or unsupported load balancing of type %T", lb)
+	}
+
+	// Querying must not change anything, so the network is created when ensuring
+	if context.plan != nil {
+		planNetwork(context)
+	}
+
+	hostPools, _, err := lbCurrent.Current.Spec(context.machinesService)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keepalived announces the virtual IPs in the containers network
+	current.Current.Ingresses = make(map[string]*infra.Address)
+	for _, vips := range hostPools {
+		for _, vip := range vips {
+			if err := validateVIP(desired, vip); err != nil {
+				return nil, err
+			}
+			for _, transport := range vip.Transport {
+				current.Current.Ingresses[transport.Name] = &infra.Address{
+					Location:     vip.IP,
+					FrontendPort: uint16(transport.FrontendPort),
+					BackendPort:  uint16(transport.BackendPort),
+				}
+			}
+		}
+	}
+
+	queryNA, installNA := naFuncs(nodeAgentsCurrent)
+	ensureNodeAgent := func(m infra.Machine) error {
+		running, err := queryNA(m, orbiterCommit)
+		if err != nil {
+			return err
+		}
+		if !running {
+			return installNA(m)
+		}
+		return nil
+	}
+
+	context.machinesService.onCreate = func(pool string, m infra.Machine) error {
+		_, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"})
+		if err != nil {
+			return err
+		}
+		return ensureNodeAgent(m)
+	}
+
+	wrappedMachines := wrap.MachinesService(context.machinesService, *lbCurrent, &dynamiclbmodel.VRRP{
+		VRRPInterface: "eth0",
+		NotifyMaster:  nil,
+		AuthCheck:     nil,
+	}, func(vip *dynamiclbmodel.VIP) string {
+		return vip.IP
+	})
+
+	if context.plan != nil {
+		// Planning doesn't ensure, so the node agent desires ensuring sets are computed now
+		if _, err := wrappedMachines.InitializeDesiredNodeAgents(); err != nil {
+			return nil, err
+		}
+		if _, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"}); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(pdf api.PushDesiredFunc) *orbiter.EnsureResult {
+		// Providers are ensured before the clusters create machines in the network
+		if err := ensureNetwork(context); err != nil {
+			return orbiter.ToEnsureResult(false, err)
+		}
+
+		var done bool
+		return orbiter.ToEnsureResult(done, helpers.Fanout([]func() error{
+			func() error {
+				return core.Each(context.machinesService, func(pool string, m infra.Machine) error {
+					return ensureNodeAgent(m)
+				})
+			},
+			func() error {
+				lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
+				if err != nil {
+					return err
+				}
+
+				fwDone, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"})
+				if err != nil {
+					return err
+				}
+				done = lbDone && fwDone
+				return nil
+			},
+		})())
+	}, addPools(current, desired, wrappedMachines)
+}
+
+// validateVIP ensures the virtual IP is in the lower half of the subnet, so no container gets it assigned
+func validateVIP(desired *Spec, vip *dynamiclbmodel.VIP) error {
+	network, ipRange, err := ipRange(desired.subnet())
+	if err != nil {
+		return err
+	}
+	_, containers, err := net.ParseCIDR(ipRange)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(vip.IP)
+	if ip == nil || !network.Contains(ip) || containers.Contains(ip) {
+		return errors.Errorf("the virtual ip %s must be in the lower half of the subnet %s", vip.IP, desired.subnet())
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ssh"
+	"github.com/caos/orbos/mntr"
+)
+
+var _ infra.Machine = (*machine)(nil)
+
+type action struct {
+	required  bool
+	require   func()
+	unrequire func()
+}
+
+// machine executes commands in its container using the container runtime,
+// so the image doesn't need an ssh server
+type machine struct {
+	monitor     mntr.Monitor
+	remove      func() error
+	context     *context
+	reboot      *action
+	replacement *action
+	poolName    string
+	X_ID        string `header:"id"`
+	X_IP        string `header:"ip"`
+}
+
+func newMachine(monitor mntr.Monitor, name, ip string, remove func() error, context *context, poolName string) *machine {
+	return &machine{
+		monitor:  monitor,
+		X_ID:     name,
+		X_IP:     ip,
+		remove:   remove,
+		context:  context,
+		poolName: poolName,
+	}
+}
+
+func (m *machine) ID() string    { return m.X_ID }
+func (m *machine) IP() string    { return m.X_IP }
+func (m *machine) Remove() error { return m.remove() }
+
+func (m *machine) Execute(stdin io.Reader, cmd string) (stdout []byte, err error) {
+
+	monitor, span := m.monitor.WithFields(map[string]interface{}{
+		"command": cmd,
+	}).StartSpan("container.execute")
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("executing %s failed: %w", cmd, err)
+		} else {
+			monitor.WithField("stdout", string(stdout)).Debug("Done executing command in container")
+		}
+		span.End(err)
+	}()
+
+	args := []string{"exec"}
+	if stdin != nil {
+		args = append(args, "--interactive")
+	}
+	return m.context.runtime.run(stdin, append(args, m.X_ID, "sh", "-c", cmd)...)
+}
+
+func (m *machine) Shell() error {
+	cmd := m.context.runtime.command(os.Stdin, "exec", "--interactive", "--tty", m.X_ID, "bash", "--login")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("executing shell failed: %w", err)
+	}
+	return nil
+}
+
+func (m *machine) WriteFile(path string, data io.Reader, permissions uint16) error {
+
+	ensurePath, writeFile := ssh.WriteFileCommands("root", path, permissions)
+
+	if _, err := m.Execute(nil, ensurePath); err != nil {
+		return fmt.Errorf("writing file %s failed: %w", path, err)
+	}
+
+	if _, err := m.Execute(data, writeFile); err != nil {
+		return fmt.Errorf("writing file %s failed: %w", path, err)
+	}
+	return nil
+}
+
+func (m *machine) ReadFile(path string, data io.Writer) error {
+	stderr := new(bytes.Buffer)
+	cmd := m.context.runtime.command(nil, "exec", m.X_ID, "cat", path)
+	cmd.Stdout = data
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("reading file %s failed with stderr %s: %w", path, stderr.String(), err)
+	}
+	return nil
+}
+
+// RebootRequired is handled by the node agent, whose reboot stops the containers init process.
+// The containers restart policy starts it again
+func (m *machine) RebootRequired() (required bool, require func(), unrequire func()) {
+
+	m.reboot = m.initAction(
+		m.reboot,
+		func() []string { return m.context.desired.RebootRequired },
+		func(machines []string) { m.context.desired.RebootRequired = machines })
+
+	return m.reboot.required, m.reboot.require, m.reboot.unrequire
+}
+
+// ReplacementRequired results in a new container in the same pool, after which this container is removed
+func (m *machine) ReplacementRequired() (required bool, require func(), unrequire func()) {
+
+	m.replacement = m.initAction(
+		m.replacement,
+		func() []string { return m.context.desired.ReplacementRequired },
+		func(machines []string) { m.context.desired.ReplacementRequired = machines })
+
+	return m.replacement.required, m.replacement.require, m.replacement.unrequire
+}
+
+func (m *machine) initAction(a *action, getSlice func() []string, setSlice func([]string)) *action {
+	if a != nil {
+		return a
+	}
+
+	newAction := &action{
+		required:  false,
+		unrequire: func() {},
+		require: func() {
+			s := getSlice()
+			s = append(s, m.ID())
+			setSlice(s)
+		},
+	}
+
+	s := getSlice()
+	for sIdx := range s {
+		req := s[sIdx]
+		if req == m.ID() {
+			newAction.required = true
+			break
+		}
+	}
+
+	if newAction.required {
+		newAction.unrequire = func() {
+			s := getSlice()
+			for sIdx := range s {
+				req := s[sIdx]
+				if req == m.ID() {
+					s = append(s[0:sIdx], s[sIdx+1:]...)
+				}
+			}
+			setSlice(s)
+		}
+	}
+
+	return newAction
+}
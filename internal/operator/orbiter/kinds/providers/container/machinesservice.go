@@ -0,0 +1,250 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+)
+
+func ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerID string) (map[string]infra.Machine, error) {
+	desired, err := parseDesired(desiredTree)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+	desiredTree.Parsed = desired
+
+	return core.ListMachines(buildContext(monitor, &desired.Spec, orbID, providerID).machinesService)
+}
+
+var _ core.MachinesService = (*machinesService)(nil)
+
+type machinesService struct {
+	context *context
+	cache   struct {
+		instances map[string][]*machine
+		sync.Mutex
+	}
+	onCreate func(pool string, machine infra.Machine) error
+}
+
+func newMachinesService(context *context) *machinesService {
+	return &machinesService{
+		context: context,
+	}
+}
+
+func (m *machinesService) Create(poolName string) (infra.Machine, error) {
+
+	desired, ok := m.context.desired.Pools[poolName]
+	if !ok {
+		return nil, fmt.Errorf("Pool %s is not configured", poolName)
+	}
+
+	if err := ensureNetwork(m.context); err != nil {
+		return nil, err
+	}
+
+	name := newName()
+	monitor := machineMonitor(m.context.monitor, name, poolName)
+
+	monitor.Debug("Creating container")
+
+	// The flags allow systemd, the container runtime and the kubelet to run in the container
+	args := []string{
+		"run", "--detach",
+		"--name", name,
+		"--hostname", name,
+		"--network", m.context.networkName(),
+		"--privileged",
+		"--security-opt", "seccomp=unconfined",
+		"--restart", "unless-stopped",
+		"--tmpfs", "/run",
+		"--tmpfs", "/tmp",
+		"--volume", "/var",
+		"--volume", "/lib/modules:/lib/modules:ro",
+		"--label", "orbos.pool=" + poolName,
+	}
+	for key, value := range m.context.labels() {
+		args = append(args, "--label", key+"="+value)
+	}
+	if desired.CPUs != "" {
+		args = append(args, "--cpus", desired.CPUs)
+	}
+	if desired.Memory != "" {
+		args = append(args, "--memory", desired.Memory)
+	}
+
+	if _, err := m.context.runtime.run(nil, append(args, desired.Image)...); err != nil {
+		return nil, err
+	}
+
+	infos, err := m.context.runtime.inspect(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) != 1 {
+		return nil, errors.Errorf("container %s not found", name)
+	}
+
+	infraMachine, err := m.toMachine(infos[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := awaitSystemd(infraMachine); err != nil {
+		return nil, err
+	}
+
+	monitor.Info("Container created")
+
+	if m.cache.instances != nil {
+		m.cache.Lock()
+		m.cache.instances[poolName] = append(m.cache.instances[poolName], infraMachine)
+		m.cache.Unlock()
+	}
+
+	if m.onCreate != nil {
+		if err := m.onCreate(poolName, infraMachine); err != nil {
+			return nil, err
+		}
+	}
+
+	monitor.Changed("Machine created")
+	return infraMachine, nil
+}
+
+// awaitSystemd waits until systemd finished booting, so services can be installed
+func awaitSystemd(m *machine) error {
+	var state string
+	err := helpers.Retry(time.NewTimer(2*time.Minute), 2*time.Second, func() bool {
+		out, _ := m.Execute(nil, "systemctl is-system-running || true")
+		state = strings.TrimSpace(string(out))
+		return state != "running" && state != "degraded"
+	})
+	return errors.Wrapf(err, "systemd in container %s didn't finish booting, its state is %s", m.ID(), state)
+}
+
+func (m *machinesService) toMachine(info *containerInfo) (*machine, error) {
+
+	network, ok := info.NetworkSettings.Networks[m.context.networkName()]
+	if !ok && info.State.Running {
+		return nil, errors.Errorf("container %s is not attached to network %s", info.Name, m.context.networkName())
+	}
+
+	poolName := info.Config.Labels["orbos.pool"]
+	return newMachine(
+		machineMonitor(m.context.monitor, info.Name, poolName),
+		info.Name,
+		network.IPAddress,
+		m.removeMachineFunc(poolName, info.Name),
+		m.context,
+		poolName,
+	), nil
+}
+
+func (m *machinesService) ListPools() ([]string, error) {
+
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	var poolNames []string
+	for poolName := range pools {
+		poolNames = append(poolNames, poolName)
+	}
+	sort.Strings(poolNames)
+	return poolNames, nil
+}
+
+func (m *machinesService) List(poolName string) (infra.Machines, error) {
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := pools[poolName]
+	machines := make([]infra.Machine, len(pool))
+	for idx := range pool {
+		machines[idx] = pool[idx]
+	}
+
+	return machines, nil
+}
+
+func (m *machinesService) machines() (map[string][]*machine, error) {
+	m.cache.Lock()
+	defer m.cache.Unlock()
+
+	if m.cache.instances != nil {
+		return m.cache.instances, nil
+	}
+
+	ids, err := m.context.runtime.list(m.context.labels())
+	if err != nil {
+		return nil, errors.Wrap(err, "listing containers failed")
+	}
+
+	infos, err := m.context.runtime.inspect(ids...)
+	if err != nil {
+		return nil, errors.Wrap(err, "inspecting containers failed")
+	}
+
+	instances := make(map[string][]*machine)
+	for _, info := range infos {
+		machine, err := m.toMachine(info)
+		if err != nil {
+			return nil, err
+		}
+		instances[machine.poolName] = append(instances[machine.poolName], machine)
+	}
+
+	for _, machines := range instances {
+		sort.Slice(machines, func(i, j int) bool { return machines[i].ID() < machines[j].ID() })
+	}
+
+	m.cache.instances = instances
+	return m.cache.instances, nil
+}
+
+func (m *machinesService) removeMachineFunc(pool, name string) func() error {
+
+	return func() error {
+		m.cache.Lock()
+		if m.cache.instances != nil {
+			cleanMachines := make([]*machine, 0)
+			for idx := range m.cache.instances[pool] {
+				cachedMachine := m.cache.instances[pool][idx]
+				if cachedMachine.ID() != name {
+					cleanMachines = append(cleanMachines, cachedMachine)
+				}
+			}
+			m.cache.instances[pool] = cleanMachines
+		}
+		m.cache.Unlock()
+
+		_, err := m.context.runtime.run(nil, "rm", "--force", "--volumes", name)
+		return errors.Wrapf(err, "removing container %s failed", name)
+	}
+}
+
+func machineMonitor(monitor mntr.Monitor, name string, poolName string) mntr.Monitor {
+	return monitor.WithFields(map[string]interface{}{
+		"machine": name,
+		"pool":    poolName,
+	})
+}
+
+func newName() string {
+	return "orbos-" + helpers.RandomStringRunes(6, []rune("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
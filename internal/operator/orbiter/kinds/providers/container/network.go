@@ -0,0 +1,55 @@
+package container
+
+import (
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+)
+
+// ensureNetwork creates the containers network if it doesn't exist yet
+func ensureNetwork(context *context) error {
+
+	name := context.networkName()
+	if _, err := context.runtime.run(nil, "network", "inspect", name); err == nil {
+		return nil
+	}
+
+	_, ipRange, err := ipRange(context.desired.subnet())
+	if err != nil {
+		return err
+	}
+
+	monitor := context.monitor.WithField("network", name)
+	monitor.Info("Creating network")
+
+	args := []string{"network", "create", "--subnet", context.desired.subnet(), "--ip-range", ipRange}
+	for key, value := range context.labels() {
+		args = append(args, "--label", key+"="+value)
+	}
+	if _, err := context.runtime.run(nil, append(args, name)...); err != nil {
+		return err
+	}
+	monitor.Changed("Network created")
+	return nil
+}
+
+// planNetwork records the network ensureNetwork would create
+func planNetwork(context *context) {
+	if _, err := context.runtime.run(nil, "network", "inspect", context.networkName()); err != nil {
+		context.plan.AddLoadBalancer(orbiter.PlannedLoadBalancer{Provider: context.providerID, Name: "network", Action: "create", Count: 1})
+	}
+}
+
+func removeNetwork(context *context) error {
+
+	name := context.networkName()
+	if _, err := context.runtime.run(nil, "network", "inspect", name); err != nil {
+		return nil
+	}
+
+	if _, err := context.runtime.run(nil, "network", "rm", name); err != nil && !strings.Contains(err.Error(), "No such network") {
+		return err
+	}
+	context.monitor.WithField("network", name).Changed("Network removed")
+	return nil
+}
@@ -0,0 +1,88 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/caos/orbos/mntr"
+)
+
+// runtime executes the docker or podman command line interface, which both support the used subcommands and flags
+type runtime struct {
+	monitor mntr.Monitor
+	binary  string
+}
+
+func (r *runtime) command(stdin io.Reader, args ...string) *exec.Cmd {
+	cmd := exec.Command(r.binary, args...)
+	cmd.Stdin = stdin
+	return cmd
+}
+
+func (r *runtime) run(stdin io.Reader, args ...string) ([]byte, error) {
+	monitor := r.monitor.WithField("args", strings.Join(args, " "))
+	monitor.Debug("Executing container runtime")
+
+	stderr := new(bytes.Buffer)
+	cmd := r.command(stdin, args...)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("executing %s %s failed with stderr %s: %w", r.binary, args[0], stderr.String(), err)
+	}
+	return out, nil
+}
+
+// containerInfo holds the fields of the inspect output that docker and podman have in common
+type containerInfo struct {
+	ID     string `json:"Id"`
+	Name   string
+	Config struct {
+		Labels map[string]string
+	}
+	State struct {
+		Running bool
+	}
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string
+		}
+	}
+}
+
+func (r *runtime) inspect(ids ...string) ([]*containerInfo, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	out, err := r.run(nil, append([]string{"inspect", "--type", "container"}, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*containerInfo
+	if err := json.Unmarshal(out, &infos); err != nil {
+		return nil, fmt.Errorf("parsing inspect output failed: %w", err)
+	}
+	for _, info := range infos {
+		info.Name = strings.TrimPrefix(info.Name, "/")
+	}
+	return infos, nil
+}
+
+// list returns the IDs of all containers with the passed labels
+func (r *runtime) list(labels map[string]string) ([]string, error) {
+	args := []string{"ps", "--all", "--quiet", "--no-trunc"}
+	for key, value := range labels {
+		args = append(args, "--filter", fmt.Sprintf("label=%s=%s", key, value))
+	}
+	out, err := r.run(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
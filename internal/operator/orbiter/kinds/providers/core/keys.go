@@ -0,0 +1,68 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+)
+
+// AuthorizeKey adds the public key to the authorized keys file on the machine.
+// All keys which are already authorized stay authorized
+func AuthorizeKey(machine infra.Machine, path, publicKey string) error {
+	return editAuthorizedKeys(machine, path, func(keys []string) []string {
+		for _, key := range keys {
+			if key == strings.TrimSpace(publicKey) {
+				return keys
+			}
+		}
+		return append(keys, strings.TrimSpace(publicKey))
+	})
+}
+
+// RevokeKey removes the public key from the authorized keys file on the machine
+func RevokeKey(machine infra.Machine, path, publicKey string) error {
+	return editAuthorizedKeys(machine, path, func(keys []string) []string {
+		remaining := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if key != strings.TrimSpace(publicKey) {
+				remaining = append(remaining, key)
+			}
+		}
+		return remaining
+	})
+}
+
+func editAuthorizedKeys(machine infra.Machine, path string, edit func(keys []string) []string) error {
+	buf := new(bytes.Buffer)
+	if err := machine.ReadFile(path, buf); err != nil {
+		return fmt.Errorf("reading authorized keys on machine %s failed: %w", machine.ID(), err)
+	}
+
+	var keys []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, line)
+		}
+	}
+
+	edited := edit(keys)
+	if len(edited) == len(keys) {
+		unchanged := true
+		for idx := range keys {
+			if keys[idx] != edited[idx] {
+				unchanged = false
+				break
+			}
+		}
+		if unchanged {
+			return nil
+		}
+	}
+
+	if err := machine.WriteFile(path, strings.NewReader(strings.Join(edited, "\n")+"\n"), 600); err != nil {
+		return fmt.Errorf("writing authorized keys on machine %s failed: %w", machine.ID(), err)
+	}
+	return nil
+}
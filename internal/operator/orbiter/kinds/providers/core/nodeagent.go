@@ -22,7 +22,7 @@ import (
 type IterateNodeAgentFuncs func(currentNodeAgents *common.CurrentNodeAgents) (queryNodeAgent func(machine infra.Machine, orbiterCommit string) (bool, error), install func(machine infra.Machine) error)
 
 func ConfigureNodeAgents(svc MachinesService, monitor mntr.Monitor, orb orb.Orb) error {
-	configure, _ := NodeAgentFuncs(monitor, orb.URL, orb.Repokey)
+	configure, _ := NodeAgentFuncs(monitor, orb.URL, orb.Repokey, orb.KnownHosts, orb.NodeAgentIngestion())
 	return Each(svc, func(pool string, machine infra.Machine) error {
 		err := configure(machine)
 		if err != nil {
@@ -39,7 +39,9 @@ func ConfigureNodeAgents(svc MachinesService, monitor mntr.Monitor, orb orb.Orb)
 func NodeAgentFuncs(
 	monitor mntr.Monitor,
 	repoURL string,
-	repoKey string) (reconfigure func(machines infra.Machine) error, iterate IterateNodeAgentFuncs) {
+	repoKey string,
+	knownHosts string,
+	ingestion string) (reconfigure func(machines infra.Machine) error, iterate IterateNodeAgentFuncs) {
 
 	configure := func(machine infra.Machine) func() error {
 		machineMonitor := monitor.WithField("machine", machine.ID())
@@ -56,6 +58,31 @@ func NodeAgentFuncs(
 				}).Debug("Written file")
 				return nil
 			},
+			func() error {
+				knownHostsPath := "/var/orbiter/repo-known-hosts"
+				if err := infra.Try(machineMonitor, time.NewTimer(8*time.Second), 2*time.Second, machine, func(cmp infra.Machine) error {
+					return errors.Wrapf(cmp.WriteFile(knownHostsPath, strings.NewReader(knownHosts), 400), "creating remote file %s failed", knownHostsPath)
+				}); err != nil {
+					return errors.Wrap(err, "writing known hosts failed")
+				}
+				machineMonitor.WithFields(map[string]interface{}{
+					"path": knownHostsPath,
+				}).Debug("Written file")
+				return nil
+			},
+			func() error {
+				// An empty file disables pushing events
+				ingestionPath := "/var/orbiter/ingestion"
+				if err := infra.Try(machineMonitor, time.NewTimer(8*time.Second), 2*time.Second, machine, func(cmp infra.Machine) error {
+					return errors.Wrapf(cmp.WriteFile(ingestionPath, strings.NewReader(ingestion), 400), "creating remote file %s failed", ingestionPath)
+				}); err != nil {
+					return errors.Wrap(err, "writing ingestion config failed")
+				}
+				machineMonitor.WithFields(map[string]interface{}{
+					"path": ingestionPath,
+				}).Debug("Written file")
+				return nil
+			},
 			func() error {
 				urlPath := "/var/orbiter/repo-url"
 				if err := infra.Try(machineMonitor, time.NewTimer(8*time.Second), 2*time.Second, machine, func(cmp infra.Machine) error {
@@ -14,7 +14,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoURL, repoKey string, oneoff bool) orbiter.AdaptFunc {
+func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoURL, repoKey, knownHosts, ingestion string, oneoff bool) orbiter.AdaptFunc {
 	return func(monitor mntr.Monitor, finishedChan chan struct{}, desiredTree *tree.Tree, currentTree *tree.Tree) (queryFunc orbiter.QueryFunc, destroyFunc orbiter.DestroyFunc, configureFunc orbiter.ConfigureFunc, migrate bool, secrets map[string]*secret.Secret, err error) {
 		defer func() {
 			err = errors.Wrapf(err, "building %s failed", desiredTree.Common.Kind)
@@ -57,6 +57,10 @@ func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbite
 			return nil, nil, nil, migrate, nil, err
 		}
 
+		// The machines stay reachable with the current key, even if it is cleared for being rotated
+		currentKey := desiredKind.Spec.SSHKey.copy()
+		var revokeKey string
+
 		current := &Current{
 			Common: &tree.Common{
 				Kind:    "orbiter.caos.ch/CloudScaleProvider",
@@ -65,7 +69,7 @@ func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbite
 		}
 		currentTree.Parsed = current
 
-		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, _ map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
+		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
 				defer func() {
 					err = errors.Wrapf(err, "querying %s failed", desiredKind.Common.Kind)
 				}()
@@ -74,6 +78,8 @@ func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbite
 					return nil, err
 				}
 
+				ctx.plan = orbiter.PlanOf(queried)
+
 				if err := ctx.machinesService.use(desiredKind.Spec.SSHKey); err != nil {
 					return nil, err
 				}
@@ -82,7 +88,7 @@ func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbite
 					return nil, err
 				}
 
-				_, naFuncs := core.NodeAgentFuncs(monitor, repoURL, repoKey)
+				_, naFuncs := core.NodeAgentFuncs(monitor, repoURL, repoKey, knownHosts, ingestion)
 
 				return query(&desiredKind.Spec, current, lbCurrent.Parsed, ctx, nodeAgentsCurrent, nodeAgentsDesired, naFuncs, orbiterCommit)
 			}, func() error {
@@ -118,7 +124,31 @@ func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbite
 					}
 				}
 
-				if err := ctx.machinesService.use(desiredKind.Spec.SSHKey); err != nil {
+				connectWith := desiredKind.Spec.SSHKey
+				switch {
+				case currentKey != nil && currentKey.Public.Value != desiredKind.Spec.SSHKey.Public.Value:
+					// Machines which are already in use have to authorize the new key when it is rotated.
+					// The rotated key stays authorized until the new key is pushed and the orb is configured again
+					connectWith = currentKey
+					if err := ctx.machinesService.use(currentKey); err != nil {
+						return err
+					}
+					if err := ctx.machinesService.authorizeKey(desiredKind.Spec.SSHKey.Public.Value); err != nil {
+						return err
+					}
+					revokeKey = currentKey.Public.Value
+					currentKey = desiredKind.Spec.SSHKey.copy()
+				case revokeKey != "":
+					if err := ctx.machinesService.use(desiredKind.Spec.SSHKey); err != nil {
+						return err
+					}
+					if err := ctx.machinesService.revokeKey(revokeKey); err != nil {
+						return err
+					}
+					revokeKey = ""
+				}
+
+				if err := ctx.machinesService.use(connectWith); err != nil {
 					panic(err)
 				}
 
@@ -8,6 +8,7 @@ import (
 
 	"github.com/cloudscale-ch/cloudscale-go-sdk"
 
+	"github.com/caos/orbos/internal/operator/orbiter"
 	"github.com/caos/orbos/mntr"
 )
 
@@ -20,6 +21,8 @@ type context struct {
 	client          *cloudscale.Client
 	machinesService *machinesService
 	ctx             ctxpkg.Context
+	// plan records the planned changes if the query is planned
+	plan *orbiter.Plan
 }
 
 func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string, oneoff bool) (*context, error) {
@@ -27,7 +30,8 @@ func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string,
 	ctx := ctxpkg.Background()
 
 	client := cloudscale.NewClient(&http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: monitor.RoundTripper(nil),
 	})
 
 	client.AuthToken = desired.APIToken.Value
@@ -48,3 +52,13 @@ func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string,
 
 	return newContext, nil
 }
+
+// planResources records the number of resources ensure creates or updates and removes
+func (c *context) planResources(resource string, ensure, remove []func() error) {
+	if len(ensure) > 0 {
+		c.plan.AddLoadBalancer(orbiter.PlannedLoadBalancer{Provider: c.providerID, Name: resource, Action: "ensure", Count: len(ensure)})
+	}
+	if len(remove) > 0 {
+		c.plan.AddLoadBalancer(orbiter.PlannedLoadBalancer{Provider: c.providerID, Name: resource, Action: "remove", Count: len(remove)})
+	}
+}
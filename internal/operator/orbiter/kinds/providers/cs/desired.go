@@ -38,6 +38,17 @@ type SSHKey struct {
 	Public  *secret.Secret `yaml:",omitempty"`
 }
 
+// copy returns a copy of a complete key pair or nil, so the key survives being cleared for rotation
+func (s *SSHKey) copy() *SSHKey {
+	if s == nil || s.Private == nil || s.Public == nil || s.Private.Value == "" || s.Public.Value == "" {
+		return nil
+	}
+	return &SSHKey{
+		Private: &secret.Secret{Value: s.Private.Value},
+		Public:  &secret.Secret{Value: s.Public.Value},
+	}
+}
+
 func (d Desired) validateAdapt() error {
 	if d.Loadbalancing == nil {
 		return errors.New("no loadbalancing configured")
@@ -89,3 +100,13 @@ func parseDesired(desiredTree *tree.Tree) (*Desired, error) {
 
 	return desiredKind, nil
 }
+
+// ValidateDesired checks the desired state without adapting it.
+// Secrets are not checked, as they are written using orbctl
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := parseDesired(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.validateAdapt()
+}
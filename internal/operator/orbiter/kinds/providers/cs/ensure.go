@@ -38,6 +38,7 @@ func query(
 	if err != nil {
 		return nil, err
 	}
+	context.planResources("floatingips", ensureFIPs, removeFIPs)
 
 	queryNA, installNA := naFuncs(nodeAgentsCurrent)
 	ensureNodeAgent := func(m infra.Machine) error {
@@ -70,6 +71,16 @@ func query(
 		NotifyMaster:  notifyMaster(hostPools, current, poolsWithUnassignedVIPs),
 		AuthCheck:     checkAuth,
 	}, desiredToCurrentVIP(current))
+	if context.plan != nil {
+		// Planning doesn't ensure, so the node agent desires ensuring sets are computed now
+		if _, err := wrappedMachines.InitializeDesiredNodeAgents(); err != nil {
+			return nil, err
+		}
+		if _, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"}); err != nil {
+			return nil, err
+		}
+	}
+
 	return func(pdf api.PushDesiredFunc) *orbiter.EnsureResult {
 		var done bool
 		return orbiter.ToEnsureResult(done, helpers.Fanout([]func() error{
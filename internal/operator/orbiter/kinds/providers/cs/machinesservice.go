@@ -50,6 +50,9 @@ func ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerI
 
 var _ core.MachinesService = (*machinesService)(nil)
 
+// authorizedKeysPath is where the orbiter users keys are authorized
+const authorizedKeysPath = "/home/orbiter/.ssh/authorized_keys"
+
 type machinesService struct {
 	context *context
 	oneoff  bool
@@ -72,10 +75,30 @@ func (m *machinesService) use(key *SSHKey) error {
 	if key == nil || key.Private == nil || key.Public == nil || key.Private.Value == "" || key.Public.Value == "" {
 		return errors.New("machines are not connectable. have you configured the orb by running orbctl configure?")
 	}
+	if m.key != nil && m.key.Private.Value != key.Private.Value {
+		// Cached machines connect with the previously used key
+		m.cache.Lock()
+		m.cache.instances = nil
+		m.cache.Unlock()
+	}
 	m.key = key
 	return nil
 }
 
+// authorizeKey additionally authorizes the public key on all machines
+func (m *machinesService) authorizeKey(publicKey string) error {
+	return core.Each(m, func(_ string, machine infra.Machine) error {
+		return core.AuthorizeKey(machine, authorizedKeysPath, publicKey)
+	})
+}
+
+// revokeKey removes the public key from all machines
+func (m *machinesService) revokeKey(publicKey string) error {
+	return core.Each(m, func(_ string, machine infra.Machine) error {
+		return core.RevokeKey(machine, authorizedKeysPath, publicKey)
+	})
+}
+
 func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 
 	desired, ok := m.context.desired.Pools[poolName]
@@ -160,7 +183,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 		return nil, err
 	}
 
-	monitor.Info("Machine created")
+	monitor.Changed("Machine created")
 	return infraMachine, nil
 }
 
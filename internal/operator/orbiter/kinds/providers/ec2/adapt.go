@@ -0,0 +1,158 @@
+package ec2
+
+import (
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/ssh"
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+	"github.com/pkg/errors"
+)
+
+func AdaptFunc(providerID, orbID string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoURL, repoKey, knownHosts, ingestion string, oneoff bool) orbiter.AdaptFunc {
+	return func(monitor mntr.Monitor, finishedChan chan struct{}, desiredTree *tree.Tree, currentTree *tree.Tree) (queryFunc orbiter.QueryFunc, destroyFunc orbiter.DestroyFunc, configureFunc orbiter.ConfigureFunc, migrate bool, secrets map[string]*secret.Secret, err error) {
+		defer func() {
+			err = errors.Wrapf(err, "building %s failed", desiredTree.Common.Kind)
+		}()
+		desiredKind, err := parseDesired(desiredTree)
+		if err != nil {
+			return nil, nil, nil, migrate, nil, errors.Wrap(err, "parsing desired state failed")
+		}
+		desiredTree.Parsed = desiredKind
+		secrets = make(map[string]*secret.Secret, 0)
+		secret.AppendSecrets("", secrets, getSecretsMap(desiredKind))
+
+		if desiredKind.Spec.RebootRequired == nil {
+			desiredKind.Spec.RebootRequired = make([]string, 0)
+			migrate = true
+		}
+
+		if desiredKind.Spec.Verbose && !monitor.IsVerbose() {
+			monitor = monitor.Verbose()
+		}
+
+		if err := desiredKind.validateAdapt(); err != nil {
+			return nil, nil, nil, migrate, nil, err
+		}
+
+		lbCurrent := &tree.Tree{}
+		var lbQuery orbiter.QueryFunc
+
+		lbQuery, lbDestroy, lbConfigure, migrateLocal, lbSecrets, err := loadbalancers.GetQueryAndDestroyFunc(monitor, whitelist, desiredKind.Loadbalancing, lbCurrent, finishedChan)
+		if err != nil {
+			return nil, nil, nil, migrate, nil, err
+		}
+		if migrateLocal {
+			migrate = true
+		}
+		secret.AppendSecrets("", secrets, lbSecrets)
+
+		ctx, err := buildContext(monitor, &desiredKind.Spec, orbID, providerID, oneoff)
+		if err != nil {
+			return nil, nil, nil, migrate, nil, err
+		}
+
+		// The machines stay reachable with the current key, even if it is cleared for being rotated
+		currentKey := desiredKind.Spec.SSHKey.copy()
+		var revokeKey string
+
+		current := &Current{
+			Common: &tree.Common{
+				Kind:    "orbiter.caos.ch/EC2Provider",
+				Version: "v0",
+			},
+		}
+		currentTree.Parsed = current
+
+		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
+				defer func() {
+					err = errors.Wrapf(err, "querying %s failed", desiredKind.Common.Kind)
+				}()
+
+				if err := desiredKind.validateQuery(); err != nil {
+					return nil, err
+				}
+
+				ctx.plan = orbiter.PlanOf(queried)
+
+				if err := ctx.machinesService.use(desiredKind.Spec.SSHKey); err != nil {
+					return nil, err
+				}
+
+				if _, err := lbQuery(nodeAgentsCurrent, nodeAgentsDesired, nil); err != nil {
+					return nil, err
+				}
+
+				_, naFuncs := core.NodeAgentFuncs(monitor, repoURL, repoKey, knownHosts, ingestion)
+
+				return query(&desiredKind.Spec, current, lbCurrent.Parsed, ctx, nodeAgentsCurrent, nodeAgentsDesired, naFuncs, orbiterCommit)
+			}, func() error {
+				if err := lbDestroy(); err != nil {
+					return err
+				}
+
+				if err := ctx.machinesService.use(desiredKind.Spec.SSHKey); err != nil {
+					return err
+				}
+
+				return destroy(ctx, current)
+			}, func(orb orb.Orb) error {
+
+				if err := desiredKind.validateCredentials(); err != nil {
+					return err
+				}
+
+				if err := lbConfigure(orb); err != nil {
+					return err
+				}
+
+				if desiredKind.Spec.SSHKey == nil ||
+					desiredKind.Spec.SSHKey.Private == nil || desiredKind.Spec.SSHKey.Private.Value == "" ||
+					desiredKind.Spec.SSHKey.Public == nil || desiredKind.Spec.SSHKey.Public.Value == "" {
+					priv, pub, err := ssh.Generate()
+					if err != nil {
+						return err
+					}
+					desiredKind.Spec.SSHKey = &SSHKey{
+						Private: &secret.Secret{Value: priv},
+						Public:  &secret.Secret{Value: pub},
+					}
+				}
+
+				connectWith := desiredKind.Spec.SSHKey
+				switch {
+				case currentKey != nil && currentKey.Public.Value != desiredKind.Spec.SSHKey.Public.Value:
+					// Machines which are already in use have to authorize the new key when it is rotated.
+					// The rotated key stays authorized until the new key is pushed and the orb is configured again
+					connectWith = currentKey
+					if err := ctx.machinesService.use(currentKey); err != nil {
+						return err
+					}
+					if err := ctx.machinesService.authorizeKey(desiredKind.Spec.SSHKey.Public.Value); err != nil {
+						return err
+					}
+					revokeKey = currentKey.Public.Value
+					currentKey = desiredKind.Spec.SSHKey.copy()
+				case revokeKey != "":
+					if err := ctx.machinesService.use(desiredKind.Spec.SSHKey); err != nil {
+						return err
+					}
+					if err := ctx.machinesService.revokeKey(revokeKey); err != nil {
+						return err
+					}
+					revokeKey = ""
+				}
+
+				if err := ctx.machinesService.use(connectWith); err != nil {
+					panic(err)
+				}
+
+				return core.ConfigureNodeAgents(ctx.machinesService, ctx.monitor, orb)
+			}, migrate, secrets, nil
+	}
+}
@@ -0,0 +1,119 @@
+package ec2
+
+import (
+	ctxpkg "context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/mntr"
+)
+
+type context struct {
+	monitor         mntr.Monitor
+	orbID           string
+	providerID      string
+	desired         *Spec
+	client          ec2iface.EC2API
+	machinesService *machinesService
+	ctx             ctxpkg.Context
+	// plan records the planned changes if the query is planned
+	plan    *orbiter.Plan
+	network struct {
+		vpc           string
+		defaultSubnet string
+		securityGroup string
+	}
+}
+
+func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string, oneoff bool) (*context, error) {
+
+	cfg := &aws.Config{
+		Region: aws.String(desired.Region),
+		HTTPClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: monitor.RoundTripper(nil),
+		},
+	}
+
+	if desired.AccessKeyID != nil && desired.SecretAccessKey != nil {
+		cfg.Credentials = credentials.NewStaticCredentials(desired.AccessKeyID.Value, desired.SecretAccessKey.Value, "")
+	}
+
+	if desired.Endpoint != "" {
+		cfg.Endpoint = aws.String(desired.Endpoint)
+	}
+
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating aws session failed")
+	}
+
+	newContext := &context{
+		monitor:    monitor,
+		orbID:      orbID,
+		providerID: providerID,
+		desired:    desired,
+		client:     ec2.New(sess),
+		ctx:        ctxpkg.Background(),
+	}
+
+	newContext.machinesService = newMachinesService(newContext, oneoff)
+
+	return newContext, nil
+}
+
+// tags returns the tags all resources of this provider are labeled with
+func (c *context) tags(additional map[string]string) []*ec2.Tag {
+	tags := []*ec2.Tag{{
+		Key:   aws.String("orb"),
+		Value: aws.String(c.orbID),
+	}, {
+		Key:   aws.String("provider"),
+		Value: aws.String(c.providerID),
+	}}
+	for key, value := range additional {
+		tags = append(tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+	return tags
+}
+
+// filters returns filters matching only resources of this provider
+func (c *context) filters(additional ...*ec2.Filter) []*ec2.Filter {
+	return append([]*ec2.Filter{{
+		Name:   aws.String("tag:orb"),
+		Values: []*string{aws.String(c.orbID)},
+	}, {
+		Name:   aws.String("tag:provider"),
+		Values: []*string{aws.String(c.providerID)},
+	}}, additional...)
+}
+
+func tag(tags []*ec2.Tag, key string) string {
+	for _, t := range tags {
+		if aws.StringValue(t.Key) == key {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
+
+// planResources records the number of resources ensure creates or updates and removes
+func (c *context) planResources(resource string, ensure, remove []func() error) {
+	if len(ensure) > 0 {
+		c.plan.AddLoadBalancer(orbiter.PlannedLoadBalancer{Provider: c.providerID, Name: resource, Action: "ensure", Count: len(ensure)})
+	}
+	if len(remove) > 0 {
+		c.plan.AddLoadBalancer(orbiter.PlannedLoadBalancer{Provider: c.providerID, Name: resource, Action: "remove", Count: len(remove)})
+	}
+}
@@ -0,0 +1,149 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/tree"
+)
+
+type Desired struct {
+	Common        *tree.Common `yaml:",inline"`
+	Spec          Spec
+	Loadbalancing *tree.Tree
+}
+
+type Pool struct {
+	// For example t3.medium
+	InstanceType string
+	// A CentOS 7 image available in the configured region
+	AMI string
+	// Size of the root volume
+	//@default: 20
+	VolumeSizeGB int64
+	// Machines are created in this subnet. By default, they are created in the default subnet of the first availability zone of the default VPC
+	Subnet string `yaml:",omitempty"`
+}
+
+func (p Pool) validate() error {
+	if p.InstanceType == "" {
+		return errors.New("no instance type configured")
+	}
+	if p.AMI == "" {
+		return errors.New("no ami configured")
+	}
+	if p.VolumeSizeGB != 0 && p.VolumeSizeGB < 20 {
+		return fmt.Errorf("at least 20GB of storage is needed for the root volume")
+	}
+	return nil
+}
+
+type SSHKey struct {
+	Private *secret.Secret `yaml:",omitempty"`
+	Public  *secret.Secret `yaml:",omitempty"`
+}
+
+// copy returns a copy of a complete key pair or nil, so the key survives being cleared for rotation
+func (s *SSHKey) copy() *SSHKey {
+	if s == nil || s.Private == nil || s.Public == nil || s.Private.Value == "" || s.Public.Value == "" {
+		return nil
+	}
+	return &SSHKey{
+		Private: &secret.Secret{Value: s.Private.Value},
+		Public:  &secret.Secret{Value: s.Public.Value},
+	}
+}
+
+type Spec struct {
+	Verbose         bool
+	AccessKeyID     *secret.Secret `yaml:",omitempty"`
+	SecretAccessKey *secret.Secret `yaml:",omitempty"`
+	Region          string
+	// Overrides the EC2 API endpoint, for example to test against a local stand-in like moto or localstack
+	Endpoint string `yaml:",omitempty"`
+	// The VPC all machines and security groups belong to. By default, the regions default VPC is used
+	VPC   string `yaml:",omitempty"`
+	Pools map[string]*Pool
+	// SSH connections are allowed from these sources only
+	//@default: ["0.0.0.0/0"]
+	SSHWhitelist        []*orbiter.CIDR `yaml:",omitempty"`
+	SSHKey              *SSHKey
+	RebootRequired      []string
+	ReplacementRequired []string
+}
+
+func (d Desired) validateAdapt() error {
+	if d.Loadbalancing == nil {
+		return errors.New("no loadbalancing configured")
+	}
+	if d.Spec.Region == "" {
+		return errors.New("no region configured")
+	}
+	if len(d.Spec.Pools) == 0 {
+		return errors.New("no pools configured")
+	}
+	for poolName, pool := range d.Spec.Pools {
+		if err := pool.validate(); err != nil {
+			return fmt.Errorf("configuring pool %s failed: %w", poolName, err)
+		}
+	}
+	for _, cidr := range d.Spec.SSHWhitelist {
+		if err := cidr.Validate(); err != nil {
+			return fmt.Errorf("configuring ssh whitelist failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (d Desired) validateCredentials() error {
+	if d.Spec.AccessKeyID == nil ||
+		d.Spec.AccessKeyID.Value == "" ||
+		d.Spec.SecretAccessKey == nil ||
+		d.Spec.SecretAccessKey.Value == "" {
+		return errors.New("aws credentials missing... please provide an access key id and a secret access key using orbctl writesecret command")
+	}
+	return nil
+}
+
+func (d Desired) validateQuery() error {
+
+	if err := d.validateCredentials(); err != nil {
+		return err
+	}
+
+	if d.Spec.SSHKey == nil ||
+		d.Spec.SSHKey.Private == nil ||
+		d.Spec.SSHKey.Private.Value == "" ||
+		d.Spec.SSHKey.Public == nil ||
+		d.Spec.SSHKey.Public.Value == "" {
+		return errors.New("ssh key missing... please initialize your orb using orbctl configure command")
+	}
+
+	return nil
+}
+
+func parseDesired(desiredTree *tree.Tree) (*Desired, error) {
+	desiredKind := &Desired{
+		Common: desiredTree.Common,
+		Spec:   Spec{},
+	}
+
+	if err := desiredTree.Original.Decode(desiredKind); err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+
+	return desiredKind, nil
+}
+
+// ValidateDesired checks the desired state without adapting it.
+// Secrets are not checked, as they are written using orbctl
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := parseDesired(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.validateAdapt()
+}
@@ -0,0 +1,61 @@
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+)
+
+func destroy(context *context, current *Current) error {
+
+	_, releaseEIPs, err := queryElasticIPs(context, nil, current)
+	if err != nil {
+		return err
+	}
+
+	pools, err := context.machinesService.machines()
+	if err != nil {
+		return err
+	}
+
+	var (
+		removeMachines []func() error
+		instanceIDs    []*string
+	)
+	for _, machines := range pools {
+		for _, m := range machines {
+			removeMachines = append(removeMachines, m.Remove)
+			instanceIDs = append(instanceIDs, m.instance.InstanceId)
+		}
+	}
+
+	if err := helpers.Fanout(append(releaseEIPs, removeMachines...))(); err != nil {
+		return err
+	}
+
+	// Security groups can only be deleted when no instance uses them anymore
+	if len(instanceIDs) > 0 {
+		if err := context.client.WaitUntilInstanceTerminatedWithContext(context.ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: instanceIDs,
+		}); err != nil {
+			return errors.Wrap(err, "waiting for instances to terminate failed")
+		}
+	}
+
+	if err := resolveNetwork(context, false); err != nil {
+		return err
+	}
+	if context.network.securityGroup == "" {
+		return nil
+	}
+
+	if _, err := context.client.DeleteSecurityGroupWithContext(context.ctx, &ec2.DeleteSecurityGroupInput{
+		GroupId: aws.String(context.network.securityGroup),
+	}); err != nil {
+		return errors.Wrapf(err, "deleting security group %s failed", context.network.securityGroup)
+	}
+	context.monitor.WithField("securitygroup", securityGroupName(context)).Changed("Security group deleted")
+	return nil
+}
@@ -0,0 +1,193 @@
+package ec2
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+)
+
+// targetPool returns the pool whose machines serve the VIP.
+// The dynamic load balancers NAT is configured on the backend pools, so the Elastic IP is associated with a machine of the first one
+func targetPool(vip *dynamic.VIP) string {
+	for _, transport := range vip.Transport {
+		for _, pool := range transport.BackendPools {
+			return pool
+		}
+	}
+	return ""
+}
+
+func queryElasticIPs(context *context, loadbalancing map[string][]*dynamic.VIP, writeTo *Current) ([]func() error, []func() error, error) {
+
+	addresses, err := context.client.DescribeAddressesWithContext(context.ctx, &ec2.DescribeAddressesInput{
+		Filters: context.filters(),
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "listing elastic ips failed")
+	}
+
+	if writeTo.Current.Ingresses == nil {
+		writeTo.Current.Ingresses = make(map[string]*infra.Address)
+	}
+
+	var ensure []func() error
+	for hostPool, vips := range loadbalancing {
+		for vipIdx, vip := range vips {
+
+			var existing *ec2.Address
+			for _, address := range addresses.Addresses {
+				if tag(address.Tags, "pool") == hostPool && tag(address.Tags, "idx") == strconv.Itoa(vipIdx) {
+					existing = address
+					break
+				}
+			}
+
+			for _, transport := range vip.Transport {
+				ingress := &infra.Address{
+					FrontendPort: uint16(transport.FrontendPort),
+					BackendPort:  uint16(transport.BackendPort),
+				}
+				if existing != nil {
+					ingress.Location = aws.StringValue(existing.PublicIp)
+				}
+				writeTo.Current.Ingresses[transport.Name] = ingress
+			}
+
+			machines, err := context.machinesService.List(targetPool(vip))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if existing == nil {
+				ensure = append(ensure, allocateElasticIPFunc(context, hostPool, vipIdx, machines))
+				continue
+			}
+
+			if len(machines) == 0 || associatedWithAny(existing, machines) {
+				continue
+			}
+			ensure = append(ensure, associateElasticIPFunc(context, existing, machines[0].(*machine)))
+		}
+	}
+
+	var remove []func() error
+removeLoop:
+	for _, address := range addresses.Addresses {
+		for hostPool, vips := range loadbalancing {
+			for vipIdx := range vips {
+				if tag(address.Tags, "pool") == hostPool && tag(address.Tags, "idx") == strconv.Itoa(vipIdx) {
+					continue removeLoop
+				}
+			}
+		}
+		remove = append(remove, releaseElasticIPFunc(context, address))
+	}
+	return ensure, remove, nil
+}
+
+func associatedWithAny(address *ec2.Address, machines infra.Machines) bool {
+	for _, m := range machines {
+		if aws.StringValue(m.(*machine).instance.InstanceId) == aws.StringValue(address.InstanceId) {
+			return true
+		}
+	}
+	return false
+}
+
+func allocateElasticIPFunc(context *context, hostPool string, vipIdx int, machines infra.Machines) func() error {
+	return func() error {
+		monitor := context.monitor.WithFields(map[string]interface{}{
+			"pool": hostPool,
+			"idx":  vipIdx,
+		})
+		monitor.Info("Allocating elastic ip")
+		allocated, err := context.client.AllocateAddressWithContext(context.ctx, &ec2.AllocateAddressInput{
+			Domain: aws.String(ec2.DomainTypeVpc),
+		})
+		if err != nil {
+			return errors.Wrap(err, "allocating elastic ip failed")
+		}
+
+		if _, err := context.client.CreateTagsWithContext(context.ctx, &ec2.CreateTagsInput{
+			Resources: []*string{allocated.AllocationId},
+			Tags: context.tags(map[string]string{
+				"pool": hostPool,
+				"idx":  strconv.Itoa(vipIdx),
+			}),
+		}); err != nil {
+			return errors.Wrapf(err, "tagging elastic ip %s failed", aws.StringValue(allocated.PublicIp))
+		}
+		monitor.WithField("ip", aws.StringValue(allocated.PublicIp)).Changed("Elastic ip allocated")
+
+		if len(machines) == 0 {
+			return nil
+		}
+		return associateElasticIPFunc(context, &ec2.Address{
+			AllocationId: allocated.AllocationId,
+			PublicIp:     allocated.PublicIp,
+		}, machines[0].(*machine))()
+	}
+}
+
+func associateElasticIPFunc(context *context, address *ec2.Address, to *machine) func() error {
+	return func() error {
+		if _, err := context.client.AssociateAddressWithContext(context.ctx, &ec2.AssociateAddressInput{
+			AllocationId:       address.AllocationId,
+			InstanceId:         to.instance.InstanceId,
+			AllowReassociation: aws.Bool(true),
+		}); err != nil {
+			return errors.Wrapf(err, "associating elastic ip %s with machine %s failed", aws.StringValue(address.PublicIp), to.ID())
+		}
+		context.monitor.WithFields(map[string]interface{}{
+			"ip":      aws.StringValue(address.PublicIp),
+			"machine": to.ID(),
+		}).Changed("Elastic ip associated")
+		return nil
+	}
+}
+
+func releaseElasticIPFunc(context *context, address *ec2.Address) func() error {
+	return func() error {
+		if address.AssociationId != nil {
+			if _, err := context.client.DisassociateAddressWithContext(context.ctx, &ec2.DisassociateAddressInput{
+				AssociationId: address.AssociationId,
+			}); err != nil {
+				return errors.Wrapf(err, "disassociating elastic ip %s failed", aws.StringValue(address.PublicIp))
+			}
+		}
+		if _, err := context.client.ReleaseAddressWithContext(context.ctx, &ec2.ReleaseAddressInput{
+			AllocationId: address.AllocationId,
+		}); err != nil {
+			return errors.Wrapf(err, "releasing elastic ip %s failed", aws.StringValue(address.PublicIp))
+		}
+		context.monitor.WithField("ip", aws.StringValue(address.PublicIp)).Changed("Elastic ip released")
+		return nil
+	}
+}
+
+// hostedElasticIPs returns the Elastic IPs the machine must be able to bind to
+func hostedElasticIPs(loadbalancing map[string][]*dynamic.VIP, m *machine, current *Current) []string {
+	seen := make(map[string]bool)
+	var ips []string
+	for _, vips := range loadbalancing {
+		for _, vip := range vips {
+			if targetPool(vip) != m.poolName {
+				continue
+			}
+			for _, transport := range vip.Transport {
+				addr, ok := current.Current.Ingresses[transport.Name]
+				if !ok || addr == nil || addr.Location == "" || seen[addr.Location] {
+					continue
+				}
+				seen[addr.Location] = true
+				ips = append(ips, addr.Location)
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,192 @@
+package ec2
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	dynamiclbmodel "github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic/wrap"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+)
+
+func query(
+	desired *Spec,
+	current *Current,
+	lb interface{},
+	context *context,
+	nodeAgentsCurrent *common.CurrentNodeAgents,
+	nodeAgentsDesired *common.DesiredNodeAgents,
+	naFuncs core.IterateNodeAgentFuncs,
+	orbiterCommit string,
+) (ensureFunc orbiter.EnsureFunc, err error) {
+
+	lbCurrent, ok := lb.(*dynamiclbmodel.Current)
+	if !ok {
+		panic(errors.Errorf("Unknown or unsupported load balancing of type %T", lb))
+	}
+
+	if err := resolveNetwork(context, true); err != nil {
+		return nil, err
+	}
+
+	hostPools, _, err := lbCurrent.Current.Spec(context.machinesService)
+	if err != nil {
+		return nil, err
+	}
+
+	var ensureEIPs, removeEIPs, ensureSG []func() error
+	if err := helpers.Fanout([]func() error{
+		func() error {
+			var err error
+			ensureEIPs, removeEIPs, err = queryElasticIPs(context, hostPools, current)
+			return err
+		},
+		func() error {
+			var err error
+			ensureSG, err = querySecurityGroup(context, hostPools)
+			return err
+		},
+	})(); err != nil {
+		return nil, err
+	}
+	context.planResources("elasticips", ensureEIPs, removeEIPs)
+	context.planResources("securitygroup", ensureSG, nil)
+
+	queryNA, installNA := naFuncs(nodeAgentsCurrent)
+	ensureNodeAgent := func(m infra.Machine) error {
+		running, err := queryNA(m, orbiterCommit)
+		if err != nil {
+			return err
+		}
+		if !running {
+			return installNA(m)
+		}
+		return nil
+	}
+
+	ensureOS := func(m *machine) error {
+		if err := ensureNodeAgent(m); err != nil {
+			return err
+		}
+		if err := ensureDummyInterface(context, m, hostedElasticIPs(hostPools, m, current)); err != nil {
+			context.monitor.WithField("machine", m.ID()).Info(fmt.Errorf("Could not yet configure elastic ips: %w", err).Error())
+		}
+		return nil
+	}
+
+	context.machinesService.onCreate = func(pool string, m infra.Machine) error {
+		_, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"})
+		if err != nil {
+			return err
+		}
+		return ensureOS(m.(*machine))
+	}
+
+	wrappedMachines := wrap.MachinesService(context.machinesService, *lbCurrent, nil, func(vip *dynamiclbmodel.VIP) string {
+		for _, transport := range vip.Transport {
+			address, ok := current.Current.Ingresses[transport.Name]
+			if ok {
+				return address.Location
+			}
+		}
+		panic(fmt.Errorf("external address for %v is not ensured", vip))
+	})
+
+	if context.plan != nil {
+		// Planning doesn't ensure, so the node agent desires ensuring sets are computed now
+		if _, err := wrappedMachines.InitializeDesiredNodeAgents(); err != nil {
+			return nil, err
+		}
+		if _, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"}); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(pdf api.PushDesiredFunc) *orbiter.EnsureResult {
+		var done bool
+		return orbiter.ToEnsureResult(done, helpers.Fanout([]func() error{
+			func() error { return helpers.Fanout(ensureSG)() },
+			func() error { return helpers.Fanout(ensureEIPs)() },
+			func() error { return helpers.Fanout(removeEIPs)() },
+			func() error {
+				pools, err := context.machinesService.machines()
+				if err != nil {
+					return err
+				}
+				var ensureMachines []func() error
+				for _, machines := range pools {
+					for _, m := range machines {
+						ensureMachines = append(ensureMachines, func(m *machine) func() error {
+							return func() error { return ensureOS(m) }
+						}(m))
+					}
+				}
+				return helpers.Fanout(ensureMachines)()
+			},
+			func() error {
+				lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
+				if err != nil {
+					return err
+				}
+
+				fwDone, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"})
+				if err != nil {
+					return err
+				}
+				done = lbDone && fwDone && len(ensureEIPs) == 0
+				return nil
+			},
+		})())
+	}, addPools(current, desired, wrappedMachines)
+}
+
+// ensureDummyInterface adds the Elastic IPs to a dummy interface, so nginx can bind to them.
+// AWS translates the Elastic IP to the machines private IP, so the nginx listening on the private IP serves the traffic
+func ensureDummyInterface(context *context, machine *machine, ips []string) error {
+
+	cmd := "true"
+	dummy1, err := machine.Execute(nil, `INNEROUT="$(set -o pipefail && sudo ip address show dummy1 | grep dummy1 | tail -n +2 | awk '{print $2}' | cut -d "/" -f 1)" && echo $INNEROUT`)
+	if err != nil {
+		cmd += " && sudo ip link add dummy1 type dummy"
+	}
+
+	added := bytes.Fields(dummy1)
+
+addLoop:
+	for _, ip := range ips {
+		for _, already := range added {
+			if string(already) == ip {
+				continue addLoop
+			}
+		}
+		cmd += fmt.Sprintf(" && sudo ip addr add %s/32 dev dummy1", ip)
+	}
+
+deleteLoop:
+	for _, already := range added {
+		for _, ip := range ips {
+			if string(already) == ip {
+				continue deleteLoop
+			}
+		}
+		cmd += fmt.Sprintf(" && sudo ip addr delete %s/32 dev dummy1", already)
+	}
+
+	if cmd == "true" {
+		return nil
+	}
+
+	context.monitor.WithFields(map[string]interface{}{
+		"cmd":     cmd,
+		"machine": machine.ID(),
+	}).Info("Executing")
+	_, err = machine.Execute(nil, cmd)
+	return err
+}
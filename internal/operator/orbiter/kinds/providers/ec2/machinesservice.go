@@ -0,0 +1,344 @@
+package ec2
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/cs"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ssh"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+)
+
+func ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerID string) (map[string]infra.Machine, error) {
+	desired, err := parseDesired(desiredTree)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+	desiredTree.Parsed = desired
+
+	ctx, err := buildContext(monitor, &desired.Spec, orbID, providerID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.machinesService.use(desired.Spec.SSHKey); err != nil {
+		invalidKey := &secret.Secret{Value: "invalid"}
+		if err := ctx.machinesService.use(&SSHKey{
+			Private: invalidKey,
+			Public:  invalidKey,
+		}); err != nil {
+			panic(err)
+		}
+	}
+
+	return core.ListMachines(ctx.machinesService)
+}
+
+var _ core.MachinesService = (*machinesService)(nil)
+
+// authorizedKeysPath is where the orbiter users keys are authorized
+const authorizedKeysPath = "/home/orbiter/.ssh/authorized_keys"
+
+type machinesService struct {
+	context *context
+	oneoff  bool
+	key     *SSHKey
+	cache   struct {
+		instances map[string][]*machine
+		sync.Mutex
+	}
+	onCreate func(pool string, machine infra.Machine) error
+}
+
+func newMachinesService(context *context, oneoff bool) *machinesService {
+	return &machinesService{
+		context: context,
+		oneoff:  oneoff,
+	}
+}
+
+func (m *machinesService) use(key *SSHKey) error {
+	if key == nil || key.Private == nil || key.Public == nil || key.Private.Value == "" || key.Public.Value == "" {
+		return errors.New("machines are not connectable. have you configured the orb by running orbctl configure?")
+	}
+	if m.key != nil && m.key.Private.Value != key.Private.Value {
+		// Cached machines connect with the previously used key
+		m.cache.Lock()
+		m.cache.instances = nil
+		m.cache.Unlock()
+	}
+	m.key = key
+	return nil
+}
+
+// authorizeKey additionally authorizes the public key on all machines
+func (m *machinesService) authorizeKey(publicKey string) error {
+	return core.Each(m, func(_ string, machine infra.Machine) error {
+		return core.AuthorizeKey(machine, authorizedKeysPath, publicKey)
+	})
+}
+
+// revokeKey removes the public key from all machines
+func (m *machinesService) revokeKey(publicKey string) error {
+	return core.Each(m, func(_ string, machine infra.Machine) error {
+		return core.RevokeKey(machine, authorizedKeysPath, publicKey)
+	})
+}
+
+func (m *machinesService) Create(poolName string) (infra.Machine, error) {
+
+	desired, ok := m.context.desired.Pools[poolName]
+	if !ok {
+		return nil, fmt.Errorf("Pool %s is not configured", poolName)
+	}
+
+	if err := resolveNetwork(m.context, true); err != nil {
+		return nil, err
+	}
+
+	name := newName()
+	monitor := machineMonitor(m.context.monitor, name, poolName)
+
+	monitor.Debug("Creating instance")
+
+	userData, err := cs.NewCloudinit().AddGroupWithoutUsers(
+		"orbiter",
+	).AddUser(
+		"orbiter",
+		true,
+		"",
+		[]string{"orbiter", "wheel"},
+		"orbiter",
+		[]string{m.context.desired.SSHKey.Public.Value},
+		"ALL=(ALL) NOPASSWD:ALL",
+	).AddCmd(
+		"sudo echo \"\n\nPermitRootLogin no\n\" >> /etc/ssh/sshd_config",
+	).AddCmd(
+		"sudo service sshd restart",
+	).ToYamlString()
+	if err != nil {
+		return nil, err
+	}
+
+	volumeSize := desired.VolumeSizeGB
+	if volumeSize == 0 {
+		volumeSize = 20
+	}
+
+	reservation, err := m.context.client.RunInstancesWithContext(m.context.ctx, &ec2.RunInstancesInput{
+		ImageId:      aws.String(desired.AMI),
+		InstanceType: aws.String(desired.InstanceType),
+		MinCount:     aws.Int64(1),
+		MaxCount:     aws.Int64(1),
+		UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{{
+			DeviceName: aws.String("/dev/sda1"),
+			Ebs: &ec2.EbsBlockDevice{
+				VolumeSize:          aws.Int64(volumeSize),
+				VolumeType:          aws.String("gp2"),
+				DeleteOnTermination: aws.Bool(true),
+			},
+		}},
+		NetworkInterfaces: []*ec2.InstanceNetworkInterfaceSpecification{{
+			DeviceIndex:              aws.Int64(0),
+			SubnetId:                 aws.String(m.context.subnet(desired)),
+			Groups:                   []*string{aws.String(m.context.network.securityGroup)},
+			AssociatePublicIpAddress: aws.Bool(true),
+			DeleteOnTermination:      aws.Bool(true),
+		}},
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags: m.context.tags(map[string]string{
+				"Name": name,
+				"pool": poolName,
+			}),
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(reservation.Instances) != 1 {
+		return nil, errors.Errorf("expected one instance to be created but got %d", len(reservation.Instances))
+	}
+	instanceID := reservation.Instances[0].InstanceId
+
+	describeCreated := &ec2.DescribeInstancesInput{InstanceIds: []*string{instanceID}}
+	if err := m.context.client.WaitUntilInstanceRunningWithContext(m.context.ctx, describeCreated); err != nil {
+		return nil, errors.Wrapf(err, "waiting for instance %s to run failed", aws.StringValue(instanceID))
+	}
+
+	created, err := m.context.client.DescribeInstancesWithContext(m.context.ctx, describeCreated)
+	if err != nil {
+		return nil, err
+	}
+	if len(created.Reservations) != 1 || len(created.Reservations[0].Instances) != 1 {
+		return nil, errors.Errorf("instance %s not found", aws.StringValue(instanceID))
+	}
+
+	monitor.Info("Instance created")
+
+	infraMachine, err := m.toMachine(created.Reservations[0].Instances[0], monitor, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.cache.instances != nil {
+		m.cache.Lock()
+		m.cache.instances[poolName] = append(m.cache.instances[poolName], infraMachine)
+		m.cache.Unlock()
+	}
+
+	if m.onCreate != nil {
+		if err := m.onCreate(poolName, infraMachine); err != nil {
+			return nil, err
+		}
+	}
+
+	monitor.Changed("Machine created")
+	return infraMachine, nil
+}
+
+func (m *machinesService) toMachine(instance *ec2.Instance, monitor mntr.Monitor, poolName string) (*machine, error) {
+
+	// The orbiter reaches the machines within the VPC, orbctl from outside
+	sshIP := aws.StringValue(instance.PrivateIpAddress)
+	if m.oneoff {
+		sshIP = aws.StringValue(instance.PublicIpAddress)
+	}
+
+	sshMachine := ssh.NewMachine(monitor, "orbiter", sshIP)
+	if err := sshMachine.UseKey([]byte(m.key.Private.Value)); err != nil {
+		return nil, err
+	}
+
+	return newMachine(
+		instance,
+		sshMachine,
+		m.removeMachineFunc(poolName, aws.StringValue(instance.InstanceId)),
+		m.context,
+		poolName,
+	), nil
+}
+
+func (m *machinesService) ListPools() ([]string, error) {
+
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	var poolNames []string
+	for poolName := range pools {
+		poolNames = append(poolNames, poolName)
+	}
+	sort.Strings(poolNames)
+	return poolNames, nil
+}
+
+func (m *machinesService) List(poolName string) (infra.Machines, error) {
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := pools[poolName]
+	machines := make([]infra.Machine, len(pool))
+	for idx := range pool {
+		machines[idx] = pool[idx]
+	}
+
+	return machines, nil
+}
+
+func (m *machinesService) machines() (map[string][]*machine, error) {
+	m.cache.Lock()
+	defer m.cache.Unlock()
+
+	if m.cache.instances != nil {
+		return m.cache.instances, nil
+	}
+
+	instances := make(map[string][]*machine)
+	var toMachineErr error
+	if err := m.context.client.DescribeInstancesPagesWithContext(m.context.ctx, &ec2.DescribeInstancesInput{
+		Filters: m.context.filters(&ec2.Filter{
+			Name: aws.String("instance-state-name"),
+			Values: aws.StringSlice([]string{
+				ec2.InstanceStateNamePending,
+				ec2.InstanceStateNameRunning,
+				ec2.InstanceStateNameStopping,
+				ec2.InstanceStateNameStopped,
+			}),
+		}),
+	}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				pool := tag(instance.Tags, "pool")
+				machine, err := m.toMachine(instance, machineMonitor(m.context.monitor, tag(instance.Tags, "Name"), pool), pool)
+				if err != nil {
+					toMachineErr = err
+					return false
+				}
+				instances[pool] = append(instances[pool], machine)
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrap(err, "listing instances failed")
+	}
+	if toMachineErr != nil {
+		return nil, toMachineErr
+	}
+
+	for _, machines := range instances {
+		sort.Slice(machines, func(i, j int) bool { return machines[i].ID() < machines[j].ID() })
+	}
+
+	m.cache.instances = instances
+	return m.cache.instances, nil
+}
+
+func (m *machinesService) removeMachineFunc(pool, instanceID string) func() error {
+
+	return func() error {
+		m.cache.Lock()
+		if m.cache.instances != nil {
+			cleanMachines := make([]*machine, 0)
+			for idx := range m.cache.instances[pool] {
+				cachedMachine := m.cache.instances[pool][idx]
+				if aws.StringValue(cachedMachine.instance.InstanceId) != instanceID {
+					cleanMachines = append(cleanMachines, cachedMachine)
+				}
+			}
+			m.cache.instances[pool] = cleanMachines
+		}
+		m.cache.Unlock()
+
+		_, err := m.context.client.TerminateInstancesWithContext(m.context.ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: []*string{aws.String(instanceID)},
+		})
+		return errors.Wrapf(err, "terminating instance %s failed", instanceID)
+	}
+}
+
+func machineMonitor(monitor mntr.Monitor, name string, poolName string) mntr.Monitor {
+	return monitor.WithFields(map[string]interface{}{
+		"machine": name,
+		"pool":    poolName,
+	})
+}
+
+func newName() string {
+	return "orbos-" + helpers.RandomStringRunes(6, []rune("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
@@ -0,0 +1,118 @@
+package ec2
+
+import (
+	"os"
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/ssh"
+	"github.com/caos/orbos/mntr"
+)
+
+// Test_machinesService runs against a local EC2 API stand-in, for example
+// docker run --rm -p 5000:5000 motoserver/moto
+// ORBOS_EC2_ENDPOINT=http://localhost:5000 go test ./internal/operator/orbiter/kinds/providers/ec2
+func Test_machinesService(t *testing.T) {
+
+	endpoint := os.Getenv("ORBOS_EC2_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("ORBOS_EC2_ENDPOINT is not set")
+	}
+
+	ami := os.Getenv("ORBOS_EC2_AMI")
+	if ami == "" {
+		ami = "ami-12c6146b"
+	}
+
+	priv, pub, err := ssh.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := &SSHKey{
+		Private: &secret.Secret{Value: priv},
+		Public:  &secret.Secret{Value: pub},
+	}
+
+	spec := &Spec{
+		AccessKeyID:     &secret.Secret{Value: "testing"},
+		SecretAccessKey: &secret.Secret{Value: "testing"},
+		Region:          "us-east-1",
+		Endpoint:        endpoint,
+		Pools: map[string]*Pool{
+			"workers": {
+				InstanceType: "t3.medium",
+				AMI:          ami,
+			},
+		},
+		SSHKey: key,
+	}
+
+	ctx, err := buildContext(mntr.Monitor{}, spec, "test", "ec2", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.machinesService.use(key); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := ctx.machinesService.Create("workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := ctx.machinesService.List("workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID() != created.ID() {
+		t.Fatalf("expected machine %s to be listed but got %v", created.ID(), listed)
+	}
+
+	ensureSG, err := querySecurityGroup(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ensureSG) == 0 {
+		t.Error("expected the ssh rule to be added")
+	}
+	for _, ensure := range ensureSG {
+		if err := ensure(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current := &Current{}
+	vips := map[string][]*dynamic.VIP{
+		"workers": {{
+			Transport: []*dynamic.Transport{{
+				Name:         "https",
+				FrontendPort: 443,
+				BackendPort:  30443,
+				BackendPools: []string{"workers"},
+			}},
+		}},
+	}
+	ensureEIPs, _, err := queryElasticIPs(ctx, vips, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ensureEIPs) != 1 {
+		t.Fatalf("expected one elastic ip to be allocated but got %d", len(ensureEIPs))
+	}
+	if err := ensureEIPs[0](); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := queryElasticIPs(ctx, vips, current); err != nil {
+		t.Fatal(err)
+	}
+	if current.Current.Ingresses["https"].Location == "" {
+		t.Error("expected the elastic ip to be written to the current state")
+	}
+
+	if err := destroy(ctx, current); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+package ec2
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+)
+
+// resolveNetwork looks up the VPC, the default subnet and the security group of the orb.
+// The security group is created if it doesn't exist yet
+func resolveNetwork(context *context, createSecurityGroup bool) error {
+
+	if context.network.securityGroup != "" {
+		return nil
+	}
+
+	vpc := context.desired.VPC
+	if vpc == "" {
+		vpcs, err := context.client.DescribeVpcsWithContext(context.ctx, &ec2.DescribeVpcsInput{
+			Filters: []*ec2.Filter{{
+				Name:   aws.String("isDefault"),
+				Values: []*string{aws.String("true")},
+			}},
+		})
+		if err != nil {
+			return errors.Wrap(err, "describing default vpc failed")
+		}
+		if len(vpcs.Vpcs) == 0 {
+			return errors.Errorf("region %s has no default vpc, please configure a vpc", context.desired.Region)
+		}
+		vpc = aws.StringValue(vpcs.Vpcs[0].VpcId)
+	}
+	context.network.vpc = vpc
+
+	subnets, err := context.client.DescribeSubnetsWithContext(context.ctx, &ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{{
+			Name:   aws.String("vpc-id"),
+			Values: []*string{aws.String(vpc)},
+		}},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "describing subnets of vpc %s failed", vpc)
+	}
+	sort.Slice(subnets.Subnets, func(i, j int) bool {
+		return aws.StringValue(subnets.Subnets[i].AvailabilityZone) < aws.StringValue(subnets.Subnets[j].AvailabilityZone)
+	})
+	for _, subnet := range subnets.Subnets {
+		if aws.BoolValue(subnet.DefaultForAz) || context.network.defaultSubnet == "" {
+			context.network.defaultSubnet = aws.StringValue(subnet.SubnetId)
+			if aws.BoolValue(subnet.DefaultForAz) {
+				break
+			}
+		}
+	}
+
+	groupName := securityGroupName(context)
+	groups, err := context.client.DescribeSecurityGroupsWithContext(context.ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{{
+			Name:   aws.String("vpc-id"),
+			Values: []*string{aws.String(vpc)},
+		}, {
+			Name:   aws.String("group-name"),
+			Values: []*string{aws.String(groupName)},
+		}},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "describing security group %s failed", groupName)
+	}
+	if len(groups.SecurityGroups) > 0 {
+		context.network.securityGroup = aws.StringValue(groups.SecurityGroups[0].GroupId)
+		return nil
+	}
+
+	if !createSecurityGroup {
+		return nil
+	}
+
+	monitor := context.monitor.WithField("securitygroup", groupName)
+	monitor.Info("Creating security group")
+	created, err := context.client.CreateSecurityGroupWithContext(context.ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(groupName),
+		Description: aws.String(fmt.Sprintf("Machines of orb %s and provider %s", context.orbID, context.providerID)),
+		VpcId:       aws.String(vpc),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "creating security group %s failed", groupName)
+	}
+	context.network.securityGroup = aws.StringValue(created.GroupId)
+
+	if _, err := context.client.CreateTagsWithContext(context.ctx, &ec2.CreateTagsInput{
+		Resources: []*string{created.GroupId},
+		Tags:      context.tags(nil),
+	}); err != nil {
+		return errors.Wrapf(err, "tagging security group %s failed", groupName)
+	}
+	monitor.Changed("Security group created")
+	return nil
+}
+
+func securityGroupName(context *context) string {
+	return fmt.Sprintf("orbos-%s-%s", context.orbID, context.providerID)
+}
+
+func (c *context) subnet(pool *Pool) string {
+	if pool != nil && pool.Subnet != "" {
+		return pool.Subnet
+	}
+	return c.network.defaultSubnet
+}
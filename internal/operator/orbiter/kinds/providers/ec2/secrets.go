@@ -0,0 +1,34 @@
+package ec2
+
+import (
+	"github.com/caos/orbos/internal/secret"
+)
+
+func getSecretsMap(desiredKind *Desired) map[string]*secret.Secret {
+	if desiredKind.Spec.AccessKeyID == nil {
+		desiredKind.Spec.AccessKeyID = &secret.Secret{}
+	}
+
+	if desiredKind.Spec.SecretAccessKey == nil {
+		desiredKind.Spec.SecretAccessKey = &secret.Secret{}
+	}
+
+	if desiredKind.Spec.SSHKey == nil {
+		desiredKind.Spec.SSHKey = &SSHKey{}
+	}
+
+	if desiredKind.Spec.SSHKey.Public == nil {
+		desiredKind.Spec.SSHKey.Public = &secret.Secret{}
+	}
+
+	if desiredKind.Spec.SSHKey.Private == nil {
+		desiredKind.Spec.SSHKey.Private = &secret.Secret{}
+	}
+
+	return map[string]*secret.Secret{
+		"accesskeyid":     desiredKind.Spec.AccessKeyID,
+		"secretaccesskey": desiredKind.Spec.SecretAccessKey,
+		"sshkeyprivate":   desiredKind.Spec.SSHKey.Private,
+		"sshkeypublic":    desiredKind.Spec.SSHKey.Public,
+	}
+}
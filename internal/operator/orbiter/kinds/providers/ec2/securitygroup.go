@@ -0,0 +1,180 @@
+package ec2
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+)
+
+// rule is a single ingress permission of the orbs security group.
+// Either cidr or group is set
+type rule struct {
+	protocol string
+	port     int64
+	cidr     string
+	group    string
+}
+
+func (r rule) String() string {
+	source := r.cidr
+	if r.group != "" {
+		source = r.group
+	}
+	return fmt.Sprintf("%s/%d from %s", r.protocol, r.port, source)
+}
+
+func (r rule) permission() *ec2.IpPermission {
+	perm := &ec2.IpPermission{
+		IpProtocol: aws.String(r.protocol),
+	}
+	if r.protocol != "-1" {
+		perm.FromPort = aws.Int64(r.port)
+		perm.ToPort = aws.Int64(r.port)
+	}
+	if r.group != "" {
+		perm.UserIdGroupPairs = []*ec2.UserIdGroupPair{{GroupId: aws.String(r.group)}}
+	} else {
+		perm.IpRanges = []*ec2.IpRange{{CidrIp: aws.String(r.cidr)}}
+	}
+	return perm
+}
+
+// desiredRules allows all traffic between the orbs machines, ssh from the ssh whitelist
+// and each load balanced transport from its whitelist
+func desiredRules(securityGroup string, sshWhitelist []*orbiter.CIDR, vips map[string][]*dynamic.VIP) []rule {
+	rules := []rule{{protocol: "-1", group: securityGroup}}
+
+	if len(sshWhitelist) == 0 {
+		rules = append(rules, rule{protocol: "tcp", port: 22, cidr: "0.0.0.0/0"})
+	}
+	for _, cidr := range sshWhitelist {
+		rules = append(rules, rule{protocol: "tcp", port: 22, cidr: string(*cidr)})
+	}
+
+	for _, poolVIPs := range vips {
+		for _, vip := range poolVIPs {
+			for _, transport := range vip.Transport {
+				for _, cidr := range transport.Whitelist {
+					rules = append(rules, rule{protocol: "tcp", port: int64(transport.FrontendPort), cidr: string(*cidr)})
+				}
+			}
+		}
+	}
+	return uniqueRules(rules)
+}
+
+// currentRules flattens the permissions returned by the EC2 API
+func currentRules(permissions []*ec2.IpPermission) []rule {
+	var rules []rule
+	for _, perm := range permissions {
+		r := rule{
+			protocol: aws.StringValue(perm.IpProtocol),
+			port:     aws.Int64Value(perm.FromPort),
+		}
+		if r.protocol == "-1" {
+			r.port = 0
+		}
+		for _, ipRange := range perm.IpRanges {
+			cidrRule := r
+			cidrRule.cidr = aws.StringValue(ipRange.CidrIp)
+			rules = append(rules, cidrRule)
+		}
+		for _, pair := range perm.UserIdGroupPairs {
+			groupRule := r
+			groupRule.group = aws.StringValue(pair.GroupId)
+			rules = append(rules, groupRule)
+		}
+	}
+	return uniqueRules(rules)
+}
+
+// diffRules returns the rules to add and the rules to remove
+func diffRules(desired, current []rule) (add []rule, remove []rule) {
+	contains := func(rules []rule, r rule) bool {
+		for _, cmp := range rules {
+			if cmp == r {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range desired {
+		if !contains(current, r) {
+			add = append(add, r)
+		}
+	}
+	for _, r := range current {
+		if !contains(desired, r) {
+			remove = append(remove, r)
+		}
+	}
+	return add, remove
+}
+
+func uniqueRules(rules []rule) []rule {
+	seen := make(map[rule]bool)
+	unique := make([]rule, 0, len(rules))
+	for _, r := range rules {
+		if !seen[r] {
+			seen[r] = true
+			unique = append(unique, r)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].String() < unique[j].String() })
+	return unique
+}
+
+func querySecurityGroup(context *context, vips map[string][]*dynamic.VIP) ([]func() error, error) {
+
+	groups, err := context.client.DescribeSecurityGroupsWithContext(context.ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds: []*string{aws.String(context.network.securityGroup)},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "describing security group %s failed", context.network.securityGroup)
+	}
+	if len(groups.SecurityGroups) == 0 {
+		return nil, errors.Errorf("security group %s not found", context.network.securityGroup)
+	}
+
+	add, remove := diffRules(
+		desiredRules(context.network.securityGroup, context.desired.SSHWhitelist, vips),
+		currentRules(groups.SecurityGroups[0].IpPermissions),
+	)
+
+	var ensure []func() error
+	for _, r := range add {
+		ensure = append(ensure, func(r rule) func() error {
+			return func() error {
+				if _, err := context.client.AuthorizeSecurityGroupIngressWithContext(context.ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+					GroupId:       aws.String(context.network.securityGroup),
+					IpPermissions: []*ec2.IpPermission{r.permission()},
+				}); err != nil {
+					return errors.Wrapf(err, "allowing %s failed", r)
+				}
+				context.monitor.WithField("rule", r.String()).Changed("Security group rule added")
+				return nil
+			}
+		}(r))
+	}
+	for _, r := range remove {
+		ensure = append(ensure, func(r rule) func() error {
+			return func() error {
+				if _, err := context.client.RevokeSecurityGroupIngressWithContext(context.ctx, &ec2.RevokeSecurityGroupIngressInput{
+					GroupId:       aws.String(context.network.securityGroup),
+					IpPermissions: []*ec2.IpPermission{r.permission()},
+				}); err != nil {
+					return errors.Wrapf(err, "revoking %s failed", r)
+				}
+				context.monitor.WithField("rule", r.String()).Changed("Security group rule removed")
+				return nil
+			}
+		}(r))
+	}
+	return ensure, nil
+}
@@ -0,0 +1,87 @@
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+)
+
+func cidr(value string) *orbiter.CIDR {
+	c := orbiter.CIDR(value)
+	return &c
+}
+
+func Test_desiredRules(t *testing.T) {
+	vips := map[string][]*dynamic.VIP{
+		"workers": {{
+			Transport: []*dynamic.Transport{{
+				Name:         "https",
+				FrontendPort: 443,
+				Whitelist:    []*orbiter.CIDR{cidr("0.0.0.0/0")},
+			}, {
+				Name:         "kubeapi",
+				FrontendPort: 6443,
+				Whitelist:    []*orbiter.CIDR{cidr("10.0.0.0/8"), cidr("10.0.0.0/8")},
+			}},
+		}},
+	}
+
+	tests := []struct {
+		name         string
+		sshWhitelist []*orbiter.CIDR
+		want         []rule
+	}{{
+		name: "ssh is allowed from everywhere if no ssh whitelist is configured",
+		want: []rule{
+			{protocol: "-1", group: "sg-1"},
+			{protocol: "tcp", port: 22, cidr: "0.0.0.0/0"},
+			{protocol: "tcp", port: 443, cidr: "0.0.0.0/0"},
+			{protocol: "tcp", port: 6443, cidr: "10.0.0.0/8"},
+		},
+	}, {
+		name:         "ssh is allowed from the ssh whitelist only",
+		sshWhitelist: []*orbiter.CIDR{cidr("192.168.0.0/16")},
+		want: []rule{
+			{protocol: "-1", group: "sg-1"},
+			{protocol: "tcp", port: 22, cidr: "192.168.0.0/16"},
+			{protocol: "tcp", port: 443, cidr: "0.0.0.0/0"},
+			{protocol: "tcp", port: 6443, cidr: "10.0.0.0/8"},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := desiredRules("sg-1", tt.sshWhitelist, vips); !reflect.DeepEqual(got, uniqueRules(tt.want)) {
+				t.Errorf("desiredRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_diffRules(t *testing.T) {
+	current := currentRules([]*ec2.IpPermission{{
+		IpProtocol:       aws.String("-1"),
+		FromPort:         aws.Int64(-1),
+		ToPort:           aws.Int64(-1),
+		UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-1")}},
+	}, {
+		IpProtocol: aws.String("tcp"),
+		FromPort:   aws.Int64(22),
+		ToPort:     aws.Int64(22),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+	}})
+
+	desired := desiredRules("sg-1", []*orbiter.CIDR{cidr("192.168.0.0/16")}, nil)
+
+	add, remove := diffRules(desired, current)
+	if want := []rule{{protocol: "tcp", port: 22, cidr: "192.168.0.0/16"}}; !reflect.DeepEqual(add, want) {
+		t.Errorf("diffRules() add = %v, want %v", add, want)
+	}
+	if want := []rule{{protocol: "tcp", port: 22, cidr: "0.0.0.0/0"}}; !reflect.DeepEqual(remove, want) {
+		t.Errorf("diffRules() remove = %v, want %v", remove, want)
+	}
+}
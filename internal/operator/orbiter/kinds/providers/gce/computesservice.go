@@ -2,6 +2,7 @@ package gce
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +27,11 @@ type machinesService struct {
 		instances map[string][]*instance
 		sync.Mutex
 	}
+	// creating tracks the zones of machines that are created concurrently
+	creating struct {
+		zones map[string][]string
+		sync.Mutex
+	}
 	onCreate func(pool string, machine infra.Machine) error
 }
 
@@ -44,6 +50,55 @@ func (m *machinesService) use(key *SSHKey) error {
 	return nil
 }
 
+// authorizeKeys replaces the SSH keys in the metadata of all instances.
+// The guest agents authorize exactly these keys for the orbiter user
+func (m *machinesService) authorizeKeys(publicKeys ...string) error {
+	pools, err := m.instances()
+	if err != nil {
+		return err
+	}
+
+	sshKeys := make([]string, len(publicKeys))
+	for idx, key := range publicKeys {
+		sshKeys[idx] = fmt.Sprintf("orbiter:%s", strings.TrimSpace(key))
+	}
+	value := strings.Join(sshKeys, "\n")
+
+	for _, pool := range pools {
+		for _, instance := range pool {
+			current, err := m.context.client.Instances.Get(m.context.projectID, instance.zone, instance.ID()).Fields("metadata").Do()
+			if err != nil {
+				return err
+			}
+
+			metadata := current.Metadata
+			if metadata == nil {
+				metadata = &compute.Metadata{}
+			}
+
+			var found bool
+			for _, item := range metadata.Items {
+				if item.Key == "ssh-keys" {
+					item.Value = &value
+					found = true
+				}
+			}
+			if !found {
+				metadata.Items = append(metadata.Items, &compute.MetadataItems{Key: "ssh-keys", Value: &value})
+			}
+
+			if err := operateFunc(
+				func() { instance.Monitor.Debug("Authorizing SSH keys") },
+				computeOpCall(m.context.client.Instances.SetMetadata(m.context.projectID, instance.zone, instance.ID(), metadata).RequestId(uuid.NewV1().String()).Do),
+				func() error { instance.Monitor.Info("SSH keys authorized"); return nil },
+			)(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (m *machinesService) restartPreemptibleMachines() error {
 	pools, err := m.instances()
 	if err != nil {
@@ -55,7 +110,7 @@ func (m *machinesService) restartPreemptibleMachines() error {
 			if instance.start {
 				if err := operateFunc(
 					func() { instance.Monitor.Debug("Restarting preemptible instance") },
-					computeOpCall(m.context.client.Instances.Start(m.context.projectID, m.context.desired.Zone, instance.ID()).RequestId(uuid.NewV1().String()).Do),
+					computeOpCall(m.context.client.Instances.Start(m.context.projectID, instance.zone, instance.ID()).RequestId(uuid.NewV1().String()).Do),
 					func() error { instance.Monitor.Info("Preemptible instance restarted"); return nil },
 				)(); err != nil {
 					return err
@@ -73,6 +128,16 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 		return nil, fmt.Errorf("Pool %s is not configured", poolName)
 	}
 
+	pools, err := m.instances()
+	if err != nil {
+		return nil, err
+	}
+	m.cache.Lock()
+	existing := append([]*instance{}, pools[poolName]...)
+	m.cache.Unlock()
+	zone, release := m.reserveZone(poolName, existing)
+	defer release()
+
 	// Calculate minimum cpu and memory according to the gce specs:
 	// https://cloud.google.com/machine/docs/instances/creating-instance-with-custom-machine-type#specifications
 	cores := desired.MinCPUCores
@@ -102,7 +167,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 		InitializeParams: &compute.AttachedDiskInitializeParams{
 			DiskSizeGb:  int64(desired.StorageGB),
 			SourceImage: desired.OSImage,
-			DiskType:    fmt.Sprintf("zones/%s/diskTypes/%s", m.context.desired.Zone, desired.StorageDiskType),
+			DiskType:    fmt.Sprintf("zones/%s/diskTypes/%s", zone, desired.StorageDiskType),
 		}},
 	}
 
@@ -115,7 +180,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 			Boot:       false,
 			Interface:  "NVME",
 			InitializeParams: &compute.AttachedDiskInitializeParams{
-				DiskType: fmt.Sprintf("zones/%s/diskTypes/local-ssd", m.context.desired.Zone),
+				DiskType: fmt.Sprintf("zones/%s/diskTypes/local-ssd", zone),
 			},
 			DeviceName: name,
 		})
@@ -126,7 +191,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 	sshKey := fmt.Sprintf("orbiter:%s", m.key.Public.Value)
 	createInstance := &compute.Instance{
 		Name:        name,
-		MachineType: fmt.Sprintf("zones/%s/machineTypes/custom-%d-%d", m.context.desired.Zone, cores, int(memory)),
+		MachineType: fmt.Sprintf("zones/%s/machineTypes/custom-%d-%d", zone, cores, int(memory)),
 		Tags:        &compute.Tags{Items: networkTags(m.context.orbID, m.context.providerID, poolName)},
 		NetworkInterfaces: []*compute.NetworkInterface{{
 			Network: m.context.networkURL,
@@ -145,17 +210,18 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 	monitor := m.context.monitor.WithFields(map[string]interface{}{
 		"machine": name,
 		"pool":    poolName,
+		"zone":    zone,
 	})
 
 	if err := operateFunc(
 		func() { monitor.Debug("Creating instance") },
-		computeOpCall(m.context.client.Instances.Insert(m.context.projectID, m.context.desired.Zone, createInstance).RequestId(uuid.NewV1().String()).Do),
+		computeOpCall(m.context.client.Instances.Insert(m.context.projectID, zone, createInstance).RequestId(uuid.NewV1().String()).Do),
 		func() error { monitor.Info("Instance created"); return nil },
 	)(); err != nil {
 		return nil, err
 	}
 
-	newInstance, err := m.context.client.Instances.Get(m.context.projectID, m.context.desired.Zone, createInstance.Name).
+	newInstance, err := m.context.client.Instances.Get(m.context.projectID, zone, createInstance.Name).
 		Fields("selfLink,networkInterfaces(networkIP)").
 		Do()
 	if err != nil {
@@ -164,7 +230,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 
 	var machine machine
 	if m.oneoff {
-		machine = newGCEMachine(m.context, monitor, createInstance.Name)
+		machine = newGCEMachine(m.context, monitor, createInstance.Name, zone)
 	} else {
 		sshMachine := ssh.NewMachine(monitor, "orbiter", newInstance.NetworkInterfaces[0].NetworkIP)
 		if err := sshMachine.UseKey([]byte(m.key.Private.Value)); err != nil {
@@ -180,9 +246,11 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 		newInstance.NetworkInterfaces[0].NetworkIP,
 		newInstance.SelfLink,
 		poolName,
+		zone,
 		m.removeMachineFunc(
 			poolName,
 			createInstance.Name,
+			zone,
 		),
 		false,
 		machine,
@@ -210,21 +278,53 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 		monitor.WithField("mountpoint", mountPoint).Info("Disk formatted")
 	}
 
+	m.cache.Lock()
 	if m.cache.instances != nil {
 		if _, ok := m.cache.instances[poolName]; !ok {
 			m.cache.instances[poolName] = make([]*instance, 0)
 		}
 		m.cache.instances[poolName] = append(m.cache.instances[poolName], infraMachine)
 	}
+	m.cache.Unlock()
 
 	if err := m.onCreate(poolName, infraMachine); err != nil {
 		return nil, err
 	}
 
-	monitor.Info("Machine created")
+	monitor.Changed("Machine created")
 	return infraMachine, nil
 }
 
+// reserveZone chooses the least used zone for a new machine, considering the machines that are created concurrently.
+// The zone stays reserved until release is called
+func (m *machinesService) reserveZone(pool string, machines []*instance) (string, func()) {
+	m.creating.Lock()
+	defer m.creating.Unlock()
+
+	if m.creating.zones == nil {
+		m.creating.zones = make(map[string][]string)
+	}
+
+	considered := append([]*instance{}, machines...)
+	for _, zone := range m.creating.zones[pool] {
+		considered = append(considered, &instance{zone: zone})
+	}
+	zone := leastUsedZone(m.context.desired.zones(pool), considered)
+	m.creating.zones[pool] = append(m.creating.zones[pool], zone)
+
+	return zone, func() {
+		m.creating.Lock()
+		defer m.creating.Unlock()
+		reserved := m.creating.zones[pool]
+		for idx := range reserved {
+			if reserved[idx] == zone {
+				m.creating.zones[pool] = append(reserved[:idx], reserved[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
 func (m *machinesService) ListPools() ([]string, error) {
 
 	pools, err := m.instances()
@@ -259,26 +359,33 @@ func (m *machinesService) instances() (map[string][]*instance, error) {
 		return m.cache.instances, nil
 	}
 
-	instances, err := m.context.client.Instances.
-		List(m.context.projectID, m.context.desired.Zone).
+	// Machines are listed in all zones, so machines in zones that are not configured anymore are found too
+	var items []*compute.Instance
+	if err := m.context.client.Instances.
+		AggregatedList(m.context.projectID).
 		Filter(fmt.Sprintf(`labels.orb=%s AND labels.provider=%s`, m.context.orbID, m.context.providerID)).
-		Fields("items(name,labels,selfLink,status,scheduling(preemptible),networkInterfaces(networkIP))").
-		Do()
-	if err != nil {
+		Fields("nextPageToken,items/*/instances(name,zone,labels,selfLink,status,scheduling(preemptible),networkInterfaces(networkIP))").
+		Pages(m.context.ctx, func(page *compute.InstanceAggregatedList) error {
+			for _, scoped := range page.Items {
+				items = append(items, scoped.Instances...)
+			}
+			return nil
+		}); err != nil {
 		return nil, err
 	}
 
 	m.cache.instances = make(map[string][]*instance)
-	for _, inst := range instances.Items {
+	for _, inst := range items {
 		if inst.Labels["orb"] != m.context.orbID || inst.Labels["provider"] != m.context.providerID {
 			continue
 		}
 
 		pool := inst.Labels["pool"]
+		zone := zoneOf(inst.Zone)
 
 		var machine machine
 		if m.oneoff {
-			machine = newGCEMachine(m.context, m.context.monitor.WithFields(toFields(inst.Labels)), inst.Name)
+			machine = newGCEMachine(m.context, m.context.monitor.WithFields(toFields(inst.Labels)), inst.Name, zone)
 		} else {
 			sshMachine := ssh.NewMachine(m.context.monitor.WithFields(toFields(inst.Labels)), "orbiter", inst.NetworkInterfaces[0].NetworkIP)
 			if err := sshMachine.UseKey([]byte(m.key.Private.Value)); err != nil {
@@ -322,7 +429,8 @@ func (m *machinesService) instances() (map[string][]*instance, error) {
 			inst.NetworkInterfaces[0].NetworkIP,
 			inst.SelfLink,
 			pool,
-			m.removeMachineFunc(pool, inst.Name),
+			zone,
+			m.removeMachineFunc(pool, inst.Name, zone),
 			inst.Status == "TERMINATED" && inst.Scheduling.Preemptible,
 			machine,
 			rebootRequired,
@@ -351,7 +459,7 @@ func toFields(labels map[string]string) map[string]interface{} {
 	return fields
 }
 
-func (m *machinesService) removeMachineFunc(pool, id string) func() error {
+func (m *machinesService) removeMachineFunc(pool, id, zone string) func() error {
 	return func() error {
 
 		m.cache.Lock()
@@ -368,10 +476,11 @@ func (m *machinesService) removeMachineFunc(pool, id string) func() error {
 			m.context.monitor.WithFields(map[string]interface{}{
 				"pool":    pool,
 				"machine": id,
+				"zone":    zone,
 			}),
 			"instance",
 			id,
-			m.context.client.Instances.Delete(m.context.projectID, m.context.desired.Zone, id).RequestId(uuid.NewV1().String()).Do,
+			m.context.client.Instances.Delete(m.context.projectID, zone, id).RequestId(uuid.NewV1().String()).Do,
 		)()
 	}
 }
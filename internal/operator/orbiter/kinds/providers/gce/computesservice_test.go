@@ -1,4 +1,4 @@
-package gce_test
+package gce
 
 import (
 	"bytes"
@@ -8,7 +8,6 @@ import (
 	"testing"
 
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
-	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/gce"
 	"github.com/caos/orbos/internal/secret"
 	"github.com/caos/orbos/internal/ssh"
 	"github.com/caos/orbos/mntr"
@@ -16,7 +15,12 @@ import (
 
 func TestComputeService(t *testing.T) {
 
-	pool := &gce.Pool{
+	jsonKey := os.Getenv("ORBOS_GCE_JSON_KEY")
+	if jsonKey == "" {
+		t.Skip("Environment variable ORBOS_GCE_JSON_KEY is empty")
+	}
+
+	pool := &Pool{
 		OSImage:     "projects/centos-cloud/global/images/centos-7-v20200429",
 		MinCPUCores: 2,
 		MinMemoryGB: 4,
@@ -28,25 +32,20 @@ func TestComputeService(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	jsonKey := os.Getenv("ORBOS_GCE_JSON_KEY")
-	t.Log(jsonKey)
-	if jsonKey == "" {
-		t.Fatal("Environment variable ORBOS_GCE_JSON_KEY is empty")
+	sshKey := &SSHKey{
+		Private: &secret.Secret{Value: private},
+		Public:  &secret.Secret{Value: public},
 	}
-
-	svc := gce.NewMachinesService(
+	ctx, err := buildContext(
 		mntr.Monitor{OnInfo: mntr.LogMessage},
-		&gce.Spec{
+		&Spec{
 			Verbose: false,
 			JSONKey: &secret.Secret{Value: jsonKey},
 
 			Region: "europe-west1",
 			Zone:   "europe-west1-b",
-			SSHKey: &gce.SSHKey{
-				Private: &secret.Secret{Value: private},
-				Public:  &secret.Secret{Value: public},
-			},
-			Pools: map[string]*gce.Pool{
+			SSHKey: sshKey,
+			Pools: map[string]*Pool{
 				"apool":       pool,
 				"anotherpool": pool,
 				"aThirdPool":  pool,
@@ -54,7 +53,16 @@ func TestComputeService(t *testing.T) {
 		},
 		"gce",
 		"orbiter-elio",
+		false,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := ctx.machinesService
+	if err := svc.use(sshKey); err != nil {
+		t.Fatal(err)
+	}
 
 	machine, err := svc.Create("apool")
 	if err != nil {
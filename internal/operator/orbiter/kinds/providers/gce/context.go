@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"net/http"
 
+	"github.com/caos/orbos/internal/operator/orbiter"
 	"github.com/caos/orbos/mntr"
 	"github.com/pkg/errors"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
 type context struct {
@@ -24,13 +27,24 @@ type context struct {
 	machinesService *machinesService
 	ctx             ctxpkg.Context
 	auth            *option.ClientOption
+	// plan records the planned changes if the query is planned
+	plan *orbiter.Plan
 }
 
 func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string, oneoff bool) (*context, error) {
 
 	jsonKey := []byte(desired.JSONKey.Value)
 	ctx := ctxpkg.Background()
-	opt := option.WithCredentialsJSON(jsonKey)
+	transport, err := htransport.NewTransport(
+		ctx,
+		monitor.RoundTripper(http.DefaultTransport),
+		option.WithCredentialsJSON(jsonKey),
+		option.WithScopes(compute.CloudPlatformScope),
+	)
+	if err != nil {
+		return nil, err
+	}
+	opt := option.WithHTTPClient(&http.Client{Transport: transport})
 	computeClient, err := compute.NewService(ctx, opt)
 	if err != nil {
 		return nil, err
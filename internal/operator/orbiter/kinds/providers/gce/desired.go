@@ -22,9 +22,12 @@ type Pool struct {
 	StorageDiskType string
 	Preemptible     bool
 	LocalSSDs       uint8
+	// Machines are spread evenly across these zones of the region.
+	// If empty, all machines are created in the specs zone
+	Zones []string `yaml:",omitempty"`
 }
 
-func (p Pool) validate() error {
+func (p Pool) validate(region string) error {
 
 	if p.MinCPUCores == 0 {
 		return errors.New("no cpu cores configured")
@@ -45,6 +48,17 @@ func (p Pool) validate() error {
 		return fmt.Errorf("DiskType \"%s\" is not supported", p.StorageDiskType)
 	}
 
+	seen := make(map[string]bool)
+	for _, zone := range p.Zones {
+		if !inRegion(zone, region) {
+			return fmt.Errorf("zone %s is not in region %s", zone, region)
+		}
+		if seen[zone] {
+			return fmt.Errorf("zone %s is configured multiple times", zone)
+		}
+		seen[zone] = true
+	}
+
 	return nil
 }
 
@@ -53,10 +67,22 @@ type SSHKey struct {
 	Public  *secret.Secret `yaml:",omitempty"`
 }
 
+// copy returns a copy of a complete key pair or nil, so the key survives being cleared for rotation
+func (s *SSHKey) copy() *SSHKey {
+	if s == nil || s.Private == nil || s.Public == nil || s.Private.Value == "" || s.Public.Value == "" {
+		return nil
+	}
+	return &SSHKey{
+		Private: &secret.Secret{Value: s.Private.Value},
+		Public:  &secret.Secret{Value: s.Public.Value},
+	}
+}
+
 type Spec struct {
-	Verbose             bool
-	JSONKey             *secret.Secret `yaml:",omitempty"`
-	Region              string
+	Verbose bool
+	JSONKey *secret.Secret `yaml:",omitempty"`
+	Region  string
+	// Machines of pools without zones are created in this zone
 	Zone                string
 	Pools               map[string]*Pool
 	SSHKey              *SSHKey
@@ -71,16 +97,19 @@ func (d Desired) validateAdapt() error {
 	if d.Spec.Region == "" {
 		return errors.New("no region configured")
 	}
-	if d.Spec.Zone == "" {
-		return errors.New("no zone configured")
+	if d.Spec.Zone != "" && !inRegion(d.Spec.Zone, d.Spec.Region) {
+		return fmt.Errorf("zone %s is not in region %s", d.Spec.Zone, d.Spec.Region)
 	}
 	if len(d.Spec.Pools) == 0 {
 		return errors.New("no pools configured")
 	}
 	for poolName, pool := range d.Spec.Pools {
-		if err := pool.validate(); err != nil {
+		if err := pool.validate(d.Spec.Region); err != nil {
 			return fmt.Errorf("configuring pool %s failed: %w", poolName, err)
 		}
+		if len(pool.Zones) == 0 && d.Spec.Zone == "" {
+			return fmt.Errorf("configuring pool %s failed: no zone configured", poolName)
+		}
 	}
 	return nil
 }
@@ -118,3 +147,13 @@ func parseDesiredV0(desiredTree *tree.Tree) (*Desired, error) {
 
 	return desiredKind, nil
 }
+
+// ValidateDesired checks the desired state without adapting it.
+// Secrets are not checked, as they are written using orbctl
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := parseDesiredV0(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.validateAdapt()
+}
@@ -74,7 +74,7 @@ func query(
 
 	queryNA, installNA := naFuncs(nodeAgentsCurrent)
 
-	desireNodeAgent := func(pool string, machine infra.Machine) error {
+	desireHealthchecks := func(pool string, machine infra.Machine) {
 
 		machineID := machine.ID()
 		machineMonitor := context.monitor.WithField("machine", machineID)
@@ -122,6 +122,10 @@ func query(
 				}
 			}
 		}
+	}
+
+	desireNodeAgent := func(pool string, machine infra.Machine) error {
+		desireHealthchecks(pool, machine)
 		running, err := queryNA(machine, orbiterCommit)
 		if err != nil {
 			return err
@@ -143,6 +147,23 @@ func query(
 		}
 		panic(fmt.Errorf("external address for %v is not ensured", vip))
 	})
+
+	if context.plan != nil {
+		// Planning doesn't ensure, so the node agent desires ensuring sets are computed now
+		if err := desireAll(context.machinesService, func(pool string, machine infra.Machine) error {
+			desireHealthchecks(pool, machine)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if _, err := wrappedMachines.InitializeDesiredNodeAgents(); err != nil {
+			return nil, err
+		}
+		if _, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, []string{"eth0"}); err != nil {
+			return nil, err
+		}
+	}
+
 	return func(pdf api.PushDesiredFunc) *orbiter.EnsureResult {
 
 		var done bool
@@ -150,29 +171,9 @@ func query(
 			func() error { return ensureIdentityAwareProxyAPIEnabled(context) },
 			func() error { return ensureNetwork(context, createFWs, deleteFWs) },
 			context.machinesService.restartPreemptibleMachines,
+			func() error { return rebalanceZones(context, pdf) },
 			ensureLB,
-			func() error {
-				pools, err := context.machinesService.ListPools()
-				if err != nil {
-					return err
-				}
-
-				var desireNodeAgents []func() error
-				for _, pool := range pools {
-					machines, listErr := context.machinesService.List(pool)
-					if listErr != nil {
-						err = helpers.Concat(err, listErr)
-					}
-					for _, machine := range machines {
-						desireNodeAgents = append(desireNodeAgents, func(p string, m infra.Machine) func() error {
-							return func() error {
-								return desireNodeAgent(p, m)
-							}
-						}(pool, machine))
-					}
-				}
-				return helpers.Fanout(desireNodeAgents)()
-			},
+			func() error { return desireAll(context.machinesService, desireNodeAgent) },
 			func() error {
 				var err error
 				lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
@@ -203,3 +204,27 @@ func externalPort(lb *normalizedLoadbalancer) uint16 {
 	}
 	return uint16(port)
 }
+
+// desireAll calls desire for all machines of all pools concurrently
+func desireAll(svc *machinesService, desire func(pool string, machine infra.Machine) error) error {
+	pools, err := svc.ListPools()
+	if err != nil {
+		return err
+	}
+
+	var desireNodeAgents []func() error
+	for _, pool := range pools {
+		machines, listErr := svc.List(pool)
+		if listErr != nil {
+			err = helpers.Concat(err, listErr)
+		}
+		for _, machine := range machines {
+			desireNodeAgents = append(desireNodeAgents, func(p string, m infra.Machine) func() error {
+				return func() error {
+					return desire(p, m)
+				}
+			}(pool, machine))
+		}
+	}
+	return helpers.Concat(err, helpers.Fanout(desireNodeAgents)())
+}
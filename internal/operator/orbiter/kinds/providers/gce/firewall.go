@@ -5,6 +5,8 @@ import (
 	"sort"
 
 	uuid "github.com/satori/go.uuid"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
 )
 
 var _ ensureFWFunc = queryFirewall
@@ -27,6 +29,7 @@ createLoop:
 				if gceFW.Allowed[0].Ports[0] != fw.gce.Allowed[0].Ports[0] ||
 					!stringsEqual(gceFW.TargetTags, fw.gce.TargetTags) ||
 					!stringsEqual(gceFW.SourceRanges, fw.gce.SourceRanges) {
+					context.plan.AddFirewall(orbiter.PlannedFirewall{Provider: context.providerID, Rule: fw.gce.Description, Action: "patch"})
 					ensure = append(ensure, operateFunc(
 						fw.log("Patching firewall", true),
 						computeOpCall(context.client.Firewalls.Patch(context.projectID, gceFW.Name, fw.gce).RequestId(uuid.NewV1().String()).Do),
@@ -37,6 +40,7 @@ createLoop:
 			}
 		}
 		fw.gce.Name = newName()
+		context.plan.AddFirewall(orbiter.PlannedFirewall{Provider: context.providerID, Rule: fw.gce.Description, Action: "create"})
 		ensure = append(ensure, operateFunc(
 			fw.log("Creating firewall", true),
 			computeOpCall(context.client.Firewalls.
@@ -55,6 +59,7 @@ removeLoop:
 				continue removeLoop
 			}
 		}
+		context.plan.AddFirewall(orbiter.PlannedFirewall{Provider: context.providerID, Rule: gceTp.Description, Action: "remove"})
 		remove = append(remove, removeResourceFunc(context.monitor, "firewall", gceTp.Name, context.client.Firewalls.
 			Delete(context.projectID, gceTp.Name).
 			RequestId(uuid.NewV1().String()).
@@ -16,13 +16,15 @@ import (
 type gceMachine struct {
 	mntr.Monitor
 	id      string
+	zone    string
 	context *context
 }
 
-func newGCEMachine(context *context, monitor mntr.Monitor, id string) machine {
+func newGCEMachine(context *context, monitor mntr.Monitor, id, zone string) machine {
 	return &gceMachine{
 		Monitor: monitor,
 		id:      id,
+		zone:    zone,
 		context: context,
 	}
 }
@@ -56,7 +58,7 @@ func (c *gceMachine) execute(stdin io.Reader, command string) (outBuf *bytes.Buf
 		cmd := exec.Command(gcloud,
 			"compute",
 			"ssh",
-			"--zone", c.context.desired.Zone,
+			"--zone", c.zone,
 			fmt.Sprintf("orbiter@%s", c.id),
 			"--tunnel-through-iap",
 			"--project", c.context.projectID,
@@ -88,7 +90,7 @@ func (c *gceMachine) Shell() error {
 		cmd := exec.Command(gcloud,
 			"compute",
 			"ssh",
-			"--zone", c.context.desired.Zone,
+			"--zone", c.zone,
 			fmt.Sprintf("orbiter@%s", c.id),
 			"--tunnel-through-iap",
 			"--project", c.context.projectID,
@@ -30,6 +30,7 @@ type instance struct {
 	ip      string
 	url     string
 	pool    string
+	zone    string
 	remove  func() error
 	context *context
 	start   bool
@@ -52,7 +53,8 @@ func newMachine(
 	id,
 	ip,
 	url,
-	pool string,
+	pool,
+	zone string,
 	remove func() error,
 	start bool,
 	machine machine,
@@ -68,6 +70,7 @@ func newMachine(
 		ip:                   ip,
 		url:                  url,
 		pool:                 pool,
+		zone:                 zone,
 		remove:               remove,
 		context:              context,
 		start:                start,
@@ -89,6 +92,10 @@ func (c *instance) IP() string {
 	return c.ip
 }
 
+func (c *instance) Zone() string {
+	return c.zone
+}
+
 func (c *instance) RebootRequired() (bool, func(), func()) {
 	return c.rebootRequired, c.requireReboot, c.unrequireReboot
 }
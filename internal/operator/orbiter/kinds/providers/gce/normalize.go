@@ -336,10 +336,10 @@ func removeResourceFunc(monitor mntr.Monitor, resource, id string, call func(...
 func queryLB(context *context, normalized []*normalizedLoadbalancer) (func() error, error) {
 	lb, err := chainInEnsureOrder(
 		context, normalized,
-		queryHealthchecks,
-		queryTargetPools,
-		queryAddresses,
-		queryForwardingRules,
+		planned("healthchecks", queryHealthchecks),
+		planned("targetpools", queryTargetPools),
+		planned("addresses", queryAddresses),
+		planned("forwardingrules", queryForwardingRules),
 	)
 
 	if err != nil {
@@ -358,6 +358,20 @@ func queryLB(context *context, normalized []*normalizedLoadbalancer) (func() err
 
 type ensureLBFunc func(*context, []*normalizedLoadbalancer) ([]func() error, []func() error, error)
 
+// planned records the number of resources query ensures and removes
+func planned(resource string, query ensureLBFunc) ensureLBFunc {
+	return func(ctx *context, lb []*normalizedLoadbalancer) ([]func() error, []func() error, error) {
+		ensure, remove, err := query(ctx, lb)
+		if len(ensure) > 0 {
+			ctx.plan.AddLoadBalancer(orbiter.PlannedLoadBalancer{Provider: ctx.providerID, Name: resource, Action: "ensure", Count: len(ensure)})
+		}
+		if len(remove) > 0 {
+			ctx.plan.AddLoadBalancer(orbiter.PlannedLoadBalancer{Provider: ctx.providerID, Name: resource, Action: "remove", Count: len(remove)})
+		}
+		return ensure, remove, err
+	}
+}
+
 type ensureFWFunc func(*context, []*firewall) ([]func() error, []func() error, error)
 
 func chainInEnsureOrder(ctx *context, lb []*normalizedLoadbalancer, query ...ensureLBFunc) ([]func() error, error) {
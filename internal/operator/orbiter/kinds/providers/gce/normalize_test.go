@@ -1,135 +1,95 @@
 package gce
 
 import (
-	"reflect"
 	"testing"
 
-	"google.golang.org/api/compute/v1"
-
 	"github.com/caos/orbos/internal/operator/orbiter"
 
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
 )
 
 func Test_normalize(t *testing.T) {
-	type args struct {
-		spec       map[string][]*dynamic.VIP
-		orbID      string
-		providerID string
-	}
-	tests := []struct {
-		name string
-		args args
-		want []*normalizedLoadbalancer
-	}{{
-		name: "It should normalize correctly",
-		args: args{
-			spec: map[string][]*dynamic.VIP{
-				"pool1": {{
-					Transport: []*dynamic.Transport{{
-						Name: "transport1",
-						Destinations: []*dynamic.Destination{{
-							HealthChecks: dynamic.HealthChecks{
-								Protocol: "http",
-								Path:     "/health",
-								Code:     200,
-							},
-							Port: 30000,
-							Pool: "target1",
-						}},
-						Whitelist: []*orbiter.CIDR{
-							cidrPtr("0.0.0.0/0"),
-						},
-					}},
-				}},
-				"pool2": {{
-					Transport: []*dynamic.Transport{{
-						Name: "transport2",
-						Destinations: []*dynamic.Destination{{
-							HealthChecks: dynamic.HealthChecks{
-								Protocol: "http",
-								Path:     "/health",
-								Code:     200,
-							},
-							Port: 30001,
-							Pool: "target2",
-						}},
-						Whitelist: []*orbiter.CIDR{
-							cidrPtr("0.0.0.0/0"),
-						},
-					}},
-				}, {
-					Transport: []*dynamic.Transport{{
-						Name: "transport3",
-						Destinations: []*dynamic.Destination{{
-							HealthChecks: dynamic.HealthChecks{
-								Protocol: "http",
-								Path:     "/health",
-								Code:     200,
-							},
-							Port: 30002,
-							Pool: "target3",
-						}},
-						Whitelist: []*orbiter.CIDR{
-							cidrPtr("0.0.0.0/0"),
-						},
-					}, {
-						Name: "transport4",
-						Destinations: []*dynamic.Destination{{
-							HealthChecks: dynamic.HealthChecks{
-								Protocol: "http",
-								Path:     "/health",
-								Code:     200,
-							},
-							Port: 30003,
-							Pool: "target4",
-						}, {
-							HealthChecks: dynamic.HealthChecks{
-								Protocol: "http",
-								Path:     "/health",
-								Code:     200,
-							},
-							Port: 30004,
-							Pool: "target5",
-						}},
-						Whitelist: []*orbiter.CIDR{
-							cidrPtr("0.0.0.0/0"),
-						},
-					}},
-				}},
+
+	transport := func(name string, frontendPort, backendPort dynamic.Port, pools ...string) *dynamic.Transport {
+		proxyProtocol := false
+		return &dynamic.Transport{
+			Name:         name,
+			FrontendPort: frontendPort,
+			BackendPort:  backendPort,
+			BackendPools: pools,
+			HealthChecks: dynamic.HealthChecks{
+				Protocol: "http",
+				Path:     "/health",
+				Code:     200,
 			},
-			orbID:      "dummyorb",
-			providerID: "dummyprovider",
-		},
-		want: []*normalizedLoadbalancer{{
-			address: &address(&compute.Address{
-				Description: "orb=dummyorb;provider=dummyprovider",
-			}),
-			ip:             "10.0.0.10",
-			forwardingRule: &forwardingRule{},
-			targetPools:    []*targetPool{},
-		}, {
-			ip:             "10.0.0.11",
-			forwardingRule: &forwardingRule{},
-			targetPools:    []*targetPool{},
+			Whitelist:     []*orbiter.CIDR{cidrPtr("0.0.0.0/0")},
+			ProxyProtocol: &proxyProtocol,
+		}
+	}
+
+	spec := map[string][]*dynamic.VIP{
+		"pool1": {{
+			Transport: []*dynamic.Transport{transport("transport1", 80, 30000, "target1")},
+		}},
+		"pool2": {{
+			Transport: []*dynamic.Transport{transport("transport2", 443, 30001, "target2")},
 		}, {
-			ip:             "10.0.0.12",
-			forwardingRule: &forwardingRule{},
-			targetPools:    []*targetPool{},
+			Transport: []*dynamic.Transport{
+				transport("transport3", 8080, 30002, "target3"),
+				transport("transport4", 8443, 30003, "target4", "target5"),
+			},
 		}},
-	},
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := normalize(tt.args.spec, tt.args.orbID, tt.args.providerID); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("normalize() = %v, want %v", got, tt.want)
-			}
-		})
+
+	lbs, firewalls := normalize(&context{orbID: "dummyorb", providerID: "dummyprovider"}, spec)
+
+	type want struct {
+		description string
+		portRange   string
+		backendPort uint16
+		hcPort      int64
+		pools       []string
+	}
+	wants := []want{
+		{"orb=dummyorb;provider=dummyprovider;transport=transport1", "80-80", 30000, 6700, []string{"target1"}},
+		{"orb=dummyorb;provider=dummyprovider;transport=transport2", "443-443", 30001, 6701, []string{"target2"}},
+		{"orb=dummyorb;provider=dummyprovider;transport=transport3", "8080-8080", 30002, 6702, []string{"target3"}},
+		{"orb=dummyorb;provider=dummyprovider;transport=transport4", "8443-8443", 30003, 6703, []string{"target4", "target5"}},
+	}
+
+	if len(lbs) != len(wants) {
+		t.Fatalf("normalize() returned %d loadbalancers, want %d", len(lbs), len(wants))
+	}
+	for idx, want := range wants {
+		lb := lbs[idx]
+		if lb.forwardingRule.gce.Description != want.description {
+			t.Errorf("loadbalancer %d has forwarding rule %s, want %s", idx, lb.forwardingRule.gce.Description, want.description)
+		}
+		if lb.forwardingRule.gce.PortRange != want.portRange {
+			t.Errorf("loadbalancer %d has port range %s, want %s", idx, lb.forwardingRule.gce.PortRange, want.portRange)
+		}
+		if lb.backendPort != want.backendPort {
+			t.Errorf("loadbalancer %d has backend port %d, want %d", idx, lb.backendPort, want.backendPort)
+		}
+		if lb.healthcheck.gce.Port != want.hcPort {
+			t.Errorf("loadbalancer %d has health check port %d, want %d", idx, lb.healthcheck.gce.Port, want.hcPort)
+		}
+		if len(lb.targetPool.destPools) != len(want.pools) {
+			t.Errorf("loadbalancer %d targets pools %v, want %v", idx, lb.targetPool.destPools, want.pools)
+		}
 	}
-}
 
-func strPtr(str string) *string {
-	return &str
+	if lbs[2].address != lbs[3].address {
+		t.Error("expected transports of the same ip to share their address")
+	}
+	if addresses := normalizedLoadbalancing(lbs).uniqueAddresses(); len(addresses) != 3 {
+		t.Errorf("expected 3 unique addresses, got %d", len(addresses))
+	}
+
+	// An external and a health check firewall per transport, internal communication and SSH
+	if len(firewalls) != 2*len(wants)+2 {
+		t.Errorf("normalize() returned %d firewalls, want %d", len(firewalls), 2*len(wants)+2)
+	}
 }
 
 func cidrPtr(str string) *orbiter.CIDR {
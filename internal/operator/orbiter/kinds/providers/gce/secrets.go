@@ -9,7 +9,21 @@ func getSecretsMap(desiredKind *Desired) map[string]*secret.Secret {
 		desiredKind.Spec.JSONKey = &secret.Secret{}
 	}
 
+	if desiredKind.Spec.SSHKey == nil {
+		desiredKind.Spec.SSHKey = &SSHKey{}
+	}
+
+	if desiredKind.Spec.SSHKey.Public == nil {
+		desiredKind.Spec.SSHKey.Public = &secret.Secret{}
+	}
+
+	if desiredKind.Spec.SSHKey.Private == nil {
+		desiredKind.Spec.SSHKey.Private = &secret.Secret{}
+	}
+
 	return map[string]*secret.Secret{
-		"jsonkey": desiredKind.Spec.JSONKey,
+		"jsonkey":       desiredKind.Spec.JSONKey,
+		"sshkeyprivate": desiredKind.Spec.SSHKey.Private,
+		"sshkeypublic":  desiredKind.Spec.SSHKey.Public,
 	}
 }
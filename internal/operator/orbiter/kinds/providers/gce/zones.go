@@ -0,0 +1,136 @@
+package gce
+
+import (
+	"path"
+	"strings"
+
+	"github.com/caos/orbos/internal/api"
+)
+
+func inRegion(zone, region string) bool {
+	return strings.HasPrefix(zone, region+"-")
+}
+
+// zoneOf extracts the zone name from a zone URL like https://www.googleapis.com/compute/v1/projects/my-project/zones/europe-west6-b
+func zoneOf(zoneURL string) string {
+	return path.Base(zoneURL)
+}
+
+// zones returns the zones the pools machines are spread across
+func (s *Spec) zones(pool string) []string {
+	if desired, ok := s.Pools[pool]; ok && len(desired.Zones) > 0 {
+		return desired.Zones
+	}
+	return []string{s.Zone}
+}
+
+// zoneUsage counts the machines per configured zone. Machines that are going to be replaced are not counted,
+// so their replacements fill the least used zones
+func zoneUsage(zones []string, machines []*instance) map[string][]*instance {
+	usage := make(map[string][]*instance)
+	for _, zone := range zones {
+		usage[zone] = nil
+	}
+	for _, machine := range machines {
+		if machine.replacementRequired {
+			continue
+		}
+		usage[machine.zone] = append(usage[machine.zone], machine)
+	}
+	return usage
+}
+
+// leastUsedZone returns the configured zone with the fewest machines.
+// Ties are resolved by the order the zones are configured in
+func leastUsedZone(zones []string, machines []*instance) string {
+	usage := zoneUsage(zones, machines)
+	least := zones[0]
+	for _, zone := range zones[1:] {
+		if len(usage[zone]) < len(usage[least]) {
+			least = zone
+		}
+	}
+	return least
+}
+
+// rebalanceCandidate returns a machine that should be replaced in order to spread the pools machines evenly.
+// Machines in zones that are not configured anymore are returned first.
+// Nil is returned if the machines are balanced or if a replacement is already pending
+func rebalanceCandidate(zones []string, machines []*instance) *instance {
+
+	for _, machine := range machines {
+		if machine.replacementRequired {
+			return nil
+		}
+	}
+
+	usage := zoneUsage(zones, machines)
+
+	configured := make(map[string]bool)
+	for _, zone := range zones {
+		configured[zone] = true
+	}
+	for zone, zoneMachines := range usage {
+		if !configured[zone] && len(zoneMachines) > 0 {
+			return firstByID(zoneMachines)
+		}
+	}
+
+	most, least := zones[0], zones[0]
+	for _, zone := range zones[1:] {
+		if len(usage[zone]) > len(usage[most]) {
+			most = zone
+		}
+		if len(usage[zone]) < len(usage[least]) {
+			least = zone
+		}
+	}
+	if len(usage[most])-len(usage[least]) <= 1 {
+		return nil
+	}
+	return firstByID(usage[most])
+}
+
+// firstByID returns the machine with the lowest id, so the same machine is chosen in each iteration
+func firstByID(machines []*instance) *instance {
+	chosen := machines[0]
+	for _, machine := range machines[1:] {
+		if machine.ID() < chosen.ID() {
+			chosen = machine
+		}
+	}
+	return chosen
+}
+
+// rebalanceZones requires replacing a machine of each pool whose machines are not spread evenly across its zones.
+// The kubernetes cluster replaces it respecting the pools disruption budget and maintenance windows
+// and the replacement is created in the least used zone
+func rebalanceZones(context *context, pdf api.PushDesiredFunc) error {
+	pools, err := context.machinesService.instances()
+	if err != nil {
+		return err
+	}
+
+	var rebalanced bool
+	for pool, machines := range pools {
+		if _, ok := context.desired.Pools[pool]; !ok {
+			continue
+		}
+		candidate := rebalanceCandidate(context.desired.zones(pool), machines)
+		if candidate == nil {
+			continue
+		}
+		candidate.requireReplacement()
+		context.monitor.WithFields(map[string]interface{}{
+			"pool":    pool,
+			"machine": candidate.ID(),
+			"zone":    candidate.zone,
+		}).Changed("Machine replacement required for spreading the pool across its zones")
+		rebalanced = true
+	}
+
+	if !rebalanced {
+		return nil
+	}
+	return pdf(context.monitor.WithField("reason", "rebalance zones"))
+}
@@ -0,0 +1,195 @@
+package gce
+
+import (
+	"reflect"
+	"testing"
+)
+
+func zonedInstance(id, zone string) *instance {
+	return &instance{X_ID: id, zone: zone}
+}
+
+func replacedInstance(id, zone string) *instance {
+	replaced := zonedInstance(id, zone)
+	replaced.replacementRequired = true
+	return replaced
+}
+
+func Test_inRegion(t *testing.T) {
+	tests := []struct {
+		zone   string
+		region string
+		want   bool
+	}{
+		{zone: "europe-west6-a", region: "europe-west6", want: true},
+		{zone: "europe-west6-c", region: "europe-west6", want: true},
+		{zone: "europe-west6-a", region: "europe-west", want: false},
+		{zone: "europe-west1-b", region: "europe-west6", want: false},
+		{zone: "us-east1-b", region: "europe-west6", want: false},
+		{zone: "europe-west6", region: "europe-west6", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone+" in "+tt.region, func(t *testing.T) {
+			if got := inRegion(tt.zone, tt.region); got != tt.want {
+				t.Errorf("inRegion() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_zoneUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		zones    []string
+		machines []*instance
+		want     map[string][]string
+	}{{
+		name:  "It should list configured zones without machines",
+		zones: []string{"a", "b"},
+		want:  map[string][]string{"a": nil, "b": nil},
+	}, {
+		name:     "It should count the machines per zone",
+		zones:    []string{"a", "b"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "b"), zonedInstance("3", "a")},
+		want:     map[string][]string{"a": {"1", "3"}, "b": {"2"}},
+	}, {
+		name:     "It should count machines in zones that are not configured anymore",
+		zones:    []string{"a"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "removed")},
+		want:     map[string][]string{"a": {"1"}, "removed": {"2"}},
+	}, {
+		name:     "It should not count machines that are going to be replaced",
+		zones:    []string{"a", "b"},
+		machines: []*instance{replacedInstance("1", "a"), zonedInstance("2", "b")},
+		want:     map[string][]string{"a": nil, "b": {"2"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string][]string)
+			for zone, machines := range zoneUsage(tt.zones, tt.machines) {
+				var ids []string
+				for _, machine := range machines {
+					ids = append(ids, machine.ID())
+				}
+				got[zone] = ids
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("zoneUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_leastUsedZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		zones    []string
+		machines []*instance
+		want     string
+	}{{
+		name:  "It should return the first zone without machines",
+		zones: []string{"a", "b", "c"},
+		want:  "a",
+	}, {
+		name:     "It should return the zone with the fewest machines",
+		zones:    []string{"a", "b", "c"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "b"), zonedInstance("3", "a"), zonedInstance("4", "c"), zonedInstance("5", "c")},
+		want:     "b",
+	}, {
+		name:     "It should resolve ties by the configured order",
+		zones:    []string{"c", "b", "a"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "c")},
+		want:     "b",
+	}, {
+		name:     "It should resolve ties between used zones by the configured order",
+		zones:    []string{"c", "b", "a"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "b"), zonedInstance("3", "c")},
+		want:     "c",
+	}, {
+		name:     "It should not count machines that are going to be replaced",
+		zones:    []string{"a", "b"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "b"), replacedInstance("3", "b"), zonedInstance("4", "b")},
+		want:     "a",
+	}, {
+		name:     "It should ignore machines in zones that are not configured anymore",
+		zones:    []string{"a", "b"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "removed"), zonedInstance("3", "removed")},
+		want:     "b",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leastUsedZone(tt.zones, tt.machines); got != tt.want {
+				t.Errorf("leastUsedZone() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rebalanceCandidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		zones    []string
+		machines []*instance
+		want     string
+	}{{
+		name:  "It should not rebalance pools without machines",
+		zones: []string{"a", "b"},
+		want:  "",
+	}, {
+		name:     "It should not rebalance a single zone",
+		zones:    []string{"a"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "a"), zonedInstance("3", "a")},
+		want:     "",
+	}, {
+		name:     "It should not rebalance balanced zones",
+		zones:    []string{"a", "b", "c"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "b"), zonedInstance("3", "c")},
+		want:     "",
+	}, {
+		name:     "It should tolerate an imbalance of one machine",
+		zones:    []string{"a", "b", "c"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "a"), zonedInstance("3", "b"), zonedInstance("4", "c")},
+		want:     "",
+	}, {
+		name:     "It should count empty zones as least used",
+		zones:    []string{"a", "b", "c"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "a"), zonedInstance("3", "b")},
+		want:     "1",
+	}, {
+		name:     "It should rebalance an imbalance of two machines",
+		zones:    []string{"a", "b"},
+		machines: []*instance{zonedInstance("3", "a"), zonedInstance("2", "a"), zonedInstance("1", "b"), zonedInstance("4", "a")},
+		want:     "2",
+	}, {
+		name:     "It should replace a machine of the first most used zone",
+		zones:    []string{"a", "b", "c"},
+		machines: []*instance{zonedInstance("1", "b"), zonedInstance("2", "b"), zonedInstance("3", "c"), zonedInstance("4", "c")},
+		want:     "1",
+	}, {
+		name:     "It should replace machines in zones that are not configured anymore first",
+		zones:    []string{"a", "b"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("3", "removed"), zonedInstance("2", "removed")},
+		want:     "2",
+	}, {
+		name:     "It should not rebalance while a replacement is pending",
+		zones:    []string{"a", "b"},
+		machines: []*instance{zonedInstance("1", "a"), zonedInstance("2", "a"), zonedInstance("3", "a"), replacedInstance("4", "a")},
+		want:     "",
+	}, {
+		name:     "It should not rebalance machines in removed zones while a replacement is pending",
+		zones:    []string{"a"},
+		machines: []*instance{zonedInstance("1", "removed"), replacedInstance("2", "a")},
+		want:     "",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if candidate := rebalanceCandidate(tt.zones, tt.machines); candidate != nil {
+				got = candidate.ID()
+			}
+			if got != tt.want {
+				t.Errorf("rebalanceCandidate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
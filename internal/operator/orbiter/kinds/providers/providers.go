@@ -4,7 +4,9 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/container"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/cs"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ec2"
 
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
 
@@ -26,7 +28,7 @@ func GetQueryAndDestroyFuncs(
 	providerCurrent *tree.Tree,
 	whitelistChan chan []*orbiter.CIDR,
 	finishedChan chan struct{},
-	orbiterCommit, repoURL, repoKey string,
+	orbiterCommit, repoURL, repoKey, knownHosts, ingestion string,
 	oneoff bool,
 ) (
 	orbiter.QueryFunc,
@@ -51,7 +53,7 @@ func GetQueryAndDestroyFuncs(
 			provID,
 			orbID(repoURL),
 			wlFunc,
-			orbiterCommit, repoURL, repoKey,
+			orbiterCommit, repoURL, repoKey, knownHosts, ingestion,
 			oneoff,
 		)(
 			monitor,
@@ -64,7 +66,7 @@ func GetQueryAndDestroyFuncs(
 			provID,
 			orbID(repoURL),
 			wlFunc,
-			orbiterCommit, repoURL, repoKey,
+			orbiterCommit, repoURL, repoKey, knownHosts, ingestion,
 			oneoff,
 		)(
 			monitor,
@@ -72,19 +74,44 @@ func GetQueryAndDestroyFuncs(
 			providerTree,
 			providerCurrent,
 		)
+	case "orbiter.caos.ch/EC2Provider":
+		return ec2.AdaptFunc(
+			provID,
+			orbID(repoURL),
+			wlFunc,
+			orbiterCommit, repoURL, repoKey, knownHosts, ingestion,
+			oneoff,
+		)(
+			monitor,
+			finishedChan,
+			providerTree,
+			providerCurrent,
+		)
+	case "orbiter.caos.ch/ContainerProvider":
+		return container.AdaptFunc(
+			provID,
+			orbID(repoURL),
+			wlFunc,
+			orbiterCommit, repoURL, repoKey, knownHosts, ingestion,
+		)(
+			monitor,
+			finishedChan,
+			providerTree,
+			providerCurrent,
+		)
 	case "orbiter.caos.ch/StaticProvider":
 		adaptFunc := func() (orbiter.QueryFunc, orbiter.DestroyFunc, orbiter.ConfigureFunc, bool, map[string]*secret.Secret, error) {
 			return static.AdaptFunc(
 				provID,
 				wlFunc,
-				orbiterCommit, repoURL, repoKey,
+				orbiterCommit, repoURL, repoKey, knownHosts, ingestion,
 			)(
 				monitor.WithFields(map[string]interface{}{"provider": provID}),
 				finishedChan,
 				providerTree,
 				providerCurrent)
 		}
-		return orbiter.AdaptFuncGoroutine(adaptFunc)
+		return orbiter.AdaptFuncGoroutine(monitor, adaptFunc)
 	default:
 		return nil, nil, nil, false, nil, errors.Errorf("unknown provider kind %s", providerTree.Common.Kind)
 	}
@@ -115,6 +142,20 @@ func ListMachines(
 			orbID(repoURL),
 			provID,
 		)
+	case "orbiter.caos.ch/EC2Provider":
+		return ec2.ListMachines(
+			monitor,
+			providerTree,
+			orbID(repoURL),
+			provID,
+		)
+	case "orbiter.caos.ch/ContainerProvider":
+		return container.ListMachines(
+			monitor,
+			providerTree,
+			orbID(repoURL),
+			provID,
+		)
 	case "orbiter.caos.ch/StaticProvider":
 		return static.ListMachines(
 			monitor,
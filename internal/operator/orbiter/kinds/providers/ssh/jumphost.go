@@ -0,0 +1,82 @@
+package ssh
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	sshlib "golang.org/x/crypto/ssh"
+
+	"github.com/caos/orbos/internal/ssh"
+)
+
+// JumpHost is a bastion host, connections to machines hop through
+type JumpHost struct {
+	address string
+	sshCfg  *sshlib.ClientConfig
+}
+
+func NewJumpHost(remoteUser, host string, port uint16, keys ...[]byte) (*JumpHost, error) {
+	if port == 0 {
+		port = 22
+	}
+
+	publicKeys, err := ssh.AuthMethodFromKeys(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JumpHost{
+		address: net.JoinHostPort(host, strconv.Itoa(int(port))),
+		sshCfg: &sshlib.ClientConfig{
+			User:            remoteUser,
+			Auth:            []sshlib.AuthMethod{publicKeys},
+			HostKeyCallback: sshlib.InsecureIgnoreHostKey(),
+		},
+	}, nil
+}
+
+// dial connects to the address by hopping through all jump hosts.
+// The returned func closes the connections to the jump hosts
+func dial(address string, cfg *sshlib.ClientConfig, jumpHosts []*JumpHost) (*sshlib.Client, func(), error) {
+
+	var clients []*sshlib.Client
+	closeClients := func() {
+		for i := len(clients) - 1; i >= 0; i-- {
+			clients[i].Close()
+		}
+	}
+
+	hop := func(address string, cfg *sshlib.ClientConfig) (*sshlib.Client, error) {
+		if len(clients) == 0 {
+			return sshlib.Dial("tcp", address, cfg)
+		}
+
+		conn, err := clients[len(clients)-1].Dial("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		clientConn, chans, reqs, err := sshlib.NewClientConn(conn, address, cfg)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return sshlib.NewClient(clientConn, chans, reqs), nil
+	}
+
+	for _, jumpHost := range jumpHosts {
+		client, err := hop(jumpHost.address, jumpHost.sshCfg)
+		if err != nil {
+			closeClients()
+			return nil, func() {}, errors.Wrapf(err, "connecting to jump host %s failed", jumpHost.address)
+		}
+		clients = append(clients, client)
+	}
+
+	client, err := hop(address, cfg)
+	if err != nil {
+		closeClients()
+		return nil, func() {}, err
+	}
+	return client, closeClients, nil
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/caos/orbos/internal/ssh"
 
@@ -13,13 +15,18 @@ import (
 	"github.com/pkg/errors"
 
 	sshlib "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 type Machine struct {
-	monitor    mntr.Monitor
-	remoteUser string
-	ip         string
-	sshCfg     *sshlib.ClientConfig
+	monitor      mntr.Monitor
+	remoteUser   string
+	ip           string
+	port         uint16
+	jumpHosts    []*JumpHost
+	forwardAgent bool
+	keys         [][]byte
+	sshCfg       *sshlib.ClientConfig
 }
 
 func NewMachine(monitor mntr.Monitor, remoteUser, ip string) *Machine {
@@ -29,21 +36,41 @@ func NewMachine(monitor mntr.Monitor, remoteUser, ip string) *Machine {
 			"host": ip,
 			"user": remoteUser,
 		}),
-		ip: ip,
+		ip:   ip,
+		port: 22,
 	}
 }
 
+// UsePort overrides the default ssh port 22
+func (c *Machine) UsePort(port uint16) {
+	if port != 0 {
+		c.port = port
+	}
+}
+
+// UseJumpHosts lets connections hop through the passed jump hosts in the passed order
+func (c *Machine) UseJumpHosts(jumpHosts ...*JumpHost) {
+	c.jumpHosts = jumpHosts
+}
+
+// ForwardAgent forwards an ssh agent to the machine.
+// If SSH_AUTH_SOCK is set, the local agent is forwarded, otherwise an agent holding the machines keys
+func (c *Machine) ForwardAgent(forward bool) {
+	c.forwardAgent = forward
+}
+
 func (c *Machine) Execute(stdin io.Reader, cmd string) (stdout []byte, err error) {
 
-	monitor := c.monitor.WithFields(map[string]interface{}{
+	monitor, span := c.monitor.WithFields(map[string]interface{}{
 		"command": cmd,
-	})
+	}).StartSpan("ssh.execute")
 	defer func() {
 		if err != nil {
 			err = fmt.Errorf("executing %s failed: %w", cmd, err)
 		} else {
 			monitor.WithField("stdout", string(stdout)).Debug("Done executing command with ssh")
 		}
+		span.End(err)
 	}()
 
 	monitor.Debug("Trying to execute with ssh")
@@ -170,24 +197,63 @@ func (c *Machine) open() (sess *sshlib.Session, close func() error, err error) {
 		return nil, close, errors.New("no ssh key passed via infra.Machine.UseKey")
 	}
 
-	address := fmt.Sprintf("%s:%d", c.ip, 22)
-	conn, err := sshlib.Dial("tcp", address, c.sshCfg)
+	address := net.JoinHostPort(c.ip, strconv.Itoa(int(c.port)))
+	conn, closeJumps, err := dial(address, c.sshCfg, c.jumpHosts)
 	if err != nil {
 		return nil, close, errors.Wrapf(err, "dialling tcp %s with user %s failed", address, c.remoteUser)
 	}
+	closeConn := func() error {
+		err := conn.Close()
+		closeJumps()
+		return err
+	}
+
+	if c.forwardAgent {
+		if err := c.forwardAgentTo(conn); err != nil {
+			closeConn()
+			return nil, close, err
+		}
+	}
 
 	sess, err = conn.NewSession()
 	if err != nil {
-		conn.Close()
+		closeConn()
 		return sess, close, err
 	}
+
+	if c.forwardAgent {
+		if err := agent.RequestAgentForwarding(sess); err != nil {
+			sess.Close()
+			closeConn()
+			return nil, close, errors.Wrap(err, "requesting agent forwarding failed")
+		}
+	}
+
 	return sess, func() error {
 		err := sess.Close()
-		err = conn.Close()
+		err = closeConn()
 		return err
 	}, nil
 }
 
+func (c *Machine) forwardAgentTo(conn *sshlib.Client) error {
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		return errors.Wrap(agent.ForwardToRemote(conn, sock), "forwarding local ssh agent failed")
+	}
+
+	keyring := agent.NewKeyring()
+	for _, key := range c.keys {
+		rawKey, err := sshlib.ParseRawPrivateKey(key)
+		if err != nil {
+			return errors.Wrap(err, "parsing private key failed")
+		}
+		if err := keyring.Add(agent.AddedKey{PrivateKey: rawKey}); err != nil {
+			return errors.Wrap(err, "adding private key to ssh agent failed")
+		}
+	}
+	return errors.Wrap(agent.ForwardToAgent(conn, keyring), "forwarding ssh agent failed")
+}
+
 func (c *Machine) UseKey(keys ...[]byte) error {
 
 	publicKeys, err := ssh.AuthMethodFromKeys(keys...)
@@ -195,6 +261,7 @@ func (c *Machine) UseKey(keys ...[]byte) error {
 		return err
 	}
 
+	c.keys = keys
 	c.sshCfg = &sshlib.ClientConfig{
 		User:            c.remoteUser,
 		Auth:            []sshlib.AuthMethod{publicKeys},
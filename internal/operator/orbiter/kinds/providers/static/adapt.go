@@ -15,7 +15,7 @@ import (
 	"github.com/caos/orbos/mntr"
 )
 
-func AdaptFunc(id string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoURL, repoKey string) orbiter.AdaptFunc {
+func AdaptFunc(id string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoURL, repoKey, knownHosts, ingestion string) orbiter.AdaptFunc {
 	return func(monitor mntr.Monitor, finishedChan chan struct{}, desiredTree *tree.Tree, currentTree *tree.Tree) (queryFunc orbiter.QueryFunc, destroyFunc orbiter.DestroyFunc, configureFunc orbiter.ConfigureFunc, migrate bool, secrets map[string]*secret.Secret, err error) {
 		defer func() {
 			err = errors.Wrapf(err, "building %s failed", desiredTree.Common.Kind)
@@ -62,7 +62,15 @@ func AdaptFunc(id string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoUR
 		currentTree.Parsed = current
 
 		svc := NewMachinesService(monitor, desiredKind, id)
-		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, _ map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
+
+		// The machines stay reachable with the current keys, even if they are cleared for being rotated
+		currentKeys := privateKeys(desiredKind.Spec)
+		var currentMaintenanceKey, revokeMaintenanceKey string
+		if desiredKind.Spec.Keys != nil && desiredKind.Spec.Keys.MaintenanceKeyPublic != nil {
+			currentMaintenanceKey = desiredKind.Spec.Keys.MaintenanceKeyPublic.Value
+		}
+
+		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
 				defer func() {
 					err = errors.Wrapf(err, "querying %s failed", desiredKind.Common.Kind)
 				}()
@@ -75,7 +83,7 @@ func AdaptFunc(id string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoUR
 					return lbQuery(nodeAgentsCurrent, nodeAgentsDesired, nil)
 				}
 
-				if _, err := orbiter.QueryFuncGoroutine(lbQueryFunc); err != nil {
+				if _, err := orbiter.QueryFuncGoroutine(monitor, lbQueryFunc); err != nil {
 					return nil, err
 				}
 
@@ -84,10 +92,10 @@ func AdaptFunc(id string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoUR
 				}
 
 				queryFunc := func() (orbiter.EnsureFunc, error) {
-					_, iterateNA := core.NodeAgentFuncs(monitor, repoURL, repoKey)
-					return query(desiredKind, current, nodeAgentsDesired, nodeAgentsCurrent, lbCurrent.Parsed, monitor, svc, iterateNA, orbiterCommit)
+					_, iterateNA := core.NodeAgentFuncs(monitor, repoURL, repoKey, knownHosts, ingestion)
+					return query(desiredKind, current, nodeAgentsDesired, nodeAgentsCurrent, lbCurrent.Parsed, monitor, svc, iterateNA, orbiterCommit, orbiter.PlanOf(queried))
 				}
-				return orbiter.QueryFuncGoroutine(queryFunc)
+				return orbiter.QueryFuncGoroutine(monitor, queryFunc)
 			}, func() error {
 				if err := lbDestroy(); err != nil {
 					return err
@@ -116,7 +124,17 @@ func AdaptFunc(id string, whitelist dynamic.WhiteListFunc, orbiterCommit, repoUR
 					}
 					desiredKind.Spec.Keys.MaintenanceKeyPrivate = &secret.Secret{Value: priv}
 					desiredKind.Spec.Keys.MaintenanceKeyPublic = &secret.Secret{Value: pub}
-					return nil
+					// Machines which are already in use have to authorize the new key when it is rotated.
+					// The rotated key stays authorized until the new key is pushed and the orb is configured again
+					revokeMaintenanceKey = currentMaintenanceKey
+					return svc.authorizeMaintenanceKey(currentKeys)
+				}
+
+				if revokeMaintenanceKey != "" && revokeMaintenanceKey != desiredKind.Spec.Keys.MaintenanceKeyPublic.Value {
+					if err := svc.revokeMaintenanceKey(revokeMaintenanceKey); err != nil {
+						return err
+					}
+					revokeMaintenanceKey = ""
 				}
 
 				return core.ConfigureNodeAgents(svc, monitor, orb)
@@ -11,6 +11,7 @@ import (
 
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ssh"
 	"github.com/caos/orbos/mntr"
 )
 
@@ -46,13 +47,90 @@ func (c *machinesService) updateKeys() error {
 
 	keys := privateKeys(c.desired.Spec)
 
+	jumpHosts, err := c.jumpHosts(keys)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		machines, err := c.cachedPool(pool)
+		if err != nil {
+			return err
+		}
+		for _, machine := range machines {
+			if err := machine.useSSH(keys, jumpHosts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jumpHosts builds the configured jump hosts. Jump hosts without an own key use the passed keys
+func (c *machinesService) jumpHosts(keys [][]byte) (map[string]*ssh.JumpHost, error) {
+	jumpHosts := make(map[string]*ssh.JumpHost)
+	for name, desired := range c.desired.Spec.JumpHosts {
+		jumpHostKeys := keys
+		if desired.PrivateKey != nil && desired.PrivateKey.Value != "" {
+			jumpHostKeys = [][]byte{[]byte(desired.PrivateKey.Value)}
+		}
+
+		user := desired.User
+		if user == "" {
+			user = "orbiter"
+		}
+
+		jumpHost, err := ssh.NewJumpHost(user, desired.Host, desired.Port, jumpHostKeys...)
+		if err != nil {
+			return nil, fmt.Errorf("configuring jump host %s failed: %w", name, err)
+		}
+		jumpHosts[name] = jumpHost
+	}
+	return jumpHosts, nil
+}
+
+// authorizeMaintenanceKey additionally authorizes the maintenance key on all active machines.
+// The machines are connected with the passed keys, as the maintenance key is not authorized yet
+func (c *machinesService) authorizeMaintenanceKey(keys [][]byte) error {
+
+	keys = append(keys, privateKeys(c.desired.Spec)...)
+	jumpHosts, err := c.jumpHosts(keys)
+	if err != nil {
+		return err
+	}
+
+	return c.eachActive(func(machine *machine) error {
+		if err := machine.useSSH(keys, jumpHosts); err != nil {
+			return err
+		}
+		return core.AuthorizeKey(machine, machine.authorizedKeysPath(), c.desired.Spec.Keys.MaintenanceKeyPublic.Value)
+	})
+}
+
+// revokeMaintenanceKey removes a rotated maintenance key from all active machines
+func (c *machinesService) revokeMaintenanceKey(publicKey string) error {
+	return c.eachActive(func(machine *machine) error {
+		return core.RevokeKey(machine, machine.authorizedKeysPath(), publicKey)
+	})
+}
+
+func (c *machinesService) eachActive(do func(machine *machine) error) error {
+
+	pools, err := c.ListPools()
+	if err != nil {
+		return err
+	}
+
 	for _, pool := range pools {
 		machines, err := c.cachedPool(pool)
 		if err != nil {
 			return err
 		}
 		for _, machine := range machines {
-			if err := machine.UseKey(keys...); err != nil {
+			if !machine.X_active {
+				continue
+			}
+			if err := do(machine); err != nil {
 				return err
 			}
 		}
@@ -88,7 +166,7 @@ func (c *machinesService) Create(poolName string) (infra.Machine, error) {
 
 	for _, machine := range pool {
 
-		if err := machine.WriteFile(fmt.Sprintf("/home/orbiter/.ssh/authorized_keys"), bytes.NewReader([]byte(c.desired.Spec.Keys.MaintenanceKeyPublic.Value)), 600); err != nil {
+		if err := machine.WriteFile(machine.authorizedKeysPath(), bytes.NewReader([]byte(c.desired.Spec.Keys.MaintenanceKeyPublic.Value)), 600); err != nil {
 			return nil, err
 		}
 
@@ -124,10 +202,15 @@ func (c *machinesService) cachedPool(poolName string) (cachedMachines, error) {
 
 	keys := privateKeys(c.desired.Spec)
 
+	jumpHosts, err := c.jumpHosts(keys)
+	if err != nil {
+		return nil, err
+	}
+
 	newCache := make([]*machine, 0)
 
 	initializeMachine := func(rebootRequired bool, replacementRequired bool, spec *Machine) *machine {
-		return newMachine(c.monitor, c.statusFile, "orbiter", &spec.ID, string(spec.IP),
+		return newMachine(c.monitor, c.statusFile, c.desired.Spec.resolveSSH(poolName, spec), &spec.ID, string(spec.IP),
 			rebootRequired,
 			func() {
 				spec.RebootRequired = true
@@ -144,7 +227,7 @@ func (c *machinesService) cachedPool(poolName string) (cachedMachines, error) {
 	for _, spec := range specifiedMachines {
 
 		machine := initializeMachine(spec.RebootRequired, spec.ReplacementRequired, spec)
-		if err := machine.UseKey(keys...); err != nil {
+		if err := machine.useSSH(keys, jumpHosts); err != nil {
 			return nil, err
 		}
 
@@ -178,8 +261,11 @@ func (c cachedMachines) Machines() infra.Machines {
 
 func privateKeys(spec Spec) [][]byte {
 	var privateKeys [][]byte
+	if spec.Keys == nil {
+		return nil
+	}
 	toBytes := func(key *secret.Secret) {
-		if key != nil {
+		if key != nil && key.Value != "" {
 			privateKeys = append(privateKeys, []byte(key.Value))
 		}
 	}
@@ -18,6 +18,101 @@ type Spec struct {
 	Pools              map[string][]*Machine
 	Keys               *Keys
 	ExternalInterfaces []string
+	// Default SSH settings for all machines
+	SSH *SSH `yaml:",omitempty"`
+	// SSH settings per pool, overriding the defaults
+	PoolSSH map[string]*SSH `yaml:",omitempty"`
+	// Bastion hosts machines can be reached through, referenced by their names in the SSH settings
+	JumpHosts map[string]*JumpHost `yaml:",omitempty"`
+}
+
+// SSH configures how the orbiter connects to machines.
+// Unset fields are inherited from the pools settings and then from the default settings
+type SSH struct {
+	//@default: orbiter
+	User string `yaml:",omitempty"`
+	//@default: 22
+	Port uint16 `yaml:",omitempty"`
+	// Names of the jump hosts connections hop through in this order
+	JumpHosts []string `yaml:",omitempty"`
+	// Forwards the local SSH agent if SSH_AUTH_SOCK is set or an agent holding the orbs keys otherwise
+	ForwardAgent *bool `yaml:",omitempty"`
+}
+
+type JumpHost struct {
+	Host string
+	//@default: 22
+	Port uint16 `yaml:",omitempty"`
+	//@default: orbiter
+	User string `yaml:",omitempty"`
+	// Written with orbctl writesecret. If empty, the bootstrap and maintenance keys are used
+	PrivateKey *secret.Secret `yaml:",omitempty"`
+}
+
+// resolveSSH merges the machines, the pools and the default SSH settings
+func (s Spec) resolveSSH(pool string, machine *Machine) SSH {
+	resolved := SSH{
+		User: "orbiter",
+		Port: 22,
+	}
+	for _, settings := range []*SSH{s.SSH, s.PoolSSH[pool], machine.SSH} {
+		if settings == nil {
+			continue
+		}
+		if settings.User != "" {
+			resolved.User = settings.User
+		}
+		if settings.Port != 0 {
+			resolved.Port = settings.Port
+		}
+		if settings.JumpHosts != nil {
+			resolved.JumpHosts = settings.JumpHosts
+		}
+		if settings.ForwardAgent != nil {
+			resolved.ForwardAgent = settings.ForwardAgent
+		}
+	}
+	return resolved
+}
+
+func (s Spec) validateSSH() error {
+	for name, jumpHost := range s.JumpHosts {
+		if jumpHost == nil || jumpHost.Host == "" {
+			return errors.Errorf("jump host %s has no host", name)
+		}
+	}
+
+	validate := func(settings *SSH) error {
+		if settings == nil {
+			return nil
+		}
+		for _, jumpHost := range settings.JumpHosts {
+			if _, ok := s.JumpHosts[jumpHost]; !ok {
+				return errors.Errorf("jump host %s is not configured", jumpHost)
+			}
+		}
+		return nil
+	}
+
+	if err := validate(s.SSH); err != nil {
+		return err
+	}
+	for pool, settings := range s.PoolSSH {
+		if _, ok := s.Pools[pool]; !ok {
+			return errors.Errorf("ssh settings are configured for unknown pool %s", pool)
+		}
+		if err := validate(settings); err != nil {
+			return errors.Wrapf(err, "validating ssh settings of pool %s failed", pool)
+		}
+	}
+	for pool, machines := range s.Pools {
+		for _, machine := range machines {
+			if err := validate(machine.SSH); err != nil {
+				return errors.Wrapf(err, "validating ssh settings of machine %s in pool %s failed", machine.ID, pool)
+			}
+		}
+	}
+	return nil
 }
 
 type Keys struct {
@@ -36,7 +131,7 @@ func (d DesiredV0) validateAdapt() error {
 			}
 		}
 	}
-	return nil
+	return d.Spec.validateSSH()
 }
 
 func (d DesiredV0) validateQuery() error {
@@ -76,6 +171,8 @@ type Machine struct {
 	IP                  orbiter.IPAddress
 	RebootRequired      bool
 	ReplacementRequired bool
+	// SSH settings of this machine, overriding the pools settings
+	SSH *SSH `yaml:",omitempty"`
 }
 
 func (c *Machine) validate() error {
@@ -84,3 +181,13 @@ func (c *Machine) validate() error {
 	}
 	return c.IP.Validate()
 }
+
+// ValidateDesired checks the desired state without adapting it.
+// Secrets are not checked, as they are written using orbctl
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := parseDesiredV0(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.validateAdapt()
+}
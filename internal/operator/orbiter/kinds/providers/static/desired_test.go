@@ -0,0 +1,55 @@
+package static
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpec_resolveSSH(t *testing.T) {
+	forward := true
+	noForward := false
+
+	spec := Spec{
+		SSH: &SSH{
+			JumpHosts:    []string{"bastion"},
+			ForwardAgent: &forward,
+		},
+		PoolSSH: map[string]*SSH{
+			"workers": {User: "admin"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		pool    string
+		machine *Machine
+		want    SSH
+	}{{
+		name:    "defaults are inherited",
+		pool:    "masters",
+		machine: &Machine{},
+		want:    SSH{User: "orbiter", Port: 22, JumpHosts: []string{"bastion"}, ForwardAgent: &forward},
+	}, {
+		name:    "pool settings override defaults",
+		pool:    "workers",
+		machine: &Machine{},
+		want:    SSH{User: "admin", Port: 22, JumpHosts: []string{"bastion"}, ForwardAgent: &forward},
+	}, {
+		name: "machine settings override pool settings",
+		pool: "workers",
+		machine: &Machine{SSH: &SSH{
+			User:         "root",
+			Port:         2222,
+			JumpHosts:    []string{},
+			ForwardAgent: &noForward,
+		}},
+		want: SSH{User: "root", Port: 2222, JumpHosts: []string{}, ForwardAgent: &noForward},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spec.resolveSSH(tt.pool, tt.machine); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveSSH() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ func query(
 	internalMachinesService *machinesService,
 	naFuncs core.IterateNodeAgentFuncs,
 	orbiterCommit string,
+	plan *orbiter.Plan,
 ) (ensureFunc orbiter.EnsureFunc, err error) {
 
 	// TODO: Allow Changes
@@ -105,6 +106,27 @@ func query(
 		return nil, errors.Errorf("Unknown load balancer of type %T", lb)
 	}
 
+	if plan != nil {
+		// Planning doesn't ensure, so the node agent desires ensuring sets are computed now
+		for _, pool := range pools {
+			machines, err := internalMachinesService.List(pool)
+			if err != nil {
+				return nil, err
+			}
+			for _, machine := range machines {
+				if _, err := desireHostnameFunc(machine, pool); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if result := ensureLBFunc(); result.Err != nil {
+			return nil, result.Err
+		}
+		if _, err := core.DesireInternalOSFirewall(monitor, nodeAgentsDesired, nodeAgentsCurrent, externalMachinesService, desired.Spec.ExternalInterfaces); err != nil {
+			return nil, err
+		}
+	}
+
 	return func(pdf api.PushDesiredFunc) *orbiter.EnsureResult {
 		var wg sync.WaitGroup
 		for _, pool := range pools {
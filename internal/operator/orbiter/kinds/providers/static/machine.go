@@ -1,6 +1,7 @@
 package static
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
@@ -23,6 +24,7 @@ type machine struct {
 	replacementRequired  bool
 	requireReplacement   func()
 	unrequireReplacement func()
+	settings             SSH
 	*ssh.Machine
 	X_ID     *string `header:"id"`
 	X_IP     string  `header:"ip"`
@@ -32,7 +34,7 @@ type machine struct {
 func newMachine(
 	monitor mntr.Monitor,
 	poolFile string,
-	remoteUser string,
+	settings SSH,
 	id *string,
 	ip string,
 	rebootRequired bool,
@@ -47,7 +49,8 @@ func newMachine(
 		poolFile:             poolFile,
 		X_ID:                 id,
 		X_IP:                 ip,
-		Machine:              ssh.NewMachine(monitor, remoteUser, ip),
+		settings:             settings,
+		Machine:              ssh.NewMachine(monitor, settings.User, ip),
 		rebootRequired:       rebootRequired,
 		requireReboot:        requireReboot,
 		unrequireReboot:      unrequireReboot,
@@ -57,6 +60,34 @@ func newMachine(
 	}
 }
 
+// useSSH configures the keys, the port, the jump hosts and the agent forwarding
+func (c *machine) useSSH(keys [][]byte, jumpHosts map[string]*ssh.JumpHost) error {
+	if err := c.UseKey(keys...); err != nil {
+		return err
+	}
+	c.UsePort(c.settings.Port)
+
+	hops := make([]*ssh.JumpHost, len(c.settings.JumpHosts))
+	for idx, name := range c.settings.JumpHosts {
+		hop, ok := jumpHosts[name]
+		if !ok {
+			return fmt.Errorf("jump host %s is not configured", name)
+		}
+		hops[idx] = hop
+	}
+	c.UseJumpHosts(hops...)
+
+	c.ForwardAgent(c.settings.ForwardAgent != nil && *c.settings.ForwardAgent)
+	return nil
+}
+
+func (c *machine) authorizedKeysPath() string {
+	if c.settings.User == "root" {
+		return "/root/.ssh/authorized_keys"
+	}
+	return fmt.Sprintf("/home/%s/.ssh/authorized_keys", c.settings.User)
+}
+
 func (c *machine) ID() string {
 	return *c.X_ID
 }
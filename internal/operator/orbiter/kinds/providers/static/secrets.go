@@ -26,10 +26,22 @@ func getSecretsMap(desiredKind *DesiredV0) map[string]*secret.Secret {
 		desiredKind.Spec.Keys.MaintenanceKeyPublic = &secret.Secret{}
 	}
 
-	return map[string]*secret.Secret{
+	secrets := map[string]*secret.Secret{
 		"bootstrapkeyprivate":   desiredKind.Spec.Keys.BootstrapKeyPrivate,
 		"bootstrapkeypublic":    desiredKind.Spec.Keys.BootstrapKeyPublic,
 		"maintenancekeyprivate": desiredKind.Spec.Keys.MaintenanceKeyPrivate,
 		"maintenancekeypublic":  desiredKind.Spec.Keys.MaintenanceKeyPublic,
 	}
+
+	for name, jumpHost := range desiredKind.Spec.JumpHosts {
+		if jumpHost == nil {
+			continue
+		}
+		if jumpHost.PrivateKey == nil {
+			jumpHost.PrivateKey = &secret.Secret{}
+		}
+		secrets["jumphosts."+name+".privatekey"] = jumpHost.PrivateKey
+	}
+
+	return secrets
 }
@@ -0,0 +1,71 @@
+package orbiter
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/caos/orbos/internal/operator/common"
+)
+
+const (
+	iterationDone    = "done"
+	iterationNotDone = "not_done"
+	iterationError   = "error"
+)
+
+var (
+	iterationsHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "orbiter_iteration_duration_seconds",
+			Help:    "Duration of reconcile iterations by outcome, which is done, not_done or error.",
+			Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200},
+		},
+		[]string{"outcome"},
+	)
+	nodeAgentsNotReadyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "orbiter_nodeagent_not_ready",
+			Help: "1 if the node agent of the machine did not report its node as ready.",
+		},
+		[]string{"machine"},
+	)
+	nodeAgentsOutdatedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "orbiter_nodeagent_outdated",
+			Help: "1 if the node agent of the machine does not run the orbiters commit.",
+		},
+		[]string{"machine", "commit"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(iterationsHistogram, nodeAgentsNotReadyGauge, nodeAgentsOutdatedGauge)
+}
+
+func observeIteration(started time.Time, outcome string) {
+	iterationsHistogram.WithLabelValues(outcome).Observe(time.Since(started).Seconds())
+}
+
+// observeNodeAgents replaces the node agent gauges for all machines with desired node agents
+func observeNodeAgents(commit string, current *common.CurrentNodeAgents, desired *common.DesiredNodeAgents) {
+
+	nodeAgentsNotReadyGauge.Reset()
+	nodeAgentsOutdatedGauge.Reset()
+
+	for _, id := range desired.List() {
+		curr, ok := current.Get(id)
+
+		var notReady float64
+		if !ok || !curr.NodeIsReady {
+			notReady = 1
+		}
+		nodeAgentsNotReadyGauge.WithLabelValues(id).Set(notReady)
+
+		var outdated float64
+		if !ok || curr.Commit != commit {
+			outdated = 1
+		}
+		nodeAgentsOutdatedGauge.WithLabelValues(id, curr.Commit).Set(outdated)
+	}
+}
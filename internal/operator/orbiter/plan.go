@@ -0,0 +1,325 @@
+package orbiter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/mntr"
+)
+
+const planKey = "orbiter.plan"
+
+// Plan describes the changes an EnsureFunc would make.
+// All methods are safe to call on a nil *Plan, so queriers can record their decisions unconditionally.
+type Plan struct {
+	Machines      []*PlannedMachine      `json:"machines"`
+	Nodes         []*PlannedNode         `json:"nodes"`
+	Versions      []*PlannedVersion      `json:"versions"`
+	Software      []*PlannedSoftware     `json:"software"`
+	Firewall      []*PlannedFirewall     `json:"firewall"`
+	LoadBalancers []*PlannedLoadBalancer `json:"loadBalancers"`
+	mux           sync.Mutex
+}
+
+// NewPlan returns an empty plan which is marshalled to empty lists instead of nulls
+func NewPlan() *Plan {
+	return &Plan{
+		Machines:      make([]*PlannedMachine, 0),
+		Nodes:         make([]*PlannedNode, 0),
+		Versions:      make([]*PlannedVersion, 0),
+		Software:      make([]*PlannedSoftware, 0),
+		Firewall:      make([]*PlannedFirewall, 0),
+		LoadBalancers: make([]*PlannedLoadBalancer, 0),
+	}
+}
+
+// PlannedMachine is a machine to be created or removed. Machine is empty for machines to be created
+type PlannedMachine struct {
+	Provider string `json:"provider"`
+	Pool     string `json:"pool"`
+	Machine  string `json:"machine,omitempty"`
+	Action   string `json:"action"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// PlannedNode is a node to be drained, rebooted or replaced
+type PlannedNode struct {
+	Machine string `json:"machine"`
+	Action  string `json:"action"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PlannedVersion is the next kubernetes version step. If To differs from Target, further steps follow
+type PlannedVersion struct {
+	Cluster string `json:"cluster"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Target  string `json:"target"`
+}
+
+// PlannedSoftware is a package a node agent is going to install or reconfigure
+type PlannedSoftware struct {
+	Machine string `json:"machine"`
+	Package string `json:"package"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+// PlannedFirewall is a port a node agent or provider is going to open or close
+type PlannedFirewall struct {
+	Machine  string `json:"machine,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+	Rule     string `json:"rule"`
+	Action   string `json:"action"`
+}
+
+// PlannedLoadBalancer is a load balancer configuration or resource that is going to change
+type PlannedLoadBalancer struct {
+	Machine  string `json:"machine,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Name     string `json:"name"`
+	Action   string `json:"action"`
+	// Count is the number of resources changed by a provider
+	Count int `json:"count,omitempty"`
+}
+
+// WithPlan returns a queried map that makes queriers record their decisions in plan
+func WithPlan(queried map[string]interface{}, plan *Plan) map[string]interface{} {
+	if queried == nil {
+		queried = make(map[string]interface{})
+	}
+	queried[planKey] = plan
+	return queried
+}
+
+// PlanOf returns the plan to record to or nil if the query is not planned
+func PlanOf(queried map[string]interface{}) *Plan {
+	plan, _ := queried[planKey].(*Plan)
+	return plan
+}
+
+// IsEmpty is true if nothing is going to change
+func (p *Plan) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return len(p.Machines)+len(p.Nodes)+len(p.Versions)+len(p.Software)+len(p.Firewall)+len(p.LoadBalancers) == 0
+}
+
+func (p *Plan) AddMachine(machine PlannedMachine) {
+	if p == nil {
+		return
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.Machines = append(p.Machines, &machine)
+}
+
+func (p *Plan) AddNode(node PlannedNode) {
+	if p == nil {
+		return
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.Nodes = append(p.Nodes, &node)
+}
+
+func (p *Plan) AddVersion(version PlannedVersion) {
+	if p == nil {
+		return
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.Versions = append(p.Versions, &version)
+}
+
+func (p *Plan) AddFirewall(firewall PlannedFirewall) {
+	if p == nil {
+		return
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.Firewall = append(p.Firewall, &firewall)
+}
+
+func (p *Plan) AddLoadBalancer(lb PlannedLoadBalancer) {
+	if p == nil {
+		return
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.LoadBalancers = append(p.LoadBalancers, &lb)
+}
+
+func (p *Plan) addSoftware(software PlannedSoftware) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.Software = append(p.Software, &software)
+}
+
+// DiffNodeAgents records the software, firewall and load balancer changes the node agents are going to make
+func (p *Plan) DiffNodeAgents(current *common.CurrentNodeAgents, desired *common.DesiredNodeAgents) {
+	if p == nil {
+		return
+	}
+
+	for _, id := range desired.List() {
+		spec, _ := desired.Get(id)
+		curr, _ := current.Get(id)
+
+		if spec.Software != nil {
+			p.diffSoftware(id, curr.Software, *spec.Software)
+		}
+		if spec.Firewall != nil {
+			p.diffFirewall(id, curr.Open, spec.Firewall)
+		}
+	}
+}
+
+func (p *Plan) diffSoftware(id string, current, desired common.Software) {
+	packages := []struct {
+		name             string
+		current, desired common.Package
+		loadbalancing    bool
+	}{
+		{"swap", current.Swap, desired.Swap, false},
+		{"kubelet", current.Kubelet, desired.Kubelet, false},
+		{"kubeadm", current.Kubeadm, desired.Kubeadm, false},
+		{"kubectl", current.Kubectl, desired.Kubectl, false},
+		{"containerruntime", current.Containerruntime, desired.Containerruntime, false},
+		{"keepalived", current.KeepaliveD, desired.KeepaliveD, true},
+		{"nginx", current.Nginx, desired.Nginx, true},
+		{"sshd", current.SSHD, desired.SSHD, false},
+		{"hostname", current.Hostname, desired.Hostname, false},
+		{"sysctl", current.Sysctl, desired.Sysctl, false},
+		{"health", current.Health, desired.Health, false},
+	}
+
+	zeroPkg := common.Package{}
+	for _, pkg := range packages {
+		// Zero packages are not managed
+		if pkg.desired.Equals(zeroPkg) || pkg.desired.Equals(pkg.current) {
+			continue
+		}
+
+		if pkg.loadbalancing && pkg.desired.Version == pkg.current.Version {
+			p.AddLoadBalancer(PlannedLoadBalancer{
+				Machine: id,
+				Name:    pkg.name,
+				Action:  "reconfigure",
+			})
+			continue
+		}
+
+		to := pkg.desired.Version
+		if to == pkg.current.Version {
+			to = "reconfigured"
+		}
+		p.addSoftware(PlannedSoftware{
+			Machine: id,
+			Package: pkg.name,
+			From:    pkg.current.Version,
+			To:      to,
+		})
+	}
+}
+
+func (p *Plan) diffFirewall(id string, current common.Current, desired *common.Firewall) {
+
+	open := make(map[string]map[string]bool)
+	for _, zone := range current {
+		if zone == nil {
+			continue
+		}
+		open[zone.Name] = make(map[string]bool)
+		for _, port := range zone.FW {
+			open[zone.Name][fmt.Sprintf("%s/%s", port.Port, port.Protocol)] = true
+		}
+	}
+
+	for name, zone := range desired.Zones {
+		if zone == nil {
+			continue
+		}
+
+		desiredPorts := make(map[string]bool)
+		for _, port := range zone.FW {
+			rule := fmt.Sprintf("%s/%s", port.Port, port.Protocol)
+			desiredPorts[rule] = true
+			if !open[name][rule] {
+				p.AddFirewall(PlannedFirewall{Machine: id, Zone: name, Rule: rule, Action: "open"})
+			}
+		}
+
+		for rule := range open[name] {
+			if !desiredPorts[rule] {
+				p.AddFirewall(PlannedFirewall{Machine: id, Zone: name, Rule: rule, Action: "close"})
+			}
+		}
+	}
+}
+
+// Sort orders all changes, so plans are comparable
+func (p *Plan) Sort() {
+	if p == nil {
+		return
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	sort.Slice(p.Machines, func(i, j int) bool {
+		return fmt.Sprint(*p.Machines[i]) < fmt.Sprint(*p.Machines[j])
+	})
+	sort.Slice(p.Nodes, func(i, j int) bool {
+		return fmt.Sprint(*p.Nodes[i]) < fmt.Sprint(*p.Nodes[j])
+	})
+	sort.Slice(p.Versions, func(i, j int) bool {
+		return p.Versions[i].Cluster < p.Versions[j].Cluster
+	})
+	sort.Slice(p.Software, func(i, j int) bool {
+		return fmt.Sprint(*p.Software[i]) < fmt.Sprint(*p.Software[j])
+	})
+	sort.Slice(p.Firewall, func(i, j int) bool {
+		return fmt.Sprint(*p.Firewall[i]) < fmt.Sprint(*p.Firewall[j])
+	})
+	sort.Slice(p.LoadBalancers, func(i, j int) bool {
+		return fmt.Sprint(*p.LoadBalancers[i]) < fmt.Sprint(*p.LoadBalancers[j])
+	})
+}
+
+// Planned queries the desired state like Takeoff, but returns the planned changes instead of ensuring them.
+// Queriers compute the node agent desires they would set while ensuring already when planning.
+// The plan covers the next iteration only, so it is partial: node agent installations,
+// changes depending on the results of earlier changes and the autoscaling decisions,
+// which are made from the nodes utilization while ensuring, are not included
+func Planned(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc) (*Plan, error) {
+
+	query, _, _, _, _, _, _, err := Adapt(gitClient, nil, monitor, make(chan struct{}, 1), adapt)
+	if err != nil {
+		return nil, err
+	}
+
+	currentNodeAgents, err := api.ReadNodeAgentsCurrent(gitClient)
+	if err != nil {
+		return nil, err
+	}
+
+	desiredNodeAgents := &common.DesiredNodeAgents{}
+	plan := NewPlan()
+	if _, err := QueryFuncGoroutine(monitor, func() (EnsureFunc, error) {
+		return query(&currentNodeAgents.Current, desiredNodeAgents, WithPlan(nil, plan))
+	}); err != nil {
+		return nil, err
+	}
+
+	plan.DiffNodeAgents(&currentNodeAgents.Current, desiredNodeAgents)
+	plan.Sort()
+	return plan, nil
+}
@@ -0,0 +1,54 @@
+package orbiter
+
+import (
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/common"
+)
+
+func TestPlan_DiffNodeAgents(t *testing.T) {
+	current := &common.CurrentNodeAgents{}
+	current.Set("a", &common.NodeAgentCurrent{
+		Software: common.Software{
+			Kubelet:    common.Package{Version: "v1.17.0"},
+			KeepaliveD: common.Package{Version: "v2", Config: map[string]string{"keepalived.conf": "old"}},
+		},
+		Open: common.Current{{Name: "external", FW: []*common.Allowed{{Port: "22", Protocol: "tcp"}, {Port: "8080", Protocol: "tcp"}}}},
+	})
+
+	desired := &common.DesiredNodeAgents{}
+	spec, _ := desired.Get("a")
+	spec.Software = &common.Software{
+		Kubelet:    common.Package{Version: "v1.18.8"},
+		KeepaliveD: common.Package{Version: "v2", Config: map[string]string{"keepalived.conf": "new"}},
+	}
+	spec.Firewall = &common.Firewall{Zones: map[string]*common.Zone{"external": {FW: map[string]*common.Allowed{
+		"ssh":   {Port: "22", Protocol: "tcp"},
+		"https": {Port: "443", Protocol: "tcp"},
+	}}}}
+
+	plan := NewPlan()
+	plan.DiffNodeAgents(current, desired)
+	plan.Sort()
+
+	if len(plan.Software) != 1 || plan.Software[0].Package != "kubelet" || plan.Software[0].To != "v1.18.8" {
+		t.Errorf("expected a kubelet upgrade, got %+v", plan.Software)
+	}
+	if len(plan.LoadBalancers) != 1 || plan.LoadBalancers[0].Name != "keepalived" {
+		t.Errorf("expected keepalived to be reconfigured, got %+v", plan.LoadBalancers)
+	}
+	if len(plan.Firewall) != 2 ||
+		plan.Firewall[0].Action != "open" || plan.Firewall[0].Rule != "443/tcp" ||
+		plan.Firewall[1].Action != "close" || plan.Firewall[1].Rule != "8080/tcp" {
+		t.Errorf("expected 443/tcp to be opened and 8080/tcp to be closed, got %+v %+v", plan.Firewall[0], plan.Firewall[1])
+	}
+}
+
+func TestPlan_NilIsNoop(t *testing.T) {
+	var plan *Plan
+	plan.AddMachine(PlannedMachine{Action: "create"})
+	plan.DiffNodeAgents(&common.CurrentNodeAgents{}, &common.DesiredNodeAgents{})
+	if !plan.IsEmpty() || PlanOf(nil) != nil {
+		t.Error("nil plan recorded changes")
+	}
+}
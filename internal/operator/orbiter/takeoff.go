@@ -3,15 +3,16 @@ package orbiter
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/store"
 
 	"github.com/caos/orbos/internal/api"
 	orbconfig "github.com/caos/orbos/internal/orb"
 	"github.com/caos/orbos/internal/tree"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"gopkg.in/yaml.v3"
 
 	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/operator/common"
@@ -49,20 +50,30 @@ type retQuery struct {
 	err    error
 }
 
-func QueryFuncGoroutine(query func() (EnsureFunc, error)) (EnsureFunc, error) {
+// QueryFuncGoroutine queries within an entered span, so spans started by the monitors
+// git clients and SSH machines captured at construction become its children
+func QueryFuncGoroutine(monitor mntr.Monitor, query func() (EnsureFunc, error)) (EnsureFunc, error) {
+	_, span := monitor.EnterSpan("query")
 	retChan := make(chan retQuery)
 	go func() {
 		ensure, err := query()
+		span.End(err)
 		retChan <- retQuery{ensure, err}
 	}()
 	ret := <-retChan
 	return ret.ensure, ret.err
 }
 
-func EnsureFuncGoroutine(ensure func() *EnsureResult) *EnsureResult {
+// EnsureFuncGoroutine ensures within an entered span, so spans started by the monitors
+// git clients and SSH machines captured at construction become its children
+func EnsureFuncGoroutine(monitor mntr.Monitor, ensure func() *EnsureResult) *EnsureResult {
+	_, span := monitor.EnterSpan("ensure")
 	retChan := make(chan *EnsureResult)
 	go func() {
-		retChan <- ensure()
+		result := ensure()
+		span.SetAttribute("done", fmt.Sprintf("%t", result.Done))
+		span.End(result.Err)
+		retChan <- result
 	}()
 	return <-retChan
 }
@@ -82,18 +93,29 @@ func Metrics() {
 	}()
 }
 
-func Adapt(gitClient *git.Client, monitor mntr.Monitor, finished chan struct{}, adapt AdaptFunc) (QueryFunc, DestroyFunc, ConfigureFunc, bool, *tree.Tree, *tree.Tree, map[string]*secret.Secret, error) {
+// Adapt parses the desired state. The current state persisted in currentStore is passed to the adapters,
+// so they can continue where the last iteration stopped. If currentStore is nil, the adapters start with an empty current state
+func Adapt(gitClient *git.Client, currentStore store.Store, monitor mntr.Monitor, finished chan struct{}, adapt AdaptFunc) (QueryFunc, DestroyFunc, ConfigureFunc, bool, *tree.Tree, *tree.Tree, map[string]*secret.Secret, error) {
+
+	if err := secret.LoadRecipients(gitClient); err != nil {
+		return nil, nil, nil, false, nil, nil, nil, err
+	}
 
 	treeDesired, err := api.ReadOrbiterYml(gitClient)
 	if err != nil {
 		return nil, nil, nil, false, nil, nil, nil, err
 	}
 	treeCurrent := &tree.Tree{}
+	if currentStore != nil {
+		if treeCurrent, err = api.ReadOrbiterCurrent(currentStore); err != nil {
+			return nil, nil, nil, false, nil, nil, nil, err
+		}
+	}
 
 	adaptFunc := func() (QueryFunc, DestroyFunc, ConfigureFunc, bool, map[string]*secret.Secret, error) {
 		return adapt(monitor, finished, treeDesired, treeCurrent)
 	}
-	query, destroy, configure, migrate, secrets, err := AdaptFuncGoroutine(adaptFunc)
+	query, destroy, configure, migrate, secrets, err := AdaptFuncGoroutine(monitor, adaptFunc)
 	return query, destroy, configure, migrate, treeDesired, treeCurrent, secrets, err
 }
 
@@ -101,7 +123,32 @@ func Takeoff(monitor mntr.Monitor, conf *Config) func() {
 
 	return func() {
 
-		query, _, _, migrate, treeDesired, treeCurrent, _, err := Adapt(conf.GitClient, monitor, conf.FinishedChan, conf.Adapt)
+		started := time.Now()
+		outcome := iterationError
+		monitor, span := monitor.EnterSpan("orbiter.iteration")
+		defer func() {
+			observeIteration(started, outcome)
+			span.SetAttribute("outcome", outcome)
+			span.End(nil)
+		}()
+
+		var currentStore store.Store = conf.GitClient
+		if conf.CurrentStore != nil {
+			currentStore = conf.CurrentStore
+			// The git client is cloned already, other stores are read for the first time
+			if err := currentStore.Clone(); err != nil {
+				monitor.Error(err)
+				return
+			}
+		}
+
+		query, _, _, migrate, treeDesired, treeCurrent, _, err := Adapt(conf.GitClient, currentStore, monitor, conf.FinishedChan, conf.Adapt)
+		if err != nil {
+			monitor.Error(err)
+			return
+		}
+
+		verification, err := verify(conf, treeDesired)
 		if err != nil {
 			monitor.Error(err)
 			return
@@ -111,13 +158,14 @@ func Takeoff(monitor mntr.Monitor, conf *Config) func() {
 			Kind:    "nodeagent.caos.ch/NodeAgents",
 			Version: "v0",
 			Spec: common.NodeAgentsSpec{
-				Commit: conf.OrbiterCommit,
+				Commit:       conf.OrbiterCommit,
+				Verification: verification,
 			},
 		}
 
 		marshalCurrentFiles := func() []git.File {
 			return []git.File{{
-				Path:    "caos-internal/orbiter/current.yml",
+				Path:    api.OrbiterCurrentFile,
 				Content: common.MarshalYAML(treeCurrent),
 			}, {
 				Path:    "caos-internal/orbiter/node-agents-desired.yml",
@@ -132,44 +180,45 @@ func Takeoff(monitor mntr.Monitor, conf *Config) func() {
 			}
 		}
 
-		currentNodeAgents := common.NodeAgentsCurrentKind{}
-		if err := yaml.Unmarshal(conf.GitClient.Read("caos-internal/orbiter/node-agents-current.yml"), &currentNodeAgents); err != nil {
+		currentNodeAgents, err := api.ReadNodeAgentsCurrent(conf.GitClient)
+		if err != nil {
 			monitor.Error(err)
 			return
 		}
 
 		handleAdapterError := func(err error) {
 			monitor.Error(err)
-			if commitErr := conf.GitClient.Commit(mntr.CommitRecord([]*mntr.Field{{Pos: 0, Key: "err", Value: err.Error()}})); commitErr != nil {
+			if commitErr := currentStore.Commit(mntr.CommitRecord([]*mntr.Field{{Pos: 0, Key: "err", Value: err.Error()}})); commitErr != nil {
 				monitor.Error(err)
 				return
 			}
-			monitor.Error(conf.GitClient.Push())
+			monitor.Error(currentStore.Push())
 		}
 
 		queryFunc := func() (EnsureFunc, error) {
 			return query(&currentNodeAgents.Current, &desiredNodeAgents.Spec.NodeAgents, nil)
 		}
-		ensure, err := QueryFuncGoroutine(queryFunc)
+		ensure, err := QueryFuncGoroutine(monitor, queryFunc)
 		if err != nil {
 			handleAdapterError(err)
 			return
 		}
+		observeNodeAgents(conf.OrbiterCommit, &currentNodeAgents.Current, &desiredNodeAgents.Spec.NodeAgents)
 
-		if err := conf.GitClient.Clone(); err != nil {
+		if err := currentStore.Clone(); err != nil {
 			monitor.Error(err)
 			return
 		}
 
 		reconciledCurrentStateMsg := "Current state reconciled"
-		currentReconciled, err := conf.GitClient.StageAndCommit(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: reconciledCurrentStateMsg}}), marshalCurrentFiles()[0])
+		currentReconciled, err := currentStore.StageAndCommit(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: reconciledCurrentStateMsg}}), marshalCurrentFiles()[0])
 		if err != nil {
 			monitor.Error(fmt.Errorf("Commiting event \"%s\" failed: %s", reconciledCurrentStateMsg, err.Error()))
 			return
 		}
 
 		if currentReconciled {
-			if err := conf.GitClient.Push(); err != nil {
+			if err := currentStore.Push(); err != nil {
 				monitor.Error(fmt.Errorf("Pushing event \"%s\" failed: %s", reconciledCurrentStateMsg, err.Error()))
 				return
 			}
@@ -179,30 +228,33 @@ func Takeoff(monitor mntr.Monitor, conf *Config) func() {
 			return ensure(api.PushOrbiterDesiredFunc(conf.GitClient, treeDesired))
 		}
 
-		result := EnsureFuncGoroutine(ensureFunc)
+		result := EnsureFuncGoroutine(monitor, ensureFunc)
 		if result.Err != nil {
 			handleAdapterError(result.Err)
 			return
 		}
 
+		ensured := iterationNotDone
 		if result.Done {
+			ensured = iterationDone
 			monitor.Info("Desired state is ensured")
 		} else {
 			monitor.Info("Desired state is not yet ensured")
 		}
-		if err := conf.GitClient.Clone(); err != nil {
+		if err := currentStore.Clone(); err != nil {
 			monitor.Error(fmt.Errorf("Commiting event \"%s\" failed: %s", reconciledCurrentStateMsg, err.Error()))
 			return
 		}
 
-		changed, err := conf.GitClient.StageAndCommit("Current state changed", marshalCurrentFiles()...)
+		changed, err := currentStore.StageAndCommit("Current state changed", marshalCurrentFiles()...)
 		if err != nil {
 			monitor.Error(fmt.Errorf("commiting current state failed: %w", err))
 			return
 		}
+		outcome = ensured
 
 		if changed {
-			monitor.Error(conf.GitClient.Push())
+			monitor.Error(currentStore.Push())
 		}
 	}
 }
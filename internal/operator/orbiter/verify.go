@@ -0,0 +1,128 @@
+package orbiter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/ingestion"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/tree"
+)
+
+const trustFile = "orbiter-trust.yml"
+
+// trust survives iterations, so a changed verification policy is only accepted
+// if it is signed by a key that was trusted before.
+// It is restored from and persisted to the configs TrustStore, so it also survives restarts
+var (
+	trust        = &git.Trust{}
+	trustLoaded  bool
+	trustedState git.TrustState
+)
+
+func loadTrust(conf *Config) error {
+	if trustLoaded || conf.TrustStore == nil {
+		return nil
+	}
+
+	if err := conf.TrustStore.Clone(); err != nil {
+		return fmt.Errorf("reading trusted keys failed: %w", err)
+	}
+
+	state := git.TrustState{}
+	if err := yaml.Unmarshal(conf.TrustStore.Read(trustFile), &state); err != nil {
+		return fmt.Errorf("parsing trusted keys failed: %w", err)
+	}
+
+	trust.Restore(state)
+	trustedState, trustLoaded = state, true
+	return nil
+}
+
+func saveTrust(conf *Config) error {
+	if conf.TrustStore == nil {
+		return nil
+	}
+
+	state := trust.State()
+	if state.Commit == trustedState.Commit && equalKeys(state.Keys, trustedState.Keys) {
+		return nil
+	}
+
+	content, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := conf.TrustStore.UpdateRemote("Trust verified keys", git.File{
+		Path:    trustFile,
+		Content: content,
+	}); err != nil {
+		return fmt.Errorf("persisting trusted keys failed: %w", err)
+	}
+	trustedState = state
+	return nil
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// verify refuses desired state that is not signed by a trusted key
+// and returns the verification policy the node agents should apply
+func verify(conf *Config, treeDesired *tree.Tree) (*common.Verification, error) {
+
+	policy := struct {
+		Spec struct {
+			Verification *common.Verification
+		}
+	}{}
+	if err := treeDesired.Original.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("parsing verification policy failed: %w", err)
+	}
+
+	if err := loadTrust(conf); err != nil {
+		return nil, err
+	}
+
+	commit, err := trust.Verify(conf.GitClient, policy.Spec.Verification.Keys(), "orbiter.yml")
+	if err == nil {
+		return policy.Spec.Verification, saveTrust(conf)
+	}
+
+	if !errors.Is(err, git.ErrUntrusted) {
+		return nil, err
+	}
+
+	hash := ""
+	if commit != nil {
+		hash = commit.Hash
+	}
+
+	if pushErr := conf.PushEvents([]*ingestion.EventRequest{{
+		CreationDate: ptypes.TimestampNow(),
+		Type:         ingestion.TypeOrbiterUntrusted,
+		Data: &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"commit": {Kind: &structpb.Value_StringValue{StringValue: hash}},
+				"reason": {Kind: &structpb.Value_StringValue{StringValue: err.Error()}},
+			},
+		},
+	}}); pushErr != nil {
+		err = fmt.Errorf("%w: pushing event failed: %s", err, pushErr.Error())
+	}
+	return nil, fmt.Errorf("refusing desired state: %w", err)
+}
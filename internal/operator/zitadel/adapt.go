@@ -36,6 +36,10 @@ func Parse(gitClient *git.Client, file string) (*tree.Tree, error) {
 		return nil, err
 	}
 
+	if err := secret.LoadRecipients(gitClient); err != nil {
+		return nil, err
+	}
+
 	tree := &tree.Tree{}
 	if err := yaml.Unmarshal(gitClient.Read(file), tree); err != nil {
 		return nil, err
@@ -78,20 +82,28 @@ func QueriersToEnsureFunc(monitor mntr.Monitor, infoLogs bool, queriers []QueryF
 	} else {
 		monitor.Debug("querying...")
 	}
+	_, querySpan := monitor.EnterSpan("query")
 	ensurers := make([]EnsureFunc, 0)
 	for _, querier := range queriers {
 		ensurer, err := querier(k8sClient, queried)
 		if err != nil {
+			querySpan.End(err)
 			return nil, errors.Wrap(err, "error while querying")
 		}
 		ensurers = append(ensurers, ensurer)
 	}
+	querySpan.End(nil)
 	if infoLogs {
 		monitor.Info("queried")
 	} else {
 		monitor.Debug("queried")
 	}
-	return func(k8sClient *kubernetes.Client) error {
+	return func(k8sClient *kubernetes.Client) (err error) {
+		_, ensureSpan := monitor.EnterSpan("ensure")
+		defer func() {
+			ensureSpan.End(err)
+		}()
+
 		if infoLogs {
 			monitor.Info("ensuring...")
 		} else {
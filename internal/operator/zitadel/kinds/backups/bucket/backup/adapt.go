@@ -161,6 +161,8 @@ func AdaptFunc(
 			monitor.Error(errors.Wrap(err, "error while waiting for backup to be completed"))
 			return err
 		}
+		monitor.Changed("Backup finished")
+		k8sClient.RecordEvent(kubernetes.ObjectReference("batch/v1", "Job", namespace, cronjobName), kubernetes.ComponentZitadel, corev1.EventTypeNormal, kubernetes.ReasonBackupFinished, "Backup finished")
 		monitor.Info("backup is completed, cleanup")
 		if err := k8sClient.DeleteJob(namespace, cronjobName); err != nil {
 			monitor.Error(errors.Wrap(err, "error while trying to cleanup backup"))
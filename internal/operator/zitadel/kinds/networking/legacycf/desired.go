@@ -23,3 +23,12 @@ func parseDesired(desiredTree *tree.Tree) (*Desired, error) {
 
 	return desiredKind, nil
 }
+
+// ValidateDesired checks the desired state without adapting it
+func ValidateDesired(desiredTree *tree.Tree) error {
+	desiredKind, err := parseDesired(desiredTree)
+	if err != nil {
+		return err
+	}
+	return desiredKind.Spec.Validate()
+}
@@ -2,6 +2,9 @@ package zitadel
 
 import (
 	"errors"
+	"fmt"
+
+	core "k8s.io/api/core/v1"
 
 	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
@@ -9,13 +12,18 @@ import (
 	"github.com/caos/orbos/mntr"
 )
 
+// The operators own deployment is the involved object of its Kubernetes Events
+var operatorDeployment = kubernetes.ObjectReference("apps/v1", "Deployment", "caos-system", "zitadel-operator")
+
 func Takeoff(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc, k8sClient *kubernetes.Client) func() {
 	return func() {
-		internalMonitor := monitor.WithField("operator", "zitadel")
+		internalMonitor, span := monitor.WithField("operator", "zitadel").EnterSpan("zitadel.iteration")
+		defer span.End(nil)
+
 		internalMonitor.Info("Takeoff")
 		treeDesired, err := Parse(gitClient, "zitadel.yml")
 		if err != nil {
-			monitor.Error(err)
+			internalMonitor.Error(err)
 			return
 		}
 		treeCurrent := &tree.Tree{}
@@ -25,6 +33,14 @@ func Takeoff(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc, k8sCl
 			return
 		}
 
+		defer func() {
+			if err != nil {
+				k8sClient.RecordEvent(operatorDeployment, kubernetes.ComponentZitadel, core.EventTypeWarning, kubernetes.ReasonReconcileFailed, fmt.Sprintf("Reconciling ZITADEL failed: %s", err.Error()))
+				return
+			}
+			k8sClient.RecordEvent(operatorDeployment, kubernetes.ComponentZitadel, core.EventTypeNormal, kubernetes.ReasonReconciled, "ZITADEL reconciled")
+		}()
+
 		query, _, _, err := adapt(internalMonitor, treeDesired, treeCurrent)
 		if err != nil {
 			internalMonitor.Error(err)
@@ -37,7 +53,7 @@ func Takeoff(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc, k8sCl
 			return
 		}
 
-		if err := ensure(k8sClient); err != nil {
+		if err = ensure(k8sClient); err != nil {
 			internalMonitor.Error(err)
 			return
 		}
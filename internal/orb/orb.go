@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
+	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/mntr"
 
 	"github.com/caos/orbos/internal/secret"
 	"github.com/pkg/errors"
@@ -13,10 +16,106 @@ import (
 )
 
 type Orb struct {
-	Path      string `yaml:"-"`
-	URL       string
-	Repokey   string
-	Masterkey string
+	Path    string `yaml:"-"`
+	URL     string
+	Repokey string
+	// KnownHosts pins the SSH host keys of the repository server in known_hosts format
+	KnownHosts string `yaml:",omitempty"`
+	Masterkey  string
+	Identity   string              `yaml:",omitempty"`
+	Vault      *secret.VaultConfig `yaml:",omitempty"`
+	// CurrentState configures where the orbiter persists its current state.
+	// It is git (default), kubernetes or directory:<path>.
+	// The node agents always persist their current states in git, as they run outside the cluster.
+	CurrentState string `yaml:",omitempty"`
+	// Signingkey is an unencrypted SSH or armored PGP private key all commits are signed with
+	Signingkey string `yaml:",omitempty"`
+	// Ingestion configures the API the operators and node agents push their events to
+	Ingestion *Ingestion `yaml:",omitempty"`
+}
+
+type Ingestion struct {
+	// Address is the host and port of the ingestion API
+	Address string
+	// Token authenticates the events pusher at the ingestion API
+	Token string `yaml:",omitempty"`
+	// CA is a PEM encoded certificate the ingestion APIs certificate must be signed by. If empty, the system roots are used
+	CA string `yaml:",omitempty"`
+	// Insecure disables TLS, which is only meant for testing with a local ingestion API
+	Insecure bool `yaml:",omitempty"`
+	// Query is the URL of the self-hosted ingestion servers query API, which orbctl events reads from
+	Query string `yaml:",omitempty"`
+}
+
+// NodeAgentIngestion returns the ingestion config the orbiter writes to the machines for the node agents
+func (o *Orb) NodeAgentIngestion() string {
+	if o.Ingestion == nil {
+		return ""
+	}
+	nodeAgent := *o.Ingestion
+	nodeAgent.Query = ""
+	content, err := yaml.Marshal(nodeAgent)
+	if err != nil {
+		panic(err)
+	}
+	return string(content)
+}
+
+// ForOperators returns the orbconfig the operators in the cluster run with.
+// It carries the dedicated operators identity and signing key instead of the engineers ones,
+// so the engineers identity and signing key never leave their workstation
+func (o *Orb) ForOperators(identity, signingkey string) *Orb {
+	operators := *o
+	operators.Identity = identity
+	operators.Signingkey = signingkey
+	return &operators
+}
+
+// PinKnownHosts migrates orbconfigs of SSH repositories without pinned host keys
+// by trusting the host key the repository server presents on first use.
+// It is meant for the operators, which run unattended. Interactive runs use ConfirmKnownHosts.
+// If the orbconfig changed, persist is called so the host key is enforced from then on
+func (o *Orb) PinKnownHosts(monitor mntr.Monitor, persist func() error) error {
+	if o.URL == "" || git.IsHTTP(o.URL) || strings.TrimSpace(o.KnownHosts) != "" {
+		return nil
+	}
+
+	knownHosts, err := git.TrustOnFirstUse(monitor, o.URL)
+	if err != nil {
+		return err
+	}
+	o.KnownHosts = string(knownHosts)
+
+	if err := persist(); err != nil {
+		return fmt.Errorf("persisting the pinned host key failed: %w", err)
+	}
+	return nil
+}
+
+// ConfirmKnownHosts pins the host key the repository server presents if confirm accepts it.
+// Other than PinKnownHosts, it is meant for interactive runs, so the host key is never trusted silently.
+// If the orbconfig changed, persist is called so the host key is enforced from then on
+func (o *Orb) ConfirmKnownHosts(monitor mntr.Monitor, confirm func(hostKey *git.HostKey) bool, persist func() error) error {
+	if o.URL == "" || git.IsHTTP(o.URL) || strings.TrimSpace(o.KnownHosts) != "" {
+		return nil
+	}
+
+	hostKey, err := git.ScanHostKey(o.URL)
+	if err != nil {
+		return err
+	}
+
+	if !confirm(hostKey) {
+		return errors.New("host key of the repository server is not trusted, confirm it or pass --accept-host-key")
+	}
+
+	monitor.WithField("fingerprint", hostKey.Fingerprint).Info("Pinning host key of the repository server in current orbconfig")
+	o.KnownHosts = hostKey.KnownHostsLine
+
+	if err := persist(); err != nil {
+		return fmt.Errorf("persisting the pinned host key failed: %w", err)
+	}
+	return nil
 }
 
 func (o *Orb) IsConnectable() (err error) {
@@ -43,8 +142,8 @@ func (o *Orb) IsComplete() (err error) {
 		}
 	}()
 
-	if o.Masterkey == "" {
-		err = helpers.Concat(err, errors.New("master key is missing"))
+	if o.Masterkey == "" && o.Identity == "" {
+		err = helpers.Concat(err, errors.New("neither master key nor identity is configured"))
 	}
 
 	if o.Path == "" {
@@ -69,6 +168,8 @@ func ParseOrbConfig(orbConfigPath string) (*Orb, error) {
 
 	orb.Path = orbConfigPath
 	secret.Masterkey = orb.Masterkey
+	secret.Identity = orb.Identity
+	secret.Vault = orb.Vault
 	return orb, nil
 }
 
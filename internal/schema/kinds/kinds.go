@@ -0,0 +1,49 @@
+// Package kinds registers all desired state kinds orbctl can validate
+package kinds
+
+import (
+	boomlatest "github.com/caos/orbos/internal/operator/boom/api/latest"
+	"github.com/caos/orbos/internal/operator/boom/api/v1beta1"
+	"github.com/caos/orbos/internal/operator/boom/api/v1beta2"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	orbiterorb "github.com/caos/orbos/internal/operator/orbiter/kinds/orb"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/container"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/cs"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ec2"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/gce"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/static"
+	"github.com/caos/orbos/internal/operator/zitadel/kinds/backups/bucket"
+	"github.com/caos/orbos/internal/operator/zitadel/kinds/databases/managed"
+	"github.com/caos/orbos/internal/operator/zitadel/kinds/databases/provided"
+	"github.com/caos/orbos/internal/operator/zitadel/kinds/iam/zitadel"
+	"github.com/caos/orbos/internal/operator/zitadel/kinds/networking/legacycf"
+	zitadelorb "github.com/caos/orbos/internal/operator/zitadel/kinds/orb"
+	"github.com/caos/orbos/internal/schema"
+)
+
+// Registry returns all kinds the operators accept in orbiter.yml, boom.yml and zitadel.yml
+func Registry() schema.Registry {
+	return schema.Registry{
+		{Kind: "orbiter.caos.ch/Orb", Version: "v0", Desired: orbiterorb.DesiredV0{}, Validate: orbiterorb.ValidateDesired},
+		{Kind: "orbiter.caos.ch/KubernetesCluster", Version: "v0", Desired: kubernetes.DesiredV0{}, Validate: kubernetes.ValidateDesired},
+		{Kind: "orbiter.caos.ch/GCEProvider", Version: "v0", Desired: gce.Desired{}, Validate: gce.ValidateDesired},
+		{Kind: "orbiter.caos.ch/CloudScaleProvider", Version: "v0", Desired: cs.Desired{}, Validate: cs.ValidateDesired},
+		{Kind: "orbiter.caos.ch/EC2Provider", Version: "v0", Desired: ec2.Desired{}, Validate: ec2.ValidateDesired},
+		{Kind: "orbiter.caos.ch/ContainerProvider", Version: "v0", Desired: container.Desired{}, Validate: container.ValidateDesired},
+		{Kind: "orbiter.caos.ch/StaticProvider", Version: "v0", Desired: static.DesiredV0{}, Validate: static.ValidateDesired},
+		{Kind: "orbiter.caos.ch/DynamicLoadBalancer", Version: "v0", Desired: dynamic.DesiredV0{}, Validate: dynamic.ValidateDesired},
+		{Kind: "orbiter.caos.ch/DynamicLoadBalancer", Version: "v1", Desired: dynamic.DesiredV1{}, Validate: dynamic.ValidateDesired},
+		{Kind: "orbiter.caos.ch/DynamicLoadBalancer", Version: "v2", Desired: dynamic.Desired{}, Validate: dynamic.ValidateDesired},
+		{Kind: "Toolset", Version: "boom.caos.ch/v1beta1", Desired: v1beta1.Toolset{}},
+		{Kind: "Toolset", Version: "boom.caos.ch/v1beta2", Desired: v1beta2.Toolset{}},
+		{Kind: "Toolset", Version: "boom.caos.ch/v1", Desired: boomlatest.Toolset{}},
+		{Kind: "zitadel.caos.ch/Orb", Version: "v0", Desired: zitadelorb.DesiredV0{}},
+		{Kind: "zitadel.caos.ch/Zitadel", Version: "v0", Desired: zitadel.DesiredV0{}},
+		{Kind: "zitadel.caos.ch/ManagedDatabase", Version: "v0", Desired: managed.DesiredV0{}},
+		// The operator only accepts this spelling
+		{Kind: "zitadel.caos.ch/ProvidedDatabse", Version: "v0", Desired: provided.DesiredV0{}},
+		{Kind: "zitadel.caos.ch/LegacyCloudflare", Version: "v0", Desired: legacycf.Desired{}, Validate: legacycf.ValidateDesired},
+		{Kind: "zitadel.caos.ch/BucketBackup", Version: "v0", Desired: bucket.DesiredV0{}},
+	}
+}
@@ -0,0 +1,48 @@
+package kinds
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_ValidateExamples(t *testing.T) {
+	for _, file := range []string{
+		"../../../examples/orbiter/gce/orbiter.yml",
+		"../../../examples/orbiter/static/orbiter.yml",
+		"../../../examples/boom/boom.yml",
+	} {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errs := Registry().Validate(content); len(errs) > 0 {
+			t.Errorf("expected %s to be valid, got %v", file, errs)
+		}
+	}
+}
+
+func TestRegistry_ValidateFindsTypos(t *testing.T) {
+	content, err := ioutil.ReadFile("../../../examples/orbiter/gce/orbiter.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typo := strings.Replace(string(content), "mincpucores: 2", "mincpucore: 2", 1)
+	errs := Registry().Validate([]byte(typo))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "providers.gcezurich.spec.pools.management.mincpucore: unknown property") {
+		t.Errorf("expected the misspelled property to be reported, got %v", errs)
+	}
+
+	invalid := strings.Replace(string(content), "version: v2", "version: v3", 1)
+	errs = Registry().Validate([]byte(invalid))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "version v3 of kind orbiter.caos.ch/DynamicLoadBalancer is not supported") {
+		t.Errorf("expected the unsupported version to be reported, got %v", errs)
+	}
+
+	unscaled := strings.Replace(string(content), "nodes: 1", "nodes: 2", 1)
+	errs = Registry().Validate([]byte(unscaled))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "Controlplane nodes can only be scaled to 1, 3 or 5") {
+		t.Errorf("expected the kinds own validation to fail, got %v", errs)
+	}
+}
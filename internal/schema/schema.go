@@ -0,0 +1,267 @@
+// Package schema generates JSON Schemas from the desired state types and validates desired state files against them
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/tree"
+)
+
+const draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is the subset of a JSON Schema (draft-07) needed to describe desired states
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Const       string             `json:"const,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties is either false or a *Schema
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Required             []string    `json:"required,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+	Minimum              *int        `json:"minimum,omitempty"`
+	AllOf                []*Schema   `json:"allOf,omitempty"`
+	If                   *Schema     `json:"if,omitempty"`
+	Then                 *Schema     `json:"then,omitempty"`
+
+	// tree marks nested desired states, which are validated against the schema of their own kind
+	tree bool
+}
+
+var (
+	treeType     = reflect.TypeOf(tree.Tree{})
+	secretType   = reflect.TypeOf(secret.Secret{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	unmarshalers = []reflect.Type{
+		reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem(),
+		reflect.TypeOf((*obsoleteUnmarshaler)(nil)).Elem(),
+	}
+)
+
+// obsoleteUnmarshaler is the yaml.v2 style unmarshaler yaml.v3 still respects
+type obsoleteUnmarshaler interface {
+	UnmarshalYAML(unmarshal func(interface{}) error) error
+}
+
+type generator struct {
+	kinds    Registry
+	visiting map[reflect.Type]bool
+}
+
+func (g *generator) generate(typ reflect.Type, root bool) *Schema {
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ {
+	case treeType:
+		return g.treeSchema()
+	case durationType:
+		return &Schema{Type: "string", Description: "A duration like 30s or 5m"}
+	case timeType:
+		return &Schema{Type: "string", Description: "A timestamp like 2020-07-01T22:00:00Z"}
+	}
+
+	// Types decoding themselves can't be described by reflection.
+	// Secrets only decrypt their fields, so they are described anyway
+	if !root && typ != secretType && decodesItself(typ) {
+		return &Schema{}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: g.generate(typ.Elem(), false)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.generate(typ.Elem(), false)}
+	case reflect.Struct:
+		// Recursive types are not expanded twice
+		if g.visiting[typ] {
+			return &Schema{Type: "object"}
+		}
+		g.visiting[typ] = true
+		defer delete(g.visiting, typ)
+
+		schema := &Schema{
+			Type:                 "object",
+			Properties:           make(map[string]*Schema),
+			AdditionalProperties: false,
+		}
+		g.fields(schema, typ)
+		return schema
+	}
+	return &Schema{}
+}
+
+// fields adds the fields of typ to schema the same way yaml.v3 decodes them
+func (g *generator) fields(schema *Schema, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		inline := false
+		for _, flag := range parts[1:] {
+			if flag == "inline" {
+				inline = true
+			}
+		}
+
+		if inline {
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			switch fieldType.Kind() {
+			case reflect.Struct:
+				g.fields(schema, fieldType)
+			case reflect.Map:
+				schema.AdditionalProperties = g.generate(fieldType.Elem(), false)
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		schema.Properties[name] = g.generate(field.Type, false)
+	}
+}
+
+// treeSchema dispatches to the schemas of all registered kinds by kind and version
+func (g *generator) treeSchema() *Schema {
+	schema := &Schema{
+		Type:     "object",
+		Required: []string{"kind"},
+		Properties: map[string]*Schema{
+			"kind":    {Type: "string"},
+			"version": {Type: "string"},
+		},
+		tree: true,
+	}
+	for _, kind := range g.kinds {
+		condition := &Schema{
+			Required: []string{"kind"},
+			Properties: map[string]*Schema{
+				"kind": {Const: kind.Kind},
+			},
+		}
+		if len(g.kinds.versions(kind.Kind)) > 1 {
+			condition.Required = append(condition.Required, "version")
+			condition.Properties["version"] = &Schema{Const: kind.Version}
+		}
+		schema.AllOf = append(schema.AllOf, &Schema{
+			If:   condition,
+			Then: &Schema{Ref: kind.File()},
+		})
+	}
+	return schema
+}
+
+func decodesItself(typ reflect.Type) bool {
+	ptr := reflect.PtrTo(typ)
+	for _, unmarshaler := range unmarshalers {
+		if typ.Implements(unmarshaler) || ptr.Implements(unmarshaler) {
+			return true
+		}
+	}
+	return false
+}
+
+// Kind is a desired state kind in a specific version
+type Kind struct {
+	Kind    string
+	Version string
+	// Desired is a value of the type the kind is decoded to
+	Desired interface{}
+	// Validate runs the checks of the kind which are not expressible in a schema. It is optional
+	Validate func(desiredTree *tree.Tree) error
+}
+
+// File is the name the schema of the kind is written to and referenced by
+func (k Kind) File() string {
+	return fmt.Sprintf("%s.%s.schema.json", strings.ReplaceAll(k.Kind, "/", "_"), strings.ReplaceAll(k.Version, "/", "_"))
+}
+
+func (k Kind) String() string {
+	return fmt.Sprintf("%s %s", k.Kind, k.Version)
+}
+
+// Registry holds all known kinds
+type Registry []Kind
+
+// Lookup returns the registered kind.
+// Kinds with only one registered version are returned for any version, as their operators migrate them
+func (r Registry) Lookup(kind, version string) (Kind, error) {
+	versions := r.versions(kind)
+	switch len(versions) {
+	case 0:
+		return Kind{}, fmt.Errorf("unknown kind %s", kind)
+	case 1:
+		return versions[0], nil
+	}
+
+	supported := make([]string, len(versions))
+	for idx, k := range versions {
+		if k.Version == version {
+			return k, nil
+		}
+		supported[idx] = k.Version
+	}
+	sort.Strings(supported)
+	return Kind{}, fmt.Errorf("version %s of kind %s is not supported, use one of %s", version, kind, strings.Join(supported, ", "))
+}
+
+func (r Registry) versions(kind string) []Kind {
+	var versions []Kind
+	for _, k := range r {
+		if k.Kind == kind {
+			versions = append(versions, k)
+		}
+	}
+	return versions
+}
+
+// Schema generates the JSON Schema of the kind
+func (r Registry) Schema(kind Kind) *Schema {
+	schema := (&generator{
+		kinds:    r,
+		visiting: make(map[reflect.Type]bool),
+	}).generate(reflect.TypeOf(kind.Desired), true)
+	schema.Schema = draft
+	schema.Title = kind.String()
+	return schema
+}
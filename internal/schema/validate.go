@@ -0,0 +1,212 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/tree"
+)
+
+// Error is a violation found in a desired state
+type Error struct {
+	Line int
+	Path string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "<root>"
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, path, e.Msg)
+}
+
+// Validate checks a desired state and all its nested desired states against the schemas of their kinds.
+// The nested desired states which match their schemas are additionally checked by their kinds Validate functions.
+// Secrets are neither decrypted nor resolved, so no keys are needed.
+func (r Registry) Validate(content []byte) []error {
+
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(content, node); err != nil {
+		return []error{err}
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return []error{&Error{Line: node.Line, Msg: "document is empty"}}
+		}
+		node = node.Content[0]
+	}
+
+	offline := secret.Offline
+	secret.Offline = true
+	defer func() { secret.Offline = offline }()
+
+	v := &validator{kinds: r}
+	v.tree("", node)
+	return v.errs
+}
+
+type validator struct {
+	kinds Registry
+	errs  []error
+}
+
+func (v *validator) fail(node *yaml.Node, path string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &Error{Line: node.Line, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// tree validates a node against the schema of the kind it declares
+func (v *validator) tree(path string, node *yaml.Node) {
+
+	if node.Kind != yaml.MappingNode {
+		v.fail(node, path, "expected a desired state with a kind")
+		return
+	}
+
+	kindName := scalar(node, "kind")
+	if kindName == "" {
+		v.fail(node, path, "kind is missing")
+		return
+	}
+
+	version := scalar(node, "version")
+	if version == "" {
+		version = scalar(node, "apiVersion")
+	}
+
+	kind, err := v.kinds.Lookup(kindName, version)
+	if err != nil {
+		v.fail(node, path, err.Error())
+		return
+	}
+
+	before := len(v.errs)
+	v.node(path, node, v.kinds.Schema(kind))
+	if len(v.errs) > before || kind.Validate == nil {
+		return
+	}
+
+	desiredTree := &tree.Tree{}
+	if err := node.Decode(desiredTree); err != nil {
+		v.fail(node, path, "decoding %s failed: %s", kind, err)
+		return
+	}
+
+	if err := kind.Validate(desiredTree); err != nil {
+		v.fail(node, path, "%s", err)
+	}
+}
+
+// node validates a node against a schema
+func (v *validator) node(path string, node *yaml.Node, schema *Schema) {
+
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	// yaml decodes null to the zero value of any type
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	if schema.tree {
+		v.tree(path, node)
+		return
+	}
+
+	switch schema.Type {
+	case "":
+		return
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			v.fail(node, path, "expected an object")
+			return
+		}
+		v.object(path, node, schema)
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.fail(node, path, "expected a list")
+			return
+		}
+		for idx, item := range node.Content {
+			v.node(fmt.Sprintf("%s[%d]", path, idx), item, schema.Items)
+		}
+	case "string":
+		if node.Kind != yaml.ScalarNode {
+			v.fail(node, path, "expected a string")
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			v.fail(node, path, "expected true or false, got %s", node.Value)
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			v.fail(node, path, "expected an integer, got %s", node.Value)
+			return
+		}
+		if schema.Minimum != nil && strings.HasPrefix(node.Value, "-") {
+			v.fail(node, path, "expected a positive integer, got %s", node.Value)
+		}
+	case "number":
+		if node.Kind != yaml.ScalarNode || (node.ShortTag() != "!!int" && node.ShortTag() != "!!float") {
+			v.fail(node, path, "expected a number, got %s", node.Value)
+		}
+	}
+}
+
+func (v *validator) object(path string, node *yaml.Node, schema *Schema) {
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		seen[key.Value] = true
+		childPath := key.Value
+		if path != "" {
+			childPath = path + "." + key.Value
+		}
+
+		if property, ok := schema.Properties[key.Value]; ok {
+			v.node(childPath, value, property)
+			continue
+		}
+
+		switch additional := schema.AdditionalProperties.(type) {
+		case *Schema:
+			v.node(childPath, value, additional)
+		case bool:
+			if !additional {
+				v.fail(key, childPath, "unknown property, use one of %s", strings.Join(keys(schema.Properties), ", "))
+			}
+		}
+	}
+
+	for _, required := range schema.Required {
+		if !seen[required] {
+			v.fail(node, path, "property %s is missing", required)
+		}
+	}
+}
+
+func scalar(node *yaml.Node, key string) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+func keys(properties map[string]*Schema) []string {
+	sorted := make([]string, 0, len(properties))
+	for key := range properties {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
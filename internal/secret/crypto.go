@@ -0,0 +1,213 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// EncryptionAES256 is the legacy format: AES-CFB with the zero padded master key.
+	// It is only decrypted anymore, never written.
+	EncryptionAES256 = "AES256"
+	// EncryptionAES256GCM is AES-GCM with a key derived from the master key by scrypt
+	EncryptionAES256GCM = "AES256-GCM-SCRYPT"
+	EncodingBase64      = "Base64"
+
+	saltSize = 16
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	derivedKeysMux sync.Mutex
+	derivedKeys    = make(map[string][]byte)
+	encryptionSalt = make(map[string][]byte)
+)
+
+func trimmedMasterkey() string {
+	return strings.Trim(Masterkey, "\n")
+}
+
+func deriveKey(masterkey string, salt []byte) ([]byte, error) {
+	derivedKeysMux.Lock()
+	defer derivedKeysMux.Unlock()
+
+	cacheKey := masterkey + string(salt)
+	if key, ok := derivedKeys[cacheKey]; ok {
+		return key, nil
+	}
+
+	key, err := scrypt.Key([]byte(masterkey), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKeys[cacheKey] = key
+	return key, nil
+}
+
+// saltFor returns a salt that is reused for all encryptions with the same master key
+// during the lifetime of the process, so that the expensive key derivation happens only once.
+// The nonce is still random for every secret.
+func saltFor(masterkey string) ([]byte, error) {
+	derivedKeysMux.Lock()
+	defer derivedKeysMux.Unlock()
+
+	if salt, ok := encryptionSalt[masterkey]; ok {
+		return salt, nil
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	encryptionSalt[masterkey] = salt
+	return salt, nil
+}
+
+func newGCM(masterkey string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(masterkey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func encrypt(value string) (*secretAlias, error) {
+
+	if len(Recipients) > 0 {
+		return encryptAge(value)
+	}
+
+	masterkey := trimmedMasterkey()
+	if masterkey == "" {
+		return nil, errors.New("Master key must not be empty")
+	}
+
+	salt, err := saltFor(masterkey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(masterkey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	// salt | nonce | ciphertext and tag
+	prefix := append(append(make([]byte, 0, len(salt)+len(nonce)), salt...), nonce...)
+	cipherText := gcm.Seal(prefix, nonce, []byte(value), []byte(EncryptionAES256GCM))
+
+	return &secretAlias{
+		Encryption: EncryptionAES256GCM,
+		Encoding:   EncodingBase64,
+		Value:      base64.URLEncoding.EncodeToString(cipherText),
+	}, nil
+}
+
+func decrypt(s *Secret) (string, error) {
+
+	if s.Encoding != "" && s.Encoding != EncodingBase64 {
+		return "", fmt.Errorf("Unsupported encoding %s", s.Encoding)
+	}
+
+	cipherText, err := base64.URLEncoding.DecodeString(s.Value)
+	if err != nil {
+		return "", err
+	}
+
+	switch s.Encryption {
+	case EncryptionAgeX25519:
+		return decryptAge(cipherText)
+	case EncryptionAES256GCM:
+		return decryptGCM(cipherText)
+	case EncryptionAES256, "":
+		return decryptLegacy(cipherText)
+	}
+	return "", fmt.Errorf("Unsupported encryption %s", s.Encryption)
+}
+
+func decryptGCM(cipherText []byte) (string, error) {
+
+	masterkey := trimmedMasterkey()
+
+	if len(cipherText) < saltSize {
+		return "", errors.New("Ciphertext is too short")
+	}
+	salt := cipherText[:saltSize]
+	cipherText = cipherText[saltSize:]
+
+	gcm, err := newGCM(masterkey, salt)
+	if err != nil {
+		return "", err
+	}
+
+	if len(cipherText) < gcm.NonceSize() {
+		return "", errors.New("Ciphertext is too short")
+	}
+	nonce := cipherText[:gcm.NonceSize()]
+	cipherText = cipherText[gcm.NonceSize():]
+
+	plainText, err := gcm.Open(nil, nonce, cipherText, []byte(EncryptionAES256GCM))
+	if err != nil {
+		return "", errors.New("Decryption failed, either the master key is wrong or the secret was tampered with")
+	}
+	return string(plainText), nil
+}
+
+func decryptLegacy(cipherText []byte) (string, error) {
+
+	if len(trimmedMasterkey()) > 32 {
+		return "", errors.New("Master key size must not exceed 32 characters for decrypting AES256 secrets")
+	}
+
+	masterKeyLocal := make([]byte, 32)
+	for idx, char := range []byte(trimmedMasterkey()) {
+		masterKeyLocal[idx] = char
+	}
+
+	block, err := aes.NewCipher(masterKeyLocal)
+	if err != nil {
+		return "", err
+	}
+
+	if len(cipherText) < aes.BlockSize {
+		return "", errors.New("Ciphertext block size is too short")
+	}
+
+	//IV needs to be unique, but doesn't have to be secure.
+	//It's common to put it at the beginning of the ciphertext.
+	iv := cipherText[:aes.BlockSize]
+	cipherText = cipherText[aes.BlockSize:]
+
+	stream := cipher.NewCFBDecrypter(block, iv)
+	// XORKeyStream can work in-place if the two arguments are the same.
+	stream.XORKeyStream(cipherText, cipherText)
+
+	if !utf8.Valid(cipherText) {
+		return "", errors.New("Decryption failed")
+	}
+	return string(cipherText), nil
+}
@@ -0,0 +1,175 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault holds the credentials for resolving secrets stored in HashiCorp Vault.
+// It is configured in the orbconfig, VAULT_ADDR and VAULT_TOKEN are used as fallbacks.
+var Vault *VaultConfig
+
+// VaultConfig: Connection to a HashiCorp Vault
+type VaultConfig struct {
+	//Address of the Vault server, e.g. https://vault.example.com:8200
+	Address string `yaml:"address,omitempty"`
+	//Token used for authentication
+	Token string `yaml:"token,omitempty"`
+	//Enterprise namespace
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// External: Reference to a value which is not stored in the repository
+type External struct {
+	//Value is read from a HashiCorp Vault KV secrets engine
+	Vault *VaultRef `json:"vault,omitempty" yaml:"vault,omitempty"`
+	//Value is read from the file at this path
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	//Value is read from the environment variable with this name
+	Env string `json:"env,omitempty" yaml:"env,omitempty"`
+}
+
+// VaultRef: Key in a HashiCorp Vault KV secret
+type VaultRef struct {
+	//Path of the secret including the mount, e.g. secret/data/orbos for a KV version 2 engine mounted at secret
+	Path string `json:"path" yaml:"path"`
+	//Key in the secret, defaults to value
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+func (e *External) String() string {
+	switch {
+	case e.Vault != nil:
+		return fmt.Sprintf("vault:%s#%s", e.Vault.Path, e.Vault.key())
+	case e.File != "":
+		return "file:" + e.File
+	case e.Env != "":
+		return "env:" + e.Env
+	}
+	return "external:none"
+}
+
+func (e *External) validate() error {
+	refs := 0
+	if e.Vault != nil {
+		refs++
+		if e.Vault.Path == "" {
+			return errors.New("vault path is missing")
+		}
+	}
+	if e.File != "" {
+		refs++
+	}
+	if e.Env != "" {
+		refs++
+	}
+	if refs != 1 {
+		return errors.New("exactly one of vault, file or env must be referenced")
+	}
+	return nil
+}
+
+func (e *External) resolve() (string, error) {
+	if err := e.validate(); err != nil {
+		return "", err
+	}
+
+	switch {
+	case e.Vault != nil:
+		return e.Vault.read(vaultConfig())
+	case e.File != "":
+		value, err := ioutil.ReadFile(e.File)
+		if err != nil {
+			return "", fmt.Errorf("reading %s failed: %w", e, err)
+		}
+		return string(value), nil
+	default:
+		value, ok := os.LookupEnv(e.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", e.Env)
+		}
+		return value, nil
+	}
+}
+
+func vaultConfig() VaultConfig {
+	cfg := VaultConfig{}
+	if Vault != nil {
+		cfg = *Vault
+	}
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	return cfg
+}
+
+func (v *VaultRef) key() string {
+	if v.Key == "" {
+		return "value"
+	}
+	return v.Key
+}
+
+func (v *VaultRef) read(cfg VaultConfig) (string, error) {
+
+	if cfg.Address == "" || cfg.Token == "" {
+		return "", errors.New("vault address or token is neither configured in the orbconfig nor by VAULT_ADDR and VAULT_TOKEN")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(cfg.Address, "/"), strings.TrimPrefix(v.Path, "/")), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", cfg.Token)
+	if cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", cfg.Namespace)
+	}
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("reading vault secret %s failed: %w", v.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reading vault secret %s failed with status %s", v.Path, resp.Status)
+	}
+
+	body := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding vault secret %s failed: %w", v.Path, err)
+	}
+
+	data := body.Data
+	// KV version 2 engines nest the key value pairs
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+		}
+	}
+
+	value, ok := data[v.key()]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in vault secret %s", v.key(), v.Path)
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s in vault secret %s is not a string", v.key(), v.Path)
+	}
+	return str, nil
+}
@@ -0,0 +1,71 @@
+package secret
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecret_ExternalVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/orbos" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"jsonkey":"my value"},"metadata":{"version":1}}}`))
+	}))
+	defer server.Close()
+
+	Vault = &VaultConfig{Address: server.URL, Token: "root"}
+	defer func() { Vault = nil }()
+
+	in := "secret:\n  external:\n    vault:\n      path: secret/data/orbos\n      key: jsonkey\n"
+	out := &testSecrets{}
+	if err := yaml.Unmarshal([]byte(in), out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Secret.Value != "my value" {
+		t.Fatalf("expected my value, got %s", out.Secret.Value)
+	}
+	if src := out.Secret.Source(); src != "vault:secret/data/orbos#jsonkey" {
+		t.Fatalf("unexpected source %s", src)
+	}
+
+	marshalled, err := yaml.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(marshalled), "value") {
+		t.Fatalf("expected only the reference to be marshalled, got:\n%s", marshalled)
+	}
+
+	Vault.Token = "wrong"
+	if err := yaml.Unmarshal([]byte(in), &testSecrets{}); err == nil {
+		t.Fatal("expected resolving with a wrong token to fail")
+	}
+}
+
+func TestSecret_ExternalEnv(t *testing.T) {
+	os.Setenv("ORBOS_TEST_SECRET", "my value")
+	defer os.Unsetenv("ORBOS_TEST_SECRET")
+
+	out := &testSecrets{}
+	if err := yaml.Unmarshal([]byte("secret:\n  external:\n    env: ORBOS_TEST_SECRET\n"), out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Secret.Value != "my value" {
+		t.Fatalf("expected my value, got %s", out.Secret.Value)
+	}
+
+	if err := yaml.Unmarshal([]byte("secret:\n  external:\n    env: ORBOS_TEST_SECRET\n    file: /tmp/secret\n"), &testSecrets{}); err == nil {
+		t.Fatal("expected multiple references to fail")
+	}
+}
@@ -0,0 +1,209 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/mntr"
+)
+
+// Entry describes a secret without revealing its value
+type Entry struct {
+	Path   string
+	Set    bool
+	Source string
+	// LastChanged is the commit that changed the value the last time
+	LastChanged *git.Commit
+	// Earliest is true if the history could not be searched further than LastChanged
+	Earliest bool
+}
+
+type ChangeType string
+
+const (
+	Added         ChangeType = "added"
+	Removed       ChangeType = "removed"
+	Changed       ChangeType = "changed"
+	SourceChanged ChangeType = "source changed"
+)
+
+type Change struct {
+	Path string
+	Type ChangeType
+}
+
+type state struct {
+	value  string
+	source string
+}
+
+// List describes all secrets and searches the commits which changed them the last time.
+// The repository must be cloned using CloneHistory.
+func List(monitor mntr.Monitor, gitClient *git.Client, getFunc GetFuncs, maxCommits int, files ...string) ([]*Entry, error) {
+
+	commits, err := gitClient.Log(files...)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits found changing any of %v", files)
+	}
+
+	current, err := states(monitor, gitClient, getFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]*Entry, len(current))
+	unresolved := make(map[string]*Entry)
+	for path, st := range current {
+		entry := &Entry{
+			Path:   path,
+			Set:    st.value != "",
+			Source: st.source,
+		}
+		entries[path] = entry
+		if entry.Set {
+			unresolved[path] = entry
+		}
+	}
+
+	defer restoreHead(monitor, gitClient)()
+
+	exhausted := true
+	for idx, commit := range commits {
+		if len(unresolved) == 0 {
+			exhausted = false
+			break
+		}
+		if maxCommits > 0 && idx >= maxCommits {
+			monitor.WithField("commits", maxCommits).Info("Stopped searching the history as the maximum number of commits is reached")
+			exhausted = false
+			break
+		}
+
+		if err := gitClient.Checkout(commit.Hash); err != nil {
+			return nil, err
+		}
+
+		previous, err := states(monitor, gitClient, getFunc)
+		if err != nil {
+			monitor.WithField("commit", commit.ShortHash()).Info(fmt.Sprintf("Stopped searching the history as the secrets are not readable: %s", err.Error()))
+			exhausted = false
+			break
+		}
+
+		for path, entry := range unresolved {
+			if prev, ok := previous[path]; ok && prev.value == current[path].value {
+				entry.LastChanged = commit
+				continue
+			}
+			delete(unresolved, path)
+		}
+	}
+
+	if !exhausted {
+		for _, entry := range unresolved {
+			entry.Earliest = true
+		}
+	}
+
+	sorted := make([]*Entry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+	return sorted, nil
+}
+
+// Diff compares the secrets of two revisions without revealing their values
+func Diff(monitor mntr.Monitor, gitClient *git.Client, getFunc GetFuncs, from, to string) ([]*Change, error) {
+
+	defer restoreHead(monitor, gitClient)()
+
+	if err := gitClient.Checkout(from); err != nil {
+		return nil, err
+	}
+	fromStates, err := states(monitor, gitClient, getFunc)
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets at %s failed: %w", from, err)
+	}
+
+	if err := gitClient.Checkout(to); err != nil {
+		return nil, err
+	}
+	toStates, err := states(monitor, gitClient, getFunc)
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets at %s failed: %w", to, err)
+	}
+
+	paths := make(map[string]struct{})
+	for path := range fromStates {
+		paths[path] = struct{}{}
+	}
+	for path := range toStates {
+		paths[path] = struct{}{}
+	}
+
+	changes := make([]*Change, 0)
+	for path := range paths {
+		fromState := fromStates[path]
+		toState := toStates[path]
+
+		var changeType ChangeType
+		switch {
+		case fromState.value == "" && toState.value != "":
+			changeType = Added
+		case fromState.value != "" && toState.value == "":
+			changeType = Removed
+		case fromState.source != toState.source && toState.value != "":
+			changeType = SourceChanged
+		case fromState.value != toState.value:
+			changeType = Changed
+		default:
+			continue
+		}
+		changes = append(changes, &Change{Path: path, Type: changeType})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+func states(monitor mntr.Monitor, gitClient *git.Client, getFunc GetFuncs) (map[string]state, error) {
+	allSecrets, _, err := getFunc(monitor, gitClient)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]state, len(allSecrets))
+	for path, secret := range allSecrets {
+		if secret == nil {
+			states[path] = state{source: "none"}
+			continue
+		}
+		states[path] = state{
+			value:  secret.Value,
+			source: secret.Source(),
+		}
+	}
+	return states, nil
+}
+
+func restoreHead(monitor mntr.Monitor, gitClient *git.Client) func() {
+	head, err := gitClient.HeadHash()
+	if err != nil {
+		monitor.Error(err)
+		return func() {}
+	}
+	return func() {
+		if err := gitClient.Checkout(head); err != nil {
+			monitor.Error(err)
+		}
+	}
+}
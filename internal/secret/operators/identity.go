@@ -0,0 +1,72 @@
+package operators
+
+import (
+	"fmt"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/mntr"
+)
+
+// Recipient is the name the operators identity is declared with in the recipients.yml
+const Recipient = secret.OperatorsRecipient
+
+// EnsureIdentity returns the age identity the operators in the clusters decrypt the secrets with.
+// If the already deployed identity is not declared as recipient anymore, a new one is generated,
+// declared as recipient and all secrets are reencrypted.
+// As long as no recipients are declared, the operators decrypt with the master key and no identity is returned
+func EnsureIdentity(monitor mntr.Monitor, gitClient *git.Client, orb *orb.Orb, deployed string) (string, error) {
+
+	recipients, err := secret.ReadRecipients(gitClient)
+	if err != nil {
+		return "", err
+	}
+
+	if len(recipients.Recipients) == 0 {
+		return "", nil
+	}
+
+	if recipients.Contains(deployed) {
+		return deployed, nil
+	}
+
+	identity, publicKey, err := secret.GenerateIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	monitor.WithField("recipient", Recipient).Info("Declaring a new operators identity as recipient")
+	if err := secret.ReplaceRecipient(monitor, gitClient, Recipient, publicKey, GetAllSecretsFunc(orb), PushAllFunc()); err != nil {
+		return "", err
+	}
+
+	return identity, secret.LoadRecipients(gitClient)
+}
+
+// AddRecipient adds a recipient and reencrypts all secrets.
+// If the operators are no recipient yet, a new operators identity is declared along with it.
+// As the operators can't decrypt the secrets anymore without it, the identity is deployed before the secrets are reencrypted
+func AddRecipient(monitor mntr.Monitor, gitClient *git.Client, orb *orb.Orb, name, publicKey string, deploy func(identity string) error) error {
+
+	recipients, err := secret.ReadRecipients(gitClient)
+	if err != nil {
+		return err
+	}
+
+	add := []*secret.Recipient{{Name: name, PublicKey: publicKey}}
+
+	if !recipients.Declares(Recipient) {
+		identity, operatorsKey, err := secret.GenerateIdentity()
+		if err != nil {
+			return err
+		}
+		if err := deploy(identity); err != nil {
+			return fmt.Errorf("deploying the new operators identity failed: %w", err)
+		}
+		monitor.WithField("recipient", Recipient).Info("Declaring the new operators identity as recipient")
+		add = append(add, &secret.Recipient{Name: Recipient, PublicKey: operatorsKey})
+	}
+
+	return secret.AddRecipients(monitor, gitClient, add, GetAllSecretsFunc(orb), PushAllFunc())
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/caos/orbos/internal/api"
 	"github.com/caos/orbos/internal/git"
 	boomapi "github.com/caos/orbos/internal/operator/boom/api"
+	"github.com/caos/orbos/internal/operator/common"
 	orbiterOrb "github.com/caos/orbos/internal/operator/orbiter/kinds/orb"
 	zitadelOrb "github.com/caos/orbos/internal/operator/zitadel/kinds/orb"
 	"github.com/caos/orbos/internal/orb"
@@ -126,3 +127,26 @@ func PushFunc() func(monitor mntr.Monitor, gitClient *git.Client, trees map[stri
 		return errors.New("Operator push function unknown")
 	}
 }
+
+func PushAllFunc() func(monitor mntr.Monitor, gitClient *git.Client, trees map[string]*tree.Tree, msg string, additional ...git.File) error {
+	return func(monitor mntr.Monitor, gitClient *git.Client, trees map[string]*tree.Tree, msg string, additional ...git.File) (err error) {
+		files := additional
+		for _, operator := range []string{orbiter, boom, zitadel} {
+			desired, found := trees[operator]
+			if !found {
+				continue
+			}
+			files = append(files, git.File{
+				Path:    operator + ".yml",
+				Content: common.MarshalYAML(desired),
+			})
+		}
+
+		monitor.OnChange = func(_ string, fields map[string]string) {
+			err = gitClient.UpdateRemote(mntr.SprintCommit(msg, fields), files...)
+			mntr.LogMessage(msg, fields)
+		}
+		monitor.Changed(msg)
+		return err
+	}
+}
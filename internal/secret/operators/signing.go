@@ -0,0 +1,124 @@
+package operators
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	sshlib "golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/orb"
+	"github.com/caos/orbos/internal/ssh"
+	"github.com/caos/orbos/mntr"
+)
+
+// EnsureSigningKey returns the SSH private key the operators in the clusters sign their commits with.
+// The engineers signing key never leaves their workstation. If the already deployed key is missing or
+// is the engineers one, a new key is generated.
+// If the orbiter.yml declares a verification policy, the operators public key is added to its trusted keys
+// by a commit signed with the engineers key.
+// As long as neither a signing key nor a verification policy is configured, no key is returned
+func EnsureSigningKey(monitor mntr.Monitor, gitClient *git.Client, orb *orb.Orb, deployed string) (string, error) {
+
+	orbiterYml := gitClient.Read("orbiter.yml")
+	policy := struct {
+		Spec struct {
+			Verification struct {
+				TrustedKeys []string `yaml:"trustedKeys"`
+			}
+		}
+	}{}
+	if err := yaml.Unmarshal(orbiterYml, &policy); err != nil {
+		return "", fmt.Errorf("parsing verification policy failed: %w", err)
+	}
+	trustedKeys := policy.Spec.Verification.TrustedKeys
+
+	if strings.TrimSpace(orb.Signingkey) == "" && len(trustedKeys) == 0 {
+		return "", nil
+	}
+
+	signingKey := deployed
+	if strings.TrimSpace(signingKey) == "" || strings.TrimSpace(signingKey) == strings.TrimSpace(orb.Signingkey) {
+		generated, _, err := ssh.Generate()
+		if err != nil {
+			return "", err
+		}
+		signingKey = generated
+	}
+
+	signer, err := sshlib.ParsePrivateKey([]byte(signingKey))
+	if err != nil {
+		return "", fmt.Errorf("parsing operators signing key failed: %w", err)
+	}
+	publicKey := signer.PublicKey()
+
+	if len(trustedKeys) == 0 || trusts(trustedKeys, publicKey) {
+		return signingKey, nil
+	}
+
+	trusted, err := addTrustedKey(orbiterYml, strings.TrimSpace(string(sshlib.MarshalAuthorizedKey(publicKey)))+" orbos-operators")
+	if err != nil {
+		return "", err
+	}
+
+	monitor.Info("Trusting the operators signing key")
+	return signingKey, gitClient.UpdateRemote("Trust the operators signing key", git.File{
+		Path:    "orbiter.yml",
+		Content: trusted,
+	})
+}
+
+func trusts(trustedKeys []string, publicKey sshlib.PublicKey) bool {
+	for _, key := range trustedKeys {
+		trusted, _, _, _, err := sshlib.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(trusted.Marshal(), publicKey.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// addTrustedKey appends the key to spec.verification.trustedKeys and leaves the rest of the document untouched
+func addTrustedKey(orbiterYml []byte, key string) ([]byte, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(orbiterYml, doc); err != nil {
+		return nil, err
+	}
+
+	node := doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, field := range []string{"spec", "verification", "trustedKeys"} {
+		if node = mappingValue(node, field); node == nil {
+			return nil, fmt.Errorf("orbiter.yml has no field %s", field)
+		}
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("trustedKeys in orbiter.yml is no list")
+	}
+
+	node.Content = append(node.Content, &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!str",
+		Value: key,
+	})
+	return yaml.Marshal(doc)
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			return node.Content[idx+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/git"
+)
+
+const (
+	// EncryptionAgeX25519 is age encryption to all recipients declared in the repository
+	EncryptionAgeX25519 = "AGE-X25519"
+
+	RecipientsFile = "recipients.yml"
+
+	// OperatorsRecipient is the name the identity of the operators in the clusters is declared with
+	OperatorsRecipient = "orbos-operators"
+)
+
+var (
+	// Recipients are the age public keys all secrets are encrypted to.
+	// If there are none, secrets are encrypted with the Masterkey.
+	Recipients []string
+	// Identity is the age private key secrets encrypted to Recipients are decrypted with
+	Identity string
+)
+
+// RecipientsList: Everybody who is able to decrypt the secrets in the repository
+type RecipientsList struct {
+	Recipients []*Recipient `yaml:"recipients"`
+}
+
+// Recipient: A person or an operator owning the identity to the public key
+type Recipient struct {
+	//Name of the recipient, used for identifying it when removing
+	Name string `yaml:"name"`
+	//Age X25519 public key of the recipient
+	PublicKey string `yaml:"publicKey"`
+}
+
+func (r *RecipientsList) PublicKeys() []string {
+	keys := make([]string, 0, len(r.Recipients))
+	for _, recipient := range r.Recipients {
+		keys = append(keys, recipient.PublicKey)
+	}
+	return keys
+}
+
+func (r *RecipientsList) Add(name, publicKey string) error {
+	if _, err := age.ParseX25519Recipient(publicKey); err != nil {
+		return fmt.Errorf("parsing public key of recipient %s failed: %w", name, err)
+	}
+
+	for _, recipient := range r.Recipients {
+		if recipient.Name == name {
+			return fmt.Errorf("recipient %s already exists", name)
+		}
+		if recipient.PublicKey == publicKey {
+			return fmt.Errorf("public key is already added as recipient %s", recipient.Name)
+		}
+	}
+	r.Recipients = append(r.Recipients, &Recipient{Name: name, PublicKey: publicKey})
+	return nil
+}
+
+func (r *RecipientsList) Remove(name string) error {
+	for idx, recipient := range r.Recipients {
+		if recipient.Name == name {
+			r.Recipients = append(r.Recipients[:idx], r.Recipients[idx+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("recipient %s not found", name)
+}
+
+// Declares returns true if a recipient with the name exists
+func (r *RecipientsList) Declares(name string) bool {
+	for _, recipient := range r.Recipients {
+		if recipient.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns true if the public key derived from identity is one of the recipients
+func (r *RecipientsList) Contains(identity string) bool {
+	if identity == "" {
+		return false
+	}
+	parsed, err := age.ParseX25519Identity(strings.TrimSpace(identity))
+	if err != nil {
+		return false
+	}
+	pub := parsed.Recipient().String()
+	for _, recipient := range r.Recipients {
+		if recipient.PublicKey == pub {
+			return true
+		}
+	}
+	return false
+}
+
+func ReadRecipients(gitClient *git.Client) (*RecipientsList, error) {
+	recipients := &RecipientsList{}
+	if err := yaml.Unmarshal(gitClient.Read(RecipientsFile), recipients); err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %w", RecipientsFile, err)
+	}
+	return recipients, nil
+}
+
+// LoadRecipients makes all subsequently marshalled secrets be encrypted to the recipients declared in the repository
+func LoadRecipients(gitClient *git.Client) error {
+	recipients, err := ReadRecipients(gitClient)
+	if err != nil {
+		return err
+	}
+	Recipients = recipients.PublicKeys()
+	return nil
+}
+
+// GenerateIdentity returns a new age identity and its public key
+func GenerateIdentity() (identity string, publicKey string, err error) {
+	generated, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", "", err
+	}
+	return generated.String(), generated.Recipient().String(), nil
+}
+
+func encryptAge(value string) (*secretAlias, error) {
+
+	recipients := make([]age.Recipient, 0, len(Recipients))
+	for _, pub := range Recipients {
+		recipient, err := age.ParseX25519Recipient(pub)
+		if err != nil {
+			return nil, fmt.Errorf("parsing recipient %s failed: %w", pub, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	buf := new(bytes.Buffer)
+	w, err := age.Encrypt(buf, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write([]byte(value)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &secretAlias{
+		Encryption: EncryptionAgeX25519,
+		Encoding:   EncodingBase64,
+		Value:      base64.URLEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+func decryptAge(cipherText []byte) (string, error) {
+
+	if Identity == "" {
+		return "", errors.New("Secret is encrypted to recipients but no identity is configured in the orbconfig")
+	}
+
+	identity, err := age.ParseX25519Identity(strings.TrimSpace(Identity))
+	if err != nil {
+		return "", fmt.Errorf("parsing identity failed: %w", err)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(cipherText), identity)
+	if err != nil {
+		return "", fmt.Errorf("Decryption failed, the configured identity is probably no recipient: %w", err)
+	}
+
+	plainText, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(plainText), nil
+}
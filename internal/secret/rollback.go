@@ -0,0 +1,71 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/tree"
+	"github.com/caos/orbos/mntr"
+)
+
+// Rollback restores the desired state of the passed operators as it was at commit and pushes it as a new commit.
+// If no operator is passed, all operators desired states existing at commit are restored.
+// The secrets are decrypted with the current identity or master key, so they must have been encrypted for it at commit.
+// They are reencrypted to the current recipients.
+func Rollback(monitor mntr.Monitor, gitClient *git.Client, commit string, operators []string, getFunc GetFuncs, pushFunc PushAllFuncs) error {
+
+	head, err := gitClient.HeadHash()
+	if err != nil {
+		return err
+	}
+
+	if err := gitClient.Checkout(commit); err != nil {
+		return err
+	}
+
+	abort := func(err error) error {
+		if checkoutErr := gitClient.Checkout(head); checkoutErr != nil {
+			monitor.Error(checkoutErr)
+		}
+		return err
+	}
+
+	_, previousTrees, err := getFunc(monitor, gitClient)
+	if err != nil {
+		return abort(fmt.Errorf("reading desired state at %s failed: %w", commit, err))
+	}
+
+	restore := make(map[string]*tree.Tree)
+	for _, operator := range operators {
+		desired, ok := previousTrees[operator]
+		if !ok {
+			return abort(fmt.Errorf("%s.yml does not exist at %s", operator, commit))
+		}
+		restore[operator] = desired
+	}
+	if len(operators) == 0 {
+		restore = previousTrees
+	}
+
+	// Pushing reclones, so the current recipients are loaded from the latest commit
+	if err := gitClient.Clone(); err != nil {
+		return err
+	}
+
+	if err := LoadRecipients(gitClient); err != nil {
+		return err
+	}
+
+	restored := make([]string, 0, len(restore))
+	for operator := range restore {
+		restored = append(restored, operator+".yml")
+	}
+	sort.Strings(restored)
+
+	return pushFunc(monitor.WithFields(map[string]interface{}{
+		"commit": commit,
+		"files":  strings.Join(restored, ","),
+	}), gitClient, restore, "Desired state rolled back")
+}
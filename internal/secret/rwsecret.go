@@ -1,22 +1,24 @@
 package secret
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 
-	"github.com/caos/orbos/internal/api"
-
 	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/store"
 	"github.com/caos/orbos/internal/tree"
 	"github.com/caos/orbos/mntr"
 )
 
-type PushFunc func(gitClient *git.Client, desired *tree.Tree) api.PushDesiredFunc
+type PushFunc func(st store.Store, desired *tree.Tree) func(monitor mntr.Monitor) error
 type PushFuncs func(monitor mntr.Monitor, gitClient *git.Client, trees map[string]*tree.Tree, path string) error
 type GetFuncs func(monitor mntr.Monitor, gitClient *git.Client) (map[string]*Secret, map[string]*tree.Tree, error)
+type PushAllFuncs func(monitor mntr.Monitor, gitClient *git.Client, trees map[string]*tree.Tree, msg string, additional ...git.File) error
 
 func Read(monitor mntr.Monitor, gitClient *git.Client, path string, getFunc GetFuncs) (string, error) {
 	allSecrets, _, err := getFunc(monitor, gitClient)
@@ -36,6 +38,20 @@ func Read(monitor mntr.Monitor, gitClient *git.Client, path string, getFunc GetF
 	return secret.Value, nil
 }
 
+// Sources returns where the values of all secrets are read from
+func Sources(monitor mntr.Monitor, gitClient *git.Client, getFunc GetFuncs) (map[string]string, error) {
+	allSecrets, _, err := getFunc(monitor, gitClient)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]string, len(allSecrets))
+	for path, secret := range allSecrets {
+		sources[path] = secret.Source()
+	}
+	return sources, nil
+}
+
 func Rewrite(monitor mntr.Monitor, gitClient *git.Client, newMasterKey string, desired *tree.Tree, pushFunc PushFunc) error {
 	oldMasterKey := Masterkey
 	Masterkey = newMasterKey
@@ -43,6 +59,10 @@ func Rewrite(monitor mntr.Monitor, gitClient *git.Client, newMasterKey string, d
 		Masterkey = oldMasterKey
 	}()
 
+	if err := LoadRecipients(gitClient); err != nil {
+		return err
+	}
+
 	return pushFunc(gitClient, desired)(monitor)
 }
 
@@ -57,11 +77,96 @@ func Write(monitor mntr.Monitor, gitClient *git.Client, path, value string, getF
 		return err
 	}
 
+	if secret.External != nil {
+		return fmt.Errorf("Secret %s is read from %s and can not be written", path, secret.External)
+	}
+
 	secret.Value = value
 
+	if err := LoadRecipients(gitClient); err != nil {
+		return err
+	}
+
 	return pushFunc(monitor, gitClient, allTrees, path)
 }
 
+func AddRecipient(monitor mntr.Monitor, gitClient *git.Client, name, publicKey string, getFunc GetFuncs, pushFunc PushAllFuncs) error {
+	return AddRecipients(monitor, gitClient, []*Recipient{{Name: name, PublicKey: publicKey}}, getFunc, pushFunc)
+}
+
+// AddRecipients adds all recipients and reencrypts the secrets only once
+func AddRecipients(monitor mntr.Monitor, gitClient *git.Client, add []*Recipient, getFunc GetFuncs, pushFunc PushAllFuncs) error {
+	msg := "Recipient added"
+	if len(add) > 1 {
+		msg = "Recipients added"
+	}
+	return rewrap(monitor, gitClient, msg, getFunc, pushFunc, func(recipients *RecipientsList) error {
+		for _, recipient := range add {
+			if err := recipients.Add(recipient.Name, recipient.PublicKey); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func RemoveRecipient(monitor mntr.Monitor, gitClient *git.Client, name string, getFunc GetFuncs, pushFunc PushAllFuncs) error {
+	return rewrap(monitor, gitClient, "Recipient removed", getFunc, pushFunc, func(recipients *RecipientsList) error {
+		return recipients.Remove(name)
+	})
+}
+
+// ReplaceRecipient declares publicKey as recipient name, replacing the public key the recipient had before if any
+func ReplaceRecipient(monitor mntr.Monitor, gitClient *git.Client, name, publicKey string, getFunc GetFuncs, pushFunc PushAllFuncs) error {
+	return rewrap(monitor, gitClient, "Recipient replaced", getFunc, pushFunc, func(recipients *RecipientsList) error {
+		// The recipient doesn't need to exist yet
+		_ = recipients.Remove(name)
+		return recipients.Add(name, publicKey)
+	})
+}
+
+// rewrap decrypts all secrets with the current identity or master key
+// and reencrypts them to the changed recipients in a single commit
+func rewrap(monitor mntr.Monitor, gitClient *git.Client, msg string, getFunc GetFuncs, pushFunc PushAllFuncs, change func(recipients *RecipientsList) error) error {
+
+	recipients, err := ReadRecipients(gitClient)
+	if err != nil {
+		return err
+	}
+
+	if err := change(recipients); err != nil {
+		return err
+	}
+
+	if len(recipients.Recipients) > 0 && !recipients.Contains(Identity) {
+		return errors.New("the identity in your orbconfig would not be able to decrypt the secrets anymore")
+	}
+
+	if len(recipients.Recipients) > 0 && !recipients.Declares(OperatorsRecipient) {
+		return fmt.Errorf("the operators in the clusters would not be able to decrypt the secrets anymore, as recipient %s is not declared", OperatorsRecipient)
+	}
+
+	if len(recipients.Recipients) == 0 && Masterkey == "" {
+		return errors.New("secrets can not be encrypted with the master key as none is configured in your orbconfig")
+	}
+
+	_, allTrees, err := getFunc(monitor, gitClient)
+	if err != nil {
+		return err
+	}
+
+	oldRecipients := Recipients
+	Recipients = recipients.PublicKeys()
+	defer func() {
+		Recipients = oldRecipients
+	}()
+
+	return pushFunc(monitor, gitClient, allTrees, msg, git.File{
+		Path:    RecipientsFile,
+		Content: common.MarshalYAML(recipients),
+	})
+}
+
 func secretsListToSlice(secrets map[string]*Secret, includeEmpty bool) []string {
 	items := make([]string, 0, len(secrets))
 	for key, value := range secrets {
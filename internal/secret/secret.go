@@ -1,14 +1,8 @@
 package secret
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
-	"io"
-	"strings"
-	"unicode/utf8"
+	"fmt"
 
 	"github.com/caos/orbos/internal/utils/clientgo"
 	"gopkg.in/yaml.v3"
@@ -16,6 +10,9 @@ import (
 
 var Masterkey = "empty"
 
+// Offline keeps secrets as they are written when unmarshalling, so no keys are needed to parse desired states
+var Offline bool
+
 // Secret: Secret handled with orbctl so no manual changes are required
 type Secret struct {
 	//Encryption algorithm used for the secret
@@ -24,6 +21,10 @@ type Secret struct {
 	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
 	//Encrypted and encoded Value
 	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	//Reference to the value in an external store, the value itself is then not written to the repository
+	External *External `json:"external,omitempty" yaml:"external,omitempty"`
+
+	existing *Existing
 }
 type secretAlias Secret
 
@@ -73,55 +74,38 @@ func (s *Secret) UnmarshalYAMLWithExisting(node *yaml.Node, existing *Existing)
 				return errors.New("Error while reading existing secret, key non-existent")
 			}
 			s.Value = string(value)
+			s.existing = existing
 		}
 	}
 
 	return nil
 }
 
+// Source returns where the value of the secret is read from
+func (s *Secret) Source() string {
+	switch {
+	case s.External != nil:
+		return s.External.String()
+	case s.existing != nil:
+		return fmt.Sprintf("kubernetes:caos-system/%s#%s", s.existing.Name, s.existing.Key)
+	case s.Value == "":
+		return "none"
+	}
+	return "repository"
+}
+
 func unmarshal(s *Secret) (string, error) {
 	if s.Value == "" {
 		return "", nil
 	}
 
-	cipherText, err := base64.URLEncoding.DecodeString(s.Value)
-	if err != nil {
-		return "", err
-	}
-
-	if len(Masterkey) < 1 || len(Masterkey) > 32 {
+	if len(Masterkey) < 1 && s.Encryption != EncryptionAgeX25519 {
 		return "", nil
-		//return errors.New("Master key size must be between 1 and 32 characters")
-	}
-
-	masterKeyLocal := make([]byte, 32)
-	for idx, char := range []byte(strings.Trim(Masterkey, "\n")) {
-		masterKeyLocal[idx] = char
+		//return errors.New("Master key must not be empty")
 	}
 
-	block, err := aes.NewCipher(masterKeyLocal)
-	if err != nil {
-		return "", err
-	}
-
-	if len(cipherText) < aes.BlockSize {
-		return "", errors.New("Ciphertext block size is too short")
-	}
-
-	//IV needs to be unique, but doesn't have to be secure.
-	//It's common to put it at the beginning of the ciphertext.
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	// XORKeyStream can work in-place if the two arguments are the same.
-	stream.XORKeyStream(cipherText, cipherText)
-
-	if !utf8.Valid(cipherText) {
-		return "", errors.New("Decryption failed")
-	}
 	//	s.monitor.Info("Decoded and decrypted secret")
-	return string(cipherText), nil
+	return decrypt(s)
 }
 
 func (s *Secret) Unmarshal(masterkey string) error {
@@ -143,14 +127,23 @@ func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
 	s.Encoding = alias.Encoding
 	s.Encryption = alias.Encryption
 	s.Value = alias.Value
+	s.External = alias.External
 
-	if alias.Value == "" {
+	if Offline {
+		return err
+	}
+
+	if alias.External != nil {
+		value, err := alias.External.resolve()
+		if err != nil {
+			return fmt.Errorf("resolving secret from %s failed: %w", alias.External, err)
+		}
+		s.Value = value
 		return nil
 	}
 
-	if len(Masterkey) < 1 || len(Masterkey) > 32 {
+	if alias.Value == "" {
 		return nil
-		//return errors.New("Master key size must be between 1 and 32 characters")
 	}
 
 	unmarshalled, err := unmarshal(s)
@@ -167,34 +160,29 @@ func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
 
 func (s *Secret) MarshalYAML() (interface{}, error) {
 
-	if s.Value == "" {
-		return nil, nil
+	if s.External != nil {
+		return &secretAlias{External: s.External}, nil
 	}
 
-	if len(Masterkey) < 1 || len(Masterkey) > 32 {
-		return nil, errors.New("Master key size must be between 1 and 32 characters")
+	if s.Value == "" {
+		return nil, nil
 	}
 
-	masterKey := make([]byte, 32)
-	for idx, char := range []byte(strings.Trim(Masterkey, "\n")) {
-		masterKey[idx] = char
-	}
+	return encrypt(s.Value)
+}
 
-	c, err := aes.NewCipher(masterKey)
-	if err != nil {
-		return nil, err
+// Unseal returns the plaintext of a node written by MarshalYAML. As every marshalling encrypts anew,
+// merging concurrently written desired states compares secrets by their plaintexts.
+func Unseal(node *yaml.Node) (string, bool, error) {
+	if node.Kind != yaml.MappingNode {
+		return "", false, nil
 	}
-
-	cipherText := make([]byte, aes.BlockSize+len(s.Value))
-	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
-		return nil, err
+	alias := new(secretAlias)
+	if err := node.Decode(alias); err != nil || alias.Encryption == "" || alias.Value == "" {
+		return "", false, nil
 	}
-
-	stream := cipher.NewCFBEncrypter(c, iv)
-	stream.XORKeyStream(cipherText[aes.BlockSize:], []byte(s.Value))
-
-	return &secretAlias{Encryption: "AES256", Encoding: "Base64", Value: base64.URLEncoding.EncodeToString(cipherText)}, nil
+	plaintext, err := decrypt((*Secret)(alias))
+	return plaintext, true, err
 }
 
 func InitIfNil(sec *Secret) *Secret {
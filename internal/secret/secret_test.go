@@ -0,0 +1,195 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/git"
+)
+
+type testSecrets struct {
+	Secret *Secret
+}
+
+func roundtrip(t *testing.T, in *testSecrets) (string, *testSecrets) {
+	marshalled, err := yaml.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &testSecrets{}
+	if err := yaml.Unmarshal(marshalled, out); err != nil {
+		t.Fatal(err)
+	}
+	return string(marshalled), out
+}
+
+func TestSecret_Roundtrip(t *testing.T) {
+	Masterkey = "a master key that is longer than thirty-two characters"
+	defer func() { Masterkey = "empty" }()
+
+	marshalled, out := roundtrip(t, &testSecrets{Secret: &Secret{Value: "my value"}})
+	if !strings.Contains(marshalled, EncryptionAES256GCM) {
+		t.Fatalf("expected %s encryption, got:\n%s", EncryptionAES256GCM, marshalled)
+	}
+	if strings.Contains(marshalled, "my value") {
+		t.Fatalf("value is not encrypted:\n%s", marshalled)
+	}
+	if out.Secret.Value != "my value" {
+		t.Fatalf("expected my value, got %s", out.Secret.Value)
+	}
+}
+
+func TestSecret_WrongMasterkey(t *testing.T) {
+	Masterkey = "right"
+	defer func() { Masterkey = "empty" }()
+
+	marshalled, _ := roundtrip(t, &testSecrets{Secret: &Secret{Value: "my value"}})
+
+	Masterkey = "wrong"
+	if err := yaml.Unmarshal([]byte(marshalled), &testSecrets{}); err == nil {
+		t.Fatal("expected decryption with the wrong master key to fail")
+	}
+}
+
+func TestSecret_Tampered(t *testing.T) {
+	Masterkey = "masterkey"
+	defer func() { Masterkey = "empty" }()
+
+	alias, err := encrypt("my value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cipherText, err := base64.URLEncoding.DecodeString(alias.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText[len(cipherText)-1] ^= 0x01
+
+	if _, err := decrypt(&Secret{
+		Encryption: alias.Encryption,
+		Encoding:   alias.Encoding,
+		Value:      base64.URLEncoding.EncodeToString(cipherText),
+	}); err == nil {
+		t.Fatal("expected decryption of a tampered secret to fail")
+	}
+}
+
+func TestSecret_Legacy(t *testing.T) {
+	Masterkey = "masterkey"
+	defer func() { Masterkey = "empty" }()
+
+	key := make([]byte, 32)
+	copy(key, Masterkey)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText := make([]byte, aes.BlockSize+len("my value"))
+	cipher.NewCFBEncrypter(block, cipherText[:aes.BlockSize]).XORKeyStream(cipherText[aes.BlockSize:], []byte("my value"))
+
+	legacy := "secret:\n  encryption: AES256\n  encoding: Base64\n  value: " + base64.URLEncoding.EncodeToString(cipherText) + "\n"
+
+	out := &testSecrets{}
+	if err := yaml.Unmarshal([]byte(legacy), out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Secret.Value != "my value" {
+		t.Fatalf("expected my value, got %s", out.Secret.Value)
+	}
+
+	marshalled, _ := roundtrip(t, out)
+	if !strings.Contains(marshalled, EncryptionAES256GCM) {
+		t.Fatalf("expected legacy secret to be rewritten with %s encryption, got:\n%s", EncryptionAES256GCM, marshalled)
+	}
+}
+
+func TestSecret_Recipients(t *testing.T) {
+	Masterkey = ""
+	defer func() {
+		Masterkey = "empty"
+		Recipients = nil
+		Identity = ""
+	}()
+
+	aliceIdentity, alicePub, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobIdentity, bobPub, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eveIdentity, _, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Recipients = []string{alicePub, bobPub}
+	marshalled, err := yaml.Marshal(&testSecrets{Secret: &Secret{Value: "my value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(marshalled), EncryptionAgeX25519) {
+		t.Fatalf("expected %s encryption, got:\n%s", EncryptionAgeX25519, marshalled)
+	}
+
+	for _, identity := range []string{aliceIdentity, bobIdentity} {
+		Identity = identity
+		out := &testSecrets{}
+		if err := yaml.Unmarshal(marshalled, out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Secret.Value != "my value" {
+			t.Fatalf("expected my value, got %s", out.Secret.Value)
+		}
+	}
+
+	Identity = eveIdentity
+	if err := yaml.Unmarshal(marshalled, &testSecrets{}); err == nil {
+		t.Fatal("expected decryption with an identity that is no recipient to fail")
+	}
+}
+
+type concurrentSecrets struct {
+	First  *Secret
+	Second *Secret
+}
+
+func marshalConcurrent(t *testing.T, first, second string) []byte {
+	marshalled, err := yaml.Marshal(&concurrentSecrets{First: &Secret{Value: first}, Second: &Secret{Value: second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return marshalled
+}
+
+func TestUnseal_ConcurrentWriters(t *testing.T) {
+	Masterkey = "masterkey"
+	defer func() { Masterkey = "empty" }()
+
+	merge := git.MergeSealedYAML(Unseal)
+	base := marshalConcurrent(t, "first", "second")
+
+	// Both writers encrypt all secrets anew, but each changes another one
+	merged, err := merge(base, marshalConcurrent(t, "first changed", "second"), marshalConcurrent(t, "first", "second changed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &concurrentSecrets{}
+	if err := yaml.Unmarshal(merged, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.First.Value != "first changed" || out.Second.Value != "second changed" {
+		t.Errorf("expected both changes to be kept, got %s and %s", out.First.Value, out.Second.Value)
+	}
+
+	if _, err := merge(base, marshalConcurrent(t, "ours", "second"), marshalConcurrent(t, "theirs", "second")); err == nil {
+		t.Error("expected merging a concurrently changed secret to fail")
+	}
+}
@@ -3,6 +3,8 @@ package start
 import (
 	"context"
 	"errors"
+	"fmt"
+	"path/filepath"
 	"runtime/debug"
 	"strings"
 	"time"
@@ -13,6 +15,7 @@ import (
 	"github.com/caos/orbos/internal/operator/zitadel"
 
 	"github.com/caos/orbos/internal/api"
+	"github.com/caos/orbos/internal/events"
 	"github.com/caos/orbos/internal/executables"
 	"github.com/caos/orbos/internal/git"
 	"github.com/caos/orbos/internal/ingestion"
@@ -22,10 +25,8 @@ import (
 	orbzitadel "github.com/caos/orbos/internal/operator/zitadel/kinds/orb"
 	orbconfig "github.com/caos/orbos/internal/orb"
 	"github.com/caos/orbos/internal/secret"
+	"github.com/caos/orbos/internal/store"
 	"github.com/caos/orbos/mntr"
-	"github.com/golang/protobuf/ptypes"
-	structpb "github.com/golang/protobuf/ptypes/struct"
-	"google.golang.org/grpc"
 )
 
 type OrbiterConfig struct {
@@ -43,6 +44,12 @@ func Orbiter(ctx context.Context, monitor mntr.Monitor, conf *OrbiterConfig, orb
 
 	go checks(monitor, orbctlGit)
 
+	queue, err := events.New(monitor, orbConfig, conf.IngestionAddress, events.DefaultBuffer(monitor, "orbiter", orbConfig.URL))
+	if err != nil {
+		monitor.Error(err)
+	}
+	monitor = queue.Monitor(monitor, "orbiter")
+
 	finishedChan := make(chan struct{})
 	takeoffChan := make(chan struct{})
 
@@ -55,7 +62,7 @@ loop:
 		case <-finishedChan:
 			break loop
 		case <-takeoffChan:
-			iterate(conf, orbctlGit, !initialized, ctx, monitor, finishedChan, func(iterated bool) {
+			iterate(conf, orbctlGit, queue, !initialized, monitor, finishedChan, func(iterated bool) {
 				if iterated {
 					initialized = true
 				}
@@ -67,7 +74,7 @@ loop:
 	return GetKubeconfigs(monitor, orbctlGit, orbConfig)
 }
 
-func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ctx context.Context, monitor mntr.Monitor, finishedChan chan struct{}, done func(iterated bool)) {
+func iterate(conf *OrbiterConfig, gitClient *git.Client, queue *events.Queue, firstIteration bool, monitor mntr.Monitor, finishedChan chan struct{}, done func(iterated bool)) {
 	orbFile, err := orbconfig.ParseOrbConfig(conf.OrbConfigPath)
 	if err != nil {
 		monitor.Error(err)
@@ -75,34 +82,27 @@ func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ct
 		return
 	}
 
-	if err := gitClient.Configure(orbFile.URL, []byte(orbFile.Repokey)); err != nil {
+	if err := orbFile.PinKnownHosts(monitor, kubernetes.PersistOrbConfigFunc(monitor, orbFile)); err != nil {
 		monitor.Error(err)
 		done(false)
 		return
 	}
 
-	if err := gitClient.Clone(); err != nil {
+	if err := gitClient.Configure(orbFile.URL, []byte(orbFile.Repokey), []byte(orbFile.KnownHosts)); err != nil {
 		monitor.Error(err)
+		done(false)
 		return
 	}
 
-	pushEvents := func(events []*ingestion.EventRequest) error { return nil }
-	if conf.IngestionAddress != "" {
-		conn, err := grpc.Dial(conf.IngestionAddress, grpc.WithInsecure())
-		if err != nil {
-			panic(err)
-		}
-
-		ingc := ingestion.NewIngestionServiceClient(conn)
-		defer conn.Close()
+	if err := gitClient.ConfigureSigning([]byte(orbFile.Signingkey)); err != nil {
+		monitor.Error(err)
+		done(false)
+		return
+	}
 
-		pushEvents = func(events []*ingestion.EventRequest) error {
-			_, err := ingc.PushEvents(ctx, &ingestion.EventsRequest{
-				Orb:    orbFile.URL,
-				Events: events,
-			})
-			return err
-		}
+	if err := gitClient.Clone(); err != nil {
+		monitor.Error(err)
+		return
 	}
 
 	if firstIteration {
@@ -110,31 +110,18 @@ func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ct
 			orbiter.Metrics()
 		}
 
-		if err := pushEvents([]*ingestion.EventRequest{{
-			CreationDate: ptypes.TimestampNow(),
-			Type:         "orbiter.tookoff",
-			Data: &structpb.Struct{
-				Fields: map[string]*structpb.Value{
-					"commit": &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: conf.GitCommit}},
-				},
-			},
-		}}); err != nil {
-			panic(err)
-		}
+		queue.Push(events.Event(ingestion.TypeOrbiterTookOff, map[string]interface{}{
+			"commit":  conf.GitCommit,
+			"version": conf.Version,
+		}))
 
 		started := float64(time.Now().UTC().Unix())
 
 		go func() {
 			for range time.Tick(time.Minute) {
-				pushEvents([]*ingestion.EventRequest{{
-					CreationDate: ptypes.TimestampNow(),
-					Type:         "orbiter.running",
-					Data: &structpb.Struct{
-						Fields: map[string]*structpb.Value{
-							"since": {Kind: &structpb.Value_NumberValue{NumberValue: started}},
-						},
-					},
-				}})
+				queue.Push(events.Event(ingestion.TypeOrbiterRunning, map[string]interface{}{
+					"since": started,
+				}))
 			}
 		}()
 
@@ -157,12 +144,21 @@ func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ct
 		gitClient,
 	)
 
+	currentStore, err := CurrentStore(monitor, orbFile, gitClient)
+	if err != nil {
+		monitor.Error(err)
+		done(false)
+		return
+	}
+
 	takeoffConf := &orbiter.Config{
 		OrbiterCommit: conf.GitCommit,
 		GitClient:     gitClient,
+		CurrentStore:  currentStore,
+		TrustStore:    TrustStore(monitor, orbFile),
 		Adapt:         adaptFunc,
 		FinishedChan:  finishedChan,
-		PushEvents:    pushEvents,
+		PushEvents:    queue.PushEvents,
 		OrbConfig:     *orbFile,
 	}
 
@@ -180,6 +176,39 @@ func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ct
 	}()
 }
 
+// CurrentStore returns the store the orbiter persists its current state in.
+// Only the orbiters current.yml is routed to it. The node agents run on the machines,
+// where neither the Kubernetes API nor the orbiters directory are reachable,
+// so their current states stay in git
+func CurrentStore(monitor mntr.Monitor, orbConfig *orbconfig.Orb, gitClient *git.Client) (store.Store, error) {
+	switch {
+	case orbConfig.CurrentState == "" || orbConfig.CurrentState == "git":
+		return gitClient, nil
+	case orbConfig.CurrentState == "kubernetes":
+		k8sStore, err := store.NewInClusterKubernetes(monitor, "caos-system", "orbiter-current-state")
+		if err != nil {
+			return nil, fmt.Errorf("connecting to the Kubernetes API for persisting the current state failed: %w", err)
+		}
+		return store.NewSplit(gitClient, k8sStore, api.OrbiterCurrentFile), nil
+	case strings.HasPrefix(orbConfig.CurrentState, "directory:"):
+		return store.NewSplit(gitClient, store.NewDirectory(monitor, strings.TrimPrefix(orbConfig.CurrentState, "directory:")), api.OrbiterCurrentFile), nil
+	}
+	return nil, fmt.Errorf("unknown current state store %s", orbConfig.CurrentState)
+}
+
+// TrustStore returns the store the orbiter persists the keys it trusts in.
+// It is never the repository, as the trust protects against changes in the repository.
+// In a cluster, it is a Kubernetes secret, otherwise a file next to the orbconfig
+func TrustStore(monitor mntr.Monitor, orbConfig *orbconfig.Orb) store.Store {
+	if strings.HasPrefix(orbConfig.CurrentState, "directory:") {
+		return store.NewDirectory(monitor, strings.TrimPrefix(orbConfig.CurrentState, "directory:"))
+	}
+	if k8sStore, err := store.NewInClusterKubernetes(monitor, "caos-system", "orbiter-trust"); err == nil {
+		return k8sStore
+	}
+	return store.NewDirectory(monitor, filepath.Dir(orbConfig.Path))
+}
+
 func GetKubeconfigs(monitor mntr.Monitor, gitClient *git.Client, orbConfig *orbconfig.Orb) ([]string, error) {
 	kubeconfigs := make([]string, 0)
 
@@ -214,6 +243,8 @@ func GetKubeconfigs(monitor mntr.Monitor, gitClient *git.Client, orbConfig *orbc
 
 func Boom(monitor mntr.Monitor, orbConfigPath string, localmode bool, version string) error {
 
+	monitor = operatorEvents(monitor, orbConfigPath, "boom", version)
+
 	ensureClient := gitClient(monitor, "ensure")
 	queryClient := gitClient(monitor, "query")
 
@@ -286,7 +317,10 @@ func checks(monitor mntr.Monitor, client *git.Client) {
 		}
 	}
 }
-func Zitadel(monitor mntr.Monitor, orbConfigPath string, k8sClient *kubernetes.Client) error {
+func Zitadel(monitor mntr.Monitor, orbConfigPath string, k8sClient *kubernetes.Client, version string) error {
+
+	monitor = operatorEvents(monitor, orbConfigPath, "zitadel", version)
+
 	takeoffChan := make(chan struct{})
 	go func() {
 		takeoffChan <- struct{}{}
@@ -299,8 +333,13 @@ func Zitadel(monitor mntr.Monitor, orbConfigPath string, k8sClient *kubernetes.C
 			return err
 		}
 
+		if err := orbConfig.PinKnownHosts(monitor, kubernetes.PersistOrbConfigFunc(monitor, orbConfig)); err != nil {
+			monitor.Error(err)
+			return err
+		}
+
 		gitClient := git.New(context.Background(), monitor, "orbos", "orbos@caos.ch")
-		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 			monitor.Error(err)
 			return err
 		}
@@ -330,18 +369,52 @@ func ZitadelBackup(monitor mntr.Monitor, orbConfigPath string, k8sClient *kubern
 		return err
 	}
 
+	if err := orbConfig.PinKnownHosts(monitor, kubernetes.PersistOrbConfigFunc(monitor, orbConfig)); err != nil {
+		monitor.Error(err)
+		return err
+	}
+
 	gitClient := git.New(context.Background(), monitor, "orbos", "orbos@caos.ch")
-	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 		monitor.Error(err)
 		return err
 	}
 
-	takeoff := zitadel.Takeoff(monitor, gitClient, orbzitadel.AdaptFunc(backup, "instantbackup"), k8sClient)
+	queue, err := events.New(monitor, orbConfig, "", events.DefaultBuffer(monitor, "zitadel", orbConfig.URL))
+	if err != nil {
+		monitor.Error(err)
+	}
+
+	takeoff := zitadel.Takeoff(queue.Monitor(monitor, "zitadel"), gitClient, orbzitadel.AdaptFunc(backup, "instantbackup"), k8sClient)
 	takeoff()
 
+	// Not yet pushed events are pushed by the next zitadel operator using the same buffer
+	monitor.Error(queue.Flush(10 * time.Second))
 	return nil
 }
 
+// operatorEvents pushes the operators took off event and returns a monitor which pushes all changes
+func operatorEvents(monitor mntr.Monitor, orbConfigPath, operator, version string) mntr.Monitor {
+
+	orbConfig, err := orbconfig.ParseOrbConfig(orbConfigPath)
+	if err != nil {
+		monitor.Error(err)
+		return monitor
+	}
+
+	queue, err := events.New(monitor, orbConfig, "", events.DefaultBuffer(monitor, operator, orbConfig.URL))
+	if err != nil {
+		monitor.Error(err)
+		return monitor
+	}
+
+	queue.Push(events.Event(ingestion.TypeOperatorTookOff, map[string]interface{}{
+		"operator": operator,
+		"version":  version,
+	}))
+	return queue.Monitor(monitor, operator)
+}
+
 func ZitadelRestore(monitor mntr.Monitor, orbConfigPath string, k8sClient *kubernetes.Client, timestamp string) error {
 	orbConfig, err := orbconfig.ParseOrbConfig(orbConfigPath)
 	if err != nil {
@@ -349,8 +422,13 @@ func ZitadelRestore(monitor mntr.Monitor, orbConfigPath string, k8sClient *kuber
 		return err
 	}
 
+	if err := orbConfig.PinKnownHosts(monitor, kubernetes.PersistOrbConfigFunc(monitor, orbConfig)); err != nil {
+		monitor.Error(err)
+		return err
+	}
+
 	gitClient := git.New(context.Background(), monitor, "orbos", "orbos@caos.ch")
-	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+	if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey), []byte(orbConfig.KnownHosts)); err != nil {
 		monitor.Error(err)
 		return err
 	}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/mntr"
+)
+
+// Directory stores files in a local directory. It is meant for tests and air-gapped environments.
+type Directory struct {
+	monitor mntr.Monitor
+	dir     string
+}
+
+var _ Store = (*Directory)(nil)
+
+func NewDirectory(monitor mntr.Monitor, dir string) *Directory {
+	return &Directory{
+		monitor: monitor.WithField("directory", dir),
+		dir:     dir,
+	}
+}
+
+// Clone ensures the directory exists
+func (d *Directory) Clone() error {
+	return os.MkdirAll(d.dir, 0700)
+}
+
+func (d *Directory) Read(path string) []byte {
+	content, err := ioutil.ReadFile(filepath.Join(d.dir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make([]byte, 0)
+		}
+		panic(err)
+	}
+	return content
+}
+
+func (d *Directory) ReadFolder(path string) (map[string][]byte, []string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(d.dir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string][]byte, 0), nil, nil
+		}
+		return nil, nil, fmt.Errorf("opening %s failed: %w", path, err)
+	}
+
+	files := make(map[string][]byte)
+	subdirs := make([]string, 0)
+	for _, info := range infos {
+		if info.IsDir() {
+			subdirs = append(subdirs, info.Name())
+			continue
+		}
+		files[info.Name()] = d.Read(filepath.Join(path, info.Name()))
+	}
+	return files, subdirs, nil
+}
+
+func (d *Directory) ExistsFolder(path string) (bool, error) {
+	info, err := os.Stat(filepath.Join(d.dir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// StageAndCommit writes the files immediately
+func (d *Directory) StageAndCommit(msg string, files ...git.File) (bool, error) {
+	changed := false
+	for _, file := range files {
+		if bytes.Equal(d.Read(file.Path), file.Content) {
+			continue
+		}
+		path := filepath.Join(d.dir, file.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return changed, err
+		}
+		if err := ioutil.WriteFile(path, file.Content, 0600); err != nil {
+			return changed, fmt.Errorf("writing %s failed: %w", file.Path, err)
+		}
+		changed = true
+	}
+	if changed {
+		d.monitor.WithField("change", msg).Debug("Files written")
+	}
+	return changed, nil
+}
+
+// Commit does nothing, as a directory has no history
+func (d *Directory) Commit(string) error { return nil }
+
+// Push does nothing, as files are written by StageAndCommit
+func (d *Directory) Push() error { return nil }
+
+func (d *Directory) UpdateRemote(msg string, files ...git.File) error {
+	_, err := d.StageAndCommit(msg, files...)
+	return err
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	mach "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/mntr"
+)
+
+const (
+	// pathSeparator replaces slashes in paths, as they are not allowed in secret keys
+	pathSeparator        = "__"
+	lastChangeAnnotation = "orbos.ch/last-change"
+	// MaxSecretSize is the maximum size of a secrets data the Kubernetes API accepts
+	MaxSecretSize = 1 << 20
+)
+
+// ErrTooLarge is returned if the files don't fit into a secret
+var ErrTooLarge = errors.New("files exceed the maximum secret size of 1MiB")
+
+// Kubernetes stores files as keys of a Kubernetes secret.
+// Changes of different files never conflict, concurrent changes of the same file are overwritten.
+type Kubernetes struct {
+	monitor   mntr.Monitor
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	data      map[string][]byte
+	staged    map[string][]byte
+	msg       string
+}
+
+var _ Store = (*Kubernetes)(nil)
+
+func NewKubernetes(monitor mntr.Monitor, client kubernetes.Interface, namespace, name string) *Kubernetes {
+	return &Kubernetes{
+		monitor: monitor.WithFields(map[string]interface{}{
+			"namespace": namespace,
+			"secret":    name,
+		}),
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		data:      make(map[string][]byte),
+		staged:    make(map[string][]byte),
+	}
+}
+
+// NewInClusterKubernetes connects to the Kubernetes API of the cluster it runs in
+func NewInClusterKubernetes(monitor mntr.Monitor, namespace, name string) (*Kubernetes, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewKubernetes(monitor, client, namespace, name), nil
+}
+
+func secretKey(path string) string {
+	return strings.ReplaceAll(strings.Trim(path, "/"), "/", pathSeparator)
+}
+
+// Clone reads the secret and discards staged changes
+func (k *Kubernetes) Clone() error {
+	secret, err := k.client.CoreV1().Secrets(k.namespace).Get(context.Background(), k.name, mach.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		secret, err = &core.Secret{}, nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading secret %s/%s failed: %w", k.namespace, k.name, err)
+	}
+
+	k.data = secret.Data
+	if k.data == nil {
+		k.data = make(map[string][]byte)
+	}
+	k.staged = make(map[string][]byte)
+	return nil
+}
+
+func (k *Kubernetes) Read(path string) []byte {
+	key := secretKey(path)
+	if content, ok := k.staged[key]; ok {
+		return content
+	}
+	if content, ok := k.data[key]; ok {
+		return content
+	}
+	return make([]byte, 0)
+}
+
+func (k *Kubernetes) keys() map[string]struct{} {
+	keys := make(map[string]struct{}, len(k.data)+len(k.staged))
+	for key := range k.data {
+		keys[key] = struct{}{}
+	}
+	for key := range k.staged {
+		keys[key] = struct{}{}
+	}
+	return keys
+}
+
+func (k *Kubernetes) ReadFolder(path string) (map[string][]byte, []string, error) {
+	prefix := secretKey(path) + pathSeparator
+	files := make(map[string][]byte)
+	subdirs := make(map[string]struct{})
+	for key := range k.keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, prefix)
+		if idx := strings.Index(rest, pathSeparator); idx >= 0 {
+			subdirs[rest[:idx]] = struct{}{}
+			continue
+		}
+		files[rest] = k.Read(path + "/" + rest)
+	}
+
+	subdirList := make([]string, 0, len(subdirs))
+	for subdir := range subdirs {
+		subdirList = append(subdirList, subdir)
+	}
+	return files, subdirList, nil
+}
+
+func (k *Kubernetes) ExistsFolder(path string) (bool, error) {
+	prefix := secretKey(path) + pathSeparator
+	for key := range k.keys() {
+		if strings.HasPrefix(key, prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StageAndCommit records the changes until Push is called
+func (k *Kubernetes) StageAndCommit(msg string, files ...git.File) (bool, error) {
+	changed := false
+	for _, file := range files {
+		if bytes.Equal(k.Read(file.Path), file.Content) {
+			continue
+		}
+		k.staged[secretKey(file.Path)] = file.Content
+		changed = true
+	}
+	if changed {
+		k.msg = msg
+	}
+	return changed, nil
+}
+
+// Commit only records the message, as secrets have no history
+func (k *Kubernetes) Commit(msg string) error {
+	k.msg = msg
+	return nil
+}
+
+// Push writes the staged changes to the secret, retrying on conflicts
+func (k *Kubernetes) Push() error {
+	if len(k.staged) == 0 {
+		return nil
+	}
+
+	secrets := k.client.CoreV1().Secrets(k.namespace)
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		secret, err := secrets.Get(context.Background(), k.name, mach.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			newSecret := k.newSecret()
+			if err := fits(newSecret.Data); err != nil {
+				return err
+			}
+			_, err = secrets.Create(context.Background(), newSecret, mach.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		for key, content := range k.staged {
+			secret.Data[key] = content
+		}
+		if err := fits(secret.Data); err != nil {
+			return err
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[lastChangeAnnotation] = k.msg
+
+		_, err = secrets.Update(context.Background(), secret, mach.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("writing secret %s/%s failed: %w", k.namespace, k.name, err)
+	}
+
+	for key, content := range k.staged {
+		k.data[key] = content
+	}
+	k.staged = make(map[string][]byte)
+	k.monitor.Debug("Secret written")
+	return nil
+}
+
+// fits fails before the Kubernetes API refuses the secret with a less obvious error
+func fits(data map[string][]byte) error {
+	size := 0
+	for key, content := range data {
+		size += len(key) + len(content)
+	}
+	if size > MaxSecretSize {
+		return fmt.Errorf("%w: %d bytes, persist the current state in git or a directory instead", ErrTooLarge, size)
+	}
+	return nil
+}
+
+func (k *Kubernetes) newSecret() *core.Secret {
+	data := make(map[string][]byte, len(k.staged))
+	for key, content := range k.staged {
+		data[key] = content
+	}
+	return &core.Secret{
+		ObjectMeta: mach.ObjectMeta{
+			Name:      k.name,
+			Namespace: k.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "orbiter.caos.ch",
+			},
+			Annotations: map[string]string{
+				lastChangeAnnotation: k.msg,
+			},
+		},
+		Type: core.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+func (k *Kubernetes) UpdateRemote(msg string, files ...git.File) error {
+	if err := k.Clone(); err != nil {
+		return err
+	}
+	changed, err := k.StageAndCommit(msg, files...)
+	if err != nil || !changed {
+		return err
+	}
+	return k.Push()
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/caos/orbos/internal/git"
+)
+
+// Split routes files to the current store if their paths are in one of the current paths
+// and to the desired store otherwise. This way, current states that change constantly
+// don't bloat the history of the desired states.
+type Split struct {
+	desired      Store
+	current      Store
+	currentPaths []string
+	// unpushed tracks which stores have commits to push
+	unpushed map[Store]bool
+}
+
+var _ Store = (*Split)(nil)
+
+func NewSplit(desired, current Store, currentPaths ...string) *Split {
+	return &Split{
+		desired:      desired,
+		current:      current,
+		currentPaths: currentPaths,
+		unpushed:     make(map[Store]bool),
+	}
+}
+
+func (s *Split) route(path string) Store {
+	for _, currentPath := range s.currentPaths {
+		if path == currentPath || strings.HasPrefix(path, strings.TrimSuffix(currentPath, "/")+"/") {
+			return s.current
+		}
+	}
+	return s.desired
+}
+
+func (s *Split) Clone() error {
+	s.unpushed = make(map[Store]bool)
+	if err := s.desired.Clone(); err != nil {
+		return err
+	}
+	return s.current.Clone()
+}
+
+func (s *Split) Read(path string) []byte {
+	return s.route(path).Read(path)
+}
+
+// ReadFolder merges the folders contents of both stores, as current paths can be files within a desired folder
+func (s *Split) ReadFolder(path string) (map[string][]byte, []string, error) {
+	if s.route(path) == s.current {
+		return s.current.ReadFolder(path)
+	}
+
+	desiredFiles, desiredSubdirs, err := s.desired.ReadFolder(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	currentFiles, currentSubdirs, err := s.current.ReadFolder(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make(map[string][]byte, len(desiredFiles))
+	for name, content := range desiredFiles {
+		if s.route(path+"/"+name) == s.desired {
+			files[name] = content
+		}
+	}
+	for name, content := range currentFiles {
+		if s.route(path+"/"+name) == s.current {
+			files[name] = content
+		}
+	}
+
+	subdirs := desiredSubdirs
+	for _, subdir := range currentSubdirs {
+		if !contains(subdirs, subdir) {
+			subdirs = append(subdirs, subdir)
+		}
+	}
+	return files, subdirs, nil
+}
+
+func (s *Split) ExistsFolder(path string) (bool, error) {
+	if s.route(path) == s.current {
+		return s.current.ExistsFolder(path)
+	}
+
+	exists, err := s.desired.ExistsFolder(path)
+	if err != nil || exists {
+		return exists, err
+	}
+	return s.current.ExistsFolder(path)
+}
+
+func contains(list []string, item string) bool {
+	for _, listItem := range list {
+		if listItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Split) split(files []git.File) (desired []git.File, current []git.File) {
+	for _, file := range files {
+		if s.route(file.Path) == s.current {
+			current = append(current, file)
+			continue
+		}
+		desired = append(desired, file)
+	}
+	return desired, current
+}
+
+func (s *Split) StageAndCommit(msg string, files ...git.File) (bool, error) {
+	desiredFiles, currentFiles := s.split(files)
+
+	var desiredChanged, currentChanged bool
+	var err error
+	if len(desiredFiles) > 0 {
+		if desiredChanged, err = s.desired.StageAndCommit(msg, desiredFiles...); err != nil {
+			return false, err
+		}
+	}
+	if len(currentFiles) > 0 {
+		if currentChanged, err = s.current.StageAndCommit(msg, currentFiles...); err != nil {
+			return desiredChanged, err
+		}
+	}
+	s.unpushed[s.desired] = s.unpushed[s.desired] || desiredChanged
+	s.unpushed[s.current] = s.unpushed[s.current] || currentChanged
+	return desiredChanged || currentChanged, nil
+}
+
+// Commit records the message in the desired store only
+func (s *Split) Commit(msg string) error {
+	if err := s.desired.Commit(msg); err != nil {
+		return err
+	}
+	s.unpushed[s.desired] = true
+	return nil
+}
+
+// Push pushes the stores having commits
+func (s *Split) Push() error {
+	for _, st := range []Store{s.current, s.desired} {
+		if !s.unpushed[st] {
+			continue
+		}
+		if err := st.Push(); err != nil {
+			return err
+		}
+		s.unpushed[st] = false
+	}
+	return nil
+}
+
+func (s *Split) UpdateRemote(msg string, files ...git.File) error {
+	desiredFiles, currentFiles := s.split(files)
+	if len(currentFiles) > 0 {
+		if err := s.current.UpdateRemote(msg, currentFiles...); err != nil {
+			return err
+		}
+	}
+	if len(desiredFiles) > 0 {
+		return s.desired.UpdateRemote(msg, desiredFiles...)
+	}
+	return nil
+}
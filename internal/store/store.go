@@ -0,0 +1,22 @@
+// Package store abstracts where the operators read and write their desired and current states.
+package store
+
+import (
+	"github.com/caos/orbos/internal/git"
+)
+
+// Store persists files containing desired or current states.
+// The method names follow the git workflow, other implementations apply them analogously:
+// Clone refreshes the local view, StageAndCommit records changes and Push persists them.
+type Store interface {
+	Clone() error
+	Read(path string) []byte
+	ReadFolder(path string) (map[string][]byte, []string, error)
+	ExistsFolder(path string) (bool, error)
+	StageAndCommit(msg string, files ...git.File) (bool, error)
+	Commit(msg string) error
+	Push() error
+	UpdateRemote(msg string, files ...git.File) error
+}
+
+var _ Store = (*git.Client)(nil)
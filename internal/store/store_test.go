@@ -0,0 +1,126 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/caos/orbos/internal/git"
+	"github.com/caos/orbos/mntr"
+)
+
+func testStore(t *testing.T, st Store) {
+	if err := st.Clone(); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := st.StageAndCommit("test", git.File{Path: "caos-internal/orbiter/current.yml", Content: []byte("a: 1\n")}, git.File{Path: "caos-internal/orbiter/nodes/node.yml", Content: []byte("b: 1\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected changes")
+	}
+	if err := st.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if content := string(st.Read("caos-internal/orbiter/current.yml")); content != "a: 1\n" {
+		t.Errorf("unexpected content %s", content)
+	}
+	if content := st.Read("caos-internal/orbiter/missing.yml"); len(content) != 0 {
+		t.Errorf("unexpected content %s", string(content))
+	}
+
+	files, subdirs, err := st.ReadFolder("caos-internal/orbiter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || string(files["current.yml"]) != "a: 1\n" {
+		t.Errorf("unexpected files %v", files)
+	}
+	if len(subdirs) != 1 || subdirs[0] != "nodes" {
+		t.Errorf("unexpected subdirectories %v", subdirs)
+	}
+
+	exists, err := st.ExistsFolder("caos-internal/orbiter/nodes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Error("folder does not exist")
+	}
+
+	changed, err = st.StageAndCommit("test", git.File{Path: "caos-internal/orbiter/current.yml", Content: []byte("a: 1\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("unchanged file was reported as changed")
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "orbos-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestDirectory(t *testing.T) {
+	testStore(t, NewDirectory(mntr.Monitor{}, tempDir(t)))
+}
+
+func TestKubernetes(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	testStore(t, NewKubernetes(mntr.Monitor{}, client, "caos-system", "state"))
+}
+
+func TestSplit(t *testing.T) {
+	desired := NewDirectory(mntr.Monitor{}, tempDir(t))
+	current := NewKubernetes(mntr.Monitor{}, fake.NewSimpleClientset(), "caos-system", "state")
+	split := NewSplit(desired, current, "caos-internal/orbiter/current.yml")
+
+	testStore(t, split)
+
+	if content := string(current.Read("caos-internal/orbiter/current.yml")); content != "a: 1\n" {
+		t.Errorf("current state is not routed to the current store: %s", content)
+	}
+	if content := desired.Read("caos-internal/orbiter/current.yml"); len(content) != 0 {
+		t.Errorf("current state is written to the desired store: %s", string(content))
+	}
+	if content := string(desired.Read("caos-internal/orbiter/nodes/node.yml")); content != "b: 1\n" {
+		t.Errorf("desired state is not routed to the desired store: %s", content)
+	}
+}
+
+func TestKubernetesRefusesTooLargeSecrets(t *testing.T) {
+	st := NewKubernetes(mntr.Monitor{}, fake.NewSimpleClientset(), "caos-system", "state")
+	if err := st.Clone(); err != nil {
+		t.Fatal(err)
+	}
+
+	half := bytes.Repeat([]byte("a"), MaxSecretSize/2)
+	if _, err := st.StageAndCommit("test", git.File{Path: "caos-internal/orbiter/current.yml", Content: half}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := st.StageAndCommit("test", git.File{Path: "caos-internal/orbiter/other.yml", Content: half}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Push(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
@@ -12,12 +12,19 @@ type OnError func(error, map[string]string)
 type OnRecoverPanic func(interface{}, map[string]string)
 
 type Monitor struct {
-	Fields         map[string]interface{}
-	OnInfo         OnMessage
+	Fields map[string]interface{}
+	// OnDebug defaults to LogMessage
+	OnDebug OnMessage
+	OnInfo  OnMessage
+	// OnWarn defaults to OnInfo
+	OnWarn         OnMessage
 	OnChange       OnMessage
 	OnError        OnError
 	OnRecoverPanic OnRecoverPanic
 	verbose        bool
+	exporter       SpanExporter
+	span           *Span
+	scope          *spanScope
 }
 
 func (m Monitor) WithField(key string, value interface{}) Monitor {
@@ -45,6 +52,26 @@ func (m Monitor) Info(msg string) {
 	m.OnInfo(msg, normalize(m.Fields))
 }
 
+func (m Monitor) Warn(msg string) {
+	onWarn := m.OnWarn
+	if onWarn == nil {
+		onWarn = m.OnInfo
+	}
+	if onWarn == nil {
+		return
+	}
+
+	m.Fields = merge(map[string]interface{}{
+		"msg": msg,
+		"ts":  now(),
+	}, m.Fields)
+
+	if m.verbose {
+		m.addDebugContext()
+	}
+	onWarn(msg, normalize(m.Fields))
+}
+
 func (m Monitor) Changed(evt string) {
 	if m.OnChange == nil {
 		return
@@ -62,7 +89,12 @@ func (m Monitor) Changed(evt string) {
 }
 
 func (m Monitor) Error(err error) {
-	if err == nil || m.OnError == nil {
+	if err == nil {
+		return
+	}
+
+	m.span.recordError(err)
+	if m.OnError == nil {
 		return
 	}
 
@@ -103,7 +135,11 @@ func (m Monitor) Debug(dbg string) {
 		"ts":  now(),
 	}, m.Fields)
 	m.addDebugContext()
-	LogMessage(dbg, normalize(m.Fields))
+	if m.OnDebug == nil {
+		LogMessage(dbg, normalize(m.Fields))
+		return
+	}
+	m.OnDebug(dbg, normalize(m.Fields))
 }
 
 func (m Monitor) Verbose() Monitor {
@@ -115,6 +151,33 @@ func (m Monitor) IsVerbose() bool {
 	return m.verbose
 }
 
+// WithSinks returns a monitor which passes all records to all sinks instead of its current callbacks.
+// Debug messages are still only recorded if the monitor is verbose
+func (m Monitor) WithSinks(sinks ...Sink) Monitor {
+	write := func(level Level, fields map[string]string) {
+		for _, sink := range sinks {
+			sink.Write(level, fields)
+		}
+	}
+	onMessage := func(level Level) OnMessage {
+		return func(_ string, fields map[string]string) {
+			write(level, fields)
+		}
+	}
+
+	m.OnDebug = onMessage(LevelDebug)
+	m.OnInfo = onMessage(LevelInfo)
+	m.OnWarn = onMessage(LevelWarn)
+	m.OnChange = onMessage(LevelInfo)
+	m.OnError = func(_ error, fields map[string]string) {
+		write(LevelError, fields)
+	}
+	m.OnRecoverPanic = func(_ interface{}, fields map[string]string) {
+		write(LevelError, fields)
+	}
+	return m
+}
+
 func now() string {
 	return time.Now().Format(time.RFC3339)
 }
@@ -0,0 +1,181 @@
+package mntr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLPExporter batches spans and sends them to an OpenTelemetry collector using OTLP over HTTP with JSON encoding
+type OTLPExporter struct {
+	url     string
+	service string
+	client  *http.Client
+	mux     sync.Mutex
+	batch   []*Span
+}
+
+// NewOTLPExporter returns an exporter sending to the OTLP HTTP receiver at endpoint, for example http://localhost:4318.
+// Batches are sent every interval, call Flush before exiting to send the remaining spans
+func NewOTLPExporter(endpoint, service string, interval time.Duration) *OTLPExporter {
+
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+
+	exporter := &OTLPExporter{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			if err := exporter.Flush(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}()
+
+	return exporter
+}
+
+func (o *OTLPExporter) Export(span *Span) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.batch = append(o.batch, span)
+}
+
+// Flush sends all batched spans
+func (o *OTLPExporter) Flush() error {
+
+	o.mux.Lock()
+	batch := o.batch
+	o.batch = nil
+	o.mux.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(o.request(batch))
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.client.Post(o.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("exporting %d spans failed: %w", len(batch), err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("exporting %d spans failed: collector responded %s", len(batch), resp.Status)
+	}
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func (o *OTLPExporter) request(batch []*Span) *otlpRequest {
+
+	spans := make([]otlpSpan, len(batch))
+	for idx, span := range batch {
+		span.mux.Lock()
+		spans[idx] = otlpSpan{
+			TraceID:           span.traceID(),
+			SpanID:            span.spanID(),
+			ParentSpanID:      span.parentID(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: fmt.Sprintf("%d", span.StartTime.UnixNano()),
+			EndTimeUnixNano:   fmt.Sprintf("%d", span.EndTime.UnixNano()),
+			Attributes:        attributes(span.Attributes),
+			Status:            otlpStatus{Code: 1},
+		}
+		if span.Err != nil {
+			spans[idx].Status = otlpStatus{Code: 2, Message: span.Err.Error()}
+		}
+		span.mux.Unlock()
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: attributes(map[string]string{"service.name": o.service}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/caos/orbos/mntr"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func attributes(fields map[string]string) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(fields))
+	for key, value := range fields {
+		attrs = append(attrs, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
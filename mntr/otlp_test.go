@@ -0,0 +1,62 @@
+package mntr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter(t *testing.T) {
+
+	received := make(chan otlpRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("expected path /v1/traces, got %s", r.URL.Path)
+		}
+		req := otlpRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		received <- req
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL, "test", time.Hour)
+	monitor := Monitor{}.WithSpanExporter(exporter)
+
+	parentMonitor, parent := monitor.WithField("pool", "workers").StartSpan("parent")
+	childMonitor, child := parentMonitor.StartSpan("child")
+	childMonitor.Error(errors.New("failed"))
+	child.End(nil)
+	parent.End(nil)
+
+	if err := exporter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-received
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	exportedChild, exportedParent := spans[0], spans[1]
+	if exportedChild.TraceID != exportedParent.TraceID || exportedChild.ParentSpanID != exportedParent.SpanID {
+		t.Errorf("child span %+v is not a child of %+v", exportedChild, exportedParent)
+	}
+	if exportedParent.ParentSpanID != "" {
+		t.Errorf("expected parent span to be a root span, but it has parent %s", exportedParent.ParentSpanID)
+	}
+	if exportedChild.Status.Code != 2 || exportedChild.Status.Message != "failed" {
+		t.Errorf("expected the error reported by the childs monitor in its status, got %+v", exportedChild.Status)
+	}
+	if exportedParent.Status.Code != 1 {
+		t.Errorf("expected parent span to be ok, got %+v", exportedParent.Status)
+	}
+	if len(exportedParent.Attributes) != 1 || exportedParent.Attributes[0].Value.StringValue != "workers" {
+		t.Errorf("expected the monitors fields as attributes, got %+v", exportedParent.Attributes)
+	}
+}
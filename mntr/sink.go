@@ -0,0 +1,162 @@
+package mntr
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+func ParseLevel(level string) (Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %s, use one of debug, info, warn or error", level)
+}
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("unknown log format %s, use text or json", format)
+}
+
+// Sink receives the records of a monitor. Use Monitor.WithSinks to fan records out to several sinks
+type Sink interface {
+	Write(level Level, fields map[string]string)
+}
+
+type SinkFunc func(level Level, fields map[string]string)
+
+func (s SinkFunc) Write(level Level, fields map[string]string) {
+	s(level, fields)
+}
+
+type writerSink struct {
+	mux    sync.Mutex
+	writer io.Writer
+	format Format
+	min    Level
+}
+
+// NewWriterSink returns a sink writing all records from level min on to writer
+func NewWriterSink(writer io.Writer, format Format, min Level) Sink {
+	return &writerSink{
+		writer: writer,
+		format: format,
+		min:    min,
+	}
+}
+
+func (w *writerSink) Write(level Level, fields map[string]string) {
+	if level < w.min {
+		return
+	}
+
+	var record string
+	switch w.format {
+	case FormatJSON:
+		record = JSONRecord(level, fields)
+	default:
+		record = LogRecord(AggregateLogFields(fields))
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if _, err := io.WriteString(w.writer, record); err != nil {
+		panic(err)
+	}
+}
+
+// NewSinks returns a writer sink per output. An output is stdout, stderr or a file path the records are appended to
+func NewSinks(format Format, min Level, outputs ...string) ([]Sink, error) {
+	sinks := make([]Sink, len(outputs))
+	for idx, output := range outputs {
+		var writer io.Writer
+		switch output {
+		case "stdout", "-":
+			writer = os.Stdout
+		case "stderr":
+			writer = os.Stderr
+		default:
+			file, err := os.OpenFile(output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return nil, fmt.Errorf("opening log output %s failed: %w", output, err)
+			}
+			writer = file
+		}
+		sinks[idx] = NewWriterSink(writer, format, min)
+	}
+	return sinks, nil
+}
+
+// WithLogging parses the log flags of the binaries and returns a monitor writing its records accordingly.
+// The debug level makes the monitor verbose
+func (m Monitor) WithLogging(level, format string, outputs ...string) (Monitor, error) {
+
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return m, err
+	}
+
+	frmt, err := ParseFormat(format)
+	if err != nil {
+		return m, err
+	}
+
+	if len(outputs) == 0 {
+		outputs = []string{"stdout"}
+	}
+
+	sinks, err := NewSinks(frmt, lvl, outputs...)
+	if err != nil {
+		return m, err
+	}
+
+	m = m.WithSinks(sinks...)
+	if lvl == LevelDebug {
+		m = m.Verbose()
+	}
+	return m, nil
+}
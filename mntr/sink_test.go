@@ -0,0 +1,43 @@
+package mntr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithSinks(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	monitor := Monitor{}.WithSinks(NewWriterSink(buf, FormatJSON, LevelInfo))
+
+	monitor.Debug("hidden")
+	monitor.WithField("machine", "a").Info("shown")
+	monitor.Warn("careful")
+	monitor.Error(errors.New("failed"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got %d: %s", len(lines), buf.String())
+	}
+
+	expect := []map[string]string{
+		{"level": "info", "msg": "shown", "machine": "a"},
+		{"level": "warn", "msg": "careful"},
+		{"level": "error", "msg": "failed", "err": "failed"},
+	}
+
+	for idx, line := range lines {
+		record := make(map[string]string)
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("record %d is no json object: %s", idx, line)
+		}
+		for key, value := range expect[idx] {
+			if record[key] != value {
+				t.Errorf("record %d: expected %s=%s, got %s", idx, key, value, record[key])
+			}
+		}
+	}
+}
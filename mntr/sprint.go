@@ -1,6 +1,7 @@
 package mntr
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,13 +10,14 @@ import (
 	structpb "github.com/golang/protobuf/ptypes/struct"
 )
 
+// EventRecord maps a change to an event of its type, see ingestion.ChangedType
 func EventRecord(namespace, evt string, fields map[string]string) *ingestion.EventRequest {
 	return &ingestion.EventRequest{
 		CreationDate: ptypes.TimestampNow(),
 		Data: &structpb.Struct{
 			Fields: protoStruct(fields),
 		},
-		Type: strings.ReplaceAll(strings.ToLower(fmt.Sprintf("%s.%s", namespace, evt)), " ", "."),
+		Type: ingestion.ChangedType(namespace, evt),
 	}
 }
 
@@ -66,3 +68,51 @@ func LogRecord(fields []*Field) string {
 
 	return strings.TrimSpace(logLine) + "\n"
 }
+
+// JSONRecord formats the fields as one JSON object per line.
+// The level is added and the message is always found at the key msg, no matter if it is an event, debug message or error
+func JSONRecord(level Level, fields map[string]string) string {
+	record := make(map[string]string, len(fields)+2)
+	for key, value := range fields {
+		record[key] = value
+	}
+	record["level"] = level.String()
+	if _, ok := record["msg"]; !ok {
+		for _, key := range []string{"evt", "dbg", "err"} {
+			if value, ok := record[key]; ok {
+				record["msg"] = value
+				break
+			}
+		}
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		panic(err)
+	}
+	return string(line) + "\n"
+}
+
+// ParseCommitRecord reverses CommitRecord. Lines formatted as key: value are returned as fields,
+// the first remaining line is returned as event.
+// Messages of commits not created by ORBOS result in their first line being the event.
+func ParseCommitRecord(record string) (evt string, fields map[string]string) {
+	fields = make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(record), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "An error occurred" {
+			continue
+		}
+		if sep := strings.Index(line, ": "); sep > 0 && !strings.ContainsAny(line[:sep], " \t") {
+			fields[line[:sep]] = line[sep+2:]
+			continue
+		}
+		if evt == "" {
+			evt = line
+		}
+	}
+	if evt == "" {
+		evt = fields["msg"]
+	}
+	return evt, fields
+}
@@ -0,0 +1,244 @@
+package mntr
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindClient   SpanKind = 3
+)
+
+// Span is a timed operation of a trace. Spans started from a monitor carrying a span become its children.
+// All methods are safe to call on a nil span, which is what monitors without exporter return
+type Span struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	ParentID   [8]byte
+	Name       string
+	Kind       SpanKind
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]string
+	Err        error
+
+	mux      sync.Mutex
+	ended    bool
+	exporter SpanExporter
+	scope    *spanScope
+}
+
+// spanScope tracks the entered spans of a sequential operator loop, innermost last
+type spanScope struct {
+	mux     sync.Mutex
+	entered []*Span
+}
+
+// parent returns the innermost entered span if the monitors own span is unset or one of the entered spans.
+// Otherwise, the own span was started within the innermost entered span and is the parent
+func (s *spanScope) parent(own *Span) *Span {
+	if s == nil {
+		return own
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if len(s.entered) == 0 {
+		return own
+	}
+	innermost := s.entered[len(s.entered)-1]
+	if own == nil {
+		return innermost
+	}
+	for _, entered := range s.entered {
+		if entered == own {
+			return innermost
+		}
+	}
+	return own
+}
+
+func (s *spanScope) enter(span *Span) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.entered = append(s.entered, span)
+}
+
+func (s *spanScope) leave(span *Span) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for idx := range s.entered {
+		if s.entered[idx] == span {
+			s.entered = append(s.entered[:idx], s.entered[idx+1:]...)
+			return
+		}
+	}
+}
+
+// SpanExporter receives all ended spans
+type SpanExporter interface {
+	Export(span *Span)
+}
+
+// WithSpanExporter returns a monitor which records spans and passes them to the exporter when they end
+func (m Monitor) WithSpanExporter(exporter SpanExporter) Monitor {
+	m.exporter = exporter
+	return m
+}
+
+// WithSpanScope returns a monitor whose derived monitors share a scope of entered spans.
+// Components capturing a monitor of the scope at construction, like git clients and SSH machines,
+// start their spans as children of the innermost span entered by EnterSpan.
+// Use it for processes which enter spans sequentially, like an operators reconcile loop
+func (m Monitor) WithSpanScope() Monitor {
+	m.scope = &spanScope{}
+	return m
+}
+
+// EnterSpan starts a span like StartSpan. Until it ends, it is the parent of spans started by the monitors of the scope
+func (m Monitor) EnterSpan(name string) (Monitor, *Span) {
+	m, span := m.startSpan(name, SpanKindInternal)
+	if span != nil && m.scope != nil {
+		span.scope = m.scope
+		m.scope.enter(span)
+	}
+	return m, span
+}
+
+// StartSpan starts a span with the monitors fields as attributes.
+// The returned monitor carries the span, so it reports errors to it and spans started from it become its children.
+func (m Monitor) StartSpan(name string) (Monitor, *Span) {
+	return m.startSpan(name, SpanKindInternal)
+}
+
+func (m Monitor) startSpan(name string, kind SpanKind) (Monitor, *Span) {
+	if m.exporter == nil {
+		return m, nil
+	}
+
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		StartTime:  time.Now(),
+		Attributes: normalize(m.Fields),
+		exporter:   m.exporter,
+	}
+
+	if parent := m.scope.parent(m.span); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else {
+		randomID(span.TraceID[:])
+	}
+	randomID(span.SpanID[:])
+
+	m.span = span
+	return m, span
+}
+
+// SetAttribute adds an attribute to the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.Attributes[key] = value
+}
+
+// End ends the span and exports it. A span with an error or an error reported by its monitor is failed.
+// Only the first call has an effect
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mux.Lock()
+	if s.ended {
+		s.mux.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	if err != nil {
+		s.Err = err
+	}
+	s.mux.Unlock()
+
+	if s.scope != nil {
+		s.scope.leave(s)
+	}
+
+	s.exporter.Export(s)
+}
+
+func (s *Span) recordError(err error) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.Err == nil {
+		s.Err = err
+	}
+}
+
+// RoundTripper returns a transport which records a client span per request
+func (m Monitor) RoundTripper(base http.RoundTripper) http.RoundTripper {
+	if m.exporter == nil {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracingTransport{monitor: m, base: base}
+}
+
+type tracingTransport struct {
+	monitor Monitor
+	base    http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	_, span := t.monitor.startSpan(fmt.Sprintf("%s %s", req.Method, req.URL.Host), SpanKindClient)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	defer func() {
+		if err == nil && resp.StatusCode >= 400 {
+			span.recordError(fmt.Errorf("status %s", resp.Status))
+		}
+		if resp != nil {
+			span.SetAttribute("http.status_code", fmt.Sprintf("%d", resp.StatusCode))
+		}
+		span.End(err)
+	}()
+	return t.base.RoundTrip(req)
+}
+
+func randomID(id []byte) {
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+}
+
+func (s *Span) traceID() string {
+	return hex.EncodeToString(s.TraceID[:])
+}
+
+func (s *Span) spanID() string {
+	return hex.EncodeToString(s.SpanID[:])
+}
+
+func (s *Span) parentID() string {
+	if s.ParentID == [8]byte{} {
+		return ""
+	}
+	return hex.EncodeToString(s.ParentID[:])
+}
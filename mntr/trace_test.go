@@ -0,0 +1,69 @@
+package mntr
+
+import (
+	"sync"
+	"testing"
+)
+
+type recordingExporter struct {
+	mux   sync.Mutex
+	spans map[string]*Span
+}
+
+func (r *recordingExporter) Export(span *Span) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.spans[span.Name] = span
+}
+
+func TestSpanScope(t *testing.T) {
+
+	exporter := &recordingExporter{spans: make(map[string]*Span)}
+	monitor := Monitor{}.WithSpanExporter(exporter).WithSpanScope()
+
+	// Captured at construction, like the monitors of git clients
+	captured := monitor.WithField("component", "git")
+
+	iterationMonitor, iteration := monitor.EnterSpan("iteration")
+	// Captured within the iteration, like the monitors of SSH machines built while adapting
+	capturedInIteration := iterationMonitor.WithField("component", "ssh")
+
+	queryMonitor, query := iterationMonitor.EnterSpan("query")
+	_, clone := captured.StartSpan("clone")
+	clone.End(nil)
+	_, execute := capturedInIteration.StartSpan("execute")
+	execute.End(nil)
+	_, explicit := queryMonitor.StartSpan("explicit")
+	nestedMonitor, nested := queryMonitor.StartSpan("nested")
+	_, grandchild := nestedMonitor.StartSpan("grandchild")
+	grandchild.End(nil)
+	nested.End(nil)
+	explicit.End(nil)
+	query.End(nil)
+
+	_, push := captured.StartSpan("push")
+	push.End(nil)
+	iteration.End(nil)
+
+	_, after := captured.StartSpan("after")
+	after.End(nil)
+
+	for child, parent := range map[string]string{
+		"query":      "iteration",
+		"clone":      "query",
+		"execute":    "query",
+		"explicit":   "query",
+		"nested":     "query",
+		"grandchild": "nested",
+		"push":       "iteration",
+	} {
+		childSpan, parentSpan := exporter.spans[child], exporter.spans[parent]
+		if childSpan.ParentID != parentSpan.SpanID || childSpan.TraceID != parentSpan.TraceID {
+			t.Errorf("expected span %s to be a child of %s", child, parent)
+		}
+	}
+
+	if exporter.spans["after"].ParentID != [8]byte{} {
+		t.Error("expected spans started after the scope was left to be root spans")
+	}
+}